@@ -0,0 +1,10 @@
+// Package api holds the API contracts: the OpenAPI document of the REST API
+// and, under proto, the gRPC definitions.
+package api
+
+import _ "embed"
+
+// OpenAPI is the OpenAPI 3.1 document of the REST API.
+//
+//go:embed openapi.json
+var OpenAPI []byte
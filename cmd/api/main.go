@@ -2,20 +2,35 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
+	"expvar"
+	"fmt"
 	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/segmentio/kafka-go"
 
+	"github.com/giannuccilli/user-api/api"
 	"github.com/giannuccilli/user-api/internal/config"
+	"github.com/giannuccilli/user-api/internal/domain"
+	"github.com/giannuccilli/user-api/internal/emailaddr"
+	"github.com/giannuccilli/user-api/internal/grpcapi"
 	"github.com/giannuccilli/user-api/internal/handler"
+	"github.com/giannuccilli/user-api/internal/mailer"
 	"github.com/giannuccilli/user-api/internal/notifier"
+	"github.com/giannuccilli/user-api/internal/ratelimit"
+	"github.com/giannuccilli/user-api/internal/repository/cache"
 	"github.com/giannuccilli/user-api/internal/repository/postgres"
 	"github.com/giannuccilli/user-api/internal/service"
+	"github.com/giannuccilli/user-api/internal/token"
+	"github.com/giannuccilli/user-api/pkg/events"
 )
 
 func main() {
@@ -46,7 +61,16 @@ func main() {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	pool, err := pgxpool.New(ctx, cfg.DatabaseURL)
+	poolConfig, err := pgxpool.ParseConfig(cfg.DatabaseURL)
+	if err != nil {
+		logger.Error("invalid DATABASE_URL", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+	if cfg.DatabaseRowLevelSecurity {
+		postgres.EnableRowLevelSecurity(poolConfig)
+	}
+
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
 	if err != nil {
 		logger.Error("failed to connect to database", slog.String("error", err.Error()))
 		os.Exit(1)
@@ -59,21 +83,166 @@ func main() {
 	}
 	logger.Info("connected to database")
 
-	userRepo := postgres.NewUserRepository(pool)
+	var userRepo domain.UserRepository = postgres.NewUserRepository(pool)
+	var cachedRepo *cache.UserRepository
+	if cfg.UserCacheSize > 0 {
+		cachedRepo = cache.NewUserRepository(userRepo, cache.NewLRU(int(cfg.UserCacheSize)), cache.WithTTL(cfg.UserCacheTTL))
+		expvar.Publish("userCache", expvar.Func(func() any { return cachedRepo.Stats() }))
+		userRepo = cachedRepo
+	}
 	failedEventRepo := postgres.NewFailedEventRepository(pool)
-	userNotifier := notifier.NewNotifier(cfg, logger, failedEventRepo)
+	auditRepo := postgres.NewAuditRepository(pool)
+	attributeRegistry := service.NewAttributeRegistry(postgres.NewAttributeRepository(pool))
+	idempotencyRepo := postgres.NewIdempotencyRepository(pool)
+	webhookRepo := postgres.NewWebhookRepository(pool)
+	webhookNotifier := notifier.NewWebhookNotifier(webhookRepo, logger, notifier.WebhookOptions{
+		MaxAttempts:  cfg.WebhookMaxAttempts,
+		Backoff:      cfg.WebhookBackoff,
+		Timeout:      cfg.WebhookTimeout,
+		DisableAfter: cfg.WebhookDisableAfter,
+		PollInterval: cfg.WebhookPollInterval,
+
+		AllowPrivateNetworks: cfg.WebhookAllowPrivateNetworks,
+	})
+	userNotifier, err := notifier.NewNotifier(cfg, logger, failedEventRepo, webhookNotifier)
+	if err != nil {
+		logger.Error("invalid NOTIFIER_SINKS", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
 	defer userNotifier.Close()
 
-	userService := service.NewUserService(userRepo, userNotifier)
-	userHandler := handler.NewUserHandler(userService)
+	tokenSecret := []byte(cfg.EmailTokenSecret)
+	if len(tokenSecret) == 0 {
+		logger.Warn("EMAIL_TOKEN_SECRET not configured: using a random secret, email tokens will not survive restarts")
+		tokenSecret = make([]byte, 32)
+		if _, err := rand.Read(tokenSecret); err != nil {
+			logger.Error("failed to generate token secret", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+	}
+
+	mailSender := mailer.NewSender(cfg, logger)
+	if mailSender == nil && cfg.EmailVerificationRequired {
+		logger.Warn("EMAIL_VERIFICATION_REQUIRED ignored: SMTP_HOST not configured")
+	}
+	if mailSender == nil && !cfg.EmailChangeSkipConfirm {
+		logger.Warn("email changes are rejected: SMTP_HOST not configured and EMAIL_CHANGE_SKIP_CONFIRMATION not set")
+	}
+
+	serviceOpts := []service.Option{
+		service.WithLogger(logger),
+		service.WithAuditRepository(auditRepo),
+		service.WithAttributeRegistry(attributeRegistry),
+		service.WithIdentityRepository(postgres.NewIdentityRepository(pool)),
+		service.WithEmailVerification(mailSender, token.NewSigner(tokenSecret), service.VerificationConfig{
+			Required:              cfg.EmailVerificationRequired,
+			TokenTTL:              cfg.EmailTokenTTL,
+			VerifyURL:             cfg.EmailVerificationURL,
+			ConfirmEmailChangeURL: cfg.EmailChangeConfirmURL,
+
+			SkipEmailChangeConfirmation: cfg.EmailChangeSkipConfirm,
+		}),
+		service.WithEmailPolicy(emailaddr.NewPolicy(emailaddr.Config{
+			AllowedDomains:    cfg.EmailAllowedDomains,
+			DeniedDomains:     cfg.EmailDeniedDomains,
+			BlockDisposable:   cfg.EmailBlockDisposable,
+			StripPlusTag:      cfg.EmailStripPlusTag,
+			CollapseGmailDots: cfg.EmailCollapseGmailDots,
+		})),
+	}
+	if cfg.StatusTransitions != "" {
+		graph, err := service.ParseTransitionGraph(cfg.StatusTransitions)
+		if err != nil {
+			logger.Error("invalid STATUS_TRANSITIONS", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		serviceOpts = append(serviceOpts, service.WithTransitionGraph(graph))
+	}
+
+	userService := service.NewUserService(userRepo, userNotifier, serviceOpts...)
+
+	schedulerCtx, stopScheduler := context.WithCancel(context.Background())
+	defer stopScheduler()
+	go service.NewReactivationScheduler(userService, cfg.SuspensionCheckInterval, logger).Run(schedulerCtx)
+	go purgePeriodically(schedulerCtx, time.Hour, logger, "idempotency keys", func(ctx context.Context) (int, error) {
+		return idempotencyRepo.DeleteExpired(ctx, time.Now())
+	})
+	go webhookNotifier.Run(schedulerCtx)
+	if cachedRepo != nil && cfg.KafkaBrokers != "" {
+		go consumeCacheInvalidations(schedulerCtx, cfg, cachedRepo, logger)
+	}
+	go purgePeriodically(schedulerCtx, time.Hour, logger, "webhook deliveries", func(ctx context.Context) (int, error) {
+		return webhookRepo.DeleteDeliveriesBefore(ctx, time.Now().Add(-cfg.WebhookDeliveryRetention))
+	})
+
+	decodeOpts := handler.DecodeOptions{
+		MaxBodyBytes:       cfg.MaxRequestBodyBytes,
+		AllowUnknownFields: cfg.AllowUnknownJSONFields,
+	}
+	userHandler := handler.NewUserHandler(userService, handler.WithDecodeOptions(decodeOpts))
+	attributeHandler := handler.NewAttributeHandler(attributeRegistry, decodeOpts)
+	tenantHandler := handler.NewTenantHandler(service.NewTenantService(postgres.NewTenantRepository(pool)), decodeOpts)
+	webhookHandler := handler.NewWebhookHandler(service.NewWebhookService(webhookRepo, service.WebhookServiceOptions{
+		AllowPrivateNetworks: cfg.WebhookAllowPrivateNetworks,
+	}), decodeOpts)
+	graphqlHandler := handler.NewGraphQLHandler(userService, decodeOpts)
 
 	mux := http.NewServeMux()
 	userHandler.RegisterRoutes(mux)
+	attributeHandler.RegisterRoutes(mux)
+	tenantHandler.RegisterRoutes(mux)
+	webhookHandler.RegisterRoutes(mux)
+	graphqlHandler.RegisterRoutes(mux)
+	handler.NewOpenAPIHandler(api.OpenAPI).RegisterRoutes(mux)
 
-	wrappedMux := handler.Chain(mux,
-		handler.Recovery(logger),
+	tenantOpts := handler.TenantOptions{Claim: cfg.TenantClaim, Default: cfg.TenantDefault, TrustHeader: cfg.TenantTrustHeader}
+	if cfg.TenantRequired {
+		tenantOpts.Default = ""
+	}
+
+	middlewares := []func(http.Handler) http.Handler{
 		handler.Logging(logger),
+		handler.ErrorFormat(handler.ErrorOptions{
+			Format:   cfg.ErrorFormat,
+			TypeBase: cfg.ProblemTypeBaseURL,
+		}),
+		handler.Recovery(logger),
+	}
+	rateLimitOpts := handler.RateLimitOptions{TrustForwardedFor: cfg.RateLimitTrustForwardedFor}
+	idempotencyOpts := handler.IdempotencyOptions{
+		TTL:          cfg.IdempotencyTTL,
+		Lease:        cfg.IdempotencyLease,
+		MaxBodyBytes: cfg.MaxRequestBodyBytes,
+	}
+	var limiter *ratelimit.Limiter
+	if cfg.RateLimitDefault != "" || cfg.RateLimitRoutes != "" {
+		var purge func(context.Context) (int, error)
+		limiter, purge, err = newRateLimiter(cfg, pool)
+		if err != nil {
+			logger.Error("invalid rate limit configuration", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		if purge != nil {
+			go purgePeriodically(schedulerCtx, time.Hour, logger, "rate limit buckets", purge)
+		}
+		middlewares = append(middlewares, handler.RateLimit(limiter, logger, rateLimitOpts))
+	}
+	middlewares = append(middlewares,
+		handler.Actor(),
+		handler.Tenant(tenantOpts),
+		handler.Idempotency(idempotencyRepo, logger, idempotencyOpts),
 	)
+	if cfg.OpenAPIValidation {
+		validate, err := handler.OpenAPIValidation(api.OpenAPI, logger, handler.OpenAPIValidationOptions{
+			MaxBodyBytes: cfg.MaxRequestBodyBytes,
+		})
+		if err != nil {
+			logger.Error("invalid OpenAPI spec", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		middlewares = append(middlewares, validate)
+	}
+	wrappedMux := handler.Chain(mux, middlewares...)
 
 	server := &http.Server{
 		Addr:         ":" + cfg.Port,
@@ -90,11 +259,56 @@ func main() {
 		}
 	}()
 
+	// The admin server exposes process internals, so it listens apart from
+	// the public API, on the loopback interface by default.
+	var adminServer *http.Server
+	if cfg.AdminAddr != "" {
+		adminMux := http.NewServeMux()
+		adminMux.Handle("GET /debug/vars", expvar.Handler())
+		adminServer = &http.Server{
+			Addr:         cfg.AdminAddr,
+			Handler:      adminMux,
+			ReadTimeout:  cfg.ReadTimeout,
+			WriteTimeout: cfg.WriteTimeout,
+		}
+
+		go func() {
+			logger.Info("starting admin server", slog.String("addr", cfg.AdminAddr))
+			if err := adminServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				logger.Error("admin server error", slog.String("error", err.Error()))
+				os.Exit(1)
+			}
+		}()
+	}
+
+	grpcServer := grpcapi.NewServer(userService, logger, grpcapi.Options{
+		Tenant:             tenantOpts,
+		RateLimiter:        limiter,
+		RateLimit:          rateLimitOpts,
+		Idempotency:        idempotencyRepo,
+		IdempotencyOptions: idempotencyOpts,
+		Reflection:         cfg.GRPCReflection,
+	})
+	grpcListener, err := net.Listen("tcp", ":"+cfg.GRPCPort)
+	if err != nil {
+		logger.Error("failed to listen for gRPC", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+
+	go func() {
+		logger.Info("starting gRPC server", slog.String("port", cfg.GRPCPort))
+		if err := grpcServer.Serve(grpcListener); err != nil {
+			logger.Error("gRPC server error", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+	}()
+
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 
 	logger.Info("shutting down server...")
+	stopScheduler()
 
 	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer shutdownCancel()
@@ -103,6 +317,107 @@ func main() {
 		logger.Error("server forced to shutdown", slog.String("error", err.Error()))
 		os.Exit(1)
 	}
+	grpcServer.GracefulStop()
+	if adminServer != nil {
+		adminServer.Shutdown(shutdownCtx)
+	}
 
 	logger.Info("server stopped")
 }
+
+// newRateLimiter builds the limiter from the RATE_LIMIT_* settings. The
+// returned purge function, if any, removes idle buckets from a shared store.
+func newRateLimiter(cfg *config.Config, pool *pgxpool.Pool) (*ratelimit.Limiter, func(context.Context) (int, error), error) {
+	var fallback ratelimit.Limit
+	if cfg.RateLimitDefault != "" {
+		limit, err := ratelimit.ParseLimit(cfg.RateLimitDefault)
+		if err != nil {
+			return nil, nil, err
+		}
+		fallback = limit
+	}
+
+	rules, err := ratelimit.ParseRules(cfg.RateLimitRoutes)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var store ratelimit.Store
+	var purge func(context.Context) (int, error)
+	switch cfg.RateLimitStore {
+	case "memory":
+		store = ratelimit.NewMemoryStore()
+	case "postgres":
+		pgStore := postgres.NewRateLimitStore(pool)
+		store = pgStore
+
+		// A bucket idle for longer than its period is full again and can be
+		// dropped.
+		idle := fallback.Per
+		for _, rule := range rules {
+			idle = max(idle, rule.Limit.Per)
+		}
+		purge = func(ctx context.Context) (int, error) {
+			return pgStore.DeleteIdle(ctx, time.Now().Add(-idle))
+		}
+	default:
+		return nil, nil, fmt.Errorf("unknown RATE_LIMIT_STORE %q", cfg.RateLimitStore)
+	}
+
+	limiter, err := ratelimit.NewLimiter(store, fallback, rules)
+	if err != nil {
+		return nil, nil, err
+	}
+	return limiter, purge, nil
+}
+
+func purgePeriodically(ctx context.Context, interval time.Duration, logger *slog.Logger, name string, purge func(context.Context) (int, error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			count, err := purge(ctx)
+			if err != nil {
+				logger.Error("failed to purge "+name, slog.String("error", err.Error()))
+				continue
+			}
+			if count > 0 {
+				logger.Info("purged "+name, slog.Int("count", count))
+			}
+		}
+	}
+}
+
+// consumeCacheInvalidations evicts the users of the events on KAFKA_TOPIC from
+// the cache, so that writes made by other replicas are not served stale until
+// USER_CACHE_TTL. Every replica needs every event, so each one reads in its
+// own consumer group, starting from the latest event.
+func consumeCacheInvalidations(ctx context.Context, cfg *config.Config, cachedRepo *cache.UserRepository, logger *slog.Logger) {
+	group := cfg.UserCacheConsumerGroup
+	if group == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			logger.Error("cache invalidation disabled: USER_CACHE_CONSUMER_GROUP not set", slog.String("error", err.Error()))
+			return
+		}
+		group = "user-api-cache-" + hostname
+	}
+
+	consumer := events.NewConsumer(kafka.NewReader(kafka.ReaderConfig{
+		Brokers:     strings.Split(cfg.KafkaBrokers, ","),
+		Topic:       cfg.KafkaTopic,
+		GroupID:     group,
+		StartOffset: kafka.LastOffset,
+	}), events.WithLogger(logger), events.WithAttempts(1, 0))
+	defer consumer.Close()
+	consumer.HandleOther(cachedRepo.HandleEvent)
+
+	logger.Info("cache invalidation consumer started", slog.String("group", group))
+	if err := consumer.Run(ctx); err != nil {
+		logger.Error("cache invalidation consumer stopped", slog.String("error", err.Error()))
+	}
+}
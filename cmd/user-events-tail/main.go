@@ -0,0 +1,100 @@
+// Command user-events-tail prints the user events published to Kafka, for
+// debugging.
+//
+//	go run ./cmd/user-events-tail -from-beginning -type user.suspended -tenant acme
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/google/uuid"
+	"github.com/segmentio/kafka-go"
+
+	"github.com/giannuccilli/user-api/pkg/events"
+)
+
+func main() {
+	brokers := flag.String("brokers", envOr("KAFKA_BROKERS", "localhost:9092"), "comma separated Kafka brokers")
+	topic := flag.String("topic", envOr("KAFKA_TOPIC", "user-events"), "topic to read")
+	fromBeginning := flag.Bool("from-beginning", false, "print the events already in the topic, not only new ones")
+	types := flag.String("type", "", "comma separated event types to print, e.g. user.created,user.deleted")
+	tenant := flag.String("tenant", "", "print only the events of this tenant")
+	raw := flag.Bool("json", false, "print each event as JSON")
+	flag.Parse()
+
+	startOffset := kafka.LastOffset
+	if *fromBeginning {
+		startOffset = kafka.FirstOffset
+	}
+	// A new group per run reads every partition from startOffset without
+	// affecting other consumers.
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:     strings.Split(*brokers, ","),
+		Topic:       *topic,
+		GroupID:     "user-events-tail-" + uuid.NewString(),
+		StartOffset: startOffset,
+	})
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	consumer := events.NewConsumer(reader, events.WithLogger(logger))
+	defer consumer.Close()
+
+	printEvent := func(ctx context.Context, event events.Event) error {
+		if *tenant != "" && event.TenantID != *tenant {
+			return nil
+		}
+		if *raw {
+			return json.NewEncoder(os.Stdout).Encode(event)
+		}
+		_, err := fmt.Println(formatEvent(event))
+		return err
+	}
+	if *types == "" {
+		consumer.HandleOther(printEvent)
+	} else {
+		for _, t := range strings.Split(*types, ",") {
+			consumer.Handle(events.Type(strings.TrimSpace(t)), printEvent)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	fmt.Fprintf(os.Stderr, "reading %s from %s, press Ctrl+C to stop\n", *topic, *brokers)
+	if err := consumer.Run(ctx); err != nil {
+		logger.Error("consumer stopped", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+}
+
+func formatEvent(e events.Event) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %-22s tenant=%s user=%s event=%s",
+		e.Timestamp.Format("2006-01-02T15:04:05.000Z07:00"), e.Type, e.TenantID, e.Data.UserID, e.ID)
+	if e.Data.Reason != "" {
+		fmt.Fprintf(&b, " reason=%s", e.Data.Reason)
+	}
+	if e.Data.SuspendedUntil != nil {
+		fmt.Fprintf(&b, " until=%s", e.Data.SuspendedUntil.Format("2006-01-02T15:04:05Z07:00"))
+	}
+	if len(e.Data.Attributes) > 0 {
+		attrs, _ := json.Marshal(e.Data.Attributes)
+		fmt.Fprintf(&b, " attributes=%s", attrs)
+	}
+	return b.String()
+}
+
+func envOr(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
@@ -2,28 +2,160 @@ package config
 
 import (
 	"os"
+	"strconv"
+	"strings"
 	"time"
 )
 
 type Config struct {
 	Port         string
+	GRPCPort     string
+	AdminAddr    string
 	DatabaseURL  string
 	LogLevel     string
 	ReadTimeout  time.Duration
 	WriteTimeout time.Duration
 	KafkaBrokers string
 	KafkaTopic   string
+
+	GRPCReflection bool
+
+	NotifierSinks []string
+
+	DatabaseRowLevelSecurity bool
+
+	ErrorFormat        string
+	ProblemTypeBaseURL string
+
+	MaxRequestBodyBytes    int64
+	AllowUnknownJSONFields bool
+	OpenAPIValidation      bool
+
+	IdempotencyTTL   time.Duration
+	IdempotencyLease time.Duration
+
+	UserCacheSize int64
+	UserCacheTTL  time.Duration
+
+	UserCacheConsumerGroup string
+
+	TenantDefault     string
+	TenantRequired    bool
+	TenantClaim       string
+	TenantTrustHeader bool
+
+	RateLimitDefault           string
+	RateLimitRoutes            string
+	RateLimitStore             string
+	RateLimitTrustForwardedFor bool
+
+	WebhookMaxAttempts       int
+	WebhookBackoff           time.Duration
+	WebhookTimeout           time.Duration
+	WebhookDisableAfter      int
+	WebhookPollInterval      time.Duration
+	WebhookDeliveryRetention time.Duration
+
+	WebhookAllowPrivateNetworks bool
+
+	StatusTransitions       string
+	SuspensionCheckInterval time.Duration
+
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUsername string
+	SMTPPassword string
+	SMTPFrom     string
+	SMTPTimeout  time.Duration
+
+	EmailTokenSecret          string
+	EmailTokenTTL             time.Duration
+	EmailVerificationRequired bool
+	EmailVerificationURL      string
+	EmailChangeConfirmURL     string
+	EmailChangeSkipConfirm    bool
+
+	EmailAllowedDomains    []string
+	EmailDeniedDomains     []string
+	EmailBlockDisposable   bool
+	EmailStripPlusTag      bool
+	EmailCollapseGmailDots bool
 }
 
 func Load() *Config {
 	return &Config{
 		Port:         getEnv("PORT", "8080"),
+		GRPCPort:     getEnv("GRPC_PORT", "9090"),
+		AdminAddr:    getEnv("ADMIN_ADDR", "localhost:9091"),
 		DatabaseURL:  getEnv("DATABASE_URL", ""),
 		LogLevel:     getEnv("LOG_LEVEL", "info"),
 		ReadTimeout:  getDuration("READ_TIMEOUT", 5*time.Second),
 		WriteTimeout: getDuration("WRITE_TIMEOUT", 10*time.Second),
 		KafkaBrokers: getEnv("KAFKA_BROKERS", ""),
 		KafkaTopic:   getEnv("KAFKA_TOPIC", "user-events"),
+
+		GRPCReflection: getBool("GRPC_REFLECTION", false),
+
+		NotifierSinks: getList("NOTIFIER_SINKS"),
+
+		DatabaseRowLevelSecurity: getBool("DATABASE_ROW_LEVEL_SECURITY", false),
+
+		ErrorFormat:        getEnv("ERROR_FORMAT", "legacy"),
+		ProblemTypeBaseURL: getEnv("PROBLEM_TYPE_BASE_URL", ""),
+
+		MaxRequestBodyBytes:    getInt64("MAX_REQUEST_BODY_BYTES", 1<<20),
+		AllowUnknownJSONFields: getBool("JSON_ALLOW_UNKNOWN_FIELDS", true),
+		OpenAPIValidation:      getBool("OPENAPI_VALIDATION", false),
+
+		IdempotencyTTL:   getDuration("IDEMPOTENCY_TTL", 24*time.Hour),
+		IdempotencyLease: getDuration("IDEMPOTENCY_LEASE", time.Minute),
+
+		UserCacheSize: getInt64("USER_CACHE_SIZE", 0),
+		UserCacheTTL:  getDuration("USER_CACHE_TTL", time.Minute),
+
+		UserCacheConsumerGroup: getEnv("USER_CACHE_CONSUMER_GROUP", ""),
+
+		TenantDefault:     getEnv("TENANT_DEFAULT", "default"),
+		TenantRequired:    getBool("TENANT_REQUIRED", false),
+		TenantClaim:       getEnv("TENANT_CLAIM", "tenant_id"),
+		TenantTrustHeader: getBool("TENANT_TRUST_HEADER", false),
+
+		RateLimitDefault:           getEnv("RATE_LIMIT_DEFAULT", ""),
+		RateLimitRoutes:            getEnv("RATE_LIMIT_ROUTES", ""),
+		RateLimitStore:             getEnv("RATE_LIMIT_STORE", "memory"),
+		RateLimitTrustForwardedFor: getBool("RATE_LIMIT_TRUST_FORWARDED_FOR", false),
+
+		WebhookMaxAttempts:       int(getInt64("WEBHOOK_MAX_ATTEMPTS", 6)),
+		WebhookBackoff:           getDuration("WEBHOOK_BACKOFF", 30*time.Second),
+		WebhookTimeout:           getDuration("WEBHOOK_TIMEOUT", 10*time.Second),
+		WebhookDisableAfter:      int(getInt64("WEBHOOK_DISABLE_AFTER", 10)),
+		WebhookPollInterval:      getDuration("WEBHOOK_POLL_INTERVAL", 5*time.Second),
+		WebhookDeliveryRetention: getDuration("WEBHOOK_DELIVERY_RETENTION", 30*24*time.Hour),
+
+		WebhookAllowPrivateNetworks: getBool("WEBHOOK_ALLOW_PRIVATE_NETWORKS", false),
+
+		StatusTransitions:       getEnv("STATUS_TRANSITIONS", ""),
+		SuspensionCheckInterval: getDuration("SUSPENSION_CHECK_INTERVAL", time.Minute),
+
+		SMTPHost:     getEnv("SMTP_HOST", ""),
+		SMTPPort:     getEnv("SMTP_PORT", "587"),
+		SMTPUsername: getEnv("SMTP_USERNAME", ""),
+		SMTPPassword: getEnv("SMTP_PASSWORD", ""),
+		SMTPFrom:     getEnv("SMTP_FROM", "no-reply@localhost"),
+		SMTPTimeout:  getDuration("SMTP_TIMEOUT", 10*time.Second),
+
+		EmailTokenSecret:          getEnv("EMAIL_TOKEN_SECRET", ""),
+		EmailTokenTTL:             getDuration("EMAIL_TOKEN_TTL", 24*time.Hour),
+		EmailVerificationRequired: getBool("EMAIL_VERIFICATION_REQUIRED", false),
+		EmailVerificationURL:      getEnv("EMAIL_VERIFICATION_URL", ""),
+		EmailChangeConfirmURL:     getEnv("EMAIL_CHANGE_CONFIRM_URL", ""),
+		EmailChangeSkipConfirm:    getBool("EMAIL_CHANGE_SKIP_CONFIRMATION", false),
+
+		EmailAllowedDomains:    getList("EMAIL_ALLOWED_DOMAINS"),
+		EmailDeniedDomains:     getList("EMAIL_DENIED_DOMAINS"),
+		EmailBlockDisposable:   getBool("EMAIL_BLOCK_DISPOSABLE", true),
+		EmailStripPlusTag:      getBool("EMAIL_STRIP_PLUS_TAG", false),
+		EmailCollapseGmailDots: getBool("EMAIL_COLLAPSE_GMAIL_DOTS", false),
 	}
 }
 
@@ -42,3 +174,35 @@ func getDuration(key string, defaultValue time.Duration) time.Duration {
 	}
 	return defaultValue
 }
+
+func getInt64(key string, defaultValue int64) int64 {
+	if value := os.Getenv(key); value != "" {
+		if n, err := strconv.ParseInt(value, 10, 64); err == nil && n > 0 {
+			return n
+		}
+	}
+	return defaultValue
+}
+
+func getBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	}
+	return defaultValue
+}
+
+func getList(key string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return nil
+	}
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,25 @@
+package config
+
+import "testing"
+
+func TestLoad_DatabaseRowLevelSecurity(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  bool
+	}{
+		{"unset", "", false},
+		{"enabled", "true", true},
+		{"disabled", "false", false},
+		{"invalid", "yes please", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("DATABASE_ROW_LEVEL_SECURITY", tt.value)
+			if got := Load().DatabaseRowLevelSecurity; got != tt.want {
+				t.Errorf("DatabaseRowLevelSecurity = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
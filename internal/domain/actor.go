@@ -0,0 +1,44 @@
+package domain
+
+import "context"
+
+const (
+	// RoleAdmin administers the users and webhooks of its tenant.
+	RoleAdmin = "admin"
+	// RolePlatformAdmin administers what is shared by every tenant: the
+	// tenants themselves and the attribute definitions.
+	RolePlatformAdmin = "platform-admin"
+)
+
+type Actor struct {
+	ID    string   `json:"id"`
+	Roles []string `json:"roles,omitempty"`
+}
+
+func (a Actor) HasRole(role string) bool {
+	for _, r := range a.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+func (a Actor) IsAdmin() bool {
+	return a.HasRole(RoleAdmin)
+}
+
+func (a Actor) IsPlatformAdmin() bool {
+	return a.HasRole(RolePlatformAdmin)
+}
+
+type actorContextKey struct{}
+
+func ContextWithActor(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, actorContextKey{}, actor)
+}
+
+func ActorFromContext(ctx context.Context) Actor {
+	actor, _ := ctx.Value(actorContextKey{}).(Actor)
+	return actor
+}
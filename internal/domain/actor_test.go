@@ -0,0 +1,59 @@
+package domain
+
+import (
+	"context"
+	"testing"
+)
+
+func TestActor_IsAdmin(t *testing.T) {
+	tests := []struct {
+		name  string
+		actor Actor
+		want  bool
+	}{
+		{"no roles", Actor{ID: "u1"}, false},
+		{"other role", Actor{ID: "u1", Roles: []string{"support"}}, false},
+		{"admin role", Actor{ID: "u1", Roles: []string{"support", RoleAdmin}}, true},
+		{"platform admin role", Actor{ID: "u1", Roles: []string{RolePlatformAdmin}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.actor.IsAdmin(); got != tt.want {
+				t.Errorf("IsAdmin() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestActor_IsPlatformAdmin(t *testing.T) {
+	tests := []struct {
+		name  string
+		actor Actor
+		want  bool
+	}{
+		{"no roles", Actor{ID: "u1"}, false},
+		{"admin role", Actor{ID: "u1", Roles: []string{RoleAdmin}}, false},
+		{"platform admin role", Actor{ID: "u1", Roles: []string{"support", RolePlatformAdmin}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.actor.IsPlatformAdmin(); got != tt.want {
+				t.Errorf("IsPlatformAdmin() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestActorFromContext(t *testing.T) {
+	if actor := ActorFromContext(context.Background()); actor.ID != "" || len(actor.Roles) != 0 {
+		t.Errorf("ActorFromContext() on empty context = %+v, want zero value", actor)
+	}
+
+	ctx := ContextWithActor(context.Background(), Actor{ID: "u1", Roles: []string{RoleAdmin}})
+	actor := ActorFromContext(ctx)
+	if actor.ID != "u1" || !actor.IsAdmin() {
+		t.Errorf("ActorFromContext() = %+v, want u1 with admin role", actor)
+	}
+}
@@ -0,0 +1,33 @@
+package domain
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+type Attributes map[string]any
+
+type AttributeDefinition struct {
+	Key         string          `json:"key"`
+	Description string          `json:"description,omitempty"`
+	Schema      json.RawMessage `json:"schema"`
+	CreatedAt   time.Time       `json:"createdAt"`
+	UpdatedAt   time.Time       `json:"updatedAt"`
+}
+
+type PutAttributeDefinitionRequest struct {
+	Description string          `json:"description,omitempty"`
+	Schema      json.RawMessage `json:"schema"`
+}
+
+type AttributeDefinitionList struct {
+	Data []AttributeDefinition `json:"data"`
+}
+
+type AttributeRepository interface {
+	List(ctx context.Context) ([]AttributeDefinition, error)
+	Get(ctx context.Context, key string) (*AttributeDefinition, error)
+	Save(ctx context.Context, def *AttributeDefinition) error
+	Delete(ctx context.Context, key string) error
+}
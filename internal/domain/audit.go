@@ -0,0 +1,30 @@
+package domain
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type AuditEntry struct {
+	ID         uuid.UUID        `json:"id"`
+	UserID     uuid.UUID        `json:"userId"`
+	Action     EventType        `json:"action"`
+	ActorID    string           `json:"actorId,omitempty"`
+	FromStatus UserStatus       `json:"fromStatus"`
+	ToStatus   UserStatus       `json:"toStatus"`
+	Reason     SuspensionReason `json:"reason,omitempty"`
+	Note       string           `json:"note,omitempty"`
+	CreatedAt  time.Time        `json:"createdAt"`
+}
+
+type AuditList struct {
+	Data       []AuditEntry `json:"data"`
+	Pagination Pagination   `json:"pagination"`
+}
+
+type AuditRepository interface {
+	Save(ctx context.Context, entry *AuditEntry) error
+	ListByUser(ctx context.Context, userID uuid.UUID, limit, offset int) ([]AuditEntry, int, error)
+}
@@ -3,7 +3,52 @@ package domain
 import "errors"
 
 var (
-	ErrUserNotFound = errors.New("user not found")
-	ErrEmailExists  = errors.New("email already exists")
-	ErrInvalidInput = errors.New("invalid input")
+	ErrUserNotFound      = errors.New("user not found")
+	ErrAttributeNotFound = errors.New("attribute definition not found")
+	ErrIdentityNotFound  = errors.New("external identity not found")
+	ErrIdentityConflict  = errors.New("external identity linked to another user")
+	ErrTenantNotFound    = errors.New("tenant not found")
+	ErrTenantExists      = errors.New("tenant already exists")
+	ErrTenantNotEmpty    = errors.New("tenant has users")
+	ErrWebhookNotFound   = errors.New("webhook subscription not found")
+	ErrEmailExists       = errors.New("email already exists")
+	ErrInvalidInput      = errors.New("invalid input")
+
+	ErrInvalidTransition = errors.New("invalid status transition")
+	ErrForbidden         = errors.New("forbidden")
+
+	ErrInvalidToken         = errors.New("invalid token")
+	ErrTokenExpired         = errors.New("token expired")
+	ErrEmailAlreadyVerified = errors.New("email already verified")
+	ErrNotSupported         = errors.New("operation not supported")
+
+	ErrInvalidEmail          = errors.New("invalid email address")
+	ErrEmailDomainNotAllowed = errors.New("email domain not allowed")
+	ErrDisposableEmail       = errors.New("disposable email address")
 )
+
+// EmailError carries the reason an address was rejected. It matches both its
+// Kind and ErrInvalidInput with errors.Is.
+type EmailError struct {
+	Kind   error
+	Reason string
+}
+
+func (e *EmailError) Error() string {
+	return e.Kind.Error() + ": " + e.Reason
+}
+
+func (e *EmailError) Code() string {
+	switch e.Kind {
+	case ErrEmailDomainNotAllowed:
+		return "EMAIL_DOMAIN_NOT_ALLOWED"
+	case ErrDisposableEmail:
+		return "DISPOSABLE_EMAIL"
+	default:
+		return "INVALID_EMAIL"
+	}
+}
+
+func (e *EmailError) Unwrap() []error {
+	return []error{e.Kind, ErrInvalidInput}
+}
@@ -13,17 +13,39 @@ const (
 	EventTypeUserCreated EventType = "user.created"
 	EventTypeUserUpdated EventType = "user.updated"
 	EventTypeUserDeleted EventType = "user.deleted"
+
+	EventTypeUserSuspended   EventType = "user.suspended"
+	EventTypeUserReactivated EventType = "user.reactivated"
+	EventTypeUserActivated   EventType = "user.activated"
+	EventTypeUserDeactivated EventType = "user.deactivated"
+
+	EventTypeUserEmailVerified EventType = "user.email_verified"
+	EventTypeUserEmailChanged  EventType = "user.email_changed"
 )
 
+func (t EventType) IsValid() bool {
+	switch t {
+	case EventTypeUserCreated, EventTypeUserUpdated, EventTypeUserDeleted,
+		EventTypeUserSuspended, EventTypeUserReactivated, EventTypeUserActivated, EventTypeUserDeactivated,
+		EventTypeUserEmailVerified, EventTypeUserEmailChanged:
+		return true
+	}
+	return false
+}
+
 type UserEvent struct {
 	EventID   uuid.UUID `json:"eventId"`
 	EventType EventType `json:"eventType"`
+	TenantID  string    `json:"tenantId"`
 	Timestamp time.Time `json:"timestamp"`
 	Data      EventData `json:"data"`
 }
 
 type EventData struct {
-	UserID uuid.UUID `json:"userId"`
+	UserID         uuid.UUID        `json:"userId"`
+	Reason         SuspensionReason `json:"reason,omitempty"`
+	SuspendedUntil *time.Time       `json:"suspendedUntil,omitempty"`
+	Attributes     Attributes       `json:"attributes,omitempty"`
 }
 
 type FailedEvent struct {
@@ -31,6 +53,8 @@ type FailedEvent struct {
 	EventID   uuid.UUID `json:"eventId"`
 	EventType EventType `json:"eventType"`
 	UserID    uuid.UUID `json:"userId"`
+	// Sink is the notifier backend the event could not be delivered to.
+	Sink      string    `json:"sink"`
 	Payload   string    `json:"payload"`
 	Error     string    `json:"error"`
 	Attempts  int       `json:"attempts"`
@@ -39,9 +63,10 @@ type FailedEvent struct {
 }
 
 type UserNotifier interface {
-	NotifyCreated(ctx context.Context, userID uuid.UUID) error
-	NotifyUpdated(ctx context.Context, userID uuid.UUID) error
+	NotifyCreated(ctx context.Context, data EventData) error
+	NotifyUpdated(ctx context.Context, data EventData) error
 	NotifyDeleted(ctx context.Context, userID uuid.UUID) error
+	Notify(ctx context.Context, eventType EventType, data EventData) error
 	Close() error
 }
 
@@ -16,6 +16,12 @@ func TestEventType_Values(t *testing.T) {
 		{EventTypeUserCreated, "user.created"},
 		{EventTypeUserUpdated, "user.updated"},
 		{EventTypeUserDeleted, "user.deleted"},
+		{EventTypeUserSuspended, "user.suspended"},
+		{EventTypeUserReactivated, "user.reactivated"},
+		{EventTypeUserActivated, "user.activated"},
+		{EventTypeUserDeactivated, "user.deactivated"},
+		{EventTypeUserEmailVerified, "user.email_verified"},
+		{EventTypeUserEmailChanged, "user.email_changed"},
 	}
 
 	for _, tt := range tests {
@@ -23,8 +29,15 @@ func TestEventType_Values(t *testing.T) {
 			if string(tt.eventType) != tt.expected {
 				t.Errorf("EventType = %v, want %v", tt.eventType, tt.expected)
 			}
+			if !tt.eventType.IsValid() {
+				t.Errorf("EventType(%q).IsValid() = false, want true", tt.eventType)
+			}
 		})
 	}
+
+	if EventType("user.renamed").IsValid() {
+		t.Error(`EventType("user.renamed").IsValid() = true, want false`)
+	}
 }
 
 func TestUserEvent_JSON(t *testing.T) {
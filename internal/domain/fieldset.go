@@ -0,0 +1,45 @@
+package domain
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// UserFields are the JSON names of the User fields a client can select.
+var UserFields = []string{
+	"id", "tenantId", "email", "emailVerified", "pendingEmail", "firstName", "lastName",
+	"status", "statusReason", "suspendedUntil", "attributes", "createdAt", "updatedAt",
+}
+
+// FieldSet is a sparse fieldset of User JSON names. A nil FieldSet selects
+// every field; a parsed one always starts with "id".
+type FieldSet []string
+
+// ParseFieldSet parses a comma-separated list of UserFields, as sent in the
+// fields query parameter. An empty value selects every field.
+func ParseFieldSet(raw string) (FieldSet, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+
+	fields := FieldSet{"id"}
+	v := &ValidationError{}
+	for _, name := range strings.Split(raw, ",") {
+		name = strings.TrimSpace(name)
+		switch {
+		case !slices.Contains(UserFields, name):
+			v.Add("fields", FieldCodeInvalidValue, fmt.Sprintf("unknown field %q", name))
+		case !slices.Contains(fields, name):
+			fields = append(fields, name)
+		}
+	}
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+func (f FieldSet) Has(name string) bool {
+	return f == nil || slices.Contains(f, name)
+}
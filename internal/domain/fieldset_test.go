@@ -0,0 +1,53 @@
+package domain
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestParseFieldSet(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		want    FieldSet
+		wantErr bool
+	}{
+		{"empty selects everything", "", nil, false},
+		{"id is always included", "email", FieldSet{"id", "email"}, false},
+		{"spaces and duplicates", " email , id,email ", FieldSet{"id", "email"}, false},
+		{"several fields", "firstName,lastName,status", FieldSet{"id", "firstName", "lastName", "status"}, false},
+		{"unknown field", "email,password", nil, true},
+		{"empty item", "email,,id", nil, true},
+		{"internal field", "emailCanonical", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseFieldSet(tt.raw)
+			if tt.wantErr {
+				var v *ValidationError
+				if !errors.As(err, &v) || v.Fields[0].Field != "fields" {
+					t.Errorf("ParseFieldSet(%q) error = %v, want validation error on fields", tt.raw, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseFieldSet(%q) unexpected error = %v", tt.raw, err)
+			}
+			if !slices.Equal(got, tt.want) || (got == nil) != (tt.want == nil) {
+				t.Errorf("ParseFieldSet(%q) = %v, want %v", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFieldSet_Has(t *testing.T) {
+	if !FieldSet(nil).Has("email") {
+		t.Error("nil FieldSet should have every field")
+	}
+	fields := FieldSet{"id", "email"}
+	if !fields.Has("email") || fields.Has("status") {
+		t.Errorf("FieldSet%v.Has() mismatch", fields)
+	}
+}
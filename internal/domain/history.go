@@ -0,0 +1,20 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type UserDiff struct {
+	UserID  uuid.UUID     `json:"userId"`
+	From    time.Time     `json:"from"`
+	To      time.Time     `json:"to"`
+	Changes []FieldChange `json:"changes"`
+}
+
+type FieldChange struct {
+	Field string `json:"field"`
+	From  any    `json:"from"`
+	To    any    `json:"to"`
+}
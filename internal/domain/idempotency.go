@@ -0,0 +1,36 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// IdempotencyRecord is the stored outcome of a request sent with an
+// Idempotency-Key. StatusCode is zero while the first request is in flight;
+// ExpiresAt is then the end of its lease rather than of the stored response.
+type IdempotencyRecord struct {
+	ActorID     string
+	Key         string
+	Fingerprint string
+	StatusCode  int
+	Header      map[string][]string
+	Body        []byte
+	CreatedAt   time.Time
+	ExpiresAt   time.Time
+}
+
+func (r *IdempotencyRecord) Completed() bool {
+	return r.StatusCode != 0
+}
+
+type IdempotencyRepository interface {
+	// Reserve stores record as in flight. If an unexpired record already
+	// exists for the same actor and key it is returned instead and nothing
+	// is stored. An expired record, in flight or not, is taken over.
+	Reserve(ctx context.Context, record *IdempotencyRecord) (*IdempotencyRecord, error)
+	// Complete and Release act on the reservation made with record only,
+	// and do nothing once another request has taken it over.
+	Complete(ctx context.Context, record *IdempotencyRecord) error
+	Release(ctx context.Context, record *IdempotencyRecord) error
+	DeleteExpired(ctx context.Context, before time.Time) (int, error)
+}
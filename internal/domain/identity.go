@@ -0,0 +1,40 @@
+package domain
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ExternalIdentity maps a user to its ID in an external system such as an
+// identity provider or a CRM. ExternalID is unique per provider.
+type ExternalIdentity struct {
+	UserID     uuid.UUID `json:"userId"`
+	Provider   string    `json:"provider"`
+	ExternalID string    `json:"externalId"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+type LinkIdentityRequest struct {
+	Provider   string `json:"provider"`
+	ExternalID string `json:"externalId"`
+}
+
+type IdentityList struct {
+	Data []ExternalIdentity `json:"data"`
+}
+
+type IdentityRepository interface {
+	// Link stores the identity. Linking an identity that already belongs to
+	// the same user is a no-op; if it belongs to another user it returns
+	// ErrIdentityConflict.
+	Link(ctx context.Context, identity *ExternalIdentity) error
+	// CreateUser creates user and links identity to it in one transaction.
+	// If the identity is already linked it stores nothing and returns
+	// ErrIdentityConflict.
+	CreateUser(ctx context.Context, user *User, identity *ExternalIdentity) error
+	Unlink(ctx context.Context, userID uuid.UUID, provider, externalID string) error
+	Get(ctx context.Context, provider, externalID string) (*ExternalIdentity, error)
+	ListByUser(ctx context.Context, userID uuid.UUID) ([]ExternalIdentity, error)
+}
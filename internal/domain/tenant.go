@@ -0,0 +1,78 @@
+package domain
+
+import (
+	"context"
+	"regexp"
+	"time"
+)
+
+// DefaultTenantID is the tenant of the users created before multi-tenancy
+// and of requests that carry no tenant.
+const DefaultTenantID = "default"
+
+var tenantIDPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,62}$`)
+
+func IsValidTenantID(id string) bool {
+	return tenantIDPattern.MatchString(id)
+}
+
+type Tenant struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+type CreateTenantRequest struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type UpdateTenantRequest struct {
+	Name string `json:"name"`
+}
+
+type TenantList struct {
+	Data []Tenant `json:"data"`
+}
+
+type TenantRepository interface {
+	Create(ctx context.Context, tenant *Tenant) error
+	Get(ctx context.Context, id string) (*Tenant, error)
+	List(ctx context.Context) ([]Tenant, error)
+	Update(ctx context.Context, tenant *Tenant) error
+	// Delete fails with ErrTenantNotEmpty while the tenant still has users.
+	Delete(ctx context.Context, id string) error
+}
+
+type tenantContextKey struct{}
+
+func ContextWithTenant(ctx context.Context, tenantID string) context.Context {
+	return context.WithValue(ctx, tenantContextKey{}, tenantID)
+}
+
+// TenantFromContext returns the tenant of the request, or false if none was
+// set. Repositories scope their queries to DefaultTenantID in that case.
+func TenantFromContext(ctx context.Context) (string, bool) {
+	tenantID, ok := ctx.Value(tenantContextKey{}).(string)
+	return tenantID, ok && tenantID != ""
+}
+
+type allTenantsContextKey struct{}
+
+// ContextWithAllTenants marks ctx as belonging to a background job that works
+// across tenants. It only has an effect on contexts without a tenant; requests
+// never carry it.
+func ContextWithAllTenants(ctx context.Context) context.Context {
+	return context.WithValue(ctx, allTenantsContextKey{}, true)
+}
+
+// AllTenantsFromContext reports whether ctx has no tenant and was marked with
+// ContextWithAllTenants.
+func AllTenantsFromContext(ctx context.Context) bool {
+	if _, ok := TenantFromContext(ctx); ok {
+		return false
+	}
+	all, _ := ctx.Value(allTenantsContextKey{}).(bool)
+	return all
+}
@@ -0,0 +1,62 @@
+package domain
+
+import (
+	"context"
+	"testing"
+)
+
+func TestIsValidTenantID(t *testing.T) {
+	tests := []struct {
+		id   string
+		want bool
+	}{
+		{"default", true},
+		{"acme-corp", true},
+		{"42", true},
+		{"", false},
+		{"-acme", false},
+		{"Acme", false},
+		{"acme_corp", false},
+		{"acme/corp", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			if got := IsValidTenantID(tt.id); got != tt.want {
+				t.Errorf("IsValidTenantID(%q) = %v, want %v", tt.id, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTenantFromContext(t *testing.T) {
+	if id, ok := TenantFromContext(context.Background()); ok || id != "" {
+		t.Errorf("TenantFromContext() on empty context = %q, %v, want none", id, ok)
+	}
+
+	id, ok := TenantFromContext(ContextWithTenant(context.Background(), "acme"))
+	if !ok || id != "acme" {
+		t.Errorf("TenantFromContext() = %q, %v, want acme", id, ok)
+	}
+}
+
+func TestAllTenantsFromContext(t *testing.T) {
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want bool
+	}{
+		{"empty context", context.Background(), false},
+		{"tenant", ContextWithTenant(context.Background(), "acme"), false},
+		{"all tenants", ContextWithAllTenants(context.Background()), true},
+		{"tenant within all tenants", ContextWithTenant(ContextWithAllTenants(context.Background()), "acme"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := AllTenantsFromContext(tt.ctx); got != tt.want {
+				t.Errorf("AllTenantsFromContext() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -13,29 +13,112 @@ const (
 	UserStatusActive    UserStatus = "active"
 	UserStatusInactive  UserStatus = "inactive"
 	UserStatusSuspended UserStatus = "suspended"
+
+	UserStatusPendingVerification UserStatus = "pending_verification"
+)
+
+type SuspensionReason string
+
+const (
+	SuspensionReasonFraud           SuspensionReason = "fraud"
+	SuspensionReasonAbuse           SuspensionReason = "abuse"
+	SuspensionReasonPolicyViolation SuspensionReason = "policy_violation"
+	SuspensionReasonPaymentIssue    SuspensionReason = "payment_issue"
+	SuspensionReasonSecurity        SuspensionReason = "security"
+	SuspensionReasonOther           SuspensionReason = "other"
 )
 
+func (s UserStatus) IsValid() bool {
+	switch s {
+	case UserStatusActive, UserStatusInactive, UserStatusSuspended, UserStatusPendingVerification:
+		return true
+	default:
+		return false
+	}
+}
+
+func (r SuspensionReason) IsValid() bool {
+	switch r {
+	case SuspensionReasonFraud, SuspensionReasonAbuse, SuspensionReasonPolicyViolation,
+		SuspensionReasonPaymentIssue, SuspensionReasonSecurity, SuspensionReasonOther:
+		return true
+	default:
+		return false
+	}
+}
+
 type User struct {
-	ID        uuid.UUID  `json:"id"`
-	Email     string     `json:"email"`
-	FirstName string     `json:"firstName"`
-	LastName  string     `json:"lastName"`
-	Status    UserStatus `json:"status"`
-	CreatedAt time.Time  `json:"createdAt"`
-	UpdatedAt time.Time  `json:"updatedAt"`
+	ID             uuid.UUID        `json:"id"`
+	TenantID       string           `json:"tenantId"`
+	Email          string           `json:"email"`
+	EmailCanonical string           `json:"-"`
+	EmailVerified  bool             `json:"emailVerified"`
+	PendingEmail   string           `json:"pendingEmail,omitempty"`
+	FirstName      string           `json:"firstName"`
+	LastName       string           `json:"lastName"`
+	Status         UserStatus       `json:"status"`
+	StatusReason   SuspensionReason `json:"statusReason,omitempty"`
+	SuspendedUntil *time.Time       `json:"suspendedUntil,omitempty"`
+	Attributes     Attributes       `json:"attributes,omitempty"`
+	CreatedAt      time.Time        `json:"createdAt"`
+	UpdatedAt      time.Time        `json:"updatedAt"`
 }
 
 type CreateUserRequest struct {
-	Email     string `json:"email"`
-	FirstName string `json:"firstName"`
-	LastName  string `json:"lastName"`
+	Email      string     `json:"email"`
+	FirstName  string     `json:"firstName"`
+	LastName   string     `json:"lastName"`
+	Attributes Attributes `json:"attributes,omitempty"`
 }
 
 type UpdateUserRequest struct {
-	Email     *string     `json:"email,omitempty"`
-	FirstName *string     `json:"firstName,omitempty"`
-	LastName  *string     `json:"lastName,omitempty"`
-	Status    *UserStatus `json:"status,omitempty"`
+	Email          *string           `json:"email,omitempty"`
+	FirstName      *string           `json:"firstName,omitempty"`
+	LastName       *string           `json:"lastName,omitempty"`
+	Status         *UserStatus       `json:"status,omitempty"`
+	StatusReason   *SuspensionReason `json:"statusReason,omitempty"`
+	SuspendedUntil *time.Time        `json:"suspendedUntil,omitempty"`
+	// Attributes is merged into the existing attributes; a null value
+	// removes the key.
+	Attributes Attributes `json:"attributes,omitempty"`
+}
+
+// UpsertUserRequest is the body of an upsert by email. Attributes are merged
+// like in UpdateUserRequest when the user already exists.
+type UpsertUserRequest struct {
+	FirstName  string     `json:"firstName"`
+	LastName   string     `json:"lastName"`
+	Attributes Attributes `json:"attributes,omitempty"`
+}
+
+type UpsertResult int
+
+const (
+	UpsertUnchanged UpsertResult = iota
+	UpsertCreated
+	UpsertUpdated
+)
+
+type SuspendUserRequest struct {
+	Reason   SuspensionReason `json:"reason"`
+	Duration string           `json:"duration,omitempty"`
+	Note     string           `json:"note,omitempty"`
+}
+
+type ActivateUserRequest struct {
+	Note string `json:"note,omitempty"`
+}
+
+type DeactivateUserRequest struct {
+	Note string `json:"note,omitempty"`
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token"`
+}
+
+type ConfirmEmailChangeRequest struct {
+	Token string `json:"token"`
 }
 
 type UserList struct {
@@ -43,17 +126,57 @@ type UserList struct {
 	Pagination Pagination `json:"pagination"`
 }
 
+type BatchGetUsersRequest struct {
+	IDs []string `json:"ids"`
+}
+
+// BatchGetUsersResult lists the found users and the missing IDs, both in
+// request order.
+type BatchGetUsersResult struct {
+	Data     []User      `json:"data"`
+	NotFound []uuid.UUID `json:"notFound"`
+}
+
+type UserFilter struct {
+	Limit  int
+	Offset int
+	// Attributes matches users whose attributes contain every key/value pair.
+	Attributes Attributes
+	// Fields limits the columns read; nil reads every field.
+	Fields FieldSet
+}
+
 type Pagination struct {
 	Total  int `json:"total"`
 	Limit  int `json:"limit"`
 	Offset int `json:"offset"`
 }
 
+// UserRepository methods are scoped to the tenant in the context, except
+// ListExpiredSuspensions which returns users of every tenant.
 type UserRepository interface {
 	Create(ctx context.Context, user *User) error
 	GetByID(ctx context.Context, id uuid.UUID) (*User, error)
+	// GetByIDs returns the users found among ids, in the order of ids, with
+	// only the given fields set.
+	GetByIDs(ctx context.Context, ids []uuid.UUID, fields FieldSet) ([]User, error)
 	GetByEmail(ctx context.Context, email string) (*User, error)
-	List(ctx context.Context, limit, offset int) ([]User, int, error)
+	GetByCanonicalEmail(ctx context.Context, canonical string) (*User, error)
+	List(ctx context.Context, filter UserFilter) ([]User, int, error)
 	Update(ctx context.Context, user *User) error
+	// UpdateWithAudit updates user and saves entry in one transaction.
+	UpdateWithAudit(ctx context.Context, user *User, entry *AuditEntry) error
+	// Upsert inserts user, or updates the names and merges the attributes
+	// of the user with the same canonical email. Email and status of an
+	// existing user are left untouched. user is filled with the stored row.
+	Upsert(ctx context.Context, user *User) (UpsertResult, error)
 	Delete(ctx context.Context, id uuid.UUID) error
+	GetAsOf(ctx context.Context, id uuid.UUID, asOf time.Time) (*User, error)
+	ListExpiredSuspensions(ctx context.Context, before time.Time, limit int) ([]User, error)
+	// ReactivateSuspension activates user only if it is still suspended with
+	// a suspension that expired at before, saving entry (if not nil) in the
+	// same transaction, and fills user with the stored row. It returns false
+	// when the user no longer matches, e.g. because another replica or an
+	// admin reactivated it first.
+	ReactivateSuspension(ctx context.Context, user *User, before time.Time, entry *AuditEntry) (bool, error)
 }
@@ -35,3 +35,27 @@ func isValidStatus(status UserStatus) bool {
 		return false
 	}
 }
+
+func TestSuspensionReason_IsValid(t *testing.T) {
+	tests := []struct {
+		reason SuspensionReason
+		want   bool
+	}{
+		{SuspensionReasonFraud, true},
+		{SuspensionReasonAbuse, true},
+		{SuspensionReasonPolicyViolation, true},
+		{SuspensionReasonPaymentIssue, true},
+		{SuspensionReasonSecurity, true},
+		{SuspensionReasonOther, true},
+		{SuspensionReason(""), false},
+		{SuspensionReason("bored"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.reason), func(t *testing.T) {
+			if got := tt.reason.IsValid(); got != tt.want {
+				t.Errorf("SuspensionReason(%q).IsValid() = %v, want %v", tt.reason, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package domain
+
+import "strings"
+
+const (
+	FieldCodeRequired      = "REQUIRED"
+	FieldCodeTooLong       = "TOO_LONG"
+	FieldCodeInvalidValue  = "INVALID_VALUE"
+	FieldCodeInvalidFormat = "INVALID_FORMAT"
+)
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// ValidationError aggregates every field that failed validation so clients
+// can report them all at once. It matches ErrInvalidInput with errors.Is, as
+// well as any cause recorded with AddCause.
+type ValidationError struct {
+	Fields []FieldError
+	causes []error
+}
+
+func (e *ValidationError) Add(field, code, message string) {
+	e.Fields = append(e.Fields, FieldError{Field: field, Code: code, Message: message})
+}
+
+func (e *ValidationError) AddCause(field, code, message string, cause error) {
+	e.Add(field, code, message)
+	e.causes = append(e.causes, cause)
+}
+
+// Err returns e if any field failed, nil otherwise.
+func (e *ValidationError) Err() error {
+	if len(e.Fields) == 0 {
+		return nil
+	}
+	return e
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		msgs[i] = f.Field + ": " + f.Message
+	}
+	return ErrInvalidInput.Error() + ": " + strings.Join(msgs, "; ")
+}
+
+func (e *ValidationError) Unwrap() []error {
+	return append([]error{ErrInvalidInput}, e.causes...)
+}
@@ -0,0 +1,36 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidationError(t *testing.T) {
+	v := &ValidationError{}
+	if err := v.Err(); err != nil {
+		t.Fatalf("Err() with no fields = %v, want nil", err)
+	}
+
+	v.Add("firstName", FieldCodeRequired, "is required")
+	v.AddCause("email", "DISPOSABLE_EMAIL", "disposable email domains are not allowed",
+		&EmailError{Kind: ErrDisposableEmail, Reason: "disposable email domains are not allowed"})
+
+	err := v.Err()
+	if err == nil {
+		t.Fatal("Err() with fields = nil, want error")
+	}
+	if !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("errors.Is(%v, ErrInvalidInput) = false, want true", err)
+	}
+	if !errors.Is(err, ErrDisposableEmail) {
+		t.Errorf("errors.Is(%v, ErrDisposableEmail) = false, want true", err)
+	}
+	if errors.Is(err, ErrInvalidEmail) {
+		t.Errorf("errors.Is(%v, ErrInvalidEmail) = true, want false", err)
+	}
+
+	want := "invalid input: firstName: is required; email: disposable email domains are not allowed"
+	if err.Error() != want {
+		t.Errorf("Error() = %q, want %q", err.Error(), want)
+	}
+}
@@ -0,0 +1,115 @@
+package domain
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type WebhookSubscription struct {
+	ID       uuid.UUID `json:"id"`
+	TenantID string    `json:"-"`
+	URL      string    `json:"url"`
+	// EventTypes filters the events sent to URL. Empty means every event.
+	EventTypes []EventType `json:"eventTypes"`
+	// Secret signs the deliveries. It is only returned when the subscription
+	// is created.
+	Secret              string     `json:"secret,omitempty"`
+	Enabled             bool       `json:"enabled"`
+	ConsecutiveFailures int        `json:"consecutiveFailures"`
+	DisabledAt          *time.Time `json:"disabledAt,omitempty"`
+	CreatedAt           time.Time  `json:"createdAt"`
+	UpdatedAt           time.Time  `json:"updatedAt"`
+}
+
+func (s *WebhookSubscription) Subscribes(eventType EventType) bool {
+	return len(s.EventTypes) == 0 || slices.Contains(s.EventTypes, eventType)
+}
+
+type CreateWebhookRequest struct {
+	URL        string      `json:"url"`
+	EventTypes []EventType `json:"eventTypes,omitempty"`
+	// Secret is generated when empty.
+	Secret string `json:"secret,omitempty"`
+}
+
+// UpdateWebhookRequest changes the fields that are set. Enabling a disabled
+// subscription resets its failure count.
+type UpdateWebhookRequest struct {
+	URL        *string      `json:"url,omitempty"`
+	EventTypes *[]EventType `json:"eventTypes,omitempty"`
+	Secret     *string      `json:"secret,omitempty"`
+	Enabled    *bool        `json:"enabled,omitempty"`
+}
+
+type WebhookList struct {
+	Data []WebhookSubscription `json:"data"`
+}
+
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryPending   WebhookDeliveryStatus = "pending"
+	WebhookDeliverySucceeded WebhookDeliveryStatus = "succeeded"
+	WebhookDeliveryFailed    WebhookDeliveryStatus = "failed"
+)
+
+type WebhookDelivery struct {
+	ID             uuid.UUID             `json:"id"`
+	TenantID       string                `json:"-"`
+	SubscriptionID uuid.UUID             `json:"subscriptionId"`
+	EventID        uuid.UUID             `json:"eventId"`
+	EventType      EventType             `json:"eventType"`
+	Payload        json.RawMessage       `json:"payload"`
+	Status         WebhookDeliveryStatus `json:"status"`
+	Attempts       int                   `json:"attempts"`
+	LastStatusCode int                   `json:"lastStatusCode,omitempty"`
+	LastError      string                `json:"lastError,omitempty"`
+	NextAttemptAt  *time.Time            `json:"nextAttemptAt,omitempty"`
+	DeliveredAt    *time.Time            `json:"deliveredAt,omitempty"`
+	CreatedAt      time.Time             `json:"createdAt"`
+	UpdatedAt      time.Time             `json:"updatedAt"`
+}
+
+// PendingWebhookDelivery is a delivery claimed for sending, with the target
+// of its subscription.
+type PendingWebhookDelivery struct {
+	WebhookDelivery
+	URL    string
+	Secret string
+}
+
+type WebhookDeliveryList struct {
+	Data       []WebhookDelivery `json:"data"`
+	Pagination Pagination        `json:"pagination"`
+}
+
+type WebhookRepository interface {
+	Create(ctx context.Context, sub *WebhookSubscription) error
+	Get(ctx context.Context, id uuid.UUID) (*WebhookSubscription, error)
+	List(ctx context.Context) ([]WebhookSubscription, error)
+	Update(ctx context.Context, sub *WebhookSubscription) error
+	Delete(ctx context.Context, id uuid.UUID) error
+
+	// ListDeliveries returns the deliveries of a subscription, newest first,
+	// and their total count.
+	ListDeliveries(ctx context.Context, subscriptionID uuid.UUID, limit, offset int) ([]WebhookDelivery, int, error)
+
+	// Enqueue creates a pending delivery of event for every enabled
+	// subscription of its tenant that subscribes to its type, and returns
+	// how many were created.
+	Enqueue(ctx context.Context, event UserEvent, payload []byte) (int, error)
+	// ClaimDue returns up to limit pending deliveries of enabled
+	// subscriptions, of every tenant, due at now. The claimed deliveries are
+	// postponed by lease so that other instances skip them while they are
+	// sent.
+	ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]PendingWebhookDelivery, error)
+	// RecordAttempt saves the outcome of an attempt to send delivery. A
+	// succeeded delivery resets the failure count of its subscription and a
+	// failed one increments it, disabling the subscription once it reaches
+	// disableAfter. It reports whether the subscription was disabled.
+	RecordAttempt(ctx context.Context, delivery *WebhookDelivery, disableAfter int) (bool, error)
+}
@@ -0,0 +1,214 @@
+package emailaddr
+
+import (
+	"bufio"
+	_ "embed"
+	"net/mail"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/idna"
+
+	"github.com/giannuccilli/user-api/internal/domain"
+)
+
+const (
+	maxLocalLength   = 64
+	maxAddressLength = 254
+)
+
+//go:embed disposable_domains.txt
+var disposableList string
+
+var idnaProfile = idna.New(
+	idna.MapForLookup(),
+	idna.BidiRule(),
+	idna.VerifyDNSLength(true),
+)
+
+type Config struct {
+	AllowedDomains    []string
+	DeniedDomains     []string
+	BlockDisposable   bool
+	StripPlusTag      bool
+	CollapseGmailDots bool
+}
+
+type Address struct {
+	Email     string
+	Canonical string
+}
+
+type Policy struct {
+	allowed    []string
+	denied     []string
+	disposable map[string]bool
+	cfg        Config
+}
+
+func NewPolicy(cfg Config) *Policy {
+	p := &Policy{
+		allowed: normalizeDomains(cfg.AllowedDomains),
+		denied:  normalizeDomains(cfg.DeniedDomains),
+		cfg:     cfg,
+	}
+	if cfg.BlockDisposable {
+		p.disposable = loadDisposable()
+	}
+	return p
+}
+
+// Normalize parses raw as a single RFC 5322 addr-spec, converts the domain
+// to its ASCII (punycode) form and applies the domain policy. Canonical is
+// the key used for duplicate detection.
+func (p *Policy) Normalize(raw string) (Address, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return Address{}, invalid("email is required")
+	}
+	if strings.ContainsAny(raw, "<>") {
+		return Address{}, invalid("email must be a bare address without a display name")
+	}
+
+	parsed, err := mail.ParseAddress(raw)
+	if err != nil || parsed.Name != "" {
+		return Address{}, invalid("email is not a valid RFC 5322 address")
+	}
+
+	at := strings.LastIndex(parsed.Address, "@")
+	local, host := quoteLocal(parsed.Address[:at]), parsed.Address[at+1:]
+	if len(local) > maxLocalLength {
+		return Address{}, invalid("local part exceeds 64 characters")
+	}
+
+	asciiHost, err := idnaProfile.ToASCII(host)
+	if err != nil || !strings.Contains(asciiHost, ".") {
+		return Address{}, invalid("email domain is not a valid hostname")
+	}
+
+	email := strings.ToLower(local) + "@" + asciiHost
+	if len(email) > maxAddressLength {
+		return Address{}, invalid("email exceeds 254 characters")
+	}
+
+	if err := p.checkDomain(asciiHost); err != nil {
+		return Address{}, err
+	}
+
+	return Address{Email: email, Canonical: p.canonical(strings.ToLower(local), asciiHost)}, nil
+}
+
+func (p *Policy) checkDomain(host string) error {
+	if matchDomain(host, p.denied) {
+		return &domain.EmailError{Kind: domain.ErrEmailDomainNotAllowed, Reason: "email domain " + host + " is not allowed"}
+	}
+	if len(p.allowed) > 0 {
+		if !matchDomain(host, p.allowed) {
+			return &domain.EmailError{Kind: domain.ErrEmailDomainNotAllowed, Reason: "email domain " + host + " is not allowed"}
+		}
+		return nil
+	}
+	if p.isDisposable(host) {
+		return &domain.EmailError{Kind: domain.ErrDisposableEmail, Reason: "disposable email domains are not allowed"}
+	}
+	return nil
+}
+
+func (p *Policy) isDisposable(host string) bool {
+	for host != "" {
+		if p.disposable[host] {
+			return true
+		}
+		_, parent, ok := strings.Cut(host, ".")
+		if !ok {
+			break
+		}
+		host = parent
+	}
+	return false
+}
+
+func (p *Policy) canonical(local, host string) string {
+	if strings.HasPrefix(local, `"`) {
+		return local + "@" + host
+	}
+
+	if p.cfg.StripPlusTag {
+		if tag := strings.Index(local, "+"); tag > 0 {
+			local = local[:tag]
+		}
+	}
+
+	if p.cfg.CollapseGmailDots && (host == "gmail.com" || host == "googlemail.com") {
+		local = strings.ReplaceAll(local, ".", "")
+		host = "gmail.com"
+	}
+
+	return local + "@" + host
+}
+
+// quoteLocal restores the quotes that net/mail strips from local parts that
+// are not a valid dot-atom, e.g. "john doe".
+func quoteLocal(local string) string {
+	if isDotAtom(local) {
+		return local
+	}
+	return strconv.Quote(local)
+}
+
+func isDotAtom(s string) bool {
+	if s == "" || strings.HasPrefix(s, ".") || strings.HasSuffix(s, ".") || strings.Contains(s, "..") {
+		return false
+	}
+	for _, r := range s {
+		if r == '.' || r >= utf8.RuneSelf || ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z') || ('0' <= r && r <= '9') {
+			continue
+		}
+		if !strings.ContainsRune("!#$%&'*+-/=?^_`{|}~", r) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchDomain(host string, domains []string) bool {
+	for _, d := range domains {
+		if host == d || strings.HasSuffix(host, "."+d) {
+			return true
+		}
+	}
+	return false
+}
+
+func normalizeDomains(domains []string) []string {
+	normalized := make([]string, 0, len(domains))
+	for _, d := range domains {
+		d = strings.TrimPrefix(strings.TrimSpace(d), "@")
+		if d == "" {
+			continue
+		}
+		if ascii, err := idnaProfile.ToASCII(d); err == nil {
+			d = ascii
+		}
+		normalized = append(normalized, strings.ToLower(d))
+	}
+	return normalized
+}
+
+func loadDisposable() map[string]bool {
+	domains := make(map[string]bool)
+	scanner := bufio.NewScanner(strings.NewReader(disposableList))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		domains[strings.ToLower(line)] = true
+	}
+	return domains
+}
+
+func invalid(reason string) error {
+	return &domain.EmailError{Kind: domain.ErrInvalidEmail, Reason: reason}
+}
@@ -0,0 +1,95 @@
+package emailaddr
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/giannuccilli/user-api/internal/domain"
+)
+
+func TestPolicy_Normalize(t *testing.T) {
+	tests := []struct {
+		name          string
+		cfg           Config
+		input         string
+		wantEmail     string
+		wantCanonical string
+		wantErr       error
+	}{
+		{name: "simple address", input: "John.Doe@Example.com", wantEmail: "john.doe@example.com", wantCanonical: "john.doe@example.com"},
+		{name: "surrounding whitespace", input: "  user@example.com ", wantEmail: "user@example.com", wantCanonical: "user@example.com"},
+		{name: "quoted local part", input: `"john doe"@example.com`, wantEmail: `"john doe"@example.com`, wantCanonical: `"john doe"@example.com`},
+		{name: "idn domain", input: "user@bücher.de", wantEmail: "user@xn--bcher-kva.de", wantCanonical: "user@xn--bcher-kva.de"},
+		{name: "empty", input: "", wantErr: domain.ErrInvalidEmail},
+		{name: "missing local part", input: "@b.c", wantErr: domain.ErrInvalidEmail},
+		{name: "missing domain label", input: "a@.", wantErr: domain.ErrInvalidEmail},
+		{name: "no at sign", input: "invalid-email", wantErr: domain.ErrInvalidEmail},
+		{name: "dotless domain", input: "user@localhost", wantErr: domain.ErrInvalidEmail},
+		{name: "display name", input: "John <john@example.com>", wantErr: domain.ErrInvalidEmail},
+		{name: "multiple addresses", input: "a@example.com, b@example.com", wantErr: domain.ErrInvalidEmail},
+		{name: "invalid hostname", input: "user@exa_mple.com", wantErr: domain.ErrInvalidEmail},
+		{name: "local part too long", input: strings.Repeat("a", 65) + "@example.com", wantErr: domain.ErrInvalidEmail},
+		{name: "address too long", input: strings.Repeat("u", 64) + "@" + strings.Repeat("a", 62) + "." + strings.Repeat("b", 62) + "." + strings.Repeat("c", 62) + ".com", wantErr: domain.ErrInvalidEmail},
+		{
+			name:          "plus tag stripped",
+			cfg:           Config{StripPlusTag: true},
+			input:         "user+news@example.com",
+			wantEmail:     "user+news@example.com",
+			wantCanonical: "user@example.com",
+		},
+		{
+			name:          "gmail dots collapsed",
+			cfg:           Config{StripPlusTag: true, CollapseGmailDots: true},
+			input:         "John.Doe+spam@googlemail.com",
+			wantEmail:     "john.doe+spam@googlemail.com",
+			wantCanonical: "johndoe@gmail.com",
+		},
+		{
+			name:          "dots kept outside gmail",
+			cfg:           Config{CollapseGmailDots: true},
+			input:         "john.doe@example.com",
+			wantEmail:     "john.doe@example.com",
+			wantCanonical: "john.doe@example.com",
+		},
+		{name: "denied domain", cfg: Config{DeniedDomains: []string{"example.com"}}, input: "user@mail.example.com", wantErr: domain.ErrEmailDomainNotAllowed},
+		{name: "not in allow list", cfg: Config{AllowedDomains: []string{"corp.com"}}, input: "user@example.com", wantErr: domain.ErrEmailDomainNotAllowed},
+		{name: "in allow list", cfg: Config{AllowedDomains: []string{"@Corp.com"}}, input: "user@corp.com", wantEmail: "user@corp.com", wantCanonical: "user@corp.com"},
+		{name: "disposable domain", cfg: Config{BlockDisposable: true}, input: "user@mailinator.com", wantErr: domain.ErrDisposableEmail},
+		{name: "disposable subdomain", cfg: Config{BlockDisposable: true}, input: "user@eu.mailinator.com", wantErr: domain.ErrDisposableEmail},
+		{name: "disposable allowed when not blocked", input: "user@mailinator.com", wantEmail: "user@mailinator.com", wantCanonical: "user@mailinator.com"},
+		{
+			name:          "allow list overrides disposable",
+			cfg:           Config{BlockDisposable: true, AllowedDomains: []string{"mailinator.com"}},
+			input:         "user@mailinator.com",
+			wantEmail:     "user@mailinator.com",
+			wantCanonical: "user@mailinator.com",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addr, err := NewPolicy(tt.cfg).Normalize(tt.input)
+
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Normalize() error = %v, want %v", err, tt.wantErr)
+				}
+				if !errors.Is(err, domain.ErrInvalidInput) {
+					t.Errorf("Normalize() error = %v, should match %v", err, domain.ErrInvalidInput)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Normalize() unexpected error = %v", err)
+			}
+			if addr.Email != tt.wantEmail {
+				t.Errorf("Normalize() email = %v, want %v", addr.Email, tt.wantEmail)
+			}
+			if addr.Canonical != tt.wantCanonical {
+				t.Errorf("Normalize() canonical = %v, want %v", addr.Canonical, tt.wantCanonical)
+			}
+		})
+	}
+}
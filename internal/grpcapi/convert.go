@@ -0,0 +1,163 @@
+package grpcapi
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	userv1 "github.com/giannuccilli/user-api/api/proto/user/v1"
+	"github.com/giannuccilli/user-api/internal/domain"
+)
+
+func toProtoUser(u *domain.User) *userv1.User {
+	return &userv1.User{
+		Id:             u.ID.String(),
+		TenantId:       u.TenantID,
+		Email:          u.Email,
+		EmailVerified:  u.EmailVerified,
+		PendingEmail:   u.PendingEmail,
+		FirstName:      u.FirstName,
+		LastName:       u.LastName,
+		Status:         string(u.Status),
+		StatusReason:   string(u.StatusReason),
+		SuspendedUntil: optionalTimestamp(u.SuspendedUntil),
+		Attributes:     toStruct(u.Attributes),
+		CreatedAt:      timestamp(u.CreatedAt),
+		UpdatedAt:      timestamp(u.UpdatedAt),
+	}
+}
+
+func toProtoUsers(users []domain.User) []*userv1.User {
+	out := make([]*userv1.User, len(users))
+	for i := range users {
+		out[i] = toProtoUser(&users[i])
+	}
+	return out
+}
+
+func toProtoIdentity(identity *domain.ExternalIdentity) *userv1.ExternalIdentity {
+	return &userv1.ExternalIdentity{
+		UserId:     identity.UserID.String(),
+		Provider:   identity.Provider,
+		ExternalId: identity.ExternalID,
+		CreatedAt:  timestamp(identity.CreatedAt),
+	}
+}
+
+func toProtoAuditEntry(entry *domain.AuditEntry) *userv1.AuditEntry {
+	return &userv1.AuditEntry{
+		Id:         entry.ID.String(),
+		UserId:     entry.UserID.String(),
+		Action:     string(entry.Action),
+		ActorId:    entry.ActorID,
+		FromStatus: string(entry.FromStatus),
+		ToStatus:   string(entry.ToStatus),
+		Reason:     string(entry.Reason),
+		Note:       entry.Note,
+		CreatedAt:  timestamp(entry.CreatedAt),
+	}
+}
+
+func toProtoPagination(p domain.Pagination) *userv1.Pagination {
+	return &userv1.Pagination{Total: int32(p.Total), Limit: int32(p.Limit), Offset: int32(p.Offset)}
+}
+
+// toProtoValue converts a diff value through its JSON form, so that it matches
+// what the REST API returns.
+func toProtoValue(v any) (*structpb.Value, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var generic any
+	if err := json.Unmarshal(data, &generic); err != nil {
+		return nil, err
+	}
+	return structpb.NewValue(generic)
+}
+
+func toStruct(attrs domain.Attributes) *structpb.Struct {
+	if len(attrs) == 0 {
+		return nil
+	}
+	s, err := structpb.NewStruct(attrs)
+	if err != nil {
+		// Attributes are decoded from JSON, so they always convert.
+		return nil
+	}
+	return s
+}
+
+func fromStruct(s *structpb.Struct) domain.Attributes {
+	if s == nil {
+		return nil
+	}
+	return s.AsMap()
+}
+
+func timestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
+
+func optionalTimestamp(t *time.Time) *timestamppb.Timestamp {
+	if t == nil {
+		return nil
+	}
+	return timestamppb.New(*t)
+}
+
+func optionalTime(ts *timestamppb.Timestamp) *time.Time {
+	if ts == nil {
+		return nil
+	}
+	t := ts.AsTime()
+	return &t
+}
+
+func parseID(raw, field string) (uuid.UUID, error) {
+	id, err := uuid.Parse(raw)
+	if err != nil {
+		return uuid.Nil, invalidID(field)
+	}
+	return id, nil
+}
+
+// fieldSet converts a read mask of User proto field names to the JSON names
+// accepted by domain.ParseFieldSet.
+func fieldSet(mask *fieldmaskpb.FieldMask) (domain.FieldSet, error) {
+	if mask == nil || len(mask.GetPaths()) == 0 {
+		return nil, nil
+	}
+
+	names := make([]string, len(mask.GetPaths()))
+	for i, path := range mask.GetPaths() {
+		names[i] = camelCase(path)
+	}
+	fields, err := domain.ParseFieldSet(strings.Join(names, ","))
+	var v *domain.ValidationError
+	if errors.As(err, &v) {
+		for i := range v.Fields {
+			v.Fields[i].Field = "read_mask"
+		}
+	}
+	return fields, err
+}
+
+func camelCase(snake string) string {
+	parts := strings.Split(snake, "_")
+	for i := 1; i < len(parts); i++ {
+		if parts[i] != "" {
+			parts[i] = strings.ToUpper(parts[i][:1]) + parts[i][1:]
+		}
+	}
+	return strings.Join(parts, "")
+}
@@ -0,0 +1,110 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"unicode"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+
+	"github.com/giannuccilli/user-api/internal/domain"
+	"github.com/giannuccilli/user-api/internal/handler"
+)
+
+// ErrorDomain is the domain of the ErrorInfo details attached to errors. The
+// reason is the same code the REST API returns.
+const ErrorDomain = "user-api"
+
+// toStatus maps a service error to a gRPC status error.
+func toStatus(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var validationErr *domain.ValidationError
+	if errors.As(err, &validationErr) {
+		violations := make([]*errdetails.BadRequest_FieldViolation, len(validationErr.Fields))
+		for i, f := range validationErr.Fields {
+			violations[i] = &errdetails.BadRequest_FieldViolation{
+				Field:       protoFieldPath(f.Field),
+				Description: f.Message,
+				Reason:      f.Code,
+			}
+		}
+		return withDetails(codes.InvalidArgument, handler.ErrCodeValidationFailed, "request validation failed",
+			&errdetails.BadRequest{FieldViolations: violations})
+	}
+
+	var code codes.Code
+	var reason, message string
+	switch {
+	case errors.Is(err, domain.ErrUserNotFound):
+		code, reason, message = codes.NotFound, handler.ErrCodeUserNotFound, "user not found"
+	case errors.Is(err, domain.ErrAttributeNotFound):
+		code, reason, message = codes.NotFound, handler.ErrCodeAttributeNotFound, "attribute definition not found"
+	case errors.Is(err, domain.ErrIdentityNotFound):
+		code, reason, message = codes.NotFound, handler.ErrCodeIdentityNotFound, "external identity not found"
+	case errors.Is(err, domain.ErrTenantNotFound):
+		code, reason, message = codes.NotFound, handler.ErrCodeTenantNotFound, "tenant not found"
+	case errors.Is(err, domain.ErrIdentityConflict):
+		code, reason, message = codes.AlreadyExists, handler.ErrCodeIdentityConflict, "external identity is linked to another user"
+	case errors.Is(err, domain.ErrEmailExists):
+		code, reason, message = codes.AlreadyExists, handler.ErrCodeEmailExists, "email already exists"
+	case errors.Is(err, domain.ErrInvalidTransition):
+		code, reason, message = codes.FailedPrecondition, handler.ErrCodeInvalidTransition, "status transition not allowed"
+	case errors.Is(err, domain.ErrEmailAlreadyVerified):
+		code, reason, message = codes.FailedPrecondition, handler.ErrCodeEmailAlreadyVerified, "email already verified"
+	case errors.Is(err, domain.ErrForbidden):
+		code, reason, message = codes.PermissionDenied, handler.ErrCodeForbidden, "operation not permitted"
+	case errors.Is(err, domain.ErrInvalidToken):
+		code, reason, message = codes.InvalidArgument, handler.ErrCodeInvalidToken, "invalid token"
+	case errors.Is(err, domain.ErrTokenExpired):
+		code, reason, message = codes.InvalidArgument, handler.ErrCodeTokenExpired, "token expired"
+	case errors.Is(err, domain.ErrNotSupported):
+		code, reason, message = codes.Unimplemented, handler.ErrCodeNotImplemented, "operation not supported"
+	case errors.Is(err, domain.ErrInvalidInput):
+		code, reason, message = codes.InvalidArgument, handler.ErrCodeInvalidRequest, "invalid request data"
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return status.FromContextError(err).Err()
+	default:
+		code, reason, message = codes.Internal, handler.ErrCodeInternalError, "internal server error"
+	}
+	return withDetails(code, reason, message)
+}
+
+func invalidID(field string) error {
+	return withDetails(codes.InvalidArgument, handler.ErrCodeInvalidID, "invalid "+field+" format")
+}
+
+func withDetails(code codes.Code, reason, message string, details ...protoadapt.MessageV1) error {
+	st := status.New(code, message)
+	details = append([]protoadapt.MessageV1{&errdetails.ErrorInfo{Reason: reason, Domain: ErrorDomain}}, details...)
+	if withDetails, err := st.WithDetails(details...); err == nil {
+		st = withDetails
+	}
+	return st.Err()
+}
+
+// protoFieldPath converts the leading camelCase name of a validation field,
+// such as firstName or ids[1], to the proto field name. Attribute keys after
+// the first dot are left as they are.
+func protoFieldPath(field string) string {
+	end := strings.IndexAny(field, ".[")
+	if end < 0 {
+		end = len(field)
+	}
+
+	var b strings.Builder
+	for _, r := range field[:end] {
+		if unicode.IsUpper(r) {
+			b.WriteByte('_')
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String() + field[end:]
+}
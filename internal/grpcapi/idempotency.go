@@ -0,0 +1,144 @@
+package grpcapi
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+
+	"github.com/giannuccilli/user-api/internal/domain"
+	"github.com/giannuccilli/user-api/internal/handler"
+)
+
+// messageTypeHeader records the type of a stored response so it can be
+// decoded on replay.
+const messageTypeHeader = "Grpc-Message-Type"
+
+// idempotency makes unary user service calls carrying idempotency-key
+// metadata safe to retry, like the REST Idempotency middleware. Keys share
+// the store with REST but the fingerprint includes the method, so a key used
+// over REST is rejected here. Only successful responses are stored; a call
+// that fails can be retried with the same key.
+func idempotency(store domain.IdempotencyRepository, logger *slog.Logger, opts handler.IdempotencyOptions) grpc.UnaryServerInterceptor {
+	if opts.TTL <= 0 {
+		opts.TTL = handler.DefaultIdempotencyTTL
+	}
+	if opts.Lease <= 0 {
+		opts.Lease = handler.DefaultIdempotencyLease
+	}
+
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, next grpc.UnaryHandler) (any, error) {
+		key := metadataValue(ctx, "idempotency-key")
+		msg, ok := req.(proto.Message)
+		if key == "" || !ok || !userServiceMethod(info.FullMethod) {
+			return next(ctx, req)
+		}
+		if len(key) > handler.MaxIdempotencyKeyLength {
+			return nil, withDetails(codes.InvalidArgument, handler.ErrCodeInvalidRequest,
+				fmt.Sprintf("idempotency-key must be at most %d characters", handler.MaxIdempotencyKeyLength))
+		}
+
+		body, err := proto.MarshalOptions{Deterministic: true}.Marshal(msg)
+		if err != nil {
+			return nil, withDetails(codes.InvalidArgument, handler.ErrCodeInvalidRequest, "invalid request")
+		}
+		record := &domain.IdempotencyRecord{
+			ActorID:     domain.ActorFromContext(ctx).ID,
+			Key:         key,
+			Fingerprint: callFingerprint(info.FullMethod, body),
+			ExpiresAt:   time.Now().Add(opts.Lease),
+		}
+
+		existing, err := store.Reserve(ctx, record)
+		if err != nil {
+			logger.Error("failed to reserve idempotency key", slog.String("error", err.Error()))
+			return nil, withDetails(codes.Internal, handler.ErrCodeInternalError, "internal server error")
+		}
+		if existing != nil {
+			switch {
+			case existing.Fingerprint != record.Fingerprint:
+				return nil, withDetails(codes.InvalidArgument, handler.ErrCodeIdempotencyKeyReused,
+					"idempotency-key was already used with a different request")
+			case !existing.Completed():
+				return nil, withDetails(codes.Aborted, handler.ErrCodeIdempotencyKeyInUse,
+					"a request with this idempotency-key is still being processed")
+			}
+			resp, err := decodeResponse(existing)
+			if err != nil {
+				logger.Error("failed to replay idempotent response", slog.String("error", err.Error()))
+				return nil, withDetails(codes.Internal, handler.ErrCodeInternalError, "internal server error")
+			}
+			grpc.SetHeader(ctx, metadata.Pairs("idempotent-replayed", "true"))
+			return resp, nil
+		}
+
+		// The outcome is stored even if the client goes away, so a retry
+		// finds it.
+		storeCtx := context.WithoutCancel(ctx)
+		completed := false
+		defer func() {
+			if completed {
+				return
+			}
+			if err := store.Release(storeCtx, record); err != nil {
+				logger.Error("failed to release idempotency key", slog.String("error", err.Error()))
+			}
+		}()
+
+		resp, err := next(ctx, req)
+		if err != nil {
+			return resp, err
+		}
+		respMsg, ok := resp.(proto.Message)
+		if !ok {
+			return resp, nil
+		}
+		if record.Body, err = proto.Marshal(respMsg); err != nil {
+			logger.Error("failed to store idempotent response", slog.String("error", err.Error()))
+			return resp, nil
+		}
+		record.StatusCode = http.StatusOK
+		record.Header = map[string][]string{messageTypeHeader: {string(proto.MessageName(respMsg))}}
+		record.ExpiresAt = time.Now().Add(opts.TTL)
+
+		if err := store.Complete(storeCtx, record); err != nil {
+			logger.Error("failed to store idempotent response", slog.String("error", err.Error()))
+			return resp, nil
+		}
+		completed = true
+		return resp, nil
+	}
+}
+
+func decodeResponse(record *domain.IdempotencyRecord) (proto.Message, error) {
+	names := record.Header[messageTypeHeader]
+	if len(names) == 0 {
+		return nil, fmt.Errorf("stored response has no message type")
+	}
+	messageType, err := protoregistry.GlobalTypes.FindMessageByName(protoreflect.FullName(names[0]))
+	if err != nil {
+		return nil, err
+	}
+	msg := messageType.New().Interface()
+	if err := proto.Unmarshal(record.Body, msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+func callFingerprint(method string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
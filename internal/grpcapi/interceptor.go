@@ -0,0 +1,209 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	userv1 "github.com/giannuccilli/user-api/api/proto/user/v1"
+	"github.com/giannuccilli/user-api/internal/domain"
+	"github.com/giannuccilli/user-api/internal/handler"
+	"github.com/giannuccilli/user-api/internal/ratelimit"
+)
+
+// interceptor is the part shared by a unary and a stream interceptor: it
+// runs next with a derived context.
+type interceptor func(ctx context.Context, method string, next func(context.Context) error) error
+
+func unaryInterceptors(interceptors []interceptor) []grpc.UnaryServerInterceptor {
+	unary := make([]grpc.UnaryServerInterceptor, len(interceptors))
+	for i, intercept := range interceptors {
+		unary[i] = func(ctx context.Context, req any, info *grpc.UnaryServerInfo, next grpc.UnaryHandler) (any, error) {
+			var resp any
+			err := intercept(ctx, info.FullMethod, func(ctx context.Context) error {
+				var err error
+				resp, err = next(ctx, req)
+				return err
+			})
+			return resp, err
+		}
+	}
+	return unary
+}
+
+func streamInterceptors(interceptors []interceptor) []grpc.StreamServerInterceptor {
+	streams := make([]grpc.StreamServerInterceptor, len(interceptors))
+	for i, intercept := range interceptors {
+		streams[i] = func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, next grpc.StreamHandler) error {
+			return intercept(ss.Context(), info.FullMethod, func(ctx context.Context) error {
+				return next(srv, &serverStream{ServerStream: ss, ctx: ctx})
+			})
+		}
+	}
+	return streams
+}
+
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+func metadataValue(ctx context.Context, key string) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// requestID reuses the x-request-id metadata or generates one, and returns
+// it in the response header like the X-Request-ID REST header.
+func requestID() interceptor {
+	return func(ctx context.Context, method string, next func(context.Context) error) error {
+		id := metadataValue(ctx, "x-request-id")
+		if id == "" {
+			id = uuid.New().String()
+		}
+		grpc.SetHeader(ctx, metadata.Pairs("x-request-id", id))
+		return next(context.WithValue(ctx, handler.RequestIDKey, id))
+	}
+}
+
+func logging(logger *slog.Logger) interceptor {
+	return func(ctx context.Context, method string, next func(context.Context) error) error {
+		start := time.Now()
+		err := next(ctx)
+
+		logger.Info("rpc completed",
+			slog.String("method", method),
+			slog.String("code", status.Code(err).String()),
+			slog.Duration("duration", time.Since(start)),
+			slog.String("request_id", handler.RequestIDFromContext(ctx)),
+		)
+		return err
+	}
+}
+
+func recovery(logger *slog.Logger) interceptor {
+	return func(ctx context.Context, method string, next func(context.Context) error) (err error) {
+		defer func() {
+			if p := recover(); p != nil {
+				logger.Error("panic recovered",
+					slog.Any("error", p),
+					slog.String("method", method),
+					slog.String("stack", string(debug.Stack())),
+				)
+				err = status.Error(codes.Internal, "internal server error")
+			}
+		}()
+		return next(ctx)
+	}
+}
+
+// actor reads the x-actor-id and x-actor-roles metadata. Like the REST Actor
+// middleware, it trusts the gateway in front of the service.
+func actor() interceptor {
+	return func(ctx context.Context, method string, next func(context.Context) error) error {
+		a := handler.ParseActor(metadataValue(ctx, "x-actor-id"), metadataValue(ctx, "x-actor-roles"))
+		return next(domain.ContextWithActor(ctx, a))
+	}
+}
+
+// userServiceMethod reports whether method belongs to the user service rather
+// than the health or reflection services.
+func userServiceMethod(method string) bool {
+	return strings.HasPrefix(method, "/"+userv1.UserService_ServiceDesc.ServiceName+"/")
+}
+
+// rateLimit applies the limiter's quotas to the user service like the REST
+// RateLimit middleware, identifying the client by its peer address and the
+// x-api-key and authorization metadata. A call is matched against the rules
+// as the HTTP/2 request it is, e.g. "POST /user.v1.UserService/CreateUser".
+func rateLimit(limiter *ratelimit.Limiter, logger *slog.Logger, opts handler.RateLimitOptions) interceptor {
+	return func(ctx context.Context, method string, next func(context.Context) error) error {
+		if !userServiceMethod(method) {
+			return next(ctx)
+		}
+		r, err := http.NewRequestWithContext(ctx, http.MethodPost, method, nil)
+		if err != nil {
+			return next(ctx)
+		}
+
+		var addr string
+		if p, ok := peer.FromContext(ctx); ok {
+			addr = p.Addr.String()
+		}
+		md, _ := metadata.FromIncomingContext(ctx)
+		ip := handler.ClientIP(addr, strings.Join(md.Get("x-forwarded-for"), ","), opts.TrustForwardedFor)
+		clients := handler.RateLimitClients(ip, metadataValue(ctx, "x-api-key"), metadataValue(ctx, "authorization"))
+
+		result, limited, err := limiter.Allow(r, clients...)
+		if err != nil {
+			logger.Error("rate limit store failed", slog.String("error", err.Error()))
+			return next(ctx)
+		}
+		if !limited {
+			return next(ctx)
+		}
+
+		header := metadata.Pairs(
+			"ratelimit-limit", strconv.Itoa(result.Limit),
+			"ratelimit-remaining", strconv.Itoa(result.Remaining),
+			"ratelimit-reset", strconv.Itoa(ceilSeconds(result.Reset)),
+		)
+		if !result.Allowed {
+			header.Set("retry-after", strconv.Itoa(max(1, ceilSeconds(result.RetryAfter))))
+			grpc.SetHeader(ctx, header)
+			return withDetails(codes.ResourceExhausted, handler.ErrCodeRateLimited, "too many requests")
+		}
+		grpc.SetHeader(ctx, header)
+		return next(ctx)
+	}
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// tenant resolves the tenant like the REST Tenant middleware. The health and
+// reflection services do not need one.
+func tenant(opts handler.TenantOptions) interceptor {
+	return func(ctx context.Context, method string, next func(context.Context) error) error {
+		if !userServiceMethod(method) {
+			return next(ctx)
+		}
+
+		tenantID, err := handler.ResolveTenant(metadataValue(ctx, "x-tenant-id"), metadataValue(ctx, "authorization"), opts)
+		switch {
+		case errors.Is(err, handler.ErrTenantMismatch):
+			return withDetails(codes.PermissionDenied, handler.ErrCodeForbidden,
+				"x-tenant-id does not match the tenant of the bearer token")
+		case errors.Is(err, handler.ErrTenantHeaderNotTrusted):
+			return withDetails(codes.PermissionDenied, handler.ErrCodeForbidden,
+				"the tenant must be given by the bearer token, not the x-tenant-id metadata")
+		case tenantID == "":
+			return withDetails(codes.InvalidArgument, handler.ErrCodeTenantRequired,
+				"request must identify a tenant with the x-tenant-id metadata")
+		case !domain.IsValidTenantID(tenantID):
+			return withDetails(codes.InvalidArgument, handler.ErrCodeInvalidRequest, "invalid tenant ID")
+		}
+		return next(domain.ContextWithTenant(ctx, tenantID))
+	}
+}
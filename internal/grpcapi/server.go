@@ -0,0 +1,394 @@
+// Package grpcapi serves the user.v1.UserService gRPC API on top of
+// service.UserService.
+package grpcapi
+
+import (
+	"context"
+	"log/slog"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	userv1 "github.com/giannuccilli/user-api/api/proto/user/v1"
+	"github.com/giannuccilli/user-api/internal/domain"
+	"github.com/giannuccilli/user-api/internal/handler"
+	"github.com/giannuccilli/user-api/internal/ratelimit"
+	"github.com/giannuccilli/user-api/internal/service"
+)
+
+// listPageSize is how many users ListUsers reads from the service at a time.
+const listPageSize = 100
+
+type Options struct {
+	Tenant handler.TenantOptions
+
+	// RateLimiter, if set, applies the same quotas as the REST API.
+	RateLimiter *ratelimit.Limiter
+	RateLimit   handler.RateLimitOptions
+
+	// Idempotency, if set, stores the responses of unary calls sent with
+	// idempotency-key metadata, like REST requests with Idempotency-Key.
+	Idempotency        domain.IdempotencyRepository
+	IdempotencyOptions handler.IdempotencyOptions
+
+	// Reflection registers the reflection service, which lets any client
+	// list the API. Enable it for development.
+	Reflection bool
+}
+
+// NewServer returns a gRPC server with the user service and the health
+// service registered, and reflection if opts.Reflection is set.
+func NewServer(svc *service.UserService, logger *slog.Logger, opts Options) *grpc.Server {
+	interceptors := []interceptor{
+		requestID(),
+		logging(logger),
+		recovery(logger),
+	}
+	if opts.RateLimiter != nil {
+		interceptors = append(interceptors, rateLimit(opts.RateLimiter, logger, opts.RateLimit))
+	}
+	interceptors = append(interceptors, actor(), tenant(opts.Tenant))
+
+	unary := unaryInterceptors(interceptors)
+	if opts.Idempotency != nil {
+		unary = append(unary, idempotency(opts.Idempotency, logger, opts.IdempotencyOptions))
+	}
+
+	srv := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(unary...),
+		grpc.ChainStreamInterceptor(streamInterceptors(interceptors)...),
+	)
+	userv1.RegisterUserServiceServer(srv, &Server{service: svc})
+	healthpb.RegisterHealthServer(srv, health.NewServer())
+	if opts.Reflection {
+		reflection.Register(srv)
+	}
+	return srv
+}
+
+type Server struct {
+	userv1.UnimplementedUserServiceServer
+	service *service.UserService
+}
+
+func (s *Server) CreateUser(ctx context.Context, req *userv1.CreateUserRequest) (*userv1.User, error) {
+	user, err := s.service.Create(ctx, domain.CreateUserRequest{
+		Email:      req.GetEmail(),
+		FirstName:  req.GetFirstName(),
+		LastName:   req.GetLastName(),
+		Attributes: fromStruct(req.GetAttributes()),
+	})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toProtoUser(user), nil
+}
+
+func (s *Server) GetUser(ctx context.Context, req *userv1.GetUserRequest) (*userv1.User, error) {
+	id, err := parseID(req.GetId(), "id")
+	if err != nil {
+		return nil, err
+	}
+	fields, err := fieldSet(req.GetReadMask())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	var user *domain.User
+	if req.GetAsOf() != nil {
+		user, err = s.service.GetAsOf(ctx, id, req.GetAsOf().AsTime())
+	} else {
+		user, err = s.service.GetByID(ctx, id, fields)
+	}
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toProtoUser(user), nil
+}
+
+func (s *Server) BatchGetUsers(ctx context.Context, req *userv1.BatchGetUsersRequest) (*userv1.BatchGetUsersResponse, error) {
+	fields, err := fieldSet(req.GetReadMask())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	result, err := s.service.BatchGet(ctx, req.GetIds(), fields)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	notFound := make([]string, len(result.NotFound))
+	for i, id := range result.NotFound {
+		notFound[i] = id.String()
+	}
+	return &userv1.BatchGetUsersResponse{Users: toProtoUsers(result.Data), NotFound: notFound}, nil
+}
+
+func (s *Server) ListUsers(req *userv1.ListUsersRequest, stream grpc.ServerStreamingServer[userv1.User]) error {
+	fields, err := fieldSet(req.GetReadMask())
+	if err != nil {
+		return toStatus(err)
+	}
+
+	filter := domain.UserFilter{
+		Limit:      listPageSize,
+		Attributes: fromStruct(req.GetAttributes()),
+		Fields:     fields,
+	}
+	for {
+		list, err := s.service.List(stream.Context(), filter)
+		if err != nil {
+			return toStatus(err)
+		}
+		for i := range list.Data {
+			if err := stream.Send(toProtoUser(&list.Data[i])); err != nil {
+				return err
+			}
+		}
+
+		filter.Offset += len(list.Data)
+		if len(list.Data) < filter.Limit || filter.Offset >= list.Pagination.Total {
+			return nil
+		}
+	}
+}
+
+func (s *Server) UpdateUser(ctx context.Context, req *userv1.UpdateUserRequest) (*userv1.User, error) {
+	id, err := parseID(req.GetId(), "id")
+	if err != nil {
+		return nil, err
+	}
+
+	update := domain.UpdateUserRequest{
+		Email:          req.Email,
+		FirstName:      req.FirstName,
+		LastName:       req.LastName,
+		SuspendedUntil: optionalTime(req.GetSuspendedUntil()),
+		Attributes:     fromStruct(req.GetAttributes()),
+	}
+	if req.Status != nil {
+		status := domain.UserStatus(req.GetStatus())
+		update.Status = &status
+	}
+	if req.StatusReason != nil {
+		reason := domain.SuspensionReason(req.GetStatusReason())
+		update.StatusReason = &reason
+	}
+
+	user, err := s.service.Update(ctx, id, update)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toProtoUser(user), nil
+}
+
+func (s *Server) UpsertUserByEmail(ctx context.Context, req *userv1.UpsertUserByEmailRequest) (*userv1.UpsertUserResponse, error) {
+	user, created, err := s.service.UpsertByEmail(ctx, req.GetEmail(), domain.UpsertUserRequest{
+		FirstName:  req.GetFirstName(),
+		LastName:   req.GetLastName(),
+		Attributes: fromStruct(req.GetAttributes()),
+	})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &userv1.UpsertUserResponse{User: toProtoUser(user), Created: created}, nil
+}
+
+func (s *Server) DeleteUser(ctx context.Context, req *userv1.DeleteUserRequest) (*emptypb.Empty, error) {
+	id, err := parseID(req.GetId(), "id")
+	if err != nil {
+		return nil, err
+	}
+	if err := s.service.Delete(ctx, id); err != nil {
+		return nil, toStatus(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *Server) GetUserDiff(ctx context.Context, req *userv1.GetUserDiffRequest) (*userv1.UserDiff, error) {
+	id, err := parseID(req.GetId(), "id")
+	if err != nil {
+		return nil, err
+	}
+
+	diff, err := s.service.Diff(ctx, id, req.GetFrom().AsTime(), req.GetTo().AsTime())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	changes := make([]*userv1.FieldChange, len(diff.Changes))
+	for i, change := range diff.Changes {
+		from, err := toProtoValue(change.From)
+		if err != nil {
+			return nil, toStatus(err)
+		}
+		to, err := toProtoValue(change.To)
+		if err != nil {
+			return nil, toStatus(err)
+		}
+		changes[i] = &userv1.FieldChange{Field: change.Field, From: from, To: to}
+	}
+	return &userv1.UserDiff{
+		UserId:  diff.UserID.String(),
+		From:    timestamp(diff.From),
+		To:      timestamp(diff.To),
+		Changes: changes,
+	}, nil
+}
+
+func (s *Server) ListUserAudit(ctx context.Context, req *userv1.ListUserAuditRequest) (*userv1.ListUserAuditResponse, error) {
+	id, err := parseID(req.GetId(), "id")
+	if err != nil {
+		return nil, err
+	}
+
+	audit, err := s.service.ListAudit(ctx, id, int(req.GetLimit()), int(req.GetOffset()))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	entries := make([]*userv1.AuditEntry, len(audit.Data))
+	for i := range audit.Data {
+		entries[i] = toProtoAuditEntry(&audit.Data[i])
+	}
+	return &userv1.ListUserAuditResponse{Entries: entries, Pagination: toProtoPagination(audit.Pagination)}, nil
+}
+
+func (s *Server) SuspendUser(ctx context.Context, req *userv1.SuspendUserRequest) (*userv1.User, error) {
+	id, err := parseID(req.GetId(), "id")
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.service.Suspend(ctx, id, domain.SuspendUserRequest{
+		Reason:   domain.SuspensionReason(req.GetReason()),
+		Duration: req.GetDuration(),
+		Note:     req.GetNote(),
+	})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toProtoUser(user), nil
+}
+
+func (s *Server) ActivateUser(ctx context.Context, req *userv1.ActivateUserRequest) (*userv1.User, error) {
+	id, err := parseID(req.GetId(), "id")
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.service.Activate(ctx, id, domain.ActivateUserRequest{Note: req.GetNote()})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toProtoUser(user), nil
+}
+
+func (s *Server) DeactivateUser(ctx context.Context, req *userv1.DeactivateUserRequest) (*userv1.User, error) {
+	id, err := parseID(req.GetId(), "id")
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.service.Deactivate(ctx, id, domain.DeactivateUserRequest{Note: req.GetNote()})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toProtoUser(user), nil
+}
+
+func (s *Server) SendVerification(ctx context.Context, req *userv1.SendVerificationRequest) (*emptypb.Empty, error) {
+	id, err := parseID(req.GetId(), "id")
+	if err != nil {
+		return nil, err
+	}
+	if err := s.service.SendVerification(ctx, id); err != nil {
+		return nil, toStatus(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *Server) VerifyEmail(ctx context.Context, req *userv1.VerifyEmailRequest) (*userv1.User, error) {
+	user, err := s.service.VerifyEmail(ctx, domain.VerifyEmailRequest{Token: req.GetToken()})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toProtoUser(user), nil
+}
+
+func (s *Server) ConfirmEmailChange(ctx context.Context, req *userv1.ConfirmEmailChangeRequest) (*userv1.User, error) {
+	user, err := s.service.ConfirmEmailChange(ctx, domain.ConfirmEmailChangeRequest{Token: req.GetToken()})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toProtoUser(user), nil
+}
+
+func (s *Server) GetUserByExternalId(ctx context.Context, req *userv1.GetUserByExternalIdRequest) (*userv1.User, error) {
+	user, err := s.service.GetByExternalID(ctx, req.GetProvider(), req.GetExternalId())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toProtoUser(user), nil
+}
+
+func (s *Server) UpsertUserByExternalId(ctx context.Context, req *userv1.UpsertUserByExternalIdRequest) (*userv1.UpsertUserResponse, error) {
+	user, created, err := s.service.UpsertByExternalID(ctx, req.GetProvider(), req.GetExternalId(), domain.CreateUserRequest{
+		Email:      req.GetEmail(),
+		FirstName:  req.GetFirstName(),
+		LastName:   req.GetLastName(),
+		Attributes: fromStruct(req.GetAttributes()),
+	})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &userv1.UpsertUserResponse{User: toProtoUser(user), Created: created}, nil
+}
+
+func (s *Server) ListIdentities(ctx context.Context, req *userv1.ListIdentitiesRequest) (*userv1.ListIdentitiesResponse, error) {
+	id, err := parseID(req.GetUserId(), "user_id")
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := s.service.ListIdentities(ctx, id)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	identities := make([]*userv1.ExternalIdentity, len(list.Data))
+	for i := range list.Data {
+		identities[i] = toProtoIdentity(&list.Data[i])
+	}
+	return &userv1.ListIdentitiesResponse{Identities: identities}, nil
+}
+
+func (s *Server) LinkIdentity(ctx context.Context, req *userv1.LinkIdentityRequest) (*userv1.ExternalIdentity, error) {
+	id, err := parseID(req.GetUserId(), "user_id")
+	if err != nil {
+		return nil, err
+	}
+
+	identity, err := s.service.LinkIdentity(ctx, id, domain.LinkIdentityRequest{
+		Provider:   req.GetProvider(),
+		ExternalID: req.GetExternalId(),
+	})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toProtoIdentity(identity), nil
+}
+
+func (s *Server) UnlinkIdentity(ctx context.Context, req *userv1.UnlinkIdentityRequest) (*emptypb.Empty, error) {
+	id, err := parseID(req.GetUserId(), "user_id")
+	if err != nil {
+		return nil, err
+	}
+	if err := s.service.UnlinkIdentity(ctx, id, req.GetProvider(), req.GetExternalId()); err != nil {
+		return nil, toStatus(err)
+	}
+	return &emptypb.Empty{}, nil
+}
@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/giannuccilli/user-api/internal/domain"
+	"github.com/giannuccilli/user-api/internal/service"
+)
+
+type AttributeHandler struct {
+	registry   *service.AttributeRegistry
+	decodeOpts DecodeOptions
+}
+
+func NewAttributeHandler(registry *service.AttributeRegistry, decodeOpts DecodeOptions) *AttributeHandler {
+	return &AttributeHandler{registry: registry, decodeOpts: decodeOpts}
+}
+
+func (h *AttributeHandler) List(w http.ResponseWriter, r *http.Request) {
+	defs, err := h.registry.List(r.Context())
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	JSON(w, http.StatusOK, defs)
+}
+
+func (h *AttributeHandler) Get(w http.ResponseWriter, r *http.Request) {
+	def, err := h.registry.Get(r.Context(), r.PathValue("key"))
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	JSON(w, http.StatusOK, def)
+}
+
+func (h *AttributeHandler) Put(w http.ResponseWriter, r *http.Request) {
+	var req domain.PutAttributeDefinitionRequest
+	if !decodeRequest(w, r, &req, h.decodeOpts, false) {
+		return
+	}
+
+	def, err := h.registry.Put(r.Context(), r.PathValue("key"), req)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	JSON(w, http.StatusOK, def)
+}
+
+func (h *AttributeHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	if err := h.registry.Delete(r.Context(), r.PathValue("key")); err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *AttributeHandler) routes() []route {
+	return []route{
+		{"GET /api/v1/attribute-definitions", h.List},
+		{"GET /api/v1/attribute-definitions/{key}", h.Get},
+		{"PUT /api/v1/attribute-definitions/{key}", h.Put},
+		{"DELETE /api/v1/attribute-definitions/{key}", h.Delete},
+	}
+}
+
+func (h *AttributeHandler) RegisterRoutes(mux *http.ServeMux) {
+	registerRoutes(mux, h.routes())
+}
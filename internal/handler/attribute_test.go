@@ -0,0 +1,149 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/giannuccilli/user-api/internal/domain"
+	"github.com/giannuccilli/user-api/internal/service"
+)
+
+type mockAttributeRepository struct {
+	defs map[string]domain.AttributeDefinition
+}
+
+func (m *mockAttributeRepository) List(ctx context.Context) ([]domain.AttributeDefinition, error) {
+	defs := make([]domain.AttributeDefinition, 0, len(m.defs))
+	for _, def := range m.defs {
+		defs = append(defs, def)
+	}
+	return defs, nil
+}
+
+func (m *mockAttributeRepository) Get(ctx context.Context, key string) (*domain.AttributeDefinition, error) {
+	def, ok := m.defs[key]
+	if !ok {
+		return nil, domain.ErrAttributeNotFound
+	}
+	return &def, nil
+}
+
+func (m *mockAttributeRepository) Save(ctx context.Context, def *domain.AttributeDefinition) error {
+	def.CreatedAt = time.Now()
+	def.UpdatedAt = def.CreatedAt
+	m.defs[def.Key] = *def
+	return nil
+}
+
+func (m *mockAttributeRepository) Delete(ctx context.Context, key string) error {
+	if _, ok := m.defs[key]; !ok {
+		return domain.ErrAttributeNotFound
+	}
+	delete(m.defs, key)
+	return nil
+}
+
+func TestAttributeHandler(t *testing.T) {
+	registry := service.NewAttributeRegistry(&mockAttributeRepository{defs: map[string]domain.AttributeDefinition{}})
+	mux := http.NewServeMux()
+	NewAttributeHandler(registry, DecodeOptions{}).RegisterRoutes(mux)
+	handler := Actor()(mux)
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		roles      string
+		wantStatus int
+		wantCode   string
+	}{
+		{"put requires admin", http.MethodPut, "/api/v1/attribute-definitions/plan", `{"schema":{"type":"string"}}`, "", http.StatusForbidden, ErrCodeForbidden},
+		{"put requires platform admin", http.MethodPut, "/api/v1/attribute-definitions/plan", `{"schema":{"type":"string"}}`, domain.RoleAdmin, http.StatusForbidden, ErrCodeForbidden},
+		{"put invalid schema", http.MethodPut, "/api/v1/attribute-definitions/plan", `{"schema":{"type":5}}`, domain.RolePlatformAdmin, http.StatusUnprocessableEntity, ErrCodeValidationFailed},
+		{"put", http.MethodPut, "/api/v1/attribute-definitions/plan", `{"description":"Billing plan","schema":{"type":"string"}}`, domain.RolePlatformAdmin, http.StatusOK, ""},
+		{"get", http.MethodGet, "/api/v1/attribute-definitions/plan", "", "", http.StatusOK, ""},
+		{"list", http.MethodGet, "/api/v1/attribute-definitions", "", "", http.StatusOK, ""},
+		{"delete", http.MethodDelete, "/api/v1/attribute-definitions/plan", "", domain.RolePlatformAdmin, http.StatusNoContent, ""},
+		{"get deleted", http.MethodGet, "/api/v1/attribute-definitions/plan", "", "", http.StatusNotFound, ErrCodeAttributeNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.roles != "" {
+				req.Header.Set("X-Actor-Roles", tt.roles)
+			}
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("%s %s status = %v, want %v (body: %s)", tt.method, tt.path, rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantCode != "" {
+				var problem Problem
+				if err := json.NewDecoder(rec.Body).Decode(&problem); err != nil {
+					t.Fatalf("Failed to decode response: %v", err)
+				}
+				if problem.Code != tt.wantCode {
+					t.Errorf("code = %v, want %v", problem.Code, tt.wantCode)
+				}
+			}
+		})
+	}
+}
+
+func TestUserHandler_ListByAttributes(t *testing.T) {
+	handler, repo := setupTestHandler()
+
+	for i, attrs := range []domain.Attributes{
+		{"plan": "pro", "seats": float64(5)},
+		{"plan": "free", "seats": float64(1)},
+		{"plan": "pro", "seats": float64(1)},
+	} {
+		user := &domain.User{
+			ID:         uuid.New(),
+			Email:      "test" + string(rune('0'+i)) + "@example.com",
+			Status:     domain.UserStatusActive,
+			Attributes: attrs,
+		}
+		repo.users[user.ID] = user
+	}
+
+	tests := []struct {
+		name    string
+		query   string
+		wantLen int
+	}{
+		{"no filter", "", 3},
+		{"string value", "?attr.plan=pro", 2},
+		{"json value", "?attr.plan=pro&attr.seats=1", 1},
+		{"no match", "?attr.plan=enterprise", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/users"+tt.query, nil)
+			rec := httptest.NewRecorder()
+
+			handler.List(rec, req)
+
+			var response domain.UserList
+			if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if len(response.Data) != tt.wantLen {
+				t.Errorf("List(%s) len = %v, want %v", tt.query, len(response.Data), tt.wantLen)
+			}
+		})
+	}
+}
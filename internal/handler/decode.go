@@ -0,0 +1,160 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+const DefaultMaxBodyBytes = 1 << 20
+
+type DecodeOptions struct {
+	MaxBodyBytes       int64
+	AllowUnknownFields bool
+}
+
+type decodeError struct {
+	status  int
+	code    string
+	message string
+}
+
+func (e *decodeError) Error() string {
+	return e.message
+}
+
+// decodeJSON decodes a single JSON value from the request body into v. On
+// failure it writes the error response and returns false.
+func (h *UserHandler) decodeJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	return decodeRequest(w, r, v, h.decodeOpts, false)
+}
+
+// decodeOptionalJSON is like decodeJSON but accepts an empty body, leaving v
+// untouched.
+func (h *UserHandler) decodeOptionalJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	return decodeRequest(w, r, v, h.decodeOpts, true)
+}
+
+func decodeRequest(w http.ResponseWriter, r *http.Request, v any, opts DecodeOptions, optional bool) bool {
+	err := decodeBody(w, r, v, opts, optional)
+	if err == nil {
+		return true
+	}
+
+	var de *decodeError
+	if !errors.As(err, &de) {
+		de = &decodeError{status: http.StatusBadRequest, code: ErrCodeInvalidRequest, message: "Invalid JSON body"}
+	}
+	ErrorWithMessage(w, r, de.status, de.code, de.message)
+	return false
+}
+
+func decodeBody(w http.ResponseWriter, r *http.Request, v any, opts DecodeOptions, optional bool) error {
+	if optional && r.ContentLength == 0 {
+		return nil
+	}
+
+	if !isJSONContentType(r.Header.Get("Content-Type")) {
+		return &decodeError{
+			status:  http.StatusUnsupportedMediaType,
+			code:    ErrCodeUnsupportedMediaType,
+			message: "Content-Type must be application/json",
+		}
+	}
+
+	maxBytes := opts.MaxBodyBytes
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxBodyBytes
+	}
+
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBytes))
+	if !opts.AllowUnknownFields {
+		dec.DisallowUnknownFields()
+	}
+
+	if err := dec.Decode(v); err != nil {
+		if optional && errors.Is(err, io.EOF) {
+			return nil
+		}
+		return translateDecodeError(err, maxBytes)
+	}
+
+	if err := dec.Decode(&struct{}{}); !errors.Is(err, io.EOF) {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			return translateDecodeError(err, maxBytes)
+		}
+		return invalidBody("Request body must contain a single JSON value")
+	}
+
+	return nil
+}
+
+func translateDecodeError(err error, maxBytes int64) error {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var maxErr *http.MaxBytesError
+
+	switch {
+	case errors.As(err, &maxErr):
+		return &decodeError{
+			status:  http.StatusRequestEntityTooLarge,
+			code:    ErrCodeRequestTooLarge,
+			message: fmt.Sprintf("Request body must not exceed %d bytes", maxBytes),
+		}
+	case errors.As(err, &syntaxErr):
+		return invalidBody(fmt.Sprintf("Malformed JSON at offset %d", syntaxErr.Offset))
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return invalidBody("Malformed JSON: unexpected end of body")
+	case errors.Is(err, io.EOF):
+		return invalidBody("Request body must not be empty")
+	case errors.As(err, &typeErr):
+		if typeErr.Field == "" {
+			return invalidBody(fmt.Sprintf("Request body must be a JSON %s", jsonTypeName(typeErr.Type)))
+		}
+		return invalidBody(fmt.Sprintf("Invalid value for field %q at offset %d: expected %s",
+			typeErr.Field, typeErr.Offset, jsonTypeName(typeErr.Type)))
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		return invalidBody("Unknown field " + strings.TrimPrefix(err.Error(), "json: unknown field "))
+	default:
+		return invalidBody("Invalid JSON body: " + strings.TrimPrefix(err.Error(), "json: "))
+	}
+}
+
+func invalidBody(message string) error {
+	return &decodeError{status: http.StatusBadRequest, code: ErrCodeInvalidRequest, message: message}
+}
+
+func isJSONContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// jsonTypeName describes t in JSON terms so internal Go type names do not
+// leak into error messages.
+func jsonTypeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.Pointer:
+		return jsonTypeName(t.Elem())
+	default:
+		return "object"
+	}
+}
@@ -0,0 +1,111 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/giannuccilli/user-api/internal/domain"
+	"github.com/giannuccilli/user-api/internal/service"
+)
+
+func TestUserHandler_Create_Decoding(t *testing.T) {
+	valid := `{"email":"test@example.com","firstName":"John","lastName":"Doe"}`
+
+	tests := []struct {
+		name        string
+		opts        DecodeOptions
+		contentType string
+		body        string
+		wantStatus  int
+		wantCode    string
+		wantDetail  string
+	}{
+		{name: "valid", contentType: "application/json", body: valid, wantStatus: http.StatusCreated},
+		{name: "json with charset", contentType: "application/json; charset=utf-8", body: valid, wantStatus: http.StatusCreated},
+		{name: "missing content type", body: valid, wantStatus: http.StatusUnsupportedMediaType, wantCode: ErrCodeUnsupportedMediaType},
+		{name: "form content type", contentType: "application/x-www-form-urlencoded", body: valid, wantStatus: http.StatusUnsupportedMediaType, wantCode: ErrCodeUnsupportedMediaType},
+		{name: "empty body", contentType: "application/json", body: "", wantStatus: http.StatusBadRequest, wantDetail: "Request body must not be empty"},
+		{name: "syntax error", contentType: "application/json", body: `{"email":}`, wantStatus: http.StatusBadRequest, wantDetail: "Malformed JSON at offset 10"},
+		{name: "truncated", contentType: "application/json", body: `{"email":"a`, wantStatus: http.StatusBadRequest, wantDetail: "Malformed JSON: unexpected end of body"},
+		{name: "wrong field type", contentType: "application/json", body: `{"email":42}`, wantStatus: http.StatusBadRequest, wantDetail: `Invalid value for field "email" at offset 11: expected string`},
+		{name: "not an object", contentType: "application/json", body: `[]`, wantStatus: http.StatusBadRequest, wantDetail: "Request body must be a JSON object"},
+		{name: "unknown field", contentType: "application/json", body: `{"email":"test@example.com","nickname":"jd"}`, wantStatus: http.StatusBadRequest, wantDetail: `Unknown field "nickname"`},
+		{
+			name:        "unknown field allowed",
+			opts:        DecodeOptions{AllowUnknownFields: true},
+			contentType: "application/json",
+			body:        `{"email":"test@example.com","firstName":"John","lastName":"Doe","nickname":"jd"}`,
+			wantStatus:  http.StatusCreated,
+		},
+		{name: "trailing data", contentType: "application/json", body: valid + `{}`, wantStatus: http.StatusBadRequest, wantDetail: "Request body must contain a single JSON value"},
+		{name: "trailing whitespace", contentType: "application/json", body: valid + "\n", wantStatus: http.StatusCreated},
+		{
+			name:        "too large",
+			opts:        DecodeOptions{MaxBodyBytes: 32},
+			contentType: "application/json",
+			body:        valid,
+			wantStatus:  http.StatusRequestEntityTooLarge,
+			wantCode:    ErrCodeRequestTooLarge,
+			wantDetail:  "Request body must not exceed 32 bytes",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewUserHandler(service.NewUserService(newMockUserRepository(), &mockNotifier{}), WithDecodeOptions(tt.opts))
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/users", bytes.NewBufferString(tt.body))
+			req.Header.Set("Accept", ContentTypeProblem)
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			rec := httptest.NewRecorder()
+
+			handler.Create(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("Create() status = %v, want %v (body: %s)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantStatus == http.StatusCreated {
+				return
+			}
+
+			var problem Problem
+			json.NewDecoder(rec.Body).Decode(&problem)
+			wantCode := tt.wantCode
+			if wantCode == "" {
+				wantCode = ErrCodeInvalidRequest
+			}
+			if problem.Code != wantCode {
+				t.Errorf("Create() code = %v, want %v", problem.Code, wantCode)
+			}
+			if tt.wantDetail != "" && problem.Detail != tt.wantDetail {
+				t.Errorf("Create() detail = %q, want %q", problem.Detail, tt.wantDetail)
+			}
+		})
+	}
+}
+
+func TestUserHandler_Action_EmptyBody(t *testing.T) {
+	handler, repo := setupTestHandler()
+	mux := http.NewServeMux()
+	handler.RegisterRoutes(mux)
+
+	user := &domain.User{ID: uuid.New(), Email: "test@example.com", FirstName: "John", LastName: "Doe", Status: domain.UserStatusActive}
+	repo.users[user.ID] = user
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/users/"+user.ID.String()+":deactivate", strings.NewReader(""))
+	rec := httptest.NewRecorder()
+
+	mux.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("deactivate without body status = %v, want %v (body: %s)", rec.Code, http.StatusOK, rec.Body.String())
+	}
+}
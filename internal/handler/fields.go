@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/giannuccilli/user-api/internal/domain"
+)
+
+// fieldsParam parses the fields query parameter. On failure it writes the
+// error response and returns false.
+func fieldsParam(w http.ResponseWriter, r *http.Request) (domain.FieldSet, bool) {
+	fields, err := domain.ParseFieldSet(r.URL.Query().Get("fields"))
+	if err != nil {
+		Error(w, r, err)
+		return nil, false
+	}
+	return fields, true
+}
+
+// projectUser keeps only the requested fields of user in the response, so
+// the zero values of the columns that were not read are left out.
+func projectUser(user domain.User, fields domain.FieldSet) any {
+	if fields == nil {
+		return user
+	}
+
+	data, err := json.Marshal(user)
+	if err != nil {
+		return user
+	}
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(data, &all); err != nil {
+		return user
+	}
+
+	projected := make(map[string]json.RawMessage, len(fields))
+	for _, name := range fields {
+		if value, ok := all[name]; ok {
+			projected[name] = value
+		}
+	}
+	return projected
+}
+
+func projectUsers(users []domain.User, fields domain.FieldSet) []any {
+	projected := make([]any, len(users))
+	for i, user := range users {
+		projected[i] = projectUser(user, fields)
+	}
+	return projected
+}
@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/giannuccilli/user-api/internal/domain"
+)
+
+func TestUserHandler_Fields(t *testing.T) {
+	handler, repo := setupTestHandler()
+
+	user := &domain.User{
+		ID:        uuid.New(),
+		TenantID:  domain.DefaultTenantID,
+		Email:     "test@example.com",
+		FirstName: "John",
+		LastName:  "Doe",
+		Status:    domain.UserStatusActive,
+	}
+	repo.users[user.ID] = user
+
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		body       string
+		call       func(w http.ResponseWriter, r *http.Request)
+		wantStatus int
+		// wantKeys are the keys of the returned user, or of the first
+		// element of data for list responses.
+		wantKeys []string
+		list     bool
+	}{
+		{
+			name:       "get with fields",
+			method:     http.MethodGet,
+			target:     "/api/v1/users/" + user.ID.String() + "?fields=email,status",
+			call:       handler.GetByID,
+			wantStatus: http.StatusOK,
+			wantKeys:   []string{"email", "id", "status"},
+		},
+		{
+			name:       "get without fields",
+			method:     http.MethodGet,
+			target:     "/api/v1/users/" + user.ID.String(),
+			call:       handler.GetByID,
+			wantStatus: http.StatusOK,
+			wantKeys:   []string{"createdAt", "email", "emailVerified", "firstName", "id", "lastName", "status", "tenantId", "updatedAt"},
+		},
+		{
+			name:       "get with unknown field",
+			method:     http.MethodGet,
+			target:     "/api/v1/users/" + user.ID.String() + "?fields=email,password",
+			call:       handler.GetByID,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "list with fields",
+			method:     http.MethodGet,
+			target:     "/api/v1/users?fields=firstName",
+			call:       handler.List,
+			wantStatus: http.StatusOK,
+			wantKeys:   []string{"firstName", "id"},
+			list:       true,
+		},
+		{
+			name:       "batch get with fields",
+			method:     http.MethodPost,
+			target:     "/api/v1/users:batchGet?fields=email",
+			body:       `{"ids":["` + user.ID.String() + `"]}`,
+			call:       handler.BatchGet,
+			wantStatus: http.StatusOK,
+			wantKeys:   []string{"email", "id"},
+			list:       true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.target, bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			req.SetPathValue("id", user.ID.String())
+			rec := httptest.NewRecorder()
+
+			tt.call(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %v, want %v", rec.Code, tt.wantStatus)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var got map[string]any
+			if tt.list {
+				var resp struct {
+					Data []map[string]any `json:"data"`
+				}
+				json.NewDecoder(rec.Body).Decode(&resp)
+				if len(resp.Data) != 1 {
+					t.Fatalf("data len = %d, want 1", len(resp.Data))
+				}
+				got = resp.Data[0]
+			} else {
+				json.NewDecoder(rec.Body).Decode(&got)
+			}
+
+			if keys := slices.Sorted(maps.Keys(got)); !slices.Equal(keys, tt.wantKeys) {
+				t.Errorf("keys = %v, want %v", keys, tt.wantKeys)
+			}
+		})
+	}
+}
@@ -0,0 +1,394 @@
+package handler
+
+import (
+	"context"
+	_ "embed"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/graph-gophers/graphql-go"
+
+	"github.com/giannuccilli/user-api/internal/domain"
+	"github.com/giannuccilli/user-api/internal/service"
+)
+
+//go:embed schema.graphql
+var graphqlSchema string
+
+const (
+	graphqlMaxDepth = 10
+	// loaderWait is how long the first user lookup of a request waits for
+	// others to join its batch.
+	loaderWait = 2 * time.Millisecond
+)
+
+type GraphQLHandler struct {
+	service    *service.UserService
+	schema     *graphql.Schema
+	decodeOpts DecodeOptions
+}
+
+func NewGraphQLHandler(service *service.UserService, decodeOpts DecodeOptions) *GraphQLHandler {
+	return &GraphQLHandler{
+		service:    service,
+		schema:     graphql.MustParseSchema(graphqlSchema, &graphqlResolver{service: service}, graphql.MaxDepth(graphqlMaxDepth)),
+		decodeOpts: decodeOpts,
+	}
+}
+
+type graphqlRequest struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+	Extensions    map[string]any `json:"extensions"`
+}
+
+func (h *GraphQLHandler) Serve(w http.ResponseWriter, r *http.Request) {
+	var req graphqlRequest
+	if !decodeRequest(w, r, &req, h.decodeOpts, false) {
+		return
+	}
+
+	ctx := context.WithValue(r.Context(), userLoaderKey{}, newUserLoader(h.service, loaderWait))
+	JSON(w, http.StatusOK, h.schema.Exec(ctx, req.Query, req.OperationName, req.Variables))
+}
+
+func (h *GraphQLHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("POST /graphql", h.Serve)
+}
+
+type userLoaderKey struct{}
+
+func loaderFromContext(ctx context.Context) *userLoader {
+	return ctx.Value(userLoaderKey{}).(*userLoader)
+}
+
+// graphqlError carries the same code and field errors as the REST error
+// responses in the GraphQL error extensions.
+type graphqlError struct {
+	resp ErrorResponse
+}
+
+func newGraphQLError(err error) error {
+	_, resp := errorResponse(err)
+	return &graphqlError{resp: resp}
+}
+
+func invalidIDError() error {
+	return &graphqlError{resp: ErrorResponse{Code: ErrCodeInvalidID, Message: "Invalid user ID format"}}
+}
+
+func (e *graphqlError) Error() string {
+	return e.resp.Message
+}
+
+func (e *graphqlError) Extensions() map[string]any {
+	ext := map[string]any{"code": e.resp.Code}
+	if len(e.resp.Fields) > 0 {
+		ext["fields"] = e.resp.Fields
+	}
+	return ext
+}
+
+type graphqlResolver struct {
+	service *service.UserService
+}
+
+func (r *graphqlResolver) User(ctx context.Context, args struct{ ID graphql.ID }) (*userResolver, error) {
+	id, err := uuid.Parse(string(args.ID))
+	if err != nil {
+		return nil, invalidIDError()
+	}
+
+	user, err := loaderFromContext(ctx).Load(ctx, id)
+	if err != nil {
+		return nil, newGraphQLError(err)
+	}
+	if user == nil {
+		return nil, nil
+	}
+	return &userResolver{user: user}, nil
+}
+
+func (r *graphqlResolver) UsersByIds(ctx context.Context, args struct{ IDs []graphql.ID }) ([]*userResolver, error) {
+	ids := make([]uuid.UUID, len(args.IDs))
+	for i, raw := range args.IDs {
+		id, err := uuid.Parse(string(raw))
+		if err != nil {
+			return nil, invalidIDError()
+		}
+		ids[i] = id
+	}
+
+	users, err := loaderFromContext(ctx).LoadMany(ctx, ids)
+	if err != nil {
+		return nil, newGraphQLError(err)
+	}
+
+	resolvers := make([]*userResolver, len(users))
+	for i, user := range users {
+		if user != nil {
+			resolvers[i] = &userResolver{user: user}
+		}
+	}
+	return resolvers, nil
+}
+
+type usersArgs struct {
+	First  *int32
+	After  *string
+	Filter *struct {
+		Attributes *jsonScalar
+	}
+}
+
+func (r *graphqlResolver) Users(ctx context.Context, args usersArgs) (*userListResolver, error) {
+	v := &domain.ValidationError{}
+	filter := domain.UserFilter{}
+	if args.First != nil {
+		filter.Limit = int(*args.First)
+	}
+	if args.After != nil {
+		offset, err := decodeCursor(*args.After)
+		if err != nil {
+			v.Add("after", domain.FieldCodeInvalidFormat, "must be a cursor returned in pageInfo.endCursor")
+		}
+		filter.Offset = offset
+	}
+	if args.Filter != nil {
+		filter.Attributes = args.Filter.Attributes.attributes(v, "filter.attributes")
+	}
+	if err := v.Err(); err != nil {
+		return nil, newGraphQLError(err)
+	}
+
+	list, err := r.service.List(ctx, filter)
+	if err != nil {
+		return nil, newGraphQLError(err)
+	}
+	return &userListResolver{list: list}, nil
+}
+
+type createUserInput struct {
+	Email      string
+	FirstName  string
+	LastName   string
+	Attributes *jsonScalar
+}
+
+func (r *graphqlResolver) CreateUser(ctx context.Context, args struct{ Input createUserInput }) (*userResolver, error) {
+	v := &domain.ValidationError{}
+	attrs := args.Input.Attributes.attributes(v, "attributes")
+	if err := v.Err(); err != nil {
+		return nil, newGraphQLError(err)
+	}
+
+	user, err := r.service.Create(ctx, domain.CreateUserRequest{
+		Email:      args.Input.Email,
+		FirstName:  args.Input.FirstName,
+		LastName:   args.Input.LastName,
+		Attributes: attrs,
+	})
+	if err != nil {
+		return nil, newGraphQLError(err)
+	}
+	return &userResolver{user: user}, nil
+}
+
+type updateUserInput struct {
+	Email          *string
+	FirstName      *string
+	LastName       *string
+	Status         *string
+	StatusReason   *string
+	SuspendedUntil *graphql.Time
+	Attributes     *jsonScalar
+}
+
+func (r *graphqlResolver) UpdateUser(ctx context.Context, args struct {
+	ID    graphql.ID
+	Input updateUserInput
+}) (*userResolver, error) {
+	id, err := uuid.Parse(string(args.ID))
+	if err != nil {
+		return nil, invalidIDError()
+	}
+
+	v := &domain.ValidationError{}
+	req := domain.UpdateUserRequest{
+		Email:      args.Input.Email,
+		FirstName:  args.Input.FirstName,
+		LastName:   args.Input.LastName,
+		Attributes: args.Input.Attributes.attributes(v, "attributes"),
+	}
+	if err := v.Err(); err != nil {
+		return nil, newGraphQLError(err)
+	}
+	if args.Input.Status != nil {
+		status := domain.UserStatus(*args.Input.Status)
+		req.Status = &status
+	}
+	if args.Input.StatusReason != nil {
+		reason := domain.SuspensionReason(*args.Input.StatusReason)
+		req.StatusReason = &reason
+	}
+	if args.Input.SuspendedUntil != nil {
+		req.SuspendedUntil = &args.Input.SuspendedUntil.Time
+	}
+
+	user, err := r.service.Update(ctx, id, req)
+	if err != nil {
+		return nil, newGraphQLError(err)
+	}
+	loaderFromContext(ctx).Forget(id)
+	return &userResolver{user: user}, nil
+}
+
+func (r *graphqlResolver) DeleteUser(ctx context.Context, args struct{ ID graphql.ID }) (graphql.ID, error) {
+	id, err := uuid.Parse(string(args.ID))
+	if err != nil {
+		return "", invalidIDError()
+	}
+
+	if err := r.service.Delete(ctx, id); err != nil {
+		return "", newGraphQLError(err)
+	}
+	loaderFromContext(ctx).Forget(id)
+	return args.ID, nil
+}
+
+type userResolver struct {
+	user *domain.User
+}
+
+func (r *userResolver) ID() graphql.ID        { return graphql.ID(r.user.ID.String()) }
+func (r *userResolver) TenantID() string      { return r.user.TenantID }
+func (r *userResolver) Email() string         { return r.user.Email }
+func (r *userResolver) EmailVerified() bool   { return r.user.EmailVerified }
+func (r *userResolver) PendingEmail() *string { return optionalString(r.user.PendingEmail) }
+func (r *userResolver) FirstName() string     { return r.user.FirstName }
+func (r *userResolver) LastName() string      { return r.user.LastName }
+func (r *userResolver) Status() string        { return string(r.user.Status) }
+func (r *userResolver) StatusReason() *string { return optionalString(string(r.user.StatusReason)) }
+func (r *userResolver) CreatedAt() graphql.Time {
+	return graphql.Time{Time: r.user.CreatedAt}
+}
+func (r *userResolver) UpdatedAt() graphql.Time {
+	return graphql.Time{Time: r.user.UpdatedAt}
+}
+
+func (r *userResolver) SuspendedUntil() *graphql.Time {
+	if r.user.SuspendedUntil == nil {
+		return nil
+	}
+	return &graphql.Time{Time: *r.user.SuspendedUntil}
+}
+
+func (r *userResolver) Attributes() *jsonScalar {
+	if len(r.user.Attributes) == 0 {
+		return nil
+	}
+	return &jsonScalar{value: map[string]any(r.user.Attributes)}
+}
+
+type userListResolver struct {
+	list *domain.UserList
+}
+
+func (r *userListResolver) Nodes() []*userResolver {
+	nodes := make([]*userResolver, len(r.list.Data))
+	for i := range r.list.Data {
+		nodes[i] = &userResolver{user: &r.list.Data[i]}
+	}
+	return nodes
+}
+
+func (r *userListResolver) TotalCount() int32 {
+	return int32(r.list.Pagination.Total)
+}
+
+func (r *userListResolver) PageInfo() *pageInfoResolver {
+	end := r.list.Pagination.Offset + len(r.list.Data)
+	info := &pageInfoResolver{hasNextPage: end < r.list.Pagination.Total}
+	if len(r.list.Data) > 0 {
+		cursor := encodeCursor(end)
+		info.endCursor = &cursor
+	}
+	return info
+}
+
+type pageInfoResolver struct {
+	hasNextPage bool
+	endCursor   *string
+}
+
+func (r *pageInfoResolver) HasNextPage() bool  { return r.hasNextPage }
+func (r *pageInfoResolver) EndCursor() *string { return r.endCursor }
+
+// Cursors are opaque to clients; they hold the offset of the next page.
+const cursorPrefix = "offset:"
+
+func encodeCursor(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(cursorPrefix + strconv.Itoa(offset)))
+}
+
+func decodeCursor(cursor string) (int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, err
+	}
+	value, ok := strings.CutPrefix(string(raw), cursorPrefix)
+	if !ok {
+		return 0, fmt.Errorf("invalid cursor %q", cursor)
+	}
+	offset, err := strconv.Atoi(value)
+	if err != nil || offset < 0 {
+		return 0, fmt.Errorf("invalid cursor %q", cursor)
+	}
+	return offset, nil
+}
+
+// jsonScalar is the JSON scalar of the schema, used for user attributes.
+type jsonScalar struct {
+	value any
+}
+
+func (jsonScalar) ImplementsGraphQLType(name string) bool {
+	return name == "JSON"
+}
+
+func (j *jsonScalar) UnmarshalGraphQL(input any) error {
+	j.value = input
+	return nil
+}
+
+func (j jsonScalar) MarshalJSON() ([]byte, error) {
+	return json.Marshal(j.value)
+}
+
+// attributes returns j as user attributes, recording a field error on v when
+// it is not an object. A nil j yields nil attributes.
+func (j *jsonScalar) attributes(v *domain.ValidationError, field string) domain.Attributes {
+	if j == nil || j.value == nil {
+		return nil
+	}
+	attrs, ok := j.value.(map[string]any)
+	if !ok {
+		v.Add(field, domain.FieldCodeInvalidValue, "must be an object")
+		return nil
+	}
+	return attrs
+}
+
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
@@ -0,0 +1,233 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/giannuccilli/user-api/internal/domain"
+	"github.com/giannuccilli/user-api/internal/service"
+)
+
+type countingUserRepository struct {
+	*mockUserRepository
+	batchCalls atomic.Int32
+}
+
+func (m *countingUserRepository) GetByIDs(ctx context.Context, ids []uuid.UUID, fields domain.FieldSet) ([]domain.User, error) {
+	m.batchCalls.Add(1)
+	return m.mockUserRepository.GetByIDs(ctx, ids, fields)
+}
+
+type graphqlResponse struct {
+	Data   map[string]json.RawMessage `json:"data"`
+	Errors []struct {
+		Message    string         `json:"message"`
+		Extensions map[string]any `json:"extensions"`
+	} `json:"errors"`
+}
+
+func execGraphQL(t *testing.T, h *GraphQLHandler, query string, variables map[string]any) graphqlResponse {
+	t.Helper()
+
+	body, _ := json.Marshal(map[string]any{"query": query, "variables": variables})
+	req := httptest.NewRequest(http.MethodPost, "/graphql", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+
+	h.Serve(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Serve() status = %v, want %v", rec.Code, http.StatusOK)
+	}
+	var resp graphqlResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	return resp
+}
+
+func setupGraphQLHandler() (*GraphQLHandler, *countingUserRepository) {
+	repo := &countingUserRepository{mockUserRepository: newMockUserRepository()}
+	svc := service.NewUserService(repo, &mockNotifier{})
+	return NewGraphQLHandler(svc, DecodeOptions{}), repo
+}
+
+func TestGraphQLHandler_BatchesLookups(t *testing.T) {
+	h, repo := setupGraphQLHandler()
+
+	first := &domain.User{ID: uuid.New(), Email: "first@example.com", Status: domain.UserStatusActive}
+	second := &domain.User{ID: uuid.New(), Email: "second@example.com", Status: domain.UserStatusActive}
+	repo.users[first.ID] = first
+	repo.users[second.ID] = second
+
+	query := `query($a: ID!, $b: ID!, $c: ID!) {
+		a: user(id: $a) { email }
+		b: user(id: $b) { email }
+		c: user(id: $c) { email }
+		many: usersByIds(ids: [$b, $c, $a]) { id }
+	}`
+	resp := execGraphQL(t, h, query, map[string]any{
+		"a": first.ID.String(),
+		"b": second.ID.String(),
+		"c": uuid.NewString(),
+	})
+
+	if len(resp.Errors) > 0 {
+		t.Fatalf("unexpected errors: %+v", resp.Errors)
+	}
+	if got := repo.batchCalls.Load(); got != 1 {
+		t.Errorf("GetByIDs calls = %d, want 1", got)
+	}
+	if got := string(resp.Data["b"]); got != `{"email":"second@example.com"}` {
+		t.Errorf("b = %s", got)
+	}
+	if got := string(resp.Data["c"]); got != "null" {
+		t.Errorf("c = %s, want null", got)
+	}
+	want := `[{"id":"` + second.ID.String() + `"},null,{"id":"` + first.ID.String() + `"}]`
+	if got := string(resp.Data["many"]); got != want {
+		t.Errorf("many = %s, want %s", got, want)
+	}
+}
+
+func TestGraphQLHandler_Errors(t *testing.T) {
+	h, repo := setupGraphQLHandler()
+
+	user := &domain.User{ID: uuid.New(), Email: "test@example.com", Status: domain.UserStatusActive}
+	repo.users[user.ID] = user
+
+	tests := []struct {
+		name     string
+		query    string
+		wantCode string
+	}{
+		{
+			name:     "invalid id",
+			query:    `{ user(id: "nope") { id } }`,
+			wantCode: ErrCodeInvalidID,
+		},
+		{
+			name:     "validation failed",
+			query:    `mutation { createUser(input: {email: "bad", firstName: "", lastName: "Doe"}) { id } }`,
+			wantCode: ErrCodeValidationFailed,
+		},
+		{
+			name:     "attributes not an object",
+			query:    `mutation { createUser(input: {email: "a@example.com", firstName: "A", lastName: "B", attributes: 3}) { id } }`,
+			wantCode: ErrCodeValidationFailed,
+		},
+		{
+			name:     "update missing user",
+			query:    `mutation { updateUser(id: "` + uuid.NewString() + `", input: {firstName: "Jane"}) { id } }`,
+			wantCode: ErrCodeUserNotFound,
+		},
+		{
+			name:     "invalid cursor",
+			query:    `{ users(after: "nope") { totalCount } }`,
+			wantCode: ErrCodeValidationFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := execGraphQL(t, h, tt.query, nil)
+			if len(resp.Errors) != 1 {
+				t.Fatalf("errors = %+v, want one error", resp.Errors)
+			}
+			if got := resp.Errors[0].Extensions["code"]; got != tt.wantCode {
+				t.Errorf("code = %v, want %v", got, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestGraphQLHandler_Mutations(t *testing.T) {
+	h, repo := setupGraphQLHandler()
+
+	resp := execGraphQL(t, h, `mutation($input: CreateUserInput!) {
+		createUser(input: $input) { id email status }
+	}`, map[string]any{"input": map[string]any{
+		"email":     "new@example.com",
+		"firstName": "John",
+		"lastName":  "Doe",
+	}})
+	if len(resp.Errors) > 0 {
+		t.Fatalf("createUser errors: %+v", resp.Errors)
+	}
+	var created struct {
+		ID     string `json:"id"`
+		Status string `json:"status"`
+	}
+	json.Unmarshal(resp.Data["createUser"], &created)
+	if created.Status != string(domain.UserStatusActive) {
+		t.Errorf("createUser status = %v, want %v", created.Status, domain.UserStatusActive)
+	}
+
+	resp = execGraphQL(t, h, `mutation($id: ID!) {
+		updateUser(id: $id, input: {firstName: "Jane"}) { firstName }
+	}`, map[string]any{"id": created.ID})
+	if got := string(resp.Data["updateUser"]); got != `{"firstName":"Jane"}` {
+		t.Errorf("updateUser = %s, errors = %+v", got, resp.Errors)
+	}
+
+	resp = execGraphQL(t, h, `mutation($id: ID!) {
+		deleteUser(id: $id)
+	}`, map[string]any{"id": created.ID})
+	if len(resp.Errors) > 0 {
+		t.Fatalf("deleteUser errors: %+v", resp.Errors)
+	}
+	if len(repo.users) != 0 {
+		t.Errorf("users after delete = %d, want 0", len(repo.users))
+	}
+}
+
+func TestGraphQLHandler_Users(t *testing.T) {
+	h, repo := setupGraphQLHandler()
+
+	for range 3 {
+		user := &domain.User{ID: uuid.New(), Email: uuid.NewString() + "@example.com", Status: domain.UserStatusActive}
+		repo.users[user.ID] = user
+	}
+
+	resp := execGraphQL(t, h, `{ users(first: 2) { totalCount nodes { id } pageInfo { hasNextPage endCursor } } }`, nil)
+	if len(resp.Errors) > 0 {
+		t.Fatalf("users errors: %+v", resp.Errors)
+	}
+
+	var list struct {
+		TotalCount int `json:"totalCount"`
+		PageInfo   struct {
+			HasNextPage bool   `json:"hasNextPage"`
+			EndCursor   string `json:"endCursor"`
+		} `json:"pageInfo"`
+	}
+	json.Unmarshal(resp.Data["users"], &list)
+	if list.TotalCount != 3 {
+		t.Errorf("totalCount = %d, want 3", list.TotalCount)
+	}
+	if offset, err := decodeCursor(list.PageInfo.EndCursor); err != nil || offset != 3 {
+		t.Errorf("endCursor offset = %d, %v, want 3", offset, err)
+	}
+}
+
+func TestCursor(t *testing.T) {
+	for _, offset := range []int{0, 20, 1000} {
+		got, err := decodeCursor(encodeCursor(offset))
+		if err != nil || got != offset {
+			t.Errorf("decodeCursor(encodeCursor(%d)) = %d, %v", offset, got, err)
+		}
+	}
+
+	for _, cursor := range []string{"", "nope", encodeCursor(-1)} {
+		if _, err := decodeCursor(cursor); err == nil {
+			t.Errorf("decodeCursor(%q) expected error", cursor)
+		}
+	}
+}
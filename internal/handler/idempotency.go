@@ -0,0 +1,183 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/giannuccilli/user-api/internal/domain"
+)
+
+const (
+	IdempotencyKeyHeader     = "Idempotency-Key"
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+	DefaultIdempotencyTTL    = 24 * time.Hour
+	DefaultIdempotencyLease  = time.Minute
+	MaxIdempotencyKeyLength  = 255
+)
+
+// replayedHeaders are the response headers stored and replayed along with the
+// status and body. Per-request headers such as X-Request-ID are not.
+var replayedHeaders = []string{"Content-Type", "Location"}
+
+type IdempotencyOptions struct {
+	// TTL is how long a completed response is stored. Lease is how long a
+	// request in flight holds its key; once it runs out, for instance because
+	// the process crashed, a retry takes the key over.
+	TTL          time.Duration
+	Lease        time.Duration
+	MaxBodyBytes int64
+}
+
+type recordingWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (rw *recordingWriter) WriteHeader(code int) {
+	rw.status = code
+	rw.ResponseWriter.WriteHeader(code)
+}
+
+func (rw *recordingWriter) Write(b []byte) (int, error) {
+	rw.body.Write(b)
+	return rw.ResponseWriter.Write(b)
+}
+
+// Idempotency makes POST and PUT requests carrying an Idempotency-Key safe to
+// retry. The first request for a key is executed and its response stored;
+// retries with the same body get the stored response back, retries with a
+// different body are rejected with 422 and concurrent retries with 409 until
+// the lease of the first one runs out.
+// Keys are scoped to the actor. Responses with a 5xx status are not stored so
+// the client can retry them.
+func Idempotency(store domain.IdempotencyRepository, logger *slog.Logger, opts IdempotencyOptions) func(http.Handler) http.Handler {
+	if opts.TTL <= 0 {
+		opts.TTL = DefaultIdempotencyTTL
+	}
+	if opts.Lease <= 0 {
+		opts.Lease = DefaultIdempotencyLease
+	}
+	if opts.MaxBodyBytes <= 0 {
+		opts.MaxBodyBytes = DefaultMaxBodyBytes
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(IdempotencyKeyHeader)
+			if key == "" || (r.Method != http.MethodPost && r.Method != http.MethodPut) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(key) > MaxIdempotencyKeyLength {
+				ErrorWithMessage(w, r, http.StatusBadRequest, ErrCodeInvalidRequest,
+					fmt.Sprintf("%s must be at most %d characters", IdempotencyKeyHeader, MaxIdempotencyKeyLength))
+				return
+			}
+
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, opts.MaxBodyBytes))
+			if err != nil {
+				var maxErr *http.MaxBytesError
+				if errors.As(err, &maxErr) {
+					ErrorWithMessage(w, r, http.StatusRequestEntityTooLarge, ErrCodeRequestTooLarge,
+						fmt.Sprintf("Request body must not exceed %d bytes", opts.MaxBodyBytes))
+					return
+				}
+				ErrorWithMessage(w, r, http.StatusBadRequest, ErrCodeInvalidRequest, "Could not read request body")
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			ctx := r.Context()
+			record := &domain.IdempotencyRecord{
+				ActorID:     domain.ActorFromContext(ctx).ID,
+				Key:         key,
+				Fingerprint: requestFingerprint(r, body),
+				ExpiresAt:   time.Now().Add(opts.Lease),
+			}
+
+			existing, err := store.Reserve(ctx, record)
+			if err != nil {
+				logger.Error("failed to reserve idempotency key", slog.String("error", err.Error()))
+				ErrorWithMessage(w, r, http.StatusInternalServerError, ErrCodeInternalError, "Internal server error")
+				return
+			}
+			if existing != nil {
+				switch {
+				case existing.Fingerprint != record.Fingerprint:
+					ErrorWithMessage(w, r, http.StatusUnprocessableEntity, ErrCodeIdempotencyKeyReused,
+						"Idempotency-Key was already used with a different request")
+				case !existing.Completed():
+					w.Header().Set("Retry-After", "1")
+					ErrorWithMessage(w, r, http.StatusConflict, ErrCodeIdempotencyKeyInUse,
+						"A request with this Idempotency-Key is still being processed")
+				default:
+					replayResponse(w, existing)
+				}
+				return
+			}
+
+			// The outcome is stored even if the client goes away, so a retry
+			// finds it.
+			storeCtx := context.WithoutCancel(ctx)
+			completed := false
+			defer func() {
+				if completed {
+					return
+				}
+				if err := store.Release(storeCtx, record); err != nil {
+					logger.Error("failed to release idempotency key", slog.String("error", err.Error()))
+				}
+			}()
+
+			rec := &recordingWriter{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rec, r)
+
+			if rec.status >= http.StatusInternalServerError {
+				return
+			}
+
+			record.StatusCode = rec.status
+			record.Header = make(map[string][]string)
+			for _, name := range replayedHeaders {
+				if values := rec.Header().Values(name); len(values) > 0 {
+					record.Header[name] = values
+				}
+			}
+			record.Body = rec.body.Bytes()
+			record.ExpiresAt = time.Now().Add(opts.TTL)
+
+			if err := store.Complete(storeCtx, record); err != nil {
+				logger.Error("failed to store idempotent response", slog.String("error", err.Error()))
+				return
+			}
+			completed = true
+		})
+	}
+}
+
+func replayResponse(w http.ResponseWriter, record *domain.IdempotencyRecord) {
+	for name, values := range record.Header {
+		for _, value := range values {
+			w.Header().Add(name, value)
+		}
+	}
+	w.Header().Set(IdempotentReplayedHeader, "true")
+	w.WriteHeader(record.StatusCode)
+	w.Write(record.Body)
+}
+
+func requestFingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(r.Method + " " + r.URL.RequestURI() + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
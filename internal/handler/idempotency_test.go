@@ -0,0 +1,138 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/giannuccilli/user-api/internal/domain"
+)
+
+type mockIdempotencyRepository struct {
+	records map[string]domain.IdempotencyRecord
+}
+
+func newMockIdempotencyRepository() *mockIdempotencyRepository {
+	return &mockIdempotencyRepository{records: make(map[string]domain.IdempotencyRecord)}
+}
+
+func (m *mockIdempotencyRepository) Reserve(ctx context.Context, record *domain.IdempotencyRecord) (*domain.IdempotencyRecord, error) {
+	k := record.ActorID + "/" + record.Key
+	if existing, ok := m.records[k]; ok && existing.ExpiresAt.After(time.Now()) {
+		return &existing, nil
+	}
+	m.records[k] = *record
+	return nil, nil
+}
+
+func (m *mockIdempotencyRepository) Complete(ctx context.Context, record *domain.IdempotencyRecord) error {
+	m.records[record.ActorID+"/"+record.Key] = *record
+	return nil
+}
+
+func (m *mockIdempotencyRepository) Release(ctx context.Context, record *domain.IdempotencyRecord) error {
+	delete(m.records, record.ActorID+"/"+record.Key)
+	return nil
+}
+
+func (m *mockIdempotencyRepository) DeleteExpired(ctx context.Context, before time.Time) (int, error) {
+	return 0, nil
+}
+
+func TestIdempotency(t *testing.T) {
+	store := newMockIdempotencyRepository()
+	calls := 0
+	status := http.StatusCreated
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Location", "/api/v1/users/1")
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		w.Write(body)
+	})
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := Chain(next, Actor(), Idempotency(store, logger, IdempotencyOptions{}))
+
+	// In-flight request for key "busy" owned by actor "a", and one for key
+	// "crashed" whose lease ran out.
+	fingerprint := requestFingerprint(httptest.NewRequest(http.MethodPost, "/api/v1/users", nil), []byte(`{}`))
+	store.records["a/busy"] = domain.IdempotencyRecord{
+		ActorID:     "a",
+		Key:         "busy",
+		Fingerprint: fingerprint,
+		ExpiresAt:   time.Now().Add(time.Minute),
+	}
+	store.records["a/crashed"] = domain.IdempotencyRecord{
+		ActorID:     "a",
+		Key:         "crashed",
+		Fingerprint: fingerprint,
+		ExpiresAt:   time.Now().Add(-time.Second),
+	}
+
+	tests := []struct {
+		name         string
+		method       string
+		key          string
+		actor        string
+		body         string
+		handlerFails bool
+		wantStatus   int
+		wantCalls    int
+		wantReplayed bool
+	}{
+		{"first request", http.MethodPost, "k1", "a", `{"n":1}`, false, http.StatusCreated, 1, false},
+		{"replay", http.MethodPost, "k1", "a", `{"n":1}`, false, http.StatusCreated, 1, true},
+		{"different body", http.MethodPost, "k1", "a", `{"n":2}`, false, http.StatusUnprocessableEntity, 1, false},
+		{"other actor same key", http.MethodPost, "k1", "b", `{"n":2}`, false, http.StatusCreated, 2, false},
+		{"in flight", http.MethodPost, "busy", "a", `{}`, false, http.StatusConflict, 2, false},
+		{"no key", http.MethodPost, "", "a", `{"n":1}`, false, http.StatusCreated, 3, false},
+		{"ignored for GET", http.MethodGet, "k1", "a", ``, false, http.StatusCreated, 4, false},
+		{"key too long", http.MethodPost, strings.Repeat("k", 256), "a", `{}`, false, http.StatusBadRequest, 4, false},
+		{"server error not stored", http.MethodPut, "k2", "a", `{}`, true, http.StatusInternalServerError, 5, false},
+		{"retry after server error", http.MethodPut, "k2", "a", `{}`, false, http.StatusCreated, 6, false},
+		{"expired lease taken over", http.MethodPost, "crashed", "a", `{}`, false, http.StatusCreated, 7, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status = http.StatusCreated
+			if tt.handlerFails {
+				status = http.StatusInternalServerError
+			}
+
+			req := httptest.NewRequest(tt.method, "/api/v1/users", bytes.NewBufferString(tt.body))
+			req.Header.Set("X-Actor-ID", tt.actor)
+			if tt.key != "" {
+				req.Header.Set(IdempotencyKeyHeader, tt.key)
+			}
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %v, want %v (body: %s)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("handler calls = %v, want %v", calls, tt.wantCalls)
+			}
+			if replayed := rec.Header().Get(IdempotentReplayedHeader) == "true"; replayed != tt.wantReplayed {
+				t.Errorf("replayed = %v, want %v", replayed, tt.wantReplayed)
+			}
+			if tt.wantReplayed {
+				if rec.Body.String() != tt.body || rec.Header().Get("Location") != "/api/v1/users/1" {
+					t.Errorf("replayed response = %q, Location %q", rec.Body.String(), rec.Header().Get("Location"))
+				}
+			}
+		})
+	}
+	if stored := store.records["a/k1"]; stored.ExpiresAt.Before(time.Now().Add(DefaultIdempotencyTTL - time.Minute)) {
+		t.Errorf("completed response expires at %v, want after the TTL", stored.ExpiresAt)
+	}
+}
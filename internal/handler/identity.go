@@ -0,0 +1,111 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/google/uuid"
+
+	"github.com/giannuccilli/user-api/internal/domain"
+)
+
+func (h *UserHandler) ListIdentities(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		ErrorWithMessage(w, r, http.StatusBadRequest, ErrCodeInvalidID, "Invalid user ID format")
+		return
+	}
+
+	identities, err := h.service.ListIdentities(r.Context(), id)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	JSON(w, http.StatusOK, identities)
+}
+
+func (h *UserHandler) LinkIdentity(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		ErrorWithMessage(w, r, http.StatusBadRequest, ErrCodeInvalidID, "Invalid user ID format")
+		return
+	}
+
+	var req domain.LinkIdentityRequest
+	if !h.decodeJSON(w, r, &req) {
+		return
+	}
+
+	identity, err := h.service.LinkIdentity(r.Context(), id, req)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	JSON(w, http.StatusOK, identity)
+}
+
+func (h *UserHandler) UnlinkIdentity(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		ErrorWithMessage(w, r, http.StatusBadRequest, ErrCodeInvalidID, "Invalid user ID format")
+		return
+	}
+
+	if err := h.service.UnlinkIdentity(r.Context(), id, r.PathValue("provider"), r.PathValue("externalId")); err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *UserHandler) GetByExternalID(w http.ResponseWriter, r *http.Request) {
+	provider, externalID, ok := externalIDParams(w, r)
+	if !ok {
+		return
+	}
+
+	user, err := h.service.GetByExternalID(r.Context(), provider, externalID)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	JSON(w, http.StatusOK, user)
+}
+
+func (h *UserHandler) UpsertByExternalID(w http.ResponseWriter, r *http.Request) {
+	provider, externalID, ok := externalIDParams(w, r)
+	if !ok {
+		return
+	}
+
+	var req domain.CreateUserRequest
+	if !h.decodeJSON(w, r, &req) {
+		return
+	}
+
+	user, created, err := h.service.UpsertByExternalID(r.Context(), provider, externalID, req)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	if created {
+		w.Header().Set("Location", "/api/v1/users/"+user.ID.String())
+		JSON(w, http.StatusCreated, user)
+		return
+	}
+	JSON(w, http.StatusOK, user)
+}
+
+func externalIDParams(w http.ResponseWriter, r *http.Request) (string, string, bool) {
+	provider := r.URL.Query().Get("provider")
+	externalID := r.URL.Query().Get("id")
+	if provider == "" || externalID == "" {
+		ErrorWithMessage(w, r, http.StatusBadRequest, ErrCodeInvalidRequest, "Query parameters provider and id are required")
+		return "", "", false
+	}
+	return provider, externalID, true
+}
@@ -0,0 +1,126 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/giannuccilli/user-api/internal/domain"
+	"github.com/giannuccilli/user-api/internal/service"
+)
+
+type mockIdentityRepository struct {
+	identities map[string]domain.ExternalIdentity
+	users      domain.UserRepository
+}
+
+func (m *mockIdentityRepository) CreateUser(ctx context.Context, user *domain.User, identity *domain.ExternalIdentity) error {
+	if _, ok := m.identities[identity.Provider+"/"+identity.ExternalID]; ok {
+		return domain.ErrIdentityConflict
+	}
+	if err := m.users.Create(ctx, user); err != nil {
+		return err
+	}
+	identity.UserID = user.ID
+	return m.Link(ctx, identity)
+}
+
+func (m *mockIdentityRepository) Link(ctx context.Context, identity *domain.ExternalIdentity) error {
+	key := identity.Provider + "/" + identity.ExternalID
+	if existing, ok := m.identities[key]; ok && existing.UserID != identity.UserID {
+		return domain.ErrIdentityConflict
+	}
+	m.identities[key] = *identity
+	return nil
+}
+
+func (m *mockIdentityRepository) Unlink(ctx context.Context, userID uuid.UUID, provider, externalID string) error {
+	key := provider + "/" + externalID
+	if existing, ok := m.identities[key]; !ok || existing.UserID != userID {
+		return domain.ErrIdentityNotFound
+	}
+	delete(m.identities, key)
+	return nil
+}
+
+func (m *mockIdentityRepository) Get(ctx context.Context, provider, externalID string) (*domain.ExternalIdentity, error) {
+	identity, ok := m.identities[provider+"/"+externalID]
+	if !ok {
+		return nil, domain.ErrIdentityNotFound
+	}
+	return &identity, nil
+}
+
+func (m *mockIdentityRepository) ListByUser(ctx context.Context, userID uuid.UUID) ([]domain.ExternalIdentity, error) {
+	identities := make([]domain.ExternalIdentity, 0)
+	for _, identity := range m.identities {
+		if identity.UserID == userID {
+			identities = append(identities, identity)
+		}
+	}
+	return identities, nil
+}
+
+func TestUserHandler_Identities(t *testing.T) {
+	repo := newMockUserRepository()
+	svc := service.NewUserService(repo, &mockNotifier{},
+		service.WithIdentityRepository(&mockIdentityRepository{identities: map[string]domain.ExternalIdentity{}, users: repo}))
+	mux := http.NewServeMux()
+	NewUserHandler(svc).RegisterRoutes(mux)
+
+	alice := &domain.User{ID: uuid.New(), Email: "alice@example.com", Status: domain.UserStatusActive}
+	bob := &domain.User{ID: uuid.New(), Email: "bob@example.com", Status: domain.UserStatusActive}
+	repo.users[alice.ID] = alice
+	repo.users[bob.ID] = bob
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		wantStatus int
+	}{
+		{"link", http.MethodPost, "/api/v1/users/" + alice.ID.String() + "/identities", `{"provider":"okta","externalId":"00u/1"}`, http.StatusOK},
+		{"link conflict", http.MethodPost, "/api/v1/users/" + bob.ID.String() + "/identities", `{"provider":"okta","externalId":"00u/1"}`, http.StatusConflict},
+		{"link invalid", http.MethodPost, "/api/v1/users/" + bob.ID.String() + "/identities", `{"provider":"okta"}`, http.StatusUnprocessableEntity},
+		{"list", http.MethodGet, "/api/v1/users/" + alice.ID.String() + "/identities", "", http.StatusOK},
+		{"lookup", http.MethodGet, "/api/v1/users:byExternalId?provider=okta&id=00u%2F1", "", http.StatusOK},
+		{"lookup missing params", http.MethodGet, "/api/v1/users:byExternalId?provider=okta", "", http.StatusBadRequest},
+		{"lookup unknown", http.MethodGet, "/api/v1/users:byExternalId?provider=okta&id=nope", "", http.StatusNotFound},
+		{"unlink", http.MethodDelete, "/api/v1/users/" + alice.ID.String() + "/identities/okta/00u/1", "", http.StatusNoContent},
+		{"unlink again", http.MethodDelete, "/api/v1/users/" + alice.ID.String() + "/identities/okta/00u/1", "", http.StatusNotFound},
+		{"upsert creates", http.MethodPut, "/api/v1/users:byExternalId?provider=crm&id=42", `{"email":"sync@example.com","firstName":"John","lastName":"Doe"}`, http.StatusCreated},
+		{"upsert updates", http.MethodPut, "/api/v1/users:byExternalId?provider=crm&id=42", `{"email":"sync@example.com","firstName":"Johnny","lastName":"Doe"}`, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+
+			mux.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("%s %s status = %v, want %v (body: %s)", tt.method, tt.path, rec.Code, tt.wantStatus, rec.Body.String())
+			}
+		})
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/users:byExternalId?provider=crm&id=42", nil)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	var user domain.User
+	if err := json.NewDecoder(rec.Body).Decode(&user); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if user.FirstName != "Johnny" {
+		t.Errorf("upserted user FirstName = %v, want Johnny", user.FirstName)
+	}
+}
@@ -0,0 +1,119 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/giannuccilli/user-api/internal/domain"
+)
+
+// Action dispatches custom methods addressed as /api/v1/users/{id}:{action}.
+// ServeMux wildcards must span a whole segment, so the id and the action are
+// split here.
+func (h *UserHandler) Action(w http.ResponseWriter, r *http.Request) {
+	_, action, _ := strings.Cut(r.PathValue("idAction"), ":")
+
+	switch action {
+	case "suspend":
+		h.Suspend(w, r)
+	case "activate":
+		h.Activate(w, r)
+	case "deactivate":
+		h.Deactivate(w, r)
+	case "sendVerification":
+		h.SendVerification(w, r)
+	default:
+		ErrorWithMessage(w, r, http.StatusNotFound, ErrCodeUnknownAction, "Unknown user action")
+	}
+}
+
+func (h *UserHandler) Suspend(w http.ResponseWriter, r *http.Request) {
+	id, ok := actionUserID(w, r)
+	if !ok {
+		return
+	}
+
+	var req domain.SuspendUserRequest
+	if !h.decodeJSON(w, r, &req) {
+		return
+	}
+
+	user, err := h.service.Suspend(r.Context(), id, req)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	JSON(w, http.StatusOK, user)
+}
+
+func (h *UserHandler) Activate(w http.ResponseWriter, r *http.Request) {
+	id, ok := actionUserID(w, r)
+	if !ok {
+		return
+	}
+
+	var req domain.ActivateUserRequest
+	if !h.decodeOptionalJSON(w, r, &req) {
+		return
+	}
+
+	user, err := h.service.Activate(r.Context(), id, req)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	JSON(w, http.StatusOK, user)
+}
+
+func (h *UserHandler) Deactivate(w http.ResponseWriter, r *http.Request) {
+	id, ok := actionUserID(w, r)
+	if !ok {
+		return
+	}
+
+	var req domain.DeactivateUserRequest
+	if !h.decodeOptionalJSON(w, r, &req) {
+		return
+	}
+
+	user, err := h.service.Deactivate(r.Context(), id, req)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	JSON(w, http.StatusOK, user)
+}
+
+func (h *UserHandler) ListAudit(w http.ResponseWriter, r *http.Request) {
+	idStr := r.PathValue("id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		ErrorWithMessage(w, r, http.StatusBadRequest, ErrCodeInvalidID, "Invalid user ID format")
+		return
+	}
+
+	limit, offset := paginationParams(r)
+
+	entries, err := h.service.ListAudit(r.Context(), id, limit, offset)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	JSON(w, http.StatusOK, entries)
+}
+
+func actionUserID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	idStr, _, _ := strings.Cut(r.PathValue("idAction"), ":")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		ErrorWithMessage(w, r, http.StatusBadRequest, ErrCodeInvalidID, "Invalid user ID format")
+		return uuid.Nil, false
+	}
+	return id, true
+}
@@ -0,0 +1,161 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/giannuccilli/user-api/internal/domain"
+)
+
+func TestUserHandler_Action(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     domain.UserStatus
+		path       func(id uuid.UUID) string
+		body       string
+		roles      string
+		wantStatus int
+		wantUser   domain.UserStatus
+	}{
+		{
+			name:       "suspend",
+			status:     domain.UserStatusActive,
+			path:       func(id uuid.UUID) string { return id.String() + ":suspend" },
+			body:       `{"reason":"abuse","duration":"24h","note":"spam"}`,
+			wantStatus: http.StatusOK,
+			wantUser:   domain.UserStatusSuspended,
+		},
+		{
+			name:       "suspend without reason",
+			status:     domain.UserStatusActive,
+			path:       func(id uuid.UUID) string { return id.String() + ":suspend" },
+			body:       `{"duration":"24h"}`,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "suspend invalid json",
+			status:     domain.UserStatusActive,
+			path:       func(id uuid.UUID) string { return id.String() + ":suspend" },
+			body:       `{invalid}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "deactivate without body",
+			status:     domain.UserStatusActive,
+			path:       func(id uuid.UUID) string { return id.String() + ":deactivate" },
+			wantStatus: http.StatusOK,
+			wantUser:   domain.UserStatusInactive,
+		},
+		{
+			name:       "activate suspended without admin",
+			status:     domain.UserStatusSuspended,
+			path:       func(id uuid.UUID) string { return id.String() + ":activate" },
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "activate suspended as admin",
+			status:     domain.UserStatusSuspended,
+			path:       func(id uuid.UUID) string { return id.String() + ":activate" },
+			body:       `{"note":"appeal accepted"}`,
+			roles:      "admin",
+			wantStatus: http.StatusOK,
+			wantUser:   domain.UserStatusActive,
+		},
+		{
+			name:       "unknown action",
+			status:     domain.UserStatusActive,
+			path:       func(id uuid.UUID) string { return id.String() + ":promote" },
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "invalid uuid",
+			status:     domain.UserStatusActive,
+			path:       func(id uuid.UUID) string { return "invalid-uuid:deactivate" },
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "non-existing user",
+			status:     domain.UserStatusActive,
+			path:       func(id uuid.UUID) string { return uuid.New().String() + ":deactivate" },
+			wantStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, repo := setupTestHandler()
+			mux := http.NewServeMux()
+			handler.RegisterRoutes(mux)
+
+			user := &domain.User{
+				ID:        uuid.New(),
+				Email:     "test@example.com",
+				FirstName: "John",
+				LastName:  "Doe",
+				Status:    tt.status,
+			}
+			repo.users[user.ID] = user
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/users/"+tt.path(user.ID), bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("X-Actor-Roles", tt.roles)
+			rec := httptest.NewRecorder()
+
+			Actor()(mux).ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %v, want %v (body: %s)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantUser == "" {
+				return
+			}
+
+			var got domain.User
+			if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if got.Status != tt.wantUser {
+				t.Errorf("user status = %v, want %v", got.Status, tt.wantUser)
+			}
+		})
+	}
+}
+
+func TestUserHandler_ListAudit(t *testing.T) {
+	handler, repo := setupTestHandler()
+
+	user := &domain.User{ID: uuid.New(), Email: "test@example.com", FirstName: "John", LastName: "Doe", Status: domain.UserStatusActive}
+	repo.users[user.ID] = user
+	for _, tt := range []struct {
+		id         uuid.UUID
+		wantStatus int
+	}{
+		{user.ID, http.StatusOK},
+		{uuid.New(), http.StatusNotFound},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/users/"+tt.id.String()+"/audit", nil)
+		req.SetPathValue("id", tt.id.String())
+		rec := httptest.NewRecorder()
+
+		handler.ListAudit(rec, req)
+
+		if rec.Code != tt.wantStatus {
+			t.Errorf("ListAudit(%s) status = %v, want %v", tt.id, rec.Code, tt.wantStatus)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/users/invalid-uuid/audit", nil)
+	req.SetPathValue("id", "invalid-uuid")
+	rec := httptest.NewRecorder()
+
+	handler.ListAudit(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("ListAudit() status = %v, want %v", rec.Code, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,135 @@
+package handler
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/giannuccilli/user-api/internal/domain"
+	"github.com/giannuccilli/user-api/internal/service"
+)
+
+// userLoader collects the user lookups of one GraphQL request that arrive
+// within wait of the first one and resolves them with a single BatchGet.
+// Results are kept for the rest of the request.
+type userLoader struct {
+	fetch    func(ctx context.Context, ids []string) (*domain.BatchGetUsersResult, error)
+	wait     time.Duration
+	maxBatch int
+
+	mu      sync.Mutex
+	pending *userBatch
+	batches map[uuid.UUID]*userBatch
+}
+
+type userBatch struct {
+	ids   []uuid.UUID
+	once  sync.Once
+	done  chan struct{}
+	users map[uuid.UUID]*domain.User
+	err   error
+}
+
+func newUserLoader(svc *service.UserService, wait time.Duration) *userLoader {
+	return &userLoader{
+		fetch: func(ctx context.Context, ids []string) (*domain.BatchGetUsersResult, error) {
+			return svc.BatchGet(ctx, ids, nil)
+		},
+		wait:     wait,
+		maxBatch: service.MaxBatchGetIDs,
+		batches:  make(map[uuid.UUID]*userBatch),
+	}
+}
+
+// Load returns the user with id, or nil if it does not exist.
+func (l *userLoader) Load(ctx context.Context, id uuid.UUID) (*domain.User, error) {
+	users, err := l.LoadMany(ctx, []uuid.UUID{id})
+	if err != nil {
+		return nil, err
+	}
+	return users[0], nil
+}
+
+// LoadMany returns the users with ids in the same order, nil for the missing
+// ones.
+func (l *userLoader) LoadMany(ctx context.Context, ids []uuid.UUID) ([]*domain.User, error) {
+	batches := make([]*userBatch, len(ids))
+	l.mu.Lock()
+	for i, id := range ids {
+		batches[i] = l.enqueue(ctx, id)
+	}
+	l.mu.Unlock()
+
+	users := make([]*domain.User, len(ids))
+	for i, b := range batches {
+		select {
+		case <-b.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if b.err != nil {
+			return nil, b.err
+		}
+		users[i] = b.users[ids[i]]
+	}
+	return users, nil
+}
+
+// Forget drops the cached result for id, so that a later lookup sees the
+// changes made by a mutation.
+func (l *userLoader) Forget(id uuid.UUID) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.batches, id)
+}
+
+// enqueue must be called with l.mu held.
+func (l *userLoader) enqueue(ctx context.Context, id uuid.UUID) *userBatch {
+	if b, ok := l.batches[id]; ok {
+		return b
+	}
+
+	if l.pending == nil {
+		b := &userBatch{done: make(chan struct{})}
+		l.pending = b
+		time.AfterFunc(l.wait, func() { l.dispatch(ctx, b) })
+	}
+	b := l.pending
+	b.ids = append(b.ids, id)
+	l.batches[id] = b
+
+	if len(b.ids) >= l.maxBatch {
+		l.pending = nil
+		go l.dispatch(ctx, b)
+	}
+	return b
+}
+
+func (l *userLoader) dispatch(ctx context.Context, b *userBatch) {
+	l.mu.Lock()
+	if l.pending == b {
+		l.pending = nil
+	}
+	l.mu.Unlock()
+
+	b.once.Do(func() {
+		defer close(b.done)
+
+		ids := make([]string, len(b.ids))
+		for i, id := range b.ids {
+			ids[i] = id.String()
+		}
+		result, err := l.fetch(ctx, ids)
+		if err != nil {
+			b.err = err
+			return
+		}
+
+		b.users = make(map[uuid.UUID]*domain.User, len(result.Data))
+		for i := range result.Data {
+			b.users[result.Data[i].ID] = &result.Data[i]
+		}
+	})
+}
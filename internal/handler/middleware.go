@@ -1,12 +1,16 @@
 package handler
 
 import (
+	"context"
 	"log/slog"
 	"net/http"
 	"runtime/debug"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
+
+	"github.com/giannuccilli/user-api/internal/domain"
 )
 
 type contextKey string
@@ -23,6 +27,11 @@ func (rw *responseWriter) WriteHeader(code int) {
 	rw.ResponseWriter.WriteHeader(code)
 }
 
+func RequestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(RequestIDKey).(string)
+	return requestID
+}
+
 func Logging(logger *slog.Logger) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -32,10 +41,12 @@ func Logging(logger *slog.Logger) func(http.Handler) http.Handler {
 			if requestID == "" {
 				requestID = uuid.New().String()
 			}
+			w.Header().Set("X-Request-ID", requestID)
 
 			wrapped := &responseWriter{ResponseWriter: w, status: http.StatusOK}
 
-			next.ServeHTTP(wrapped, r)
+			ctx := context.WithValue(r.Context(), RequestIDKey, requestID)
+			next.ServeHTTP(wrapped, r.WithContext(ctx))
 
 			logger.Info("request completed",
 				slog.String("method", r.Method),
@@ -48,6 +59,30 @@ func Logging(logger *slog.Logger) func(http.Handler) http.Handler {
 	}
 }
 
+// Actor trusts the identity headers set by the API gateway in front of the
+// service; it must not be exposed to clients directly.
+func Actor() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			actor := ParseActor(r.Header.Get("X-Actor-ID"), r.Header.Get("X-Actor-Roles"))
+			ctx := domain.ContextWithActor(r.Context(), actor)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// ParseActor builds the actor from the values of the X-Actor-ID and the
+// comma-separated X-Actor-Roles headers.
+func ParseActor(id, roles string) domain.Actor {
+	actor := domain.Actor{ID: id}
+	for _, role := range strings.Split(roles, ",") {
+		if role = strings.TrimSpace(role); role != "" {
+			actor.Roles = append(actor.Roles, role)
+		}
+	}
+	return actor
+}
+
 func Recovery(logger *slog.Logger) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -57,7 +92,7 @@ func Recovery(logger *slog.Logger) func(http.Handler) http.Handler {
 						slog.Any("error", err),
 						slog.String("stack", string(debug.Stack())),
 					)
-					ErrorWithMessage(w, http.StatusInternalServerError,
+					ErrorWithMessage(w, r, http.StatusInternalServerError,
 						ErrCodeInternalError, "Internal server error")
 				}
 			}()
@@ -0,0 +1,353 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"github.com/santhosh-tekuri/jsonschema/v6/kind"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+
+	"github.com/giannuccilli/user-api/internal/domain"
+)
+
+const openAPIDocURL = "openapi.json"
+
+// openAPIDocsPage renders the spec with Redoc.
+const openAPIDocsPage = `<!DOCTYPE html>
+<html>
+<head>
+  <meta charset="utf-8">
+  <title>User API</title>
+</head>
+<body>
+  <redoc spec-url="/openapi.json"></redoc>
+  <script src="https://cdn.jsdelivr.net/npm/redoc@2/bundles/redoc.standalone.js"></script>
+</body>
+</html>
+`
+
+type OpenAPIHandler struct {
+	spec []byte
+}
+
+func NewOpenAPIHandler(spec []byte) *OpenAPIHandler {
+	return &OpenAPIHandler{spec: spec}
+}
+
+func (h *OpenAPIHandler) Spec(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(h.spec)
+}
+
+func (h *OpenAPIHandler) Docs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	io.WriteString(w, openAPIDocsPage)
+}
+
+func (h *OpenAPIHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /openapi.json", h.Spec)
+	mux.HandleFunc("GET /docs", h.Docs)
+}
+
+type OpenAPIValidationOptions struct {
+	// MaxBodyBytes is the largest request body validated. Larger bodies are
+	// passed on for the handler to reject.
+	MaxBodyBytes int64
+}
+
+// OpenAPIValidation checks requests and responses of the operations in spec.
+// Request bodies that do not match their schema are rejected with 422 like a
+// service validation error; responses that do not match are only logged, as
+// they are a bug in the API rather than in the client. It buffers every
+// response and is meant for development.
+func OpenAPIValidation(spec []byte, logger *slog.Logger, opts OpenAPIValidationOptions) (func(http.Handler) http.Handler, error) {
+	if opts.MaxBodyBytes <= 0 {
+		opts.MaxBodyBytes = DefaultMaxBodyBytes
+	}
+	ops, err := compileOpenAPI(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			op := matchOperation(ops, r.Method, r.URL.Path)
+			if op == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if op.request != nil {
+				if err := validateRequestBody(r, op.request, opts.MaxBodyBytes); err != nil {
+					Error(w, r, err)
+					return
+				}
+			}
+
+			rec := &recordingWriter{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rec, r)
+
+			if err := op.validateResponse(rec.status, rec.Header().Get("Content-Type"), rec.body.Bytes()); err != nil {
+				logger.Warn("response does not match OpenAPI spec",
+					slog.String("method", r.Method),
+					slog.String("path", r.URL.Path),
+					slog.String("operation", op.id),
+					slog.Int("status", rec.status),
+					slog.String("error", err.Error()),
+				)
+			}
+		})
+	}, nil
+}
+
+type openAPIOperation struct {
+	id      string
+	method  string
+	path    *regexp.Regexp
+	literal int
+	request *jsonschema.Schema
+	// responses holds the schema of each status and media type. A nil map
+	// for a status means the response has no body.
+	responses map[string]map[string]*jsonschema.Schema
+}
+
+type openAPIDocument struct {
+	Paths      map[string]map[string]openAPIOperationDoc `json:"paths"`
+	Components struct {
+		Responses map[string]openAPIResponseDoc `json:"responses"`
+	} `json:"components"`
+}
+
+type openAPIOperationDoc struct {
+	OperationID string `json:"operationId"`
+	RequestBody *struct {
+		Content map[string]json.RawMessage `json:"content"`
+	} `json:"requestBody"`
+	Responses map[string]openAPIResponseDoc `json:"responses"`
+}
+
+type openAPIResponseDoc struct {
+	Ref     string                     `json:"$ref"`
+	Content map[string]json.RawMessage `json:"content"`
+}
+
+var openAPIMethods = []string{"get", "put", "post", "delete", "patch"}
+
+func compileOpenAPI(spec []byte) ([]*openAPIOperation, error) {
+	var doc openAPIDocument
+	if err := json.Unmarshal(spec, &doc); err != nil {
+		return nil, fmt.Errorf("parse OpenAPI spec: %w", err)
+	}
+	raw, err := jsonschema.UnmarshalJSON(bytes.NewReader(spec))
+	if err != nil {
+		return nil, fmt.Errorf("parse OpenAPI spec: %w", err)
+	}
+
+	c := jsonschema.NewCompiler()
+	c.DefaultDraft(jsonschema.Draft2020)
+	if err := c.AddResource(openAPIDocURL, raw); err != nil {
+		return nil, err
+	}
+	compile := func(pointer ...string) (*jsonschema.Schema, error) {
+		tokens := make([]string, len(pointer))
+		for i, p := range pointer {
+			tokens[i] = strings.ReplaceAll(strings.ReplaceAll(p, "~", "~0"), "/", "~1")
+		}
+		return c.Compile(openAPIDocURL + "#/" + strings.Join(tokens, "/"))
+	}
+
+	var ops []*openAPIOperation
+	for path, item := range doc.Paths {
+		pattern, literal := openAPIPathPattern(path)
+		for _, method := range openAPIMethods {
+			opDoc, ok := item[method]
+			if !ok {
+				continue
+			}
+			op := &openAPIOperation{
+				id:        opDoc.OperationID,
+				method:    strings.ToUpper(method),
+				path:      pattern,
+				literal:   literal,
+				responses: make(map[string]map[string]*jsonschema.Schema),
+			}
+
+			if opDoc.RequestBody != nil {
+				if _, ok := opDoc.RequestBody.Content["application/json"]; ok {
+					if op.request, err = compile("paths", path, method, "requestBody", "content", "application/json", "schema"); err != nil {
+						return nil, fmt.Errorf("%s %s: %w", op.method, path, err)
+					}
+				}
+			}
+
+			for status, resp := range opDoc.Responses {
+				pointer := []string{"paths", path, method, "responses", status}
+				if name, ok := strings.CutPrefix(resp.Ref, "#/components/responses/"); ok {
+					resp = doc.Components.Responses[name]
+					pointer = []string{"components", "responses", name}
+				}
+				op.responses[status] = nil
+				for mediaType := range resp.Content {
+					if op.responses[status] == nil {
+						op.responses[status] = make(map[string]*jsonschema.Schema)
+					}
+					schema, err := compile(append(slices.Clone(pointer), "content", mediaType, "schema")...)
+					if err != nil {
+						return nil, fmt.Errorf("%s %s: %w", op.method, path, err)
+					}
+					op.responses[status][mediaType] = schema
+				}
+			}
+			ops = append(ops, op)
+		}
+	}
+
+	// Paths with more literal characters win, so /users/{id}:suspend is
+	// tried before /users/{id}.
+	sort.Slice(ops, func(i, j int) bool { return ops[i].literal > ops[j].literal })
+	return ops, nil
+}
+
+var openAPIPathParam = regexp.MustCompile(`\{[^}]+\}`)
+
+func openAPIPathPattern(path string) (*regexp.Regexp, int) {
+	literals := openAPIPathParam.Split(path, -1)
+	literal := 0
+	for i, l := range literals {
+		literal += len(l)
+		literals[i] = regexp.QuoteMeta(l)
+	}
+	return regexp.MustCompile("^" + strings.Join(literals, "[^/]+") + "$"), literal
+}
+
+func matchOperation(ops []*openAPIOperation, method, path string) *openAPIOperation {
+	for _, op := range ops {
+		if op.method == method && op.path.MatchString(path) {
+			return op
+		}
+	}
+	return nil
+}
+
+// validateRequestBody returns a domain.ValidationError if the body does not
+// match schema. Bodies that are missing, too large or not JSON are left for
+// the handler to report.
+func validateRequestBody(r *http.Request, schema *jsonschema.Schema, maxBytes int64) error {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxBytes+1))
+	r.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(body), r.Body), r.Body}
+	if err != nil || len(body) == 0 || int64(len(body)) > maxBytes || !isJSONContentType(r.Header.Get("Content-Type")) {
+		return nil
+	}
+
+	instance, err := jsonschema.UnmarshalJSON(bytes.NewReader(body))
+	if err != nil {
+		return nil
+	}
+	validationErr, ok := schema.Validate(instance).(*jsonschema.ValidationError)
+	if !ok {
+		return nil
+	}
+
+	v := &domain.ValidationError{}
+	addSchemaFieldErrors(v, validationErr)
+	return v.Err()
+}
+
+func (op *openAPIOperation) validateResponse(status int, contentType string, body []byte) error {
+	content, ok := op.responses[strconv.Itoa(status)]
+	if !ok {
+		content, ok = op.responses[strconv.Itoa(status/100)+"XX"]
+	}
+	if !ok {
+		content, ok = op.responses["default"]
+	}
+	if !ok {
+		return fmt.Errorf("status %d is not documented", status)
+	}
+
+	if content == nil {
+		if len(body) > 0 {
+			return fmt.Errorf("status %d is documented without a body", status)
+		}
+		return nil
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	schema, ok := content[mediaType]
+	if !ok {
+		return fmt.Errorf("content type %q is not documented for status %d", contentType, status)
+	}
+
+	instance, err := jsonschema.UnmarshalJSON(bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("invalid JSON body: %w", err)
+	}
+	validationErr, ok := schema.Validate(instance).(*jsonschema.ValidationError)
+	if !ok {
+		return nil
+	}
+	v := &domain.ValidationError{}
+	addSchemaFieldErrors(v, validationErr)
+	msgs := make([]string, len(v.Fields))
+	for i, f := range v.Fields {
+		msgs[i] = f.Field + ": " + f.Message
+	}
+	return fmt.Errorf("%s", strings.Join(msgs, "; "))
+}
+
+var schemaErrorPrinter = message.NewPrinter(language.English)
+
+// addSchemaFieldErrors adds a field error for each leaf of a jsonschema
+// error, naming fields like the service does, e.g. attributes.plan or ids[1].
+func addSchemaFieldErrors(v *domain.ValidationError, err *jsonschema.ValidationError) {
+	if len(err.Causes) > 0 {
+		for _, cause := range err.Causes {
+			addSchemaFieldErrors(v, cause)
+		}
+		return
+	}
+
+	field := schemaFieldPath(err.InstanceLocation)
+	msg := err.ErrorKind.LocalizedString(schemaErrorPrinter)
+	switch k := err.ErrorKind.(type) {
+	case *kind.Required:
+		for _, name := range k.Missing {
+			v.Add(schemaFieldPath(append(slices.Clone(err.InstanceLocation), name)), domain.FieldCodeRequired, "is required")
+		}
+	case *kind.MaxLength, *kind.MaxItems, *kind.MaxProperties:
+		v.Add(field, domain.FieldCodeTooLong, msg)
+	case *kind.Format, *kind.Pattern:
+		v.Add(field, domain.FieldCodeInvalidFormat, msg)
+	default:
+		v.Add(field, domain.FieldCodeInvalidValue, msg)
+	}
+}
+
+func schemaFieldPath(location []string) string {
+	var b strings.Builder
+	for _, segment := range location {
+		if _, err := strconv.Atoi(segment); err == nil {
+			b.WriteString("[" + segment + "]")
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteByte('.')
+		}
+		b.WriteString(segment)
+	}
+	return b.String()
+}
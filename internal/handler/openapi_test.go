@@ -0,0 +1,235 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/giannuccilli/user-api/api"
+)
+
+func TestOpenAPI_MatchesRoutes(t *testing.T) {
+	var doc struct {
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(api.OpenAPI, &doc); err != nil {
+		t.Fatalf("failed to parse spec: %v", err)
+	}
+
+	var routes []route
+	routes = append(routes, (&UserHandler{}).routes()...)
+	routes = append(routes, (&AttributeHandler{}).routes()...)
+	routes = append(routes, (&TenantHandler{}).routes()...)
+	routes = append(routes, (&WebhookHandler{}).routes()...)
+	mux := http.NewServeMux()
+	registerRoutes(mux, routes)
+
+	documented := make(map[string]bool)
+	for path, item := range doc.Paths {
+		concrete := openAPIPathParam.ReplaceAllStringFunc(path, func(param string) string {
+			if param == "{id}" {
+				return uuid.NewString()
+			}
+			return "sample"
+		})
+		for method := range item {
+			req := httptest.NewRequest(strings.ToUpper(method), concrete, nil)
+			_, pattern := mux.Handler(req)
+			if pattern == "" {
+				t.Errorf("%s %s is documented but not routed", strings.ToUpper(method), path)
+				continue
+			}
+			documented[pattern] = true
+		}
+	}
+
+	for _, rt := range routes {
+		if !documented[rt.pattern] {
+			t.Errorf("route %q is not documented in api/openapi.json", rt.pattern)
+		}
+	}
+}
+
+func TestOpenAPI_Compiles(t *testing.T) {
+	ops, err := compileOpenAPI(api.OpenAPI)
+	if err != nil {
+		t.Fatalf("compileOpenAPI() error = %v", err)
+	}
+	for _, op := range ops {
+		if op.id == "" || len(op.responses) == 0 {
+			t.Errorf("operation %s %s has no operationId or responses", op.method, op.path)
+		}
+	}
+
+	tests := []struct {
+		method string
+		path   string
+		wantID string
+	}{
+		{http.MethodGet, "/api/v1/users/550e8400-e29b-41d4-a716-446655440000", "getUser"},
+		{http.MethodPost, "/api/v1/users/550e8400-e29b-41d4-a716-446655440000:suspend", "suspendUser"},
+		{http.MethodPost, "/api/v1/users:batchGet", "batchGetUsers"},
+		{http.MethodPut, "/api/v1/users:byEmail/john@example.com", "upsertUserByEmail"},
+		{http.MethodGet, "/graphql", ""},
+	}
+	for _, tt := range tests {
+		op := matchOperation(ops, tt.method, tt.path)
+		var got string
+		if op != nil {
+			got = op.id
+		}
+		if got != tt.wantID {
+			t.Errorf("matchOperation(%s %s) = %q, want %q", tt.method, tt.path, got, tt.wantID)
+		}
+	}
+}
+
+func TestOpenAPIValidation(t *testing.T) {
+	h, _ := setupTestHandler()
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+	// A handler that breaks the contract of GET /api/v1/tenants.
+	mux.HandleFunc("GET /api/v1/tenants", func(w http.ResponseWriter, r *http.Request) {
+		JSON(w, http.StatusOK, map[string]any{"data": "not a list"})
+	})
+
+	var logs bytes.Buffer
+	validate, err := OpenAPIValidation(api.OpenAPI, slog.New(slog.NewTextHandler(&logs, nil)), OpenAPIValidationOptions{})
+	if err != nil {
+		t.Fatalf("OpenAPIValidation() error = %v", err)
+	}
+	server := validate(mux)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, req)
+		return rec
+	}
+
+	created := do(http.MethodPost, "/api/v1/users", `{"email":"john@example.com","firstName":"John","lastName":"Doe"}`)
+	if created.Code != http.StatusCreated {
+		t.Fatalf("create status = %d, want %d: %s", created.Code, http.StatusCreated, created.Body)
+	}
+	var user struct {
+		ID string `json:"id"`
+	}
+	json.Unmarshal(created.Body.Bytes(), &user)
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		wantStatus int
+		wantFields []string
+		wantWarn   bool
+	}{
+		{
+			name:       "wrong type",
+			method:     http.MethodPost,
+			path:       "/api/v1/users",
+			body:       `{"email":"jane@example.com","firstName":42,"lastName":"Doe"}`,
+			wantStatus: http.StatusUnprocessableEntity,
+			wantFields: []string{"firstName:INVALID_VALUE"},
+		},
+		{
+			name:       "missing required field",
+			method:     http.MethodPost,
+			path:       "/api/v1/users/" + user.ID + ":suspend",
+			body:       `{"note":"` + strings.Repeat("x", 1001) + `"}`,
+			wantStatus: http.StatusUnprocessableEntity,
+			wantFields: []string{"reason:REQUIRED", "note:TOO_LONG"},
+		},
+		{
+			name:       "array item",
+			method:     http.MethodPost,
+			path:       "/api/v1/users:batchGet",
+			body:       `{"ids":["` + user.ID + `",7]}`,
+			wantStatus: http.StatusUnprocessableEntity,
+			wantFields: []string{"ids[1]:INVALID_VALUE"},
+		},
+		{
+			name:       "malformed JSON is left to the handler",
+			method:     http.MethodPost,
+			path:       "/api/v1/users",
+			body:       `{invalid`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "sparse fieldset matches",
+			method:     http.MethodGet,
+			path:       "/api/v1/users/" + user.ID + "?fields=email",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "documented error",
+			method:     http.MethodGet,
+			path:       "/api/v1/users/not-a-uuid",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "undocumented route",
+			method:     http.MethodGet,
+			path:       "/healthz",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "response breaking the spec",
+			method:     http.MethodGet,
+			path:       "/api/v1/tenants",
+			wantStatus: http.StatusOK,
+			wantWarn:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logs.Reset()
+			rec := do(tt.method, tt.path, tt.body)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if tt.wantFields != nil {
+				var problem Problem
+				json.Unmarshal(rec.Body.Bytes(), &problem)
+				var got []string
+				for _, f := range problem.Fields {
+					got = append(got, f.Field+":"+f.Code)
+				}
+				if strings.Join(got, ",") != strings.Join(tt.wantFields, ",") {
+					t.Errorf("fields = %v, want %v", got, tt.wantFields)
+				}
+			}
+			if warned := strings.Contains(logs.String(), "does not match OpenAPI spec"); warned != tt.wantWarn {
+				t.Errorf("warned = %v, want %v: %s", warned, tt.wantWarn, logs.String())
+			}
+		})
+	}
+}
+
+func TestOpenAPIHandler(t *testing.T) {
+	mux := http.NewServeMux()
+	NewOpenAPIHandler(api.OpenAPI).RegisterRoutes(mux)
+
+	for path, wantType := range map[string]string{
+		"/openapi.json": "application/json",
+		"/docs":         "text/html; charset=utf-8",
+	} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != wantType {
+			t.Errorf("GET %s = %d %q, want 200 %q", path, rec.Code, rec.Header().Get("Content-Type"), wantType)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/giannuccilli/user-api/internal/domain"
+)
+
+const (
+	ContentTypeProblem = "application/problem+json"
+
+	ErrorFormatProblem = "problem"
+	ErrorFormatLegacy  = "legacy"
+
+	DefaultProblemTypeBase = "urn:user-api:problem:"
+)
+
+const errorOptionsKey contextKey = "errorOptions"
+
+// Problem is an RFC 7807 problem details object. Code, RequestID, Details and
+// Fields are extension members; Code and Fields keep the names used by the
+// legacy ErrorResponse so clients can migrate gradually.
+type Problem struct {
+	Type      string              `json:"type"`
+	Title     string              `json:"title"`
+	Status    int                 `json:"status"`
+	Detail    string              `json:"detail,omitempty"`
+	Instance  string              `json:"instance,omitempty"`
+	Code      string              `json:"code"`
+	RequestID string              `json:"requestId,omitempty"`
+	Details   []string            `json:"details,omitempty"`
+	Fields    []domain.FieldError `json:"fields,omitempty"`
+}
+
+type ErrorOptions struct {
+	// Format is the error format of clients that do not ask for one:
+	// ErrorFormatLegacy, the default, or ErrorFormatProblem. Clients choose
+	// through the Accept header: application/problem+json for problem
+	// details, application/json for the legacy format.
+	Format   string
+	TypeBase string
+}
+
+func ErrorFormat(opts ErrorOptions) func(http.Handler) http.Handler {
+	if opts.TypeBase == "" {
+		opts.TypeBase = DefaultProblemTypeBase
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), errorOptionsKey, opts)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+func errorOptionsFromContext(ctx context.Context) ErrorOptions {
+	if opts, ok := ctx.Value(errorOptionsKey).(ErrorOptions); ok {
+		return opts
+	}
+	return ErrorOptions{Format: ErrorFormatLegacy, TypeBase: DefaultProblemTypeBase}
+}
+
+func writeError(w http.ResponseWriter, r *http.Request, status int, errResp ErrorResponse) {
+	opts := errorOptionsFromContext(r.Context())
+	if negotiateErrorFormat(r, opts.Format) == ErrorFormatLegacy {
+		JSON(w, status, errResp)
+		return
+	}
+
+	problem := Problem{
+		Type:      problemType(opts.TypeBase, errResp.Code),
+		Title:     http.StatusText(status),
+		Status:    status,
+		Detail:    errResp.Message,
+		Instance:  r.URL.Path,
+		Code:      errResp.Code,
+		RequestID: RequestIDFromContext(r.Context()),
+		Details:   errResp.Details,
+		Fields:    errResp.Fields,
+	}
+
+	w.Header().Set("Content-Type", ContentTypeProblem)
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(problem)
+}
+
+func problemType(base, code string) string {
+	return base + strings.ReplaceAll(strings.ToLower(code), "_", "-")
+}
+
+func negotiateErrorFormat(r *http.Request, format string) string {
+	switch {
+	case accepts(r, ContentTypeProblem):
+		return ErrorFormatProblem
+	case accepts(r, "application/json"):
+		return ErrorFormatLegacy
+	case format == ErrorFormatProblem:
+		return ErrorFormatProblem
+	default:
+		return ErrorFormatLegacy
+	}
+}
+
+// accepts reports whether the Accept header lists contentType explicitly
+// and does not refuse it with q=0.
+func accepts(r *http.Request, contentType string) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil || mediaType != contentType {
+			continue
+		}
+		if q, ok := params["q"]; ok && strings.Trim(q, "0.") == "" {
+			continue
+		}
+		return true
+	}
+	return false
+}
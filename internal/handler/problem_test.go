@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestError_Format(t *testing.T) {
+	handler, _ := setupTestHandler()
+	mux := http.NewServeMux()
+	handler.RegisterRoutes(mux)
+
+	tests := []struct {
+		name            string
+		format          string
+		accept          string
+		wantContentType string
+	}{
+		{"legacy by default", "", "", "application/json"},
+		{"problem configured", ErrorFormatProblem, "", ContentTypeProblem},
+		{"problem configured, json requested", ErrorFormatProblem, "application/json", "application/json"},
+		{"problem configured, any type", ErrorFormatProblem, "*/*", ContentTypeProblem},
+		{"legacy configured", ErrorFormatLegacy, "application/json", "application/json"},
+		{"legacy negotiated to problem", ErrorFormatLegacy, "application/json, application/problem+json", ContentTypeProblem},
+		{"problem refused with q=0", ErrorFormatLegacy, "application/problem+json;q=0", "application/json"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := Chain(mux,
+				Logging(slog.New(slog.NewTextHandler(io.Discard, nil))),
+				ErrorFormat(ErrorOptions{Format: tt.format}),
+			)
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/users/"+uuid.New().String(), nil)
+			req.Header.Set("X-Request-ID", "req-123")
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			rec := httptest.NewRecorder()
+
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusNotFound {
+				t.Fatalf("status = %v, want %v", rec.Code, http.StatusNotFound)
+			}
+			if got := rec.Header().Get("Content-Type"); got != tt.wantContentType {
+				t.Fatalf("Content-Type = %v, want %v", got, tt.wantContentType)
+			}
+
+			if tt.wantContentType != ContentTypeProblem {
+				var resp ErrorResponse
+				json.NewDecoder(rec.Body).Decode(&resp)
+				if resp.Code != ErrCodeUserNotFound || resp.Message != "User not found" {
+					t.Errorf("legacy body = %+v", resp)
+				}
+				return
+			}
+
+			var problem Problem
+			json.NewDecoder(rec.Body).Decode(&problem)
+			want := Problem{
+				Type:      DefaultProblemTypeBase + "user-not-found",
+				Title:     "Not Found",
+				Status:    http.StatusNotFound,
+				Detail:    "User not found",
+				Instance:  req.URL.Path,
+				Code:      ErrCodeUserNotFound,
+				RequestID: "req-123",
+			}
+			if problem.Type != want.Type || problem.Title != want.Title || problem.Status != want.Status ||
+				problem.Detail != want.Detail || problem.Instance != want.Instance || problem.Code != want.Code ||
+				problem.RequestID != want.RequestID {
+				t.Errorf("problem = %+v, want %+v", problem, want)
+			}
+		})
+	}
+}
+
+func TestError_ProblemTypeBase(t *testing.T) {
+	h := ErrorFormat(ErrorOptions{Format: ErrorFormatProblem, TypeBase: "https://docs.example.com/errors/"})(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ErrorWithMessage(w, r, http.StatusBadRequest, ErrCodeInvalidRequest, "Invalid JSON body")
+		}),
+	)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/users", nil))
+
+	var problem Problem
+	json.NewDecoder(rec.Body).Decode(&problem)
+	if problem.Type != "https://docs.example.com/errors/invalid-request" {
+		t.Errorf("type = %v, want https://docs.example.com/errors/invalid-request", problem.Type)
+	}
+}
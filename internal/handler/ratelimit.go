@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/giannuccilli/user-api/internal/ratelimit"
+)
+
+type RateLimitOptions struct {
+	// TrustForwardedFor uses the first X-Forwarded-For address as the client
+	// IP. Enable it only behind a proxy that sets the header.
+	TrustForwardedFor bool
+}
+
+// RateLimit enforces the limiter's quotas per client IP and, when the request
+// carries one, per API key or JWT subject as well. Bearer tokens and API keys
+// are not verified here, so the IP quota always applies: otherwise a client
+// could get a fresh quota by sending a new key. The response reports the
+// most restrictive quota. If the store fails the request is let through.
+func RateLimit(limiter *ratelimit.Limiter, logger *slog.Logger, opts RateLimitOptions) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			result, limited, err := limiter.Allow(r, rateLimitClients(r, opts.TrustForwardedFor)...)
+			if err != nil {
+				logger.Error("rate limit store failed", slog.String("error", err.Error()))
+				next.ServeHTTP(w, r)
+				return
+			}
+			if !limited {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+			w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			w.Header().Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+
+			if !result.Allowed {
+				w.Header().Set("Retry-After", strconv.Itoa(max(1, ceilSeconds(result.RetryAfter))))
+				ErrorWithMessage(w, r, http.StatusTooManyRequests, ErrCodeRateLimited, "Too many requests")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func rateLimitClients(r *http.Request, trustForwardedFor bool) []string {
+	ip := ClientIP(r.RemoteAddr, strings.Join(r.Header.Values("X-Forwarded-For"), ","), trustForwardedFor)
+	return RateLimitClients(ip, r.Header.Get("X-API-Key"), r.Header.Get("Authorization"))
+}
+
+// RateLimitClients returns the buckets a request is counted in: its IP and,
+// if present, its API key or else the subject of its bearer token.
+func RateLimitClients(ip, apiKey, authorization string) []string {
+	clients := []string{"ip:" + ip}
+	if apiKey != "" {
+		sum := sha256.Sum256([]byte(apiKey))
+		return append(clients, "key:"+hex.EncodeToString(sum[:16]))
+	}
+	if sub := bearerClaim(authorization, "sub"); sub != "" {
+		return append(clients, "sub:"+sub)
+	}
+	return clients
+}
+
+// bearerClaim returns a string claim of the bearer token without verifying
+// it.
+func bearerClaim(authorization, name string) string {
+	token, ok := strings.CutPrefix(authorization, "Bearer ")
+	if !ok {
+		return ""
+	}
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return ""
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return ""
+	}
+	var claims map[string]any
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return ""
+	}
+	value, _ := claims[name].(string)
+	return value
+}
+
+// ClientIP returns the host of remoteAddr or, if trustForwardedFor is set,
+// the last address of the X-Forwarded-For value. The proxy in front of the
+// API appends the address it received the request from; anything before it
+// was sent by the client and can be spoofed.
+func ClientIP(remoteAddr, forwardedFor string, trustForwardedFor bool) string {
+	if trustForwardedFor {
+		last := forwardedFor[strings.LastIndex(forwardedFor, ",")+1:]
+		if last = strings.TrimSpace(last); last != "" {
+			return last
+		}
+	}
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		return remoteAddr
+	}
+	return host
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,176 @@
+package handler
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/giannuccilli/user-api/internal/ratelimit"
+)
+
+type failingRateLimitStore struct{}
+
+func (failingRateLimitStore) Take(ctx context.Context, key string, limit ratelimit.Limit) (ratelimit.Result, error) {
+	return ratelimit.Result{}, errors.New("store unavailable")
+}
+
+func testBearer(sub string) string {
+	return testBearerClaims(`{"sub":"` + sub + `"}`)
+}
+
+func testBearerClaims(claims string) string {
+	return "Bearer eyJhbGciOiJIUzI1NiJ9." + base64.RawURLEncoding.EncodeToString([]byte(claims)) + ".sig"
+}
+
+func TestRateLimit(t *testing.T) {
+	limiter, err := ratelimit.NewLimiter(ratelimit.NewMemoryStore(), ratelimit.Limit{}, []ratelimit.Rule{
+		{Pattern: "POST /api/v1/users", Limit: ratelimit.Limit{Requests: 1, Per: time.Minute}},
+	})
+	if err != nil {
+		t.Fatalf("NewLimiter() error = %v", err)
+	}
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	})
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := RateLimit(limiter, logger, RateLimitOptions{})(next)
+
+	tests := []struct {
+		name        string
+		method      string
+		headers     map[string]string
+		remoteAddr  string
+		wantStatus  int
+		wantHeaders bool
+	}{
+		{"ip first request", http.MethodPost, nil, "10.0.0.1:1234", http.StatusCreated, true},
+		{"ip exhausted", http.MethodPost, nil, "10.0.0.1:5678", http.StatusTooManyRequests, true},
+		{"other ip", http.MethodPost, nil, "10.0.0.2:1234", http.StatusCreated, true},
+		{"api key", http.MethodPost, map[string]string{"X-API-Key": "k1"}, "10.0.0.3:1234", http.StatusCreated, true},
+		{"api key exhausted", http.MethodPost, map[string]string{"X-API-Key": "k1"}, "10.0.0.4:1234", http.StatusTooManyRequests, true},
+		{"new api key from exhausted ip", http.MethodPost, map[string]string{"X-API-Key": "k2"}, "10.0.0.1:1234", http.StatusTooManyRequests, true},
+		{"jwt subject", http.MethodPost, map[string]string{"Authorization": testBearer("u1")}, "10.0.0.5:1234", http.StatusCreated, true},
+		{"jwt subject exhausted", http.MethodPost, map[string]string{"Authorization": testBearer("u1")}, "10.0.0.6:1234", http.StatusTooManyRequests, true},
+		{"new jwt subject from exhausted ip", http.MethodPost, map[string]string{"Authorization": testBearer("u2")}, "10.0.0.5:1234", http.StatusTooManyRequests, true},
+		{"forwarded for not trusted", http.MethodPost, map[string]string{"X-Forwarded-For": "10.0.0.9"}, "10.0.0.1:1234", http.StatusTooManyRequests, true},
+		{"unlimited route", http.MethodGet, nil, "10.0.0.1:1234", http.StatusCreated, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/api/v1/users", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %v, want %v", rec.Code, tt.wantStatus)
+			}
+			if got := rec.Header().Get("RateLimit-Limit") != ""; got != tt.wantHeaders {
+				t.Errorf("RateLimit-Limit present = %v, want %v", got, tt.wantHeaders)
+			}
+			if tt.wantStatus == http.StatusTooManyRequests {
+				if rec.Header().Get("Retry-After") != "60" || rec.Header().Get("RateLimit-Remaining") != "0" {
+					t.Errorf("Retry-After = %q, RateLimit-Remaining = %q", rec.Header().Get("Retry-After"), rec.Header().Get("RateLimit-Remaining"))
+				}
+				var problem Problem
+				if err := json.NewDecoder(rec.Body).Decode(&problem); err != nil {
+					t.Fatalf("Failed to decode response: %v", err)
+				}
+				if problem.Code != ErrCodeRateLimited {
+					t.Errorf("code = %v, want %v", problem.Code, ErrCodeRateLimited)
+				}
+			}
+		})
+	}
+}
+
+func TestRateLimit_FailsOpen(t *testing.T) {
+	limiter, err := ratelimit.NewLimiter(failingRateLimitStore{}, ratelimit.Limit{Requests: 1, Per: time.Minute}, nil)
+	if err != nil {
+		t.Fatalf("NewLimiter() error = %v", err)
+	}
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := RateLimit(limiter, logger, RateLimitOptions{})(next)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/users", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("status = %v, want %v", rec.Code, http.StatusOK)
+	}
+}
+
+func TestRateLimitClients(t *testing.T) {
+	sum := sha256.Sum256([]byte("k1"))
+	apiKey := "key:" + hex.EncodeToString(sum[:16])
+
+	tests := []struct {
+		name              string
+		headers           map[string]string
+		trustForwardedFor bool
+		want              []string
+	}{
+		{"remote addr", nil, false, []string{"ip:192.0.2.1"}},
+		{"forwarded for ignored", map[string]string{"X-Forwarded-For": "203.0.113.5"}, false, []string{"ip:192.0.2.1"}},
+		{"forwarded for trusted", map[string]string{"X-Forwarded-For": "203.0.113.5"}, true, []string{"ip:203.0.113.5"}},
+		{"forwarded for spoofed", map[string]string{"X-Forwarded-For": "198.51.100.7, 10.0.0.9, 203.0.113.5"}, true, []string{"ip:203.0.113.5"}},
+		{"jwt subject", map[string]string{"Authorization": testBearer("u1")}, false, []string{"ip:192.0.2.1", "sub:u1"}},
+		{"api key over jwt subject", map[string]string{"X-API-Key": "k1", "Authorization": testBearer("u1")}, false, []string{"ip:192.0.2.1", apiKey}},
+		{"malformed jwt", map[string]string{"Authorization": "Bearer abc"}, false, []string{"ip:192.0.2.1"}},
+		{"basic auth", map[string]string{"Authorization": "Basic dTpw"}, false, []string{"ip:192.0.2.1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			if got := rateLimitClients(req, tt.trustForwardedFor); !slices.Equal(got, tt.want) {
+				t.Errorf("rateLimitClients() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		name              string
+		forwardedFor      string
+		trustForwardedFor bool
+		want              string
+	}{
+		{"remote addr", "", false, "192.0.2.1"},
+		{"forwarded for not trusted", "203.0.113.5", false, "192.0.2.1"},
+		{"single entry", "203.0.113.5", true, "203.0.113.5"},
+		{"spoofed entries", "198.51.100.7,198.51.100.8, 203.0.113.5", true, "203.0.113.5"},
+		{"empty last entry", "203.0.113.5, ", true, "192.0.2.1"},
+		{"empty header", "", true, "192.0.2.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ClientIP("192.0.2.1:1234", tt.forwardedFor, tt.trustForwardedFor); got != tt.want {
+				t.Errorf("ClientIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
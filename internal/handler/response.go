@@ -9,9 +9,10 @@ import (
 )
 
 type ErrorResponse struct {
-	Code    string   `json:"code"`
-	Message string   `json:"message"`
-	Details []string `json:"details,omitempty"`
+	Code    string              `json:"code"`
+	Message string              `json:"message"`
+	Details []string            `json:"details,omitempty"`
+	Fields  []domain.FieldError `json:"fields,omitempty"`
 }
 
 const (
@@ -20,6 +21,35 @@ const (
 	ErrCodeUserNotFound   = "USER_NOT_FOUND"
 	ErrCodeEmailExists    = "EMAIL_EXISTS"
 	ErrCodeInternalError  = "INTERNAL_ERROR"
+
+	ErrCodeInvalidTransition = "INVALID_STATUS_TRANSITION"
+	ErrCodeForbidden         = "FORBIDDEN"
+	ErrCodeUnknownAction     = "UNKNOWN_ACTION"
+
+	ErrCodeInvalidToken         = "INVALID_TOKEN"
+	ErrCodeTokenExpired         = "TOKEN_EXPIRED"
+	ErrCodeEmailAlreadyVerified = "EMAIL_ALREADY_VERIFIED"
+	ErrCodeNotImplemented       = "NOT_IMPLEMENTED"
+
+	ErrCodeValidationFailed = "VALIDATION_FAILED"
+
+	ErrCodeUnsupportedMediaType = "UNSUPPORTED_MEDIA_TYPE"
+	ErrCodeRequestTooLarge      = "REQUEST_TOO_LARGE"
+
+	ErrCodeIdempotencyKeyReused = "IDEMPOTENCY_KEY_REUSED"
+	ErrCodeIdempotencyKeyInUse  = "IDEMPOTENCY_KEY_IN_USE"
+	ErrCodeRateLimited          = "RATE_LIMITED"
+
+	ErrCodeAttributeNotFound = "ATTRIBUTE_NOT_FOUND"
+	ErrCodeIdentityNotFound  = "IDENTITY_NOT_FOUND"
+	ErrCodeIdentityConflict  = "IDENTITY_CONFLICT"
+
+	ErrCodeTenantRequired = "TENANT_REQUIRED"
+	ErrCodeTenantNotFound = "TENANT_NOT_FOUND"
+	ErrCodeTenantExists   = "TENANT_EXISTS"
+	ErrCodeTenantNotEmpty = "TENANT_NOT_EMPTY"
+
+	ErrCodeWebhookNotFound = "WEBHOOK_NOT_FOUND"
 )
 
 func JSON(w http.ResponseWriter, status int, data any) {
@@ -30,23 +60,115 @@ func JSON(w http.ResponseWriter, status int, data any) {
 	}
 }
 
-func Error(w http.ResponseWriter, err error) {
+func Error(w http.ResponseWriter, r *http.Request, err error) {
+	status, errResp := errorResponse(err)
+	writeError(w, r, status, errResp)
+}
+
+// errorResponse maps a service error to its HTTP status and error code.
+func errorResponse(err error) (int, ErrorResponse) {
 	var status int
 	var errResp ErrorResponse
+	var validationErr *domain.ValidationError
 
 	switch {
+	case errors.As(err, &validationErr):
+		status = http.StatusUnprocessableEntity
+		errResp = ErrorResponse{
+			Code:    ErrCodeValidationFailed,
+			Message: "Request validation failed",
+			Fields:  validationErr.Fields,
+		}
 	case errors.Is(err, domain.ErrUserNotFound):
 		status = http.StatusNotFound
 		errResp = ErrorResponse{
 			Code:    ErrCodeUserNotFound,
 			Message: "User not found",
 		}
+	case errors.Is(err, domain.ErrAttributeNotFound):
+		status = http.StatusNotFound
+		errResp = ErrorResponse{
+			Code:    ErrCodeAttributeNotFound,
+			Message: "Attribute definition not found",
+		}
+	case errors.Is(err, domain.ErrIdentityNotFound):
+		status = http.StatusNotFound
+		errResp = ErrorResponse{
+			Code:    ErrCodeIdentityNotFound,
+			Message: "External identity not found",
+		}
+	case errors.Is(err, domain.ErrIdentityConflict):
+		status = http.StatusConflict
+		errResp = ErrorResponse{
+			Code:    ErrCodeIdentityConflict,
+			Message: "External identity is linked to another user",
+		}
+	case errors.Is(err, domain.ErrTenantNotFound):
+		status = http.StatusNotFound
+		errResp = ErrorResponse{
+			Code:    ErrCodeTenantNotFound,
+			Message: "Tenant not found",
+		}
+	case errors.Is(err, domain.ErrTenantExists):
+		status = http.StatusConflict
+		errResp = ErrorResponse{
+			Code:    ErrCodeTenantExists,
+			Message: "Tenant already exists",
+		}
+	case errors.Is(err, domain.ErrTenantNotEmpty):
+		status = http.StatusConflict
+		errResp = ErrorResponse{
+			Code:    ErrCodeTenantNotEmpty,
+			Message: "Tenant still has users",
+		}
+	case errors.Is(err, domain.ErrWebhookNotFound):
+		status = http.StatusNotFound
+		errResp = ErrorResponse{
+			Code:    ErrCodeWebhookNotFound,
+			Message: "Webhook subscription not found",
+		}
 	case errors.Is(err, domain.ErrEmailExists):
 		status = http.StatusConflict
 		errResp = ErrorResponse{
 			Code:    ErrCodeEmailExists,
 			Message: "Email already exists",
 		}
+	case errors.Is(err, domain.ErrInvalidTransition):
+		status = http.StatusConflict
+		errResp = ErrorResponse{
+			Code:    ErrCodeInvalidTransition,
+			Message: "Status transition not allowed",
+		}
+	case errors.Is(err, domain.ErrForbidden):
+		status = http.StatusForbidden
+		errResp = ErrorResponse{
+			Code:    ErrCodeForbidden,
+			Message: "Operation not permitted",
+		}
+	case errors.Is(err, domain.ErrInvalidToken):
+		status = http.StatusBadRequest
+		errResp = ErrorResponse{
+			Code:    ErrCodeInvalidToken,
+			Message: "Invalid token",
+		}
+	case errors.Is(err, domain.ErrTokenExpired):
+		status = http.StatusBadRequest
+		errResp = ErrorResponse{
+			Code:    ErrCodeTokenExpired,
+			Message: "Token expired",
+		}
+	case errors.Is(err, domain.ErrEmailAlreadyVerified):
+		status = http.StatusConflict
+		errResp = ErrorResponse{
+			Code:    ErrCodeEmailAlreadyVerified,
+			Message: "Email already verified",
+		}
+	case errors.Is(err, domain.ErrNotSupported):
+		status = http.StatusNotImplemented
+		errResp = ErrorResponse{
+			Code:    ErrCodeNotImplemented,
+			Message: "Operation not supported",
+		}
 	case errors.Is(err, domain.ErrInvalidInput):
 		status = http.StatusBadRequest
 		errResp = ErrorResponse{
@@ -61,14 +183,14 @@ func Error(w http.ResponseWriter, err error) {
 		}
 	}
 
-	JSON(w, status, errResp)
+	return status, errResp
 }
 
-func ErrorWithMessage(w http.ResponseWriter, status int, code, message string, details ...string) {
+func ErrorWithMessage(w http.ResponseWriter, r *http.Request, status int, code, message string, details ...string) {
 	errResp := ErrorResponse{
 		Code:    code,
 		Message: message,
 		Details: details,
 	}
-	JSON(w, status, errResp)
+	writeError(w, r, status, errResp)
 }
@@ -0,0 +1,173 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/giannuccilli/user-api/internal/domain"
+	"github.com/giannuccilli/user-api/internal/service"
+)
+
+const (
+	TenantHeader       = "X-Tenant-ID"
+	DefaultTenantClaim = "tenant_id"
+)
+
+var (
+	ErrTenantMismatch         = errors.New("tenant header does not match the bearer token")
+	ErrTenantHeaderNotTrusted = errors.New("tenant header not accepted")
+)
+
+type TenantOptions struct {
+	// Claim is the bearer token claim holding the tenant ID.
+	Claim string
+	// Default is used when the request names no tenant. If empty, such
+	// requests are rejected.
+	Default string
+	// TrustHeader accepts the X-Tenant-ID header on requests without a
+	// tenant claim. Enable it only behind a gateway that sets the header
+	// itself and drops the one sent by the client.
+	TrustHeader bool
+}
+
+// Tenant resolves the tenant of the request from the bearer token claim, then
+// the X-Tenant-ID header, then the default. Like Actor, it trusts the gateway
+// in front of the service to have authenticated the caller for that tenant.
+func Tenant(opts TenantOptions) func(http.Handler) http.Handler {
+	if opts.Claim == "" {
+		opts.Claim = DefaultTenantClaim
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tenantID, err := ResolveTenant(r.Header.Get(TenantHeader), r.Header.Get("Authorization"), opts)
+			switch {
+			case errors.Is(err, ErrTenantMismatch):
+				ErrorWithMessage(w, r, http.StatusForbidden, ErrCodeForbidden,
+					TenantHeader+" does not match the tenant of the bearer token")
+				return
+			case errors.Is(err, ErrTenantHeaderNotTrusted):
+				ErrorWithMessage(w, r, http.StatusForbidden, ErrCodeForbidden,
+					"The tenant must be given by the bearer token, not the "+TenantHeader+" header")
+				return
+			case tenantID == "":
+				ErrorWithMessage(w, r, http.StatusBadRequest, ErrCodeTenantRequired,
+					"Request must identify a tenant with the "+TenantHeader+" header")
+				return
+			case !domain.IsValidTenantID(tenantID):
+				ErrorWithMessage(w, r, http.StatusBadRequest, ErrCodeInvalidRequest, "Invalid tenant ID")
+				return
+			}
+
+			ctx := domain.ContextWithTenant(r.Context(), tenantID)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// ResolveTenant picks the tenant from the claim of the Authorization bearer
+// token, then the X-Tenant-ID header value, then opts.Default. It returns ""
+// when none is set, ErrTenantMismatch when the header names another tenant
+// than the claim and ErrTenantHeaderNotTrusted when only the header names one
+// and opts.TrustHeader is off.
+func ResolveTenant(header, authorization string, opts TenantOptions) (string, error) {
+	if opts.Claim == "" {
+		opts.Claim = DefaultTenantClaim
+	}
+	if tenantID := bearerClaim(authorization, opts.Claim); tenantID != "" {
+		if header != "" && header != tenantID {
+			return "", ErrTenantMismatch
+		}
+		return tenantID, nil
+	}
+	if header != "" {
+		if !opts.TrustHeader {
+			return "", ErrTenantHeaderNotTrusted
+		}
+		return header, nil
+	}
+	return opts.Default, nil
+}
+
+type TenantHandler struct {
+	service    *service.TenantService
+	decodeOpts DecodeOptions
+}
+
+func NewTenantHandler(service *service.TenantService, decodeOpts DecodeOptions) *TenantHandler {
+	return &TenantHandler{service: service, decodeOpts: decodeOpts}
+}
+
+func (h *TenantHandler) List(w http.ResponseWriter, r *http.Request) {
+	tenants, err := h.service.List(r.Context())
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	JSON(w, http.StatusOK, tenants)
+}
+
+func (h *TenantHandler) Get(w http.ResponseWriter, r *http.Request) {
+	tenant, err := h.service.Get(r.Context(), r.PathValue("id"))
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	JSON(w, http.StatusOK, tenant)
+}
+
+func (h *TenantHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req domain.CreateTenantRequest
+	if !decodeRequest(w, r, &req, h.decodeOpts, false) {
+		return
+	}
+
+	tenant, err := h.service.Create(r.Context(), req)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Location", "/api/v1/tenants/"+tenant.ID)
+	JSON(w, http.StatusCreated, tenant)
+}
+
+func (h *TenantHandler) Update(w http.ResponseWriter, r *http.Request) {
+	var req domain.UpdateTenantRequest
+	if !decodeRequest(w, r, &req, h.decodeOpts, false) {
+		return
+	}
+
+	tenant, err := h.service.Update(r.Context(), r.PathValue("id"), req)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	JSON(w, http.StatusOK, tenant)
+}
+
+func (h *TenantHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	if err := h.service.Delete(r.Context(), r.PathValue("id")); err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *TenantHandler) routes() []route {
+	return []route{
+		{"GET /api/v1/tenants", h.List},
+		{"POST /api/v1/tenants", h.Create},
+		{"GET /api/v1/tenants/{id}", h.Get},
+		{"PUT /api/v1/tenants/{id}", h.Update},
+		{"DELETE /api/v1/tenants/{id}", h.Delete},
+	}
+}
+
+func (h *TenantHandler) RegisterRoutes(mux *http.ServeMux) {
+	registerRoutes(mux, h.routes())
+}
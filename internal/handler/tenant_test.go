@@ -0,0 +1,171 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/giannuccilli/user-api/internal/domain"
+	"github.com/giannuccilli/user-api/internal/service"
+)
+
+type mockTenantRepository struct {
+	tenants map[string]domain.Tenant
+	users   map[string]int
+}
+
+func (m *mockTenantRepository) Create(ctx context.Context, tenant *domain.Tenant) error {
+	if _, ok := m.tenants[tenant.ID]; ok {
+		return domain.ErrTenantExists
+	}
+	tenant.CreatedAt = time.Now()
+	tenant.UpdatedAt = tenant.CreatedAt
+	m.tenants[tenant.ID] = *tenant
+	return nil
+}
+
+func (m *mockTenantRepository) Get(ctx context.Context, id string) (*domain.Tenant, error) {
+	tenant, ok := m.tenants[id]
+	if !ok {
+		return nil, domain.ErrTenantNotFound
+	}
+	return &tenant, nil
+}
+
+func (m *mockTenantRepository) List(ctx context.Context) ([]domain.Tenant, error) {
+	tenants := make([]domain.Tenant, 0, len(m.tenants))
+	for _, tenant := range m.tenants {
+		tenants = append(tenants, tenant)
+	}
+	return tenants, nil
+}
+
+func (m *mockTenantRepository) Update(ctx context.Context, tenant *domain.Tenant) error {
+	existing, ok := m.tenants[tenant.ID]
+	if !ok {
+		return domain.ErrTenantNotFound
+	}
+	tenant.CreatedAt = existing.CreatedAt
+	tenant.UpdatedAt = time.Now()
+	m.tenants[tenant.ID] = *tenant
+	return nil
+}
+
+func (m *mockTenantRepository) Delete(ctx context.Context, id string) error {
+	if _, ok := m.tenants[id]; !ok {
+		return domain.ErrTenantNotFound
+	}
+	if m.users[id] > 0 {
+		return domain.ErrTenantNotEmpty
+	}
+	delete(m.tenants, id)
+	return nil
+}
+
+func TestTenant(t *testing.T) {
+	tests := []struct {
+		name       string
+		opts       TenantOptions
+		headers    map[string]string
+		wantStatus int
+		wantTenant string
+	}{
+		{"trusted header", TenantOptions{Default: "default", TrustHeader: true}, map[string]string{TenantHeader: "acme"}, http.StatusOK, "acme"},
+		{"untrusted header", TenantOptions{Default: "default"}, map[string]string{TenantHeader: "acme"}, http.StatusForbidden, ""},
+		{"header matching claim", TenantOptions{}, map[string]string{TenantHeader: "globex", "Authorization": testBearerClaims(`{"tenant_id":"globex"}`)}, http.StatusOK, "globex"},
+		{"header conflicting with claim", TenantOptions{TrustHeader: true}, map[string]string{TenantHeader: "acme", "Authorization": testBearerClaims(`{"tenant_id":"globex"}`)}, http.StatusForbidden, ""},
+		{"default claim", TenantOptions{}, map[string]string{"Authorization": testBearerClaims(`{"tenant_id":"globex"}`)}, http.StatusOK, "globex"},
+		{"custom claim", TenantOptions{Claim: "org"}, map[string]string{"Authorization": testBearerClaims(`{"org":"initech"}`)}, http.StatusOK, "initech"},
+		{"default", TenantOptions{Default: "default"}, nil, http.StatusOK, "default"},
+		{"required", TenantOptions{}, nil, http.StatusBadRequest, ""},
+		{"invalid", TenantOptions{TrustHeader: true}, map[string]string{TenantHeader: "Acme Corp"}, http.StatusBadRequest, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotTenant string
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotTenant, _ = domain.TenantFromContext(r.Context())
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/users", nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+
+			Tenant(tt.opts)(next).ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %v, want %v", rec.Code, tt.wantStatus)
+			}
+			if gotTenant != tt.wantTenant {
+				t.Errorf("tenant = %q, want %q", gotTenant, tt.wantTenant)
+			}
+		})
+	}
+}
+
+func TestTenantHandler(t *testing.T) {
+	repo := &mockTenantRepository{
+		tenants: map[string]domain.Tenant{domain.DefaultTenantID: {ID: domain.DefaultTenantID, Name: "Default"}},
+		users:   map[string]int{"globex": 1},
+	}
+	mux := http.NewServeMux()
+	NewTenantHandler(service.NewTenantService(repo), DecodeOptions{}).RegisterRoutes(mux)
+	handler := Actor()(mux)
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		roles      string
+		wantStatus int
+		wantCode   string
+	}{
+		{"list requires admin", http.MethodGet, "/api/v1/tenants", "", "", http.StatusForbidden, ErrCodeForbidden},
+		{"create requires platform admin", http.MethodPost, "/api/v1/tenants", `{"id":"acme","name":"Acme"}`, domain.RoleAdmin, http.StatusForbidden, ErrCodeForbidden},
+		{"create invalid", http.MethodPost, "/api/v1/tenants", `{"id":"Acme","name":""}`, domain.RolePlatformAdmin, http.StatusUnprocessableEntity, ErrCodeValidationFailed},
+		{"create", http.MethodPost, "/api/v1/tenants", `{"id":"acme","name":"Acme"}`, domain.RolePlatformAdmin, http.StatusCreated, ""},
+		{"create duplicate", http.MethodPost, "/api/v1/tenants", `{"id":"acme","name":"Acme"}`, domain.RolePlatformAdmin, http.StatusConflict, ErrCodeTenantExists},
+		{"create with users", http.MethodPost, "/api/v1/tenants", `{"id":"globex","name":"Globex"}`, domain.RolePlatformAdmin, http.StatusCreated, ""},
+		{"update", http.MethodPut, "/api/v1/tenants/acme", `{"name":"Acme Corp"}`, domain.RolePlatformAdmin, http.StatusOK, ""},
+		{"get", http.MethodGet, "/api/v1/tenants/acme", "", domain.RolePlatformAdmin, http.StatusOK, ""},
+		{"list", http.MethodGet, "/api/v1/tenants", "", domain.RolePlatformAdmin, http.StatusOK, ""},
+		{"delete not empty", http.MethodDelete, "/api/v1/tenants/globex", "", domain.RolePlatformAdmin, http.StatusConflict, ErrCodeTenantNotEmpty},
+		{"delete default", http.MethodDelete, "/api/v1/tenants/default", "", domain.RolePlatformAdmin, http.StatusForbidden, ErrCodeForbidden},
+		{"delete", http.MethodDelete, "/api/v1/tenants/acme", "", domain.RolePlatformAdmin, http.StatusNoContent, ""},
+		{"get deleted", http.MethodGet, "/api/v1/tenants/acme", "", domain.RolePlatformAdmin, http.StatusNotFound, ErrCodeTenantNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.roles != "" {
+				req.Header.Set("X-Actor-Roles", tt.roles)
+			}
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("%s %s status = %v, want %v (body: %s)", tt.method, tt.path, rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantCode != "" {
+				var problem Problem
+				if err := json.NewDecoder(rec.Body).Decode(&problem); err != nil {
+					t.Fatalf("Failed to decode response: %v", err)
+				}
+				if problem.Code != tt.wantCode {
+					t.Errorf("code = %v, want %v", problem.Code, tt.wantCode)
+				}
+			}
+		})
+	}
+}
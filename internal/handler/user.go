@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 
@@ -12,23 +14,35 @@ import (
 )
 
 type UserHandler struct {
-	service *service.UserService
+	service    *service.UserService
+	decodeOpts DecodeOptions
 }
 
-func NewUserHandler(service *service.UserService) *UserHandler {
-	return &UserHandler{service: service}
+type Option func(*UserHandler)
+
+func WithDecodeOptions(opts DecodeOptions) Option {
+	return func(h *UserHandler) {
+		h.decodeOpts = opts
+	}
+}
+
+func NewUserHandler(service *service.UserService, opts ...Option) *UserHandler {
+	h := &UserHandler{service: service}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
 }
 
 func (h *UserHandler) Create(w http.ResponseWriter, r *http.Request) {
 	var req domain.CreateUserRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		ErrorWithMessage(w, http.StatusBadRequest, ErrCodeInvalidRequest, "Invalid JSON body")
+	if !h.decodeJSON(w, r, &req) {
 		return
 	}
 
 	user, err := h.service.Create(r.Context(), req)
 	if err != nil {
-		Error(w, err)
+		Error(w, r, err)
 		return
 	}
 
@@ -40,87 +54,259 @@ func (h *UserHandler) GetByID(w http.ResponseWriter, r *http.Request) {
 	idStr := r.PathValue("id")
 	id, err := uuid.Parse(idStr)
 	if err != nil {
-		ErrorWithMessage(w, http.StatusBadRequest, ErrCodeInvalidID, "Invalid user ID format")
+		ErrorWithMessage(w, r, http.StatusBadRequest, ErrCodeInvalidID, "Invalid user ID format")
+		return
+	}
+
+	fields, ok := fieldsParam(w, r)
+	if !ok {
 		return
 	}
 
-	user, err := h.service.GetByID(r.Context(), id)
+	if asOfStr := r.URL.Query().Get("asOf"); asOfStr != "" {
+		asOf, err := time.Parse(time.RFC3339, asOfStr)
+		if err != nil {
+			ErrorWithMessage(w, r, http.StatusBadRequest, ErrCodeInvalidRequest, "Invalid asOf timestamp, expected RFC 3339")
+			return
+		}
+
+		user, err := h.service.GetAsOf(r.Context(), id, asOf)
+		if err != nil {
+			Error(w, r, err)
+			return
+		}
+
+		JSON(w, http.StatusOK, projectUser(*user, fields))
+		return
+	}
+
+	user, err := h.service.GetByID(r.Context(), id, fields)
 	if err != nil {
-		Error(w, err)
+		Error(w, r, err)
 		return
 	}
 
-	JSON(w, http.StatusOK, user)
+	JSON(w, http.StatusOK, projectUser(*user, fields))
 }
 
-func (h *UserHandler) List(w http.ResponseWriter, r *http.Request) {
-	limit := 20
-	offset := 0
+func (h *UserHandler) BatchGet(w http.ResponseWriter, r *http.Request) {
+	fields, ok := fieldsParam(w, r)
+	if !ok {
+		return
+	}
 
-	if l := r.URL.Query().Get("limit"); l != "" {
-		if parsed, err := strconv.Atoi(l); err == nil {
-			limit = parsed
-		}
+	var req domain.BatchGetUsersRequest
+	if !h.decodeJSON(w, r, &req) {
+		return
 	}
 
-	if o := r.URL.Query().Get("offset"); o != "" {
-		if parsed, err := strconv.Atoi(o); err == nil {
-			offset = parsed
-		}
+	result, err := h.service.BatchGet(r.Context(), req.IDs, fields)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	if fields == nil {
+		JSON(w, http.StatusOK, result)
+		return
+	}
+	JSON(w, http.StatusOK, struct {
+		Data     []any       `json:"data"`
+		NotFound []uuid.UUID `json:"notFound"`
+	}{projectUsers(result.Data, fields), result.NotFound})
+}
+
+func (h *UserHandler) Diff(w http.ResponseWriter, r *http.Request) {
+	idStr := r.PathValue("id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		ErrorWithMessage(w, r, http.StatusBadRequest, ErrCodeInvalidID, "Invalid user ID format")
+		return
+	}
+
+	from, err := time.Parse(time.RFC3339, r.URL.Query().Get("from"))
+	if err != nil {
+		ErrorWithMessage(w, r, http.StatusBadRequest, ErrCodeInvalidRequest, "Invalid from timestamp, expected RFC 3339")
+		return
+	}
+
+	to, err := time.Parse(time.RFC3339, r.URL.Query().Get("to"))
+	if err != nil {
+		ErrorWithMessage(w, r, http.StatusBadRequest, ErrCodeInvalidRequest, "Invalid to timestamp, expected RFC 3339")
+		return
+	}
+
+	diff, err := h.service.Diff(r.Context(), id, from, to)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	JSON(w, http.StatusOK, diff)
+}
+
+func (h *UserHandler) List(w http.ResponseWriter, r *http.Request) {
+	limit, offset := paginationParams(r)
+	fields, ok := fieldsParam(w, r)
+	if !ok {
+		return
 	}
 
-	users, err := h.service.List(r.Context(), limit, offset)
+	users, err := h.service.List(r.Context(), domain.UserFilter{
+		Limit:      limit,
+		Offset:     offset,
+		Attributes: attributeFilter(r),
+		Fields:     fields,
+	})
 	if err != nil {
-		Error(w, err)
+		Error(w, r, err)
 		return
 	}
 
-	JSON(w, http.StatusOK, users)
+	if fields == nil {
+		JSON(w, http.StatusOK, users)
+		return
+	}
+	JSON(w, http.StatusOK, struct {
+		Data       []any             `json:"data"`
+		Pagination domain.Pagination `json:"pagination"`
+	}{projectUsers(users.Data, fields), users.Pagination})
 }
 
 func (h *UserHandler) Update(w http.ResponseWriter, r *http.Request) {
 	idStr := r.PathValue("id")
 	id, err := uuid.Parse(idStr)
 	if err != nil {
-		ErrorWithMessage(w, http.StatusBadRequest, ErrCodeInvalidID, "Invalid user ID format")
+		ErrorWithMessage(w, r, http.StatusBadRequest, ErrCodeInvalidID, "Invalid user ID format")
 		return
 	}
 
 	var req domain.UpdateUserRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		ErrorWithMessage(w, http.StatusBadRequest, ErrCodeInvalidRequest, "Invalid JSON body")
+	if !h.decodeJSON(w, r, &req) {
 		return
 	}
 
 	user, err := h.service.Update(r.Context(), id, req)
 	if err != nil {
-		Error(w, err)
+		Error(w, r, err)
 		return
 	}
 
 	JSON(w, http.StatusOK, user)
 }
 
+func (h *UserHandler) UpsertByEmail(w http.ResponseWriter, r *http.Request) {
+	var req domain.UpsertUserRequest
+	if !h.decodeJSON(w, r, &req) {
+		return
+	}
+
+	user, created, err := h.service.UpsertByEmail(r.Context(), r.PathValue("email"), req)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	if created {
+		w.Header().Set("Location", "/api/v1/users/"+user.ID.String())
+		JSON(w, http.StatusCreated, user)
+		return
+	}
+	JSON(w, http.StatusOK, user)
+}
+
 func (h *UserHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	idStr := r.PathValue("id")
 	id, err := uuid.Parse(idStr)
 	if err != nil {
-		ErrorWithMessage(w, http.StatusBadRequest, ErrCodeInvalidID, "Invalid user ID format")
+		ErrorWithMessage(w, r, http.StatusBadRequest, ErrCodeInvalidID, "Invalid user ID format")
 		return
 	}
 
 	if err := h.service.Delete(r.Context(), id); err != nil {
-		Error(w, err)
+		Error(w, r, err)
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
 
+func paginationParams(r *http.Request) (int, int) {
+	limit := 20
+	offset := 0
+
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil {
+			limit = parsed
+		}
+	}
+
+	if o := r.URL.Query().Get("offset"); o != "" {
+		if parsed, err := strconv.Atoi(o); err == nil {
+			offset = parsed
+		}
+	}
+
+	return limit, offset
+}
+
+// attributeFilter collects attr.<key>=<value> query parameters. Values that
+// parse as JSON are matched with their JSON type, anything else as a string.
+func attributeFilter(r *http.Request) domain.Attributes {
+	var attrs domain.Attributes
+	for name, values := range r.URL.Query() {
+		key, ok := strings.CutPrefix(name, "attr.")
+		if !ok || key == "" || len(values) == 0 {
+			continue
+		}
+		if attrs == nil {
+			attrs = domain.Attributes{}
+		}
+
+		var value any
+		if err := json.Unmarshal([]byte(values[0]), &value); err != nil {
+			value = values[0]
+		}
+		attrs[key] = value
+	}
+	return attrs
+}
+
+// route pairs a ServeMux pattern with its handler. Handlers list their routes
+// so tests can check them against the OpenAPI spec.
+type route struct {
+	pattern string
+	handler http.HandlerFunc
+}
+
+func registerRoutes(mux *http.ServeMux, routes []route) {
+	for _, rt := range routes {
+		mux.HandleFunc(rt.pattern, rt.handler)
+	}
+}
+
+func (h *UserHandler) routes() []route {
+	return []route{
+		{"POST /api/v1/users", h.Create},
+		{"GET /api/v1/users", h.List},
+		{"POST /api/v1/users:batchGet", h.BatchGet},
+		{"GET /api/v1/users:byExternalId", h.GetByExternalID},
+		{"PUT /api/v1/users:byExternalId", h.UpsertByExternalID},
+		{"PUT /api/v1/users:byEmail/{email}", h.UpsertByEmail},
+		{"GET /api/v1/users/{id}", h.GetByID},
+		{"GET /api/v1/users/{id}/diff", h.Diff},
+		{"GET /api/v1/users/{id}/audit", h.ListAudit},
+		{"GET /api/v1/users/{id}/identities", h.ListIdentities},
+		{"POST /api/v1/users/{id}/identities", h.LinkIdentity},
+		{"DELETE /api/v1/users/{id}/identities/{provider}/{externalId...}", h.UnlinkIdentity},
+		{"POST /api/v1/users/{idAction}", h.Action},
+		{"POST /api/v1/verify-email", h.VerifyEmail},
+		{"POST /api/v1/confirm-email-change", h.ConfirmEmailChange},
+		{"PUT /api/v1/users/{id}", h.Update},
+		{"DELETE /api/v1/users/{id}", h.Delete},
+	}
+}
+
 func (h *UserHandler) RegisterRoutes(mux *http.ServeMux) {
-	mux.HandleFunc("POST /api/v1/users", h.Create)
-	mux.HandleFunc("GET /api/v1/users", h.List)
-	mux.HandleFunc("GET /api/v1/users/{id}", h.GetByID)
-	mux.HandleFunc("PUT /api/v1/users/{id}", h.Update)
-	mux.HandleFunc("DELETE /api/v1/users/{id}", h.Delete)
+	registerRoutes(mux, h.routes())
 }
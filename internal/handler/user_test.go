@@ -6,11 +6,15 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 
 	"github.com/giannuccilli/user-api/internal/domain"
+	"github.com/giannuccilli/user-api/internal/emailaddr"
 	"github.com/giannuccilli/user-api/internal/service"
 )
 
@@ -40,6 +44,16 @@ func (m *mockUserRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain
 	return nil, domain.ErrUserNotFound
 }
 
+func (m *mockUserRepository) GetByIDs(ctx context.Context, ids []uuid.UUID, fields domain.FieldSet) ([]domain.User, error) {
+	users := make([]domain.User, 0, len(ids))
+	for _, id := range ids {
+		if user, ok := m.users[id]; ok {
+			users = append(users, *user)
+		}
+	}
+	return users, nil
+}
+
 func (m *mockUserRepository) GetByEmail(ctx context.Context, email string) (*domain.User, error) {
 	if user, ok := m.byEmail[email]; ok {
 		return user, nil
@@ -47,14 +61,34 @@ func (m *mockUserRepository) GetByEmail(ctx context.Context, email string) (*dom
 	return nil, domain.ErrUserNotFound
 }
 
-func (m *mockUserRepository) List(ctx context.Context, limit, offset int) ([]domain.User, int, error) {
+func (m *mockUserRepository) GetByCanonicalEmail(ctx context.Context, canonical string) (*domain.User, error) {
+	for _, user := range m.users {
+		if user.EmailCanonical == canonical || (user.EmailCanonical == "" && user.Email == canonical) {
+			return user, nil
+		}
+	}
+	return nil, domain.ErrUserNotFound
+}
+
+func (m *mockUserRepository) List(ctx context.Context, filter domain.UserFilter) ([]domain.User, int, error) {
 	users := make([]domain.User, 0)
 	for _, u := range m.users {
-		users = append(users, *u)
+		if matchesAttributes(u.Attributes, filter.Attributes) {
+			users = append(users, *u)
+		}
 	}
 	return users, len(users), nil
 }
 
+func matchesAttributes(attrs, filter domain.Attributes) bool {
+	for k, want := range filter {
+		if got, ok := attrs[k]; !ok || !reflect.DeepEqual(got, want) {
+			return false
+		}
+	}
+	return true
+}
+
 func (m *mockUserRepository) Update(ctx context.Context, user *domain.User) error {
 	if _, ok := m.users[user.ID]; !ok {
 		return domain.ErrUserNotFound
@@ -63,6 +97,46 @@ func (m *mockUserRepository) Update(ctx context.Context, user *domain.User) erro
 	return nil
 }
 
+func (m *mockUserRepository) UpdateWithAudit(ctx context.Context, user *domain.User, entry *domain.AuditEntry) error {
+	return m.Update(ctx, user)
+}
+
+func (m *mockUserRepository) Upsert(ctx context.Context, user *domain.User) (domain.UpsertResult, error) {
+	merged := domain.Attributes{}
+	existing, err := m.GetByCanonicalEmail(ctx, user.EmailCanonical)
+	if err == nil {
+		for k, v := range existing.Attributes {
+			merged[k] = v
+		}
+	}
+	for k, v := range user.Attributes {
+		if v == nil {
+			delete(merged, k)
+		} else {
+			merged[k] = v
+		}
+	}
+
+	if existing == nil {
+		user.ID = uuid.New()
+		user.Attributes = merged
+		m.users[user.ID] = user
+		m.byEmail[user.Email] = user
+		return domain.UpsertCreated, nil
+	}
+
+	if existing.FirstName == user.FirstName && existing.LastName == user.LastName &&
+		len(merged) == len(existing.Attributes) && (len(merged) == 0 || reflect.DeepEqual(merged, existing.Attributes)) {
+		*user = *existing
+		return domain.UpsertUnchanged, nil
+	}
+	existing.FirstName = user.FirstName
+	existing.LastName = user.LastName
+	existing.Attributes = merged
+	*user = *existing
+	return domain.UpsertUpdated, nil
+}
+
 func (m *mockUserRepository) Delete(ctx context.Context, id uuid.UUID) error {
 	if _, ok := m.users[id]; !ok {
 		return domain.ErrUserNotFound
@@ -71,12 +145,31 @@ func (m *mockUserRepository) Delete(ctx context.Context, id uuid.UUID) error {
 	return nil
 }
 
+func (m *mockUserRepository) GetAsOf(ctx context.Context, id uuid.UUID, asOf time.Time) (*domain.User, error) {
+	if user, ok := m.users[id]; ok && !user.CreatedAt.After(asOf) {
+		return user, nil
+	}
+	return nil, domain.ErrUserNotFound
+}
+
+func (m *mockUserRepository) ListExpiredSuspensions(ctx context.Context, before time.Time, limit int) ([]domain.User, error) {
+	return []domain.User{}, nil
+}
+
+func (m *mockUserRepository) ReactivateSuspension(ctx context.Context, user *domain.User, before time.Time, entry *domain.AuditEntry) (bool, error) {
+	return false, nil
+}
+
 type mockNotifier struct{}
 
-func (m *mockNotifier) NotifyCreated(ctx context.Context, userID uuid.UUID) error { return nil }
-func (m *mockNotifier) NotifyUpdated(ctx context.Context, userID uuid.UUID) error { return nil }
-func (m *mockNotifier) NotifyDeleted(ctx context.Context, userID uuid.UUID) error { return nil }
-func (m *mockNotifier) Close() error                                              { return nil }
+func (m *mockNotifier) NotifyCreated(ctx context.Context, data domain.EventData) error { return nil }
+func (m *mockNotifier) NotifyUpdated(ctx context.Context, data domain.EventData) error { return nil }
+func (m *mockNotifier) NotifyDeleted(ctx context.Context, userID uuid.UUID) error      { return nil }
+func (m *mockNotifier) Close() error                                                   { return nil }
+
+func (m *mockNotifier) Notify(ctx context.Context, eventType domain.EventType, data domain.EventData) error {
+	return nil
+}
 
 func setupTestHandler() (*UserHandler, *mockUserRepository) {
 	repo := newMockUserRepository()
@@ -106,7 +199,7 @@ func TestUserHandler_Create(t *testing.T) {
 		{
 			name:       "missing email",
 			body:       `{"firstName":"John","lastName":"Doe"}`,
-			wantStatus: http.StatusBadRequest,
+			wantStatus: http.StatusUnprocessableEntity,
 		},
 	}
 
@@ -125,6 +218,77 @@ func TestUserHandler_Create(t *testing.T) {
 	}
 }
 
+func TestUserHandler_Create_ValidationErrors(t *testing.T) {
+	handler, _ := setupTestHandler()
+
+	body := `{"email":"not-an-email","firstName":"","lastName":"` + strings.Repeat("x", 101) + `"}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/users", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+
+	handler.Create(rec, req)
+
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("Create() status = %v, want %v", rec.Code, http.StatusUnprocessableEntity)
+	}
+
+	var resp ErrorResponse
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if resp.Code != ErrCodeValidationFailed {
+		t.Errorf("Create() code = %v, want %v", resp.Code, ErrCodeValidationFailed)
+	}
+
+	want := []domain.FieldError{
+		{Field: "email", Code: "INVALID_EMAIL"},
+		{Field: "firstName", Code: domain.FieldCodeRequired},
+		{Field: "lastName", Code: domain.FieldCodeTooLong},
+	}
+	if len(resp.Fields) != len(want) {
+		t.Fatalf("Create() fields = %+v, want %d entries", resp.Fields, len(want))
+	}
+	for i, f := range want {
+		if resp.Fields[i].Field != f.Field || resp.Fields[i].Code != f.Code || resp.Fields[i].Message == "" {
+			t.Errorf("Create() fields[%d] = %+v, want %s/%s with a message", i, resp.Fields[i], f.Field, f.Code)
+		}
+	}
+}
+
+func TestUserHandler_Create_EmailPolicy(t *testing.T) {
+	policy := emailaddr.NewPolicy(emailaddr.Config{BlockDisposable: true, DeniedDomains: []string{"blocked.com"}})
+	handler := NewUserHandler(service.NewUserService(newMockUserRepository(), &mockNotifier{}, service.WithEmailPolicy(policy)))
+
+	tests := []struct {
+		name     string
+		email    string
+		wantCode string
+	}{
+		{"malformed", "a@.", "INVALID_EMAIL"},
+		{"denied domain", "user@blocked.com", "EMAIL_DOMAIN_NOT_ALLOWED"},
+		{"disposable domain", "user@yopmail.com", "DISPOSABLE_EMAIL"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := `{"email":"` + tt.email + `","firstName":"John","lastName":"Doe"}`
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/users", bytes.NewBufferString(body))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+
+			handler.Create(rec, req)
+
+			if rec.Code != http.StatusUnprocessableEntity {
+				t.Fatalf("Create() status = %v, want %v", rec.Code, http.StatusUnprocessableEntity)
+			}
+
+			var resp ErrorResponse
+			json.NewDecoder(rec.Body).Decode(&resp)
+			if len(resp.Fields) != 1 || resp.Fields[0].Field != "email" || resp.Fields[0].Code != tt.wantCode {
+				t.Errorf("Create() fields = %+v, want email with code %v", resp.Fields, tt.wantCode)
+			}
+		})
+	}
+}
+
 func TestUserHandler_GetByID(t *testing.T) {
 	handler, repo := setupTestHandler()
 
@@ -268,6 +432,39 @@ func TestUserHandler_Update(t *testing.T) {
 	}
 }
 
+func TestUserHandler_UpsertByEmail(t *testing.T) {
+	handler, _ := setupTestHandler()
+	mux := http.NewServeMux()
+	handler.RegisterRoutes(mux)
+
+	tests := []struct {
+		name       string
+		email      string
+		body       string
+		wantStatus int
+	}{
+		{"creates", "sync@example.com", `{"firstName":"John","lastName":"Doe"}`, http.StatusCreated},
+		{"updates", "sync@example.com", `{"firstName":"Johnny","lastName":"Doe"}`, http.StatusOK},
+		{"unchanged", "sync@example.com", `{"firstName":"Johnny","lastName":"Doe"}`, http.StatusOK},
+		{"missing names", "sync@example.com", `{}`, http.StatusUnprocessableEntity},
+		{"invalid email", "not-an-email", `{"firstName":"John","lastName":"Doe"}`, http.StatusUnprocessableEntity},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPut, "/api/v1/users:byEmail/"+tt.email, bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+
+			mux.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("UpsertByEmail() status = %v, want %v (body: %s)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+		})
+	}
+}
+
 func TestUserHandler_Delete(t *testing.T) {
 	handler, repo := setupTestHandler()
 
@@ -316,3 +513,234 @@ func TestUserHandler_Delete(t *testing.T) {
 		})
 	}
 }
+
+func TestUserHandler_BatchGet(t *testing.T) {
+	handler, repo := setupTestHandler()
+
+	user := &domain.User{
+		ID:     uuid.New(),
+		Email:  "test@example.com",
+		Status: domain.UserStatusActive,
+	}
+	repo.users[user.ID] = user
+	missing := uuid.New()
+
+	tests := []struct {
+		name         string
+		body         string
+		wantStatus   int
+		wantData     int
+		wantNotFound int
+	}{
+		{
+			name:         "found and missing",
+			body:         `{"ids":["` + user.ID.String() + `","` + missing.String() + `"]}`,
+			wantStatus:   http.StatusOK,
+			wantData:     1,
+			wantNotFound: 1,
+		},
+		{
+			name:       "empty ids",
+			body:       `{"ids":[]}`,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "invalid id",
+			body:       `{"ids":["invalid-uuid"]}`,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "invalid json",
+			body:       `{invalid}`,
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/users:batchGet", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+
+			handler.BatchGet(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("BatchGet() status = %v, want %v", rec.Code, tt.wantStatus)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var resp domain.BatchGetUsersResult
+			json.NewDecoder(rec.Body).Decode(&resp)
+			if len(resp.Data) != tt.wantData || len(resp.NotFound) != tt.wantNotFound {
+				t.Errorf("BatchGet() data = %d, notFound = %d, want %d and %d", len(resp.Data), len(resp.NotFound), tt.wantData, tt.wantNotFound)
+			}
+		})
+	}
+}
+
+func TestUserHandler_GetByID_AsOf(t *testing.T) {
+	handler, repo := setupTestHandler()
+
+	user := &domain.User{
+		ID:        uuid.New(),
+		Email:     "test@example.com",
+		FirstName: "John",
+		LastName:  "Doe",
+		Status:    domain.UserStatusActive,
+		CreatedAt: time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC),
+	}
+	repo.users[user.ID] = user
+
+	tests := []struct {
+		name       string
+		asOf       string
+		wantStatus int
+	}{
+		{"after creation", "2026-02-01T00:00:00Z", http.StatusOK},
+		{"before creation", "2025-12-01T00:00:00Z", http.StatusNotFound},
+		{"invalid timestamp", "yesterday", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/users/"+user.ID.String()+"?asOf="+tt.asOf, nil)
+			req.SetPathValue("id", user.ID.String())
+			rec := httptest.NewRecorder()
+
+			handler.GetByID(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("GetByID() status = %v, want %v", rec.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestUserHandler_Diff(t *testing.T) {
+	handler, repo := setupTestHandler()
+
+	user := &domain.User{
+		ID:        uuid.New(),
+		Email:     "test@example.com",
+		FirstName: "John",
+		LastName:  "Doe",
+		Status:    domain.UserStatusActive,
+		CreatedAt: time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC),
+	}
+	repo.users[user.ID] = user
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+	}{
+		{"valid range", "from=2026-01-02T00:00:00Z&to=2026-02-01T00:00:00Z", http.StatusOK},
+		{"missing to", "from=2026-01-02T00:00:00Z", http.StatusBadRequest},
+		{"reversed range", "from=2026-02-01T00:00:00Z&to=2026-01-02T00:00:00Z", http.StatusUnprocessableEntity},
+		{"before creation", "from=2025-12-01T00:00:00Z&to=2026-02-01T00:00:00Z", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/users/"+user.ID.String()+"/diff?"+tt.query, nil)
+			req.SetPathValue("id", user.ID.String())
+			rec := httptest.NewRecorder()
+
+			handler.Diff(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("Diff() status = %v, want %v", rec.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestUserHandler_Update_StatusLifecycle(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     domain.UserStatus
+		roles      string
+		body       string
+		wantStatus int
+	}{
+		{
+			name:       "suspend with reason",
+			status:     domain.UserStatusActive,
+			body:       `{"status":"suspended","statusReason":"abuse"}`,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "suspend without reason",
+			status:     domain.UserStatusActive,
+			body:       `{"status":"suspended"}`,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "reactivate without admin role",
+			status:     domain.UserStatusSuspended,
+			body:       `{"status":"active"}`,
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "reactivate as admin",
+			status:     domain.UserStatusSuspended,
+			roles:      "support, admin",
+			body:       `{"status":"active"}`,
+			wantStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, repo := setupTestHandler()
+
+			user := &domain.User{
+				ID:        uuid.New(),
+				Email:     "test@example.com",
+				FirstName: "John",
+				LastName:  "Doe",
+				Status:    tt.status,
+			}
+			repo.users[user.ID] = user
+
+			req := httptest.NewRequest(http.MethodPut, "/api/v1/users/"+user.ID.String(), bytes.NewBufferString(tt.body))
+			req.SetPathValue("id", user.ID.String())
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("X-Actor-Roles", tt.roles)
+			rec := httptest.NewRecorder()
+
+			Actor()(http.HandlerFunc(handler.Update)).ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("Update() status = %v, want %v", rec.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestUserHandler_Update_InvalidTransition(t *testing.T) {
+	repo := newMockUserRepository()
+	graph := service.NewTransitionGraph(service.Transition{From: domain.UserStatusActive, To: domain.UserStatusInactive})
+	handler := NewUserHandler(service.NewUserService(repo, &mockNotifier{}, service.WithTransitionGraph(graph)))
+
+	user := &domain.User{
+		ID:        uuid.New(),
+		Email:     "test@example.com",
+		FirstName: "John",
+		LastName:  "Doe",
+		Status:    domain.UserStatusInactive,
+	}
+	repo.users[user.ID] = user
+
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/users/"+user.ID.String(), bytes.NewBufferString(`{"status":"active"}`))
+	req.SetPathValue("id", user.ID.String())
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+
+	handler.Update(rec, req)
+
+	if rec.Code != http.StatusConflict {
+		t.Errorf("Update() status = %v, want %v", rec.Code, http.StatusConflict)
+	}
+}
@@ -0,0 +1,51 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/giannuccilli/user-api/internal/domain"
+)
+
+func (h *UserHandler) SendVerification(w http.ResponseWriter, r *http.Request) {
+	id, ok := actionUserID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.service.SendVerification(r.Context(), id); err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (h *UserHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var req domain.VerifyEmailRequest
+	if !h.decodeJSON(w, r, &req) {
+		return
+	}
+
+	user, err := h.service.VerifyEmail(r.Context(), req)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	JSON(w, http.StatusOK, user)
+}
+
+func (h *UserHandler) ConfirmEmailChange(w http.ResponseWriter, r *http.Request) {
+	var req domain.ConfirmEmailChangeRequest
+	if !h.decodeJSON(w, r, &req) {
+		return
+	}
+
+	user, err := h.service.ConfirmEmailChange(r.Context(), req)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	JSON(w, http.StatusOK, user)
+}
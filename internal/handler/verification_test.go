@@ -0,0 +1,160 @@
+package handler
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/giannuccilli/user-api/internal/domain"
+	"github.com/giannuccilli/user-api/internal/mailer"
+	"github.com/giannuccilli/user-api/internal/service"
+	"github.com/giannuccilli/user-api/internal/token"
+)
+
+func TestUserHandler_EmailVerificationFlow(t *testing.T) {
+	repo := newMockUserRepository()
+	sender := mailer.NewMemorySender()
+	svc := service.NewUserService(repo, &mockNotifier{}, service.WithEmailVerification(
+		sender, token.NewSigner([]byte("test-secret")), service.VerificationConfig{TokenTTL: time.Hour},
+	))
+	mux := http.NewServeMux()
+	NewUserHandler(svc).RegisterRoutes(mux)
+
+	user := &domain.User{
+		ID:        uuid.New(),
+		Email:     "test@example.com",
+		FirstName: "John",
+		LastName:  "Doe",
+		Status:    domain.UserStatusPendingVerification,
+	}
+	repo.users[user.ID] = user
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/users/"+user.ID.String()+":sendVerification", nil)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("sendVerification status = %v, want %v", rec.Code, http.StatusAccepted)
+	}
+
+	messages := sender.Messages()
+	if len(messages) != 1 {
+		t.Fatalf("sendVerification sent %v emails, want 1", len(messages))
+	}
+	_, tok, _ := strings.Cut(messages[0].Body, "\n\n")
+	tok, _, _ = strings.Cut(strings.TrimPrefix(tok, "Please confirm your email address using the link below:\n\n"), "\n")
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{"invalid json", `{invalid}`, http.StatusBadRequest},
+		{"invalid token", `{"token":"garbage"}`, http.StatusBadRequest},
+		{"valid token", `{"token":"` + tok + `"}`, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/verify-email", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+
+			mux.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("verify-email status = %v, want %v (body: %s)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+		})
+	}
+
+	if !repo.users[user.ID].EmailVerified || repo.users[user.ID].Status != domain.UserStatusActive {
+		t.Errorf("user after verification = %+v, want verified and active", repo.users[user.ID])
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/users/"+user.ID.String()+":sendVerification", nil)
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusConflict {
+		t.Errorf("sendVerification for verified user status = %v, want %v", rec.Code, http.StatusConflict)
+	}
+}
+
+func TestUserHandler_SendVerification_NotConfigured(t *testing.T) {
+	handler, repo := setupTestHandler()
+	mux := http.NewServeMux()
+	handler.RegisterRoutes(mux)
+
+	user := &domain.User{ID: uuid.New(), Email: "test@example.com", Status: domain.UserStatusActive}
+	repo.users[user.ID] = user
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/users/"+user.ID.String()+":sendVerification", nil)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNotImplemented {
+		t.Errorf("sendVerification status = %v, want %v", rec.Code, http.StatusNotImplemented)
+	}
+}
+
+func TestUserHandler_ConfirmEmailChange(t *testing.T) {
+	repo := newMockUserRepository()
+	sender := mailer.NewMemorySender()
+	svc := service.NewUserService(repo, &mockNotifier{}, service.WithEmailVerification(
+		sender, token.NewSigner([]byte("test-secret")), service.VerificationConfig{TokenTTL: time.Hour},
+	))
+	mux := http.NewServeMux()
+	NewUserHandler(svc).RegisterRoutes(mux)
+
+	user := &domain.User{ID: uuid.New(), Email: "old@example.com", FirstName: "John", LastName: "Doe", Status: domain.UserStatusActive}
+	repo.users[user.ID] = user
+
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/users/"+user.ID.String(), bytes.NewBufferString(`{"email":"new@example.com"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Update() status = %v, want %v", rec.Code, http.StatusOK)
+	}
+	if repo.users[user.ID].Email != "old@example.com" {
+		t.Fatalf("Update() changed email before confirmation")
+	}
+
+	_, tok, _ := strings.Cut(sender.Messages()[0].Body, "link below:\n\n")
+	tok, _, _ = strings.Cut(tok, "\n")
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{"invalid json", `{invalid}`, http.StatusBadRequest},
+		{"invalid token", `{"token":"garbage"}`, http.StatusBadRequest},
+		{"valid token", `{"token":"` + tok + `"}`, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/confirm-email-change", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+
+			mux.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("confirm-email-change status = %v, want %v (body: %s)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+		})
+	}
+
+	if repo.users[user.ID].Email != "new@example.com" {
+		t.Errorf("email after confirmation = %v, want new@example.com", repo.users[user.ID].Email)
+	}
+}
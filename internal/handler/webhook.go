@@ -0,0 +1,134 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/google/uuid"
+
+	"github.com/giannuccilli/user-api/internal/domain"
+	"github.com/giannuccilli/user-api/internal/service"
+)
+
+type WebhookHandler struct {
+	service    *service.WebhookService
+	decodeOpts DecodeOptions
+}
+
+func NewWebhookHandler(service *service.WebhookService, decodeOpts DecodeOptions) *WebhookHandler {
+	return &WebhookHandler{service: service, decodeOpts: decodeOpts}
+}
+
+func (h *WebhookHandler) List(w http.ResponseWriter, r *http.Request) {
+	subs, err := h.service.List(r.Context())
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	JSON(w, http.StatusOK, subs)
+}
+
+func (h *WebhookHandler) Get(w http.ResponseWriter, r *http.Request) {
+	id, ok := webhookID(w, r)
+	if !ok {
+		return
+	}
+
+	sub, err := h.service.Get(r.Context(), id)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	JSON(w, http.StatusOK, sub)
+}
+
+func (h *WebhookHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req domain.CreateWebhookRequest
+	if !decodeRequest(w, r, &req, h.decodeOpts, false) {
+		return
+	}
+
+	sub, err := h.service.Create(r.Context(), req)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Location", "/api/v1/webhooks/"+sub.ID.String())
+	JSON(w, http.StatusCreated, sub)
+}
+
+func (h *WebhookHandler) Update(w http.ResponseWriter, r *http.Request) {
+	id, ok := webhookID(w, r)
+	if !ok {
+		return
+	}
+
+	var req domain.UpdateWebhookRequest
+	if !decodeRequest(w, r, &req, h.decodeOpts, false) {
+		return
+	}
+
+	sub, err := h.service.Update(r.Context(), id, req)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	JSON(w, http.StatusOK, sub)
+}
+
+func (h *WebhookHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, ok := webhookID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.service.Delete(r.Context(), id); err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *WebhookHandler) ListDeliveries(w http.ResponseWriter, r *http.Request) {
+	id, ok := webhookID(w, r)
+	if !ok {
+		return
+	}
+
+	limit, offset := paginationParams(r)
+	deliveries, err := h.service.ListDeliveries(r.Context(), id, limit, offset)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	JSON(w, http.StatusOK, deliveries)
+}
+
+func webhookID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		ErrorWithMessage(w, r, http.StatusBadRequest, ErrCodeInvalidID, "Invalid webhook ID format")
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+func (h *WebhookHandler) routes() []route {
+	return []route{
+		{"GET /api/v1/webhooks", h.List},
+		{"POST /api/v1/webhooks", h.Create},
+		{"GET /api/v1/webhooks/{id}", h.Get},
+		{"PUT /api/v1/webhooks/{id}", h.Update},
+		{"DELETE /api/v1/webhooks/{id}", h.Delete},
+		{"GET /api/v1/webhooks/{id}/deliveries", h.ListDeliveries},
+	}
+}
+
+func (h *WebhookHandler) RegisterRoutes(mux *http.ServeMux) {
+	registerRoutes(mux, h.routes())
+}
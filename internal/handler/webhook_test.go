@@ -0,0 +1,154 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/giannuccilli/user-api/internal/domain"
+	"github.com/giannuccilli/user-api/internal/service"
+)
+
+var testWebhookID = uuid.MustParse("7a3c2a4e-5b61-4a0e-9f57-0b8f6c1d2e3f")
+
+type mockWebhookRepository struct {
+	subs       map[uuid.UUID]domain.WebhookSubscription
+	deliveries []domain.WebhookDelivery
+}
+
+func (m *mockWebhookRepository) Create(ctx context.Context, sub *domain.WebhookSubscription) error {
+	sub.ID = testWebhookID
+	sub.CreatedAt = time.Now()
+	sub.UpdatedAt = sub.CreatedAt
+	m.subs[sub.ID] = *sub
+	return nil
+}
+
+func (m *mockWebhookRepository) Get(ctx context.Context, id uuid.UUID) (*domain.WebhookSubscription, error) {
+	sub, ok := m.subs[id]
+	if !ok {
+		return nil, domain.ErrWebhookNotFound
+	}
+	return &sub, nil
+}
+
+func (m *mockWebhookRepository) List(ctx context.Context) ([]domain.WebhookSubscription, error) {
+	subs := make([]domain.WebhookSubscription, 0, len(m.subs))
+	for _, sub := range m.subs {
+		subs = append(subs, sub)
+	}
+	return subs, nil
+}
+
+func (m *mockWebhookRepository) Update(ctx context.Context, sub *domain.WebhookSubscription) error {
+	if _, ok := m.subs[sub.ID]; !ok {
+		return domain.ErrWebhookNotFound
+	}
+	sub.UpdatedAt = time.Now()
+	m.subs[sub.ID] = *sub
+	return nil
+}
+
+func (m *mockWebhookRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	if _, ok := m.subs[id]; !ok {
+		return domain.ErrWebhookNotFound
+	}
+	delete(m.subs, id)
+	return nil
+}
+
+func (m *mockWebhookRepository) ListDeliveries(ctx context.Context, subscriptionID uuid.UUID, limit, offset int) ([]domain.WebhookDelivery, int, error) {
+	return m.deliveries, len(m.deliveries), nil
+}
+
+func (m *mockWebhookRepository) Enqueue(ctx context.Context, event domain.UserEvent, payload []byte) (int, error) {
+	return 0, nil
+}
+
+func (m *mockWebhookRepository) ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]domain.PendingWebhookDelivery, error) {
+	return nil, nil
+}
+
+func (m *mockWebhookRepository) RecordAttempt(ctx context.Context, delivery *domain.WebhookDelivery, disableAfter int) (bool, error) {
+	return false, nil
+}
+
+func TestWebhookHandler(t *testing.T) {
+	repo := &mockWebhookRepository{
+		subs: make(map[uuid.UUID]domain.WebhookSubscription),
+		deliveries: []domain.WebhookDelivery{{
+			ID:             uuid.New(),
+			SubscriptionID: testWebhookID,
+			EventType:      domain.EventTypeUserCreated,
+			Payload:        json.RawMessage(`{}`),
+			Status:         domain.WebhookDeliveryFailed,
+			Attempts:       6,
+			LastStatusCode: 500,
+		}},
+	}
+	mux := http.NewServeMux()
+	NewWebhookHandler(service.NewWebhookService(repo, service.WebhookServiceOptions{}), DecodeOptions{}).RegisterRoutes(mux)
+	handler := Actor()(mux)
+
+	path := "/api/v1/webhooks/" + testWebhookID.String()
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		admin      bool
+		wantStatus int
+		wantCode   string
+		wantBody   string
+	}{
+		{"list requires admin", http.MethodGet, "/api/v1/webhooks", "", false, http.StatusForbidden, ErrCodeForbidden, ""},
+		{"create invalid", http.MethodPost, "/api/v1/webhooks", `{"url":"ftp://example.com","eventTypes":["user.renamed"],"secret":"short"}`, true, http.StatusUnprocessableEntity, ErrCodeValidationFailed, ""},
+		{"create", http.MethodPost, "/api/v1/webhooks", `{"url":"https://example.com/hooks","eventTypes":["user.deleted","user.created"]}`, true, http.StatusCreated, "", `"secret":"`},
+		{"get hides the secret", http.MethodGet, path, "", true, http.StatusOK, "", `"eventTypes":["user.created","user.deleted"]`},
+		{"disable", http.MethodPut, path, `{"enabled":false}`, true, http.StatusOK, "", `"enabled":false`},
+		{"deliveries", http.MethodGet, path + "/deliveries", "", true, http.StatusOK, "", `"lastStatusCode":500`},
+		{"invalid id", http.MethodGet, "/api/v1/webhooks/not-a-uuid", "", true, http.StatusBadRequest, ErrCodeInvalidID, ""},
+		{"delete", http.MethodDelete, path, "", true, http.StatusNoContent, "", ""},
+		{"get deleted", http.MethodGet, path, "", true, http.StatusNotFound, ErrCodeWebhookNotFound, ""},
+		{"deliveries of deleted", http.MethodGet, path + "/deliveries", "", true, http.StatusNotFound, ErrCodeWebhookNotFound, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.admin {
+				req.Header.Set("X-Actor-Roles", domain.RoleAdmin)
+			}
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("%s %s status = %v, want %v (body: %s)", tt.method, tt.path, rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantBody != "" && !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("body = %s, want it to contain %s", rec.Body, tt.wantBody)
+			}
+			if tt.method == http.MethodGet && strings.Contains(rec.Body.String(), `"secret"`) {
+				t.Errorf("body = %s, want no secret", rec.Body)
+			}
+			if tt.wantCode != "" {
+				var problem Problem
+				if err := json.NewDecoder(rec.Body).Decode(&problem); err != nil {
+					t.Fatalf("Failed to decode response: %v", err)
+				}
+				if problem.Code != tt.wantCode {
+					t.Errorf("code = %v, want %v", problem.Code, tt.wantCode)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,19 @@
+package mailer
+
+import (
+	"log/slog"
+
+	"github.com/giannuccilli/user-api/internal/config"
+	"github.com/giannuccilli/user-api/internal/domain"
+)
+
+// NewSender returns nil when SMTP_HOST is not configured. Email verification
+// and confirmed email changes need the token to reach the user, so they are
+// disabled rather than sending it nowhere.
+func NewSender(cfg *config.Config, logger *slog.Logger) domain.MailSender {
+	if cfg.SMTPHost == "" {
+		logger.Info("email delivery disabled: SMTP_HOST not configured")
+		return nil
+	}
+	return NewSMTPSender(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.SMTPFrom, cfg.SMTPTimeout, logger)
+}
@@ -0,0 +1,115 @@
+package mailer
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/giannuccilli/user-api/internal/config"
+	"github.com/giannuccilli/user-api/internal/domain"
+)
+
+func testLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+}
+
+func TestMemorySender_Send(t *testing.T) {
+	s := NewMemorySender()
+
+	msg := domain.MailMessage{To: "test@example.com", Subject: "Hello", Body: "Body"}
+	if err := s.Send(context.Background(), msg); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	messages := s.Messages()
+	if len(messages) != 1 || messages[0] != msg {
+		t.Errorf("Messages() = %v, want [%v]", messages, msg)
+	}
+}
+
+func TestNewSender(t *testing.T) {
+	if s := NewSender(&config.Config{}, testLogger()); s != nil {
+		t.Errorf("NewSender() without SMTP_HOST = %T, want nil", s)
+	}
+	if s, ok := NewSender(&config.Config{SMTPHost: "smtp.example.com", SMTPPort: "587"}, testLogger()).(*SMTPSender); !ok || s.addr != "smtp.example.com:587" {
+		t.Errorf("NewSender() with SMTP_HOST = %v, want an SMTP sender", s)
+	}
+}
+
+func TestSMTPSender_BuildMessage(t *testing.T) {
+	s := NewSMTPSender("smtp.example.com", "587", "", "", "noreply@example.com", 0, testLogger())
+
+	data := string(s.buildMessage(domain.MailMessage{
+		To:      "test@example.com",
+		Subject: "Verify your email",
+		Body:    "line 1\nline 2",
+	}))
+
+	for _, want := range []string{
+		"From: noreply@example.com\r\n",
+		"To: test@example.com\r\n",
+		"Subject: Verify your email\r\n",
+		"\r\n\r\nline 1\r\nline 2",
+	} {
+		if !strings.Contains(data, want) {
+			t.Errorf("buildMessage() missing %q in %q", want, data)
+		}
+	}
+}
+
+func TestSMTPSender_Send_CanceledContext(t *testing.T) {
+	s := NewSMTPSender("smtp.example.com", "587", "", "", "noreply@example.com", 0, testLogger())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := s.Send(ctx, domain.MailMessage{To: "test@example.com"}); err == nil {
+		t.Error("Send() with canceled context should fail")
+	}
+}
+
+func TestSMTPSender_Send_UnresponsiveServer(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	defer ln.Close()
+	go func() {
+		// Accept connections but never greet, like a blackholed server.
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	host, port, _ := net.SplitHostPort(ln.Addr().String())
+	s := NewSMTPSender(host, port, "", "", "noreply@example.com", 100*time.Millisecond, testLogger())
+
+	start := time.Now()
+	if err := s.Send(context.Background(), domain.MailMessage{To: "test@example.com"}); err == nil {
+		t.Error("Send() to an unresponsive server should fail")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Send() took %v, want it bounded by the timeout", elapsed)
+	}
+}
+
+func TestSMTPSender_BuildMessage_StripsHeaderInjection(t *testing.T) {
+	s := NewSMTPSender("smtp.example.com", "587", "", "", "noreply@example.com", 0, testLogger())
+
+	data := string(s.buildMessage(domain.MailMessage{
+		To:      "test@example.com\r\nBcc: victim@example.com",
+		Subject: "Hi",
+	}))
+
+	if strings.Contains(data, "\r\nBcc:") {
+		t.Errorf("buildMessage() allowed header injection: %q", data)
+	}
+}
@@ -0,0 +1,30 @@
+package mailer
+
+import (
+	"context"
+	"sync"
+
+	"github.com/giannuccilli/user-api/internal/domain"
+)
+
+type MemorySender struct {
+	mu       sync.Mutex
+	messages []domain.MailMessage
+}
+
+func NewMemorySender() *MemorySender {
+	return &MemorySender{}
+}
+
+func (s *MemorySender) Send(ctx context.Context, msg domain.MailMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.messages = append(s.messages, msg)
+	return nil
+}
+
+func (s *MemorySender) Messages() []domain.MailMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]domain.MailMessage(nil), s.messages...)
+}
@@ -0,0 +1,144 @@
+package mailer
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/giannuccilli/user-api/internal/domain"
+)
+
+const defaultSMTPTimeout = 10 * time.Second
+
+type SMTPSender struct {
+	host    string
+	addr    string
+	from    string
+	auth    smtp.Auth
+	timeout time.Duration
+	logger  *slog.Logger
+}
+
+// NewSMTPSender sends each email within timeout, or the deadline of its
+// context if earlier, so that an unresponsive server cannot hang the request
+// that triggered it.
+func NewSMTPSender(host, port, username, password, from string, timeout time.Duration, logger *slog.Logger) *SMTPSender {
+	var auth smtp.Auth
+	if username != "" {
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+	if timeout <= 0 {
+		timeout = defaultSMTPTimeout
+	}
+
+	logger.Info("smtp mail sender initialized",
+		slog.String("host", host),
+		slog.String("port", port),
+	)
+
+	return &SMTPSender{
+		host:    host,
+		addr:    net.JoinHostPort(host, port),
+		from:    from,
+		auth:    auth,
+		timeout: timeout,
+		logger:  logger,
+	}
+}
+
+func (s *SMTPSender) Send(ctx context.Context, msg domain.MailMessage) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if err := s.send(ctx, msg); err != nil {
+		s.logger.Error("failed to send email",
+			slog.String("subject", msg.Subject),
+			slog.String("error", err.Error()),
+		)
+		return fmt.Errorf("send email: %w", err)
+	}
+
+	return nil
+}
+
+// send does what smtp.SendMail does, over a connection bounded by the
+// timeout and closed if ctx is canceled.
+func (s *SMTPSender) send(ctx context.Context, msg domain.MailMessage) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", s.addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	deadline, _ := ctx.Deadline()
+	if err := conn.SetDeadline(deadline); err != nil {
+		return err
+	}
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	c, err := smtp.NewClient(conn, s.host)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: s.host}); err != nil {
+			return err
+		}
+	}
+	if s.auth != nil {
+		if ok, _ := c.Extension("AUTH"); !ok {
+			return fmt.Errorf("server does not support AUTH")
+		}
+		if err := c.Auth(s.auth); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(s.from); err != nil {
+		return err
+	}
+	if err := c.Rcpt(msg.To); err != nil {
+		return err
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(s.buildMessage(msg)); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+func (s *SMTPSender) buildMessage(msg domain.MailMessage) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", headerValue(s.from))
+	fmt.Fprintf(&b, "To: %s\r\n", headerValue(msg.To))
+	fmt.Fprintf(&b, "Subject: %s\r\n", headerValue(msg.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().UTC().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return []byte(b.String())
+}
+
+var headerReplacer = strings.NewReplacer("\r", "", "\n", "")
+
+func headerValue(v string) string {
+	return headerReplacer.Replace(v)
+}
@@ -0,0 +1,56 @@
+// Package netguard keeps requests to URLs supplied by API clients, such as
+// webhook subscriptions, away from the network the service runs in.
+package netguard
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+	"syscall"
+)
+
+var ErrNotPublic = errors.New("address is not public")
+
+// sharedAddressSpace is the carrier-grade NAT range, RFC 6598.
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// IsPublic reports whether addr is a public unicast address: not loopback,
+// private, link-local (which includes cloud metadata endpoints such as
+// 169.254.169.254), shared, multicast or unspecified.
+func IsPublic(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	switch {
+	case !addr.IsValid(), addr.IsUnspecified(), addr.IsLoopback(), addr.IsPrivate(),
+		addr.IsLinkLocalUnicast(), addr.IsLinkLocalMulticast(), addr.IsInterfaceLocalMulticast(), addr.IsMulticast():
+		return false
+	}
+	return !sharedAddressSpace.Contains(addr)
+}
+
+// IsPublicHost reports whether the host of a URL may be public. It is false
+// for IP literals that are not public and for localhost names; other names
+// can only be checked once resolved, by Control.
+func IsPublicHost(host string) bool {
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return IsPublic(addr)
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	return host != "localhost" && !strings.HasSuffix(host, ".localhost")
+}
+
+// Control rejects connections to addresses that are not public. It is meant
+// for net.Dialer.Control, which runs after name resolution for every address
+// dialed, so a name resolving to an internal address is rejected as well.
+func Control(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil || !IsPublic(addr) {
+		return fmt.Errorf("%w: %s", ErrNotPublic, host)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package netguard
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestIsPublicHost(t *testing.T) {
+	tests := []struct {
+		host string
+		want bool
+	}{
+		{"example.com", true},
+		{"93.184.216.34", true},
+		{"2606:4700::1111", true},
+		{"localhost", false},
+		{"api.localhost.", false},
+		{"127.0.0.1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"100.64.0.1", false},
+		{"0.0.0.0", false},
+		{"::1", false},
+		{"fd00::1", false},
+		{"fe80::1%eth0", false},
+		{"::ffff:127.0.0.1", false},
+		{"224.0.0.1", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			if got := IsPublicHost(tt.host); got != tt.want {
+				t.Errorf("IsPublicHost(%q) = %v, want %v", tt.host, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestControl(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("request reached a loopback server")
+	}))
+	defer server.Close()
+
+	dialer := &net.Dialer{Control: Control}
+	client := &http.Client{Transport: &http.Transport{DialContext: dialer.DialContext}}
+
+	// localhost is resolved before Control sees it.
+	u, _ := url.Parse(server.URL)
+	target := "http://localhost:" + u.Port()
+	if _, err := client.Get(target); !errors.Is(err, ErrNotPublic) {
+		t.Errorf("Get(%s) error = %v, want %v", target, err, ErrNotPublic)
+	}
+}
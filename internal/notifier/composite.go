@@ -0,0 +1,151 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/giannuccilli/user-api/internal/domain"
+)
+
+// Publisher sends an event built by its caller. Sinks implement it so that
+// all of them, and the failed_events entries of those that fail, receive the
+// same event with the same EventID.
+type Publisher interface {
+	Publish(ctx context.Context, event domain.UserEvent) error
+	Close() error
+}
+
+// Sink is a notifier backend events are fanned out to. Its name attributes
+// the events it fails to deliver in failed_events.
+type Sink struct {
+	Name      string
+	Publisher Publisher
+}
+
+// CompositeNotifier sends every event to all of its sinks concurrently. A sink
+// that fails or panics does not affect the others: the event is saved to
+// failed_events under the sink name and the change that raised it still
+// succeeds, like with a single notifier.
+type CompositeNotifier struct {
+	sinks           []Sink
+	logger          *slog.Logger
+	failedEventRepo domain.FailedEventRepository
+}
+
+func NewCompositeNotifier(logger *slog.Logger, failedEventRepo domain.FailedEventRepository, sinks ...Sink) *CompositeNotifier {
+	return &CompositeNotifier{
+		sinks:           sinks,
+		logger:          logger,
+		failedEventRepo: failedEventRepo,
+	}
+}
+
+func (n *CompositeNotifier) NotifyCreated(ctx context.Context, data domain.EventData) error {
+	return n.fanOut(ctx, newUserEvent(ctx, domain.EventTypeUserCreated, data))
+}
+
+func (n *CompositeNotifier) NotifyUpdated(ctx context.Context, data domain.EventData) error {
+	return n.fanOut(ctx, newUserEvent(ctx, domain.EventTypeUserUpdated, data))
+}
+
+func (n *CompositeNotifier) NotifyDeleted(ctx context.Context, userID uuid.UUID) error {
+	return n.fanOut(ctx, newUserEvent(ctx, domain.EventTypeUserDeleted, domain.EventData{UserID: userID}))
+}
+
+func (n *CompositeNotifier) Notify(ctx context.Context, eventType domain.EventType, data domain.EventData) error {
+	return n.fanOut(ctx, newUserEvent(ctx, eventType, data))
+}
+
+// Close closes every sink, even if some of them fail.
+func (n *CompositeNotifier) Close() error {
+	var errs []error
+	for _, s := range n.sinks {
+		if err := s.Publisher.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", s.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (n *CompositeNotifier) fanOut(ctx context.Context, event domain.UserEvent) error {
+	errs := make([]error, len(n.sinks))
+
+	var wg sync.WaitGroup
+	for i, s := range n.sinks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() {
+				if r := recover(); r != nil {
+					errs[i] = fmt.Errorf("panic: %v", r)
+				}
+			}()
+			errs[i] = s.Publisher.Publish(ctx, event)
+		}()
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			n.saveToDLQ(ctx, n.sinks[i].Name, event, err)
+		}
+	}
+	return nil
+}
+
+func (n *CompositeNotifier) saveToDLQ(ctx context.Context, sink string, event domain.UserEvent, sinkErr error) {
+	attrs := []any{
+		slog.String("sink", sink),
+		slog.String("event_id", event.EventID.String()),
+		slog.String("event_type", string(event.EventType)),
+		slog.String("tenant_id", event.TenantID),
+		slog.String("user_id", event.Data.UserID.String()),
+	}
+	n.logger.Error("notifier sink failed", append(attrs, slog.String("error", sinkErr.Error()))...)
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		n.logger.Error("failed to marshal event", append(attrs, slog.String("error", err.Error()))...)
+		return
+	}
+
+	failedEvent := &domain.FailedEvent{
+		EventID:   event.EventID,
+		EventType: event.EventType,
+		UserID:    event.Data.UserID,
+		Sink:      sink,
+		Payload:   string(payload),
+		Error:     sinkErr.Error(),
+		Attempts:  1,
+		CreatedAt: event.Timestamp,
+		LastError: event.Timestamp,
+	}
+	if err := n.failedEventRepo.Save(ctx, failedEvent); err != nil {
+		n.logger.Error("failed to save event to DLQ", append(attrs, slog.String("error", err.Error()))...)
+		return
+	}
+
+	n.logger.Warn("event saved to DLQ", attrs...)
+}
+
+// newUserEvent builds the event published for a change made on behalf of ctx.
+func newUserEvent(ctx context.Context, eventType domain.EventType, data domain.EventData) domain.UserEvent {
+	tenantID, ok := domain.TenantFromContext(ctx)
+	if !ok {
+		tenantID = domain.DefaultTenantID
+	}
+	return domain.UserEvent{
+		EventID:   uuid.New(),
+		EventType: eventType,
+		TenantID:  tenantID,
+		Timestamp: time.Now().UTC(),
+		Data:      data,
+	}
+}
@@ -0,0 +1,116 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/giannuccilli/user-api/internal/domain"
+)
+
+type stubPublisher struct {
+	mu       sync.Mutex
+	events   []domain.UserEvent
+	err      error
+	panics   bool
+	closeErr error
+}
+
+func (p *stubPublisher) Publish(ctx context.Context, event domain.UserEvent) error {
+	if p.panics {
+		panic("sink exploded")
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.events = append(p.events, event)
+	return p.err
+}
+
+func (p *stubPublisher) Close() error {
+	return p.closeErr
+}
+
+func TestCompositeNotifier_FanOut(t *testing.T) {
+	tests := []struct {
+		name      string
+		sinks     map[string]*stubPublisher
+		wantSinks []string
+	}{
+		{
+			name:  "all delivered",
+			sinks: map[string]*stubPublisher{"kafka": {}, "webhooks": {}},
+		},
+		{
+			name:      "failing sink is isolated",
+			sinks:     map[string]*stubPublisher{"kafka": {}, "webhooks": {err: errors.New("database down")}},
+			wantSinks: []string{"webhooks"},
+		},
+		{
+			name:      "panicking sink is isolated",
+			sinks:     map[string]*stubPublisher{"kafka": {panics: true}, "log": {}},
+			wantSinks: []string{"kafka"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &mockFailedEventRepository{}
+			var sinks []Sink
+			for name, s := range tt.sinks {
+				sinks = append(sinks, Sink{Name: name, Publisher: s})
+			}
+			n := NewCompositeNotifier(testLogger(), repo, sinks...)
+
+			ctx := domain.ContextWithTenant(context.Background(), "acme")
+			userID := uuid.New()
+			if err := n.NotifyCreated(ctx, domain.EventData{UserID: userID}); err != nil {
+				t.Fatalf("NotifyCreated() error = %v", err)
+			}
+
+			// Every sink, and failed_events, get the same event.
+			var eventID uuid.UUID
+			for name, s := range tt.sinks {
+				if s.panics {
+					continue
+				}
+				if len(s.events) != 1 || s.events[0].EventType != domain.EventTypeUserCreated {
+					t.Fatalf("sink %s received %v, want one user.created", name, s.events)
+				}
+				if eventID == uuid.Nil {
+					eventID = s.events[0].EventID
+				}
+				if s.events[0].EventID != eventID {
+					t.Errorf("sink %s event ID = %v, want %v", name, s.events[0].EventID, eventID)
+				}
+			}
+
+			if len(repo.events) != len(tt.wantSinks) {
+				t.Fatalf("saved %d failed events, want %d", len(repo.events), len(tt.wantSinks))
+			}
+			for i, saved := range repo.events {
+				if saved.Sink != tt.wantSinks[i] || saved.EventID != eventID || saved.UserID != userID || saved.EventType != domain.EventTypeUserCreated || saved.Error == "" {
+					t.Errorf("failed event = %+v", saved)
+				}
+				var event domain.UserEvent
+				if err := json.Unmarshal([]byte(saved.Payload), &event); err != nil || event.TenantID != "acme" || event.EventID != saved.EventID {
+					t.Errorf("payload = %s (%v)", saved.Payload, err)
+				}
+			}
+		})
+	}
+}
+
+func TestCompositeNotifier_Close(t *testing.T) {
+	n := NewCompositeNotifier(testLogger(), &mockFailedEventRepository{},
+		Sink{Name: "kafka", Publisher: &stubPublisher{closeErr: errors.New("broker gone")}},
+		Sink{Name: "log", Publisher: &stubPublisher{}},
+	)
+
+	if err := n.Close(); err == nil || err.Error() != "kafka: broker gone" {
+		t.Errorf("Close() error = %v, want kafka: broker gone", err)
+	}
+}
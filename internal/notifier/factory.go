@@ -1,15 +1,66 @@
 package notifier
 
 import (
+	"fmt"
 	"log/slog"
 
 	"github.com/giannuccilli/user-api/internal/config"
 	"github.com/giannuccilli/user-api/internal/domain"
 )
 
-func NewNotifier(cfg *config.Config, logger *slog.Logger, failedEventRepo domain.FailedEventRepository) domain.UserNotifier {
-	if cfg.KafkaBrokers == "" {
-		return NewNoopNotifier(logger)
+// Sink names accepted in NOTIFIER_SINKS and recorded in failed_events.
+const (
+	SinkKafka    = "kafka"
+	SinkWebhooks = "webhooks"
+	SinkLog      = "log"
+	SinkNone     = "none"
+)
+
+// NewNotifier builds the notifier fanning events out to the sinks in
+// cfg.NotifierSinks. When it is not set, events go to Kafka, if KAFKA_BROKERS
+// is configured, and to webhooks. The caller runs the webhooks dispatcher.
+func NewNotifier(cfg *config.Config, logger *slog.Logger, failedEventRepo domain.FailedEventRepository, webhooks *WebhookNotifier) (domain.UserNotifier, error) {
+	names := cfg.NotifierSinks
+	if len(names) == 0 {
+		if cfg.KafkaBrokers != "" {
+			names = append(names, SinkKafka)
+		}
+		names = append(names, SinkWebhooks)
+	}
+
+	var sinks []Sink
+	seen := make(map[string]bool)
+	for _, name := range names {
+		if seen[name] {
+			return nil, fmt.Errorf("notifier sink %q listed more than once", name)
+		}
+		seen[name] = true
+
+		switch name {
+		case SinkKafka:
+			if cfg.KafkaBrokers == "" {
+				return nil, fmt.Errorf("notifier sink %q requires KAFKA_BROKERS", name)
+			}
+			sinks = append(sinks, Sink{Name: name, Publisher: NewKafkaNotifier(cfg.KafkaBrokers, cfg.KafkaTopic, logger, failedEventRepo)})
+		case SinkWebhooks:
+			sinks = append(sinks, Sink{Name: name, Publisher: webhooks})
+		case SinkLog:
+			sinks = append(sinks, Sink{Name: name, Publisher: NewLogNotifier(logger)})
+		case SinkNone:
+		default:
+			return nil, fmt.Errorf("unknown notifier sink %q", name)
+		}
 	}
-	return NewKafkaNotifier(cfg.KafkaBrokers, cfg.KafkaTopic, logger, failedEventRepo)
+
+	if len(sinks) == 0 {
+		return NewNoopNotifier(logger), nil
+	}
+
+	sinkNames := make([]string, len(sinks))
+	for i, s := range sinks {
+		sinkNames[i] = s.Name
+	}
+	logger.Info("notifier initialized", slog.Any("sinks", sinkNames))
+
+	return NewCompositeNotifier(logger, failedEventRepo, sinks...), nil
 }
@@ -0,0 +1,58 @@
+package notifier
+
+import (
+	"testing"
+
+	"github.com/giannuccilli/user-api/internal/config"
+)
+
+func TestNewNotifier_Sinks(t *testing.T) {
+	webhooks := NewWebhookNotifier(&mockWebhookRepository{}, testLogger(), WebhookOptions{})
+
+	tests := []struct {
+		name      string
+		cfg       config.Config
+		wantSinks []string
+		wantErr   bool
+	}{
+		{name: "default without kafka", wantSinks: []string{SinkWebhooks}},
+		{name: "default with kafka", cfg: config.Config{KafkaBrokers: "localhost:9092"}, wantSinks: []string{SinkKafka, SinkWebhooks}},
+		{name: "explicit", cfg: config.Config{NotifierSinks: []string{"log", "webhooks"}}, wantSinks: []string{SinkLog, SinkWebhooks}},
+		{name: "none", cfg: config.Config{NotifierSinks: []string{"none"}}},
+		{name: "kafka without brokers", cfg: config.Config{NotifierSinks: []string{"kafka"}}, wantErr: true},
+		{name: "unknown", cfg: config.Config{NotifierSinks: []string{"nats"}}, wantErr: true},
+		{name: "duplicate", cfg: config.Config{NotifierSinks: []string{"log", "log"}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n, err := NewNotifier(&tt.cfg, testLogger(), &mockFailedEventRepository{}, webhooks)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewNotifier() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			defer n.Close()
+
+			var got []string
+			switch n := n.(type) {
+			case *CompositeNotifier:
+				for _, s := range n.sinks {
+					got = append(got, s.Name)
+				}
+			case *NoopNotifier:
+			default:
+				t.Fatalf("NewNotifier() = %T", n)
+			}
+			if len(got) != len(tt.wantSinks) {
+				t.Fatalf("sinks = %v, want %v", got, tt.wantSinks)
+			}
+			for i := range got {
+				if got[i] != tt.wantSinks[i] {
+					t.Errorf("sinks = %v, want %v", got, tt.wantSinks)
+				}
+			}
+		})
+	}
+}
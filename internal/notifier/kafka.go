@@ -46,31 +46,28 @@ func NewKafkaNotifier(brokers, topic string, logger *slog.Logger, failedEventRep
 	}
 }
 
-func (n *KafkaNotifier) NotifyCreated(ctx context.Context, userID uuid.UUID) error {
-	return n.publish(ctx, domain.EventTypeUserCreated, userID)
+func (n *KafkaNotifier) NotifyCreated(ctx context.Context, data domain.EventData) error {
+	return n.Publish(ctx, newUserEvent(ctx, domain.EventTypeUserCreated, data))
 }
 
-func (n *KafkaNotifier) NotifyUpdated(ctx context.Context, userID uuid.UUID) error {
-	return n.publish(ctx, domain.EventTypeUserUpdated, userID)
+func (n *KafkaNotifier) NotifyUpdated(ctx context.Context, data domain.EventData) error {
+	return n.Publish(ctx, newUserEvent(ctx, domain.EventTypeUserUpdated, data))
 }
 
 func (n *KafkaNotifier) NotifyDeleted(ctx context.Context, userID uuid.UUID) error {
-	return n.publish(ctx, domain.EventTypeUserDeleted, userID)
+	return n.Publish(ctx, newUserEvent(ctx, domain.EventTypeUserDeleted, domain.EventData{UserID: userID}))
+}
+
+func (n *KafkaNotifier) Notify(ctx context.Context, eventType domain.EventType, data domain.EventData) error {
+	return n.Publish(ctx, newUserEvent(ctx, eventType, data))
 }
 
 func (n *KafkaNotifier) Close() error {
 	return n.writer.Close()
 }
 
-func (n *KafkaNotifier) publish(ctx context.Context, eventType domain.EventType, userID uuid.UUID) error {
-	event := domain.UserEvent{
-		EventID:   uuid.New(),
-		EventType: eventType,
-		Timestamp: time.Now().UTC(),
-		Data: domain.EventData{
-			UserID: userID,
-		},
-	}
+func (n *KafkaNotifier) Publish(ctx context.Context, event domain.UserEvent) error {
+	eventType, tenantID, userID := event.EventType, event.TenantID, event.Data.UserID
 
 	payload, err := json.Marshal(event)
 	if err != nil {
@@ -83,7 +80,7 @@ func (n *KafkaNotifier) publish(ctx context.Context, eventType domain.EventType,
 	}
 
 	msg := kafka.Message{
-		Key:   []byte(userID.String()),
+		Key:   messageKey(tenantID, userID),
 		Value: payload,
 		Headers: []kafka.Header{
 			{Key: "content-type", Value: []byte("application/json")},
@@ -100,6 +97,7 @@ func (n *KafkaNotifier) publish(ctx context.Context, eventType domain.EventType,
 			n.logger.Info("event published",
 				slog.String("event_id", event.EventID.String()),
 				slog.String("event_type", string(eventType)),
+				slog.String("tenant_id", tenantID),
 				slog.String("user_id", userID.String()),
 			)
 			return nil
@@ -128,6 +126,7 @@ func (n *KafkaNotifier) saveToDLQ(ctx context.Context, event domain.UserEvent, p
 		EventID:   event.EventID,
 		EventType: event.EventType,
 		UserID:    event.Data.UserID,
+		Sink:      SinkKafka,
 		Payload:   string(payload),
 		Error:     lastErr.Error(),
 		Attempts:  maxRetries,
@@ -152,3 +151,9 @@ func (n *KafkaNotifier) saveToDLQ(ctx context.Context, event domain.UserEvent, p
 		slog.Int("attempts", maxRetries),
 	)
 }
+
+// messageKey keeps the events of a user ordered within one partition. The
+// tenant prefix lets consumers route by tenant without decoding the payload.
+func messageKey(tenantID string, userID uuid.UUID) []byte {
+	return []byte(tenantID + "/" + userID.String())
+}
@@ -70,7 +70,7 @@ func TestKafkaNotifier_Integration_PublishAndVerify(t *testing.T) {
 	notifier := NewKafkaNotifier(testBrokers, testTopic, logger, mockRepo)
 	defer notifier.Close()
 
-	ctx := context.Background()
+	ctx := domain.ContextWithTenant(context.Background(), "acme")
 
 	conn, err := kafka.DialLeader(ctx, "tcp", testBrokers, testTopic, 0)
 	if err != nil {
@@ -80,7 +80,7 @@ func TestKafkaNotifier_Integration_PublishAndVerify(t *testing.T) {
 	conn.Close()
 
 	userID := uuid.New()
-	err = notifier.NotifyCreated(ctx, userID)
+	err = notifier.NotifyCreated(ctx, domain.EventData{UserID: userID})
 	if err != nil {
 		t.Fatalf("NotifyCreated() error = %v", err)
 	}
@@ -104,8 +104,8 @@ func TestKafkaNotifier_Integration_PublishAndVerify(t *testing.T) {
 		t.Fatalf("Failed to read message: %v", err)
 	}
 
-	if string(msg.Key) != userID.String() {
-		t.Errorf("Key = %s, want %s", string(msg.Key), userID.String())
+	if want := "acme/" + userID.String(); string(msg.Key) != want {
+		t.Errorf("Key = %s, want %s", string(msg.Key), want)
 	}
 
 	var event domain.UserEvent
@@ -119,6 +119,9 @@ func TestKafkaNotifier_Integration_PublishAndVerify(t *testing.T) {
 	if event.Data.UserID != userID {
 		t.Errorf("UserID = %v, want %v", event.Data.UserID, userID)
 	}
+	if event.TenantID != "acme" {
+		t.Errorf("TenantID = %v, want acme", event.TenantID)
+	}
 
 	t.Log("Successfully published and verified event in Kafka")
 }
@@ -139,8 +142,8 @@ func TestKafkaNotifier_Integration_AllEventTypes(t *testing.T) {
 		notify    func(uuid.UUID) error
 		eventType domain.EventType
 	}{
-		{"created", func(id uuid.UUID) error { return notifier.NotifyCreated(ctx, id) }, domain.EventTypeUserCreated},
-		{"updated", func(id uuid.UUID) error { return notifier.NotifyUpdated(ctx, id) }, domain.EventTypeUserUpdated},
+		{"created", func(id uuid.UUID) error { return notifier.NotifyCreated(ctx, domain.EventData{UserID: id}) }, domain.EventTypeUserCreated},
+		{"updated", func(id uuid.UUID) error { return notifier.NotifyUpdated(ctx, domain.EventData{UserID: id}) }, domain.EventTypeUserUpdated},
 		{"deleted", func(id uuid.UUID) error { return notifier.NotifyDeleted(ctx, id) }, domain.EventTypeUserDeleted},
 	}
 
@@ -173,7 +176,7 @@ func TestKafkaNotifier_Integration_DLQOnFailure(t *testing.T) {
 
 	userID := uuid.New()
 
-	_ = notifier.NotifyCreated(ctx, userID)
+	_ = notifier.NotifyCreated(ctx, domain.EventData{UserID: userID})
 
 	if len(mockRepo.events) != 1 {
 		t.Errorf("Expected 1 event in DLQ, got %d", len(mockRepo.events))
@@ -83,6 +83,9 @@ func TestKafkaNotifier_SavesToDLQ_AfterRetries(t *testing.T) {
 	if saved.Error != "kafka connection refused" {
 		t.Errorf("Error = %v, want 'kafka connection refused'", saved.Error)
 	}
+	if saved.Sink != SinkKafka {
+		t.Errorf("Sink = %v, want %v", saved.Sink, SinkKafka)
+	}
 	if saved.Attempts != maxRetries {
 		t.Errorf("Attempts = %v, want %v", saved.Attempts, maxRetries)
 	}
@@ -112,3 +115,11 @@ func TestKafkaNotifier_DLQSaveError_LogsButDoesNotPanic(t *testing.T) {
 
 	n.saveToDLQ(context.Background(), event, []byte(`{}`), errors.New("kafka error"))
 }
+
+func TestMessageKey(t *testing.T) {
+	userID := uuid.MustParse("123e4567-e89b-12d3-a456-426614174000")
+
+	if got := string(messageKey("acme", userID)); got != "acme/123e4567-e89b-12d3-a456-426614174000" {
+		t.Errorf("messageKey() = %v", got)
+	}
+}
@@ -0,0 +1,50 @@
+package notifier
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/google/uuid"
+
+	"github.com/giannuccilli/user-api/internal/domain"
+)
+
+// LogNotifier writes every event to the log. It is meant for local
+// development and for auditing what the other sinks receive.
+type LogNotifier struct {
+	logger *slog.Logger
+}
+
+func NewLogNotifier(logger *slog.Logger) *LogNotifier {
+	return &LogNotifier{logger: logger}
+}
+
+func (n *LogNotifier) NotifyCreated(ctx context.Context, data domain.EventData) error {
+	return n.Notify(ctx, domain.EventTypeUserCreated, data)
+}
+
+func (n *LogNotifier) NotifyUpdated(ctx context.Context, data domain.EventData) error {
+	return n.Notify(ctx, domain.EventTypeUserUpdated, data)
+}
+
+func (n *LogNotifier) NotifyDeleted(ctx context.Context, userID uuid.UUID) error {
+	return n.Notify(ctx, domain.EventTypeUserDeleted, domain.EventData{UserID: userID})
+}
+
+func (n *LogNotifier) Notify(ctx context.Context, eventType domain.EventType, data domain.EventData) error {
+	return n.Publish(ctx, newUserEvent(ctx, eventType, data))
+}
+
+func (n *LogNotifier) Publish(ctx context.Context, event domain.UserEvent) error {
+	n.logger.Info("user event",
+		slog.String("event_id", event.EventID.String()),
+		slog.String("event_type", string(event.EventType)),
+		slog.String("tenant_id", event.TenantID),
+		slog.String("user_id", event.Data.UserID.String()),
+	)
+	return nil
+}
+
+func (n *LogNotifier) Close() error {
+	return nil
+}
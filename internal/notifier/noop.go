@@ -5,6 +5,8 @@ import (
 	"log/slog"
 
 	"github.com/google/uuid"
+
+	"github.com/giannuccilli/user-api/internal/domain"
 )
 
 type NoopNotifier struct {
@@ -12,15 +14,15 @@ type NoopNotifier struct {
 }
 
 func NewNoopNotifier(logger *slog.Logger) *NoopNotifier {
-	logger.Info("notifications disabled: KAFKA_BROKERS not configured")
+	logger.Info("notifications disabled: no notifier sinks configured")
 	return &NoopNotifier{logger: logger}
 }
 
-func (n *NoopNotifier) NotifyCreated(ctx context.Context, userID uuid.UUID) error {
+func (n *NoopNotifier) NotifyCreated(ctx context.Context, data domain.EventData) error {
 	return nil
 }
 
-func (n *NoopNotifier) NotifyUpdated(ctx context.Context, userID uuid.UUID) error {
+func (n *NoopNotifier) NotifyUpdated(ctx context.Context, data domain.EventData) error {
 	return nil
 }
 
@@ -28,6 +30,10 @@ func (n *NoopNotifier) NotifyDeleted(ctx context.Context, userID uuid.UUID) erro
 	return nil
 }
 
+func (n *NoopNotifier) Notify(ctx context.Context, eventType domain.EventType, data domain.EventData) error {
+	return nil
+}
+
 func (n *NoopNotifier) Close() error {
 	return nil
 }
@@ -7,13 +7,15 @@ import (
 	"testing"
 
 	"github.com/google/uuid"
+
+	"github.com/giannuccilli/user-api/internal/domain"
 )
 
 func TestNoopNotifier_NotifyCreated(t *testing.T) {
 	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
 	n := NewNoopNotifier(logger)
 
-	err := n.NotifyCreated(context.Background(), uuid.New())
+	err := n.NotifyCreated(context.Background(), domain.EventData{UserID: uuid.New()})
 	if err != nil {
 		t.Errorf("NotifyCreated() error = %v, want nil", err)
 	}
@@ -23,7 +25,7 @@ func TestNoopNotifier_NotifyUpdated(t *testing.T) {
 	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
 	n := NewNoopNotifier(logger)
 
-	err := n.NotifyUpdated(context.Background(), uuid.New())
+	err := n.NotifyUpdated(context.Background(), domain.EventData{UserID: uuid.New()})
 	if err != nil {
 		t.Errorf("NotifyUpdated() error = %v, want nil", err)
 	}
@@ -39,6 +41,16 @@ func TestNoopNotifier_NotifyDeleted(t *testing.T) {
 	}
 }
 
+func TestNoopNotifier_Notify(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	n := NewNoopNotifier(logger)
+
+	err := n.Notify(context.Background(), domain.EventTypeUserSuspended, domain.EventData{UserID: uuid.New()})
+	if err != nil {
+		t.Errorf("Notify() error = %v, want nil", err)
+	}
+}
+
 func TestNoopNotifier_Close(t *testing.T) {
 	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
 	n := NewNoopNotifier(logger)
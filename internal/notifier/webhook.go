@@ -0,0 +1,270 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/giannuccilli/user-api/internal/domain"
+	"github.com/giannuccilli/user-api/internal/netguard"
+	"github.com/giannuccilli/user-api/pkg/events"
+)
+
+const (
+	webhookBatchSize   = 100
+	webhookConcurrency = 10
+	webhookMaxBackoff  = time.Hour
+	webhookUserAgent   = "user-api-webhooks"
+)
+
+type WebhookOptions struct {
+	// MaxAttempts is how many times a delivery is sent before it is marked
+	// failed.
+	MaxAttempts int
+	// Backoff is the wait after the first failed attempt, doubled after each
+	// further one up to an hour.
+	Backoff time.Duration
+	// Timeout bounds each request to a subscriber.
+	Timeout time.Duration
+	// DisableAfter is how many deliveries in a row may fail before the
+	// subscription is disabled.
+	DisableAfter int
+	// PollInterval is how often due deliveries are looked up.
+	PollInterval time.Duration
+	// AllowPrivateNetworks lets deliveries reach loopback, private and
+	// link-local addresses. Without it such connections are refused and
+	// HTTP_PROXY is ignored, since the proxy would connect on our behalf.
+	AllowPrivateNetworks bool
+}
+
+// WebhookNotifier queues every event for the webhook subscriptions of its
+// tenant and Run sends the queued deliveries. They are stored in the
+// database, so they survive restarts and several instances can send them.
+type WebhookNotifier struct {
+	repo   domain.WebhookRepository
+	client *http.Client
+	logger *slog.Logger
+	opts   WebhookOptions
+}
+
+func NewWebhookNotifier(repo domain.WebhookRepository, logger *slog.Logger, opts WebhookOptions) *WebhookNotifier {
+	if opts.MaxAttempts < 1 {
+		opts.MaxAttempts = 6
+	}
+	if opts.Backoff <= 0 {
+		opts.Backoff = 30 * time.Second
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 10 * time.Second
+	}
+	if opts.DisableAfter < 1 {
+		opts.DisableAfter = 10
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = 5 * time.Second
+	}
+
+	return &WebhookNotifier{
+		repo:   repo,
+		client: newWebhookClient(opts),
+		logger: logger,
+		opts:   opts,
+	}
+}
+
+// newWebhookClient returns the client used for deliveries. Redirects are not
+// followed: a subscriber must answer with 2xx itself, and following them
+// would let it send us to an address that was never checked.
+func newWebhookClient(opts WebhookOptions) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if !opts.AllowPrivateNetworks {
+		dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second, Control: netguard.Control}
+		transport.DialContext = dialer.DialContext
+		transport.Proxy = nil
+	}
+	return &http.Client{
+		Timeout:   opts.Timeout,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+func (n *WebhookNotifier) NotifyCreated(ctx context.Context, data domain.EventData) error {
+	return n.Publish(ctx, newUserEvent(ctx, domain.EventTypeUserCreated, data))
+}
+
+func (n *WebhookNotifier) NotifyUpdated(ctx context.Context, data domain.EventData) error {
+	return n.Publish(ctx, newUserEvent(ctx, domain.EventTypeUserUpdated, data))
+}
+
+func (n *WebhookNotifier) NotifyDeleted(ctx context.Context, userID uuid.UUID) error {
+	return n.Publish(ctx, newUserEvent(ctx, domain.EventTypeUserDeleted, domain.EventData{UserID: userID}))
+}
+
+func (n *WebhookNotifier) Notify(ctx context.Context, eventType domain.EventType, data domain.EventData) error {
+	return n.Publish(ctx, newUserEvent(ctx, eventType, data))
+}
+
+func (n *WebhookNotifier) Close() error {
+	return nil
+}
+
+// Publish queues a delivery of event for every matching subscription.
+func (n *WebhookNotifier) Publish(ctx context.Context, event domain.UserEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	if _, err := n.repo.Enqueue(ctx, event, payload); err != nil {
+		return fmt.Errorf("queue webhook deliveries: %w", err)
+	}
+	return nil
+}
+
+// Run sends the due deliveries every PollInterval until ctx is canceled.
+func (n *WebhookNotifier) Run(ctx context.Context) {
+	ticker := time.NewTicker(n.opts.PollInterval)
+	defer ticker.Stop()
+
+	n.logger.Info("webhook dispatcher started", slog.Duration("interval", n.opts.PollInterval))
+
+	for {
+		select {
+		case <-ctx.Done():
+			n.logger.Info("webhook dispatcher stopped")
+			return
+		case <-ticker.C:
+			n.dispatch(ctx)
+		}
+	}
+}
+
+func (n *WebhookNotifier) dispatch(ctx context.Context) {
+	// A claimed delivery is skipped by other instances until its request
+	// has had time to finish.
+	lease := n.opts.Timeout + time.Minute
+
+	for ctx.Err() == nil {
+		pending, err := n.repo.ClaimDue(ctx, time.Now().UTC(), lease, webhookBatchSize)
+		if err != nil {
+			n.logger.Error("failed to claim webhook deliveries", slog.String("error", err.Error()))
+			return
+		}
+
+		var wg sync.WaitGroup
+		sem := make(chan struct{}, webhookConcurrency)
+		for _, p := range pending {
+			wg.Add(1)
+			sem <- struct{}{}
+			go func() {
+				defer wg.Done()
+				defer func() { <-sem }()
+				n.deliver(ctx, p)
+			}()
+		}
+		wg.Wait()
+
+		if len(pending) < webhookBatchSize {
+			return
+		}
+	}
+}
+
+func (n *WebhookNotifier) deliver(ctx context.Context, p domain.PendingWebhookDelivery) {
+	statusCode, err := n.send(ctx, p)
+	if ctx.Err() != nil {
+		// Shutting down: the delivery is sent again once its lease expires.
+		return
+	}
+
+	d := p.WebhookDelivery
+	now := time.Now().UTC()
+	d.Attempts++
+	d.LastStatusCode = statusCode
+	d.LastError = ""
+	d.NextAttemptAt = nil
+	switch {
+	case err == nil:
+		d.Status = domain.WebhookDeliverySucceeded
+		d.DeliveredAt = &now
+	case d.Attempts >= n.opts.MaxAttempts:
+		d.Status = domain.WebhookDeliveryFailed
+		d.LastError = err.Error()
+	default:
+		d.Status = domain.WebhookDeliveryPending
+		d.LastError = err.Error()
+		next := now.Add(n.backoff(d.Attempts))
+		d.NextAttemptAt = &next
+	}
+
+	attrs := []any{
+		slog.String("delivery_id", d.ID.String()),
+		slog.String("subscription_id", d.SubscriptionID.String()),
+		slog.String("event_type", string(d.EventType)),
+		slog.String("tenant_id", d.TenantID),
+		slog.Int("attempt", d.Attempts),
+	}
+	if err != nil {
+		n.logger.Warn("webhook delivery failed", append(attrs, slog.String("error", err.Error()))...)
+	} else {
+		n.logger.Info("webhook delivered", attrs...)
+	}
+
+	disabled, err := n.repo.RecordAttempt(ctx, &d, n.opts.DisableAfter)
+	if err != nil {
+		n.logger.Error("failed to record webhook delivery", append(attrs, slog.String("error", err.Error()))...)
+		return
+	}
+	if disabled {
+		n.logger.Warn("webhook subscription disabled after repeated failures",
+			slog.String("subscription_id", d.SubscriptionID.String()),
+			slog.String("tenant_id", d.TenantID),
+			slog.Int("failures", n.opts.DisableAfter),
+		)
+	}
+}
+
+// send posts the delivery payload and returns the response status, if any.
+// Any status other than 2xx is an error.
+func (n *WebhookNotifier) send(ctx context.Context, p domain.PendingWebhookDelivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.URL, bytes.NewReader(p.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", webhookUserAgent)
+	req.Header.Set(events.HeaderWebhookID, p.EventID.String())
+	req.Header.Set(events.HeaderWebhookEvent, string(p.EventType))
+	req.Header.Set(events.HeaderWebhookSignature, events.Sign(p.Secret, time.Now(), p.Payload))
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+func (n *WebhookNotifier) backoff(attempt int) time.Duration {
+	delay := n.opts.Backoff
+	for i := 1; i < attempt && delay < webhookMaxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, webhookMaxBackoff)
+}
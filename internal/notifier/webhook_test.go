@@ -0,0 +1,245 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/giannuccilli/user-api/internal/domain"
+	"github.com/giannuccilli/user-api/pkg/events"
+)
+
+type mockWebhookRepository struct {
+	mu       sync.Mutex
+	events   []domain.UserEvent
+	pending  []domain.PendingWebhookDelivery
+	attempts []domain.WebhookDelivery
+	disable  bool
+}
+
+func (m *mockWebhookRepository) Create(ctx context.Context, sub *domain.WebhookSubscription) error {
+	return nil
+}
+
+func (m *mockWebhookRepository) Get(ctx context.Context, id uuid.UUID) (*domain.WebhookSubscription, error) {
+	return nil, domain.ErrWebhookNotFound
+}
+
+func (m *mockWebhookRepository) List(ctx context.Context) ([]domain.WebhookSubscription, error) {
+	return nil, nil
+}
+
+func (m *mockWebhookRepository) Update(ctx context.Context, sub *domain.WebhookSubscription) error {
+	return nil
+}
+
+func (m *mockWebhookRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	return nil
+}
+
+func (m *mockWebhookRepository) ListDeliveries(ctx context.Context, subscriptionID uuid.UUID, limit, offset int) ([]domain.WebhookDelivery, int, error) {
+	return nil, 0, nil
+}
+
+func (m *mockWebhookRepository) Enqueue(ctx context.Context, event domain.UserEvent, payload []byte) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.events = append(m.events, event)
+	return 1, nil
+}
+
+func (m *mockWebhookRepository) ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]domain.PendingWebhookDelivery, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n := min(limit, len(m.pending))
+	claimed := m.pending[:n]
+	m.pending = m.pending[n:]
+	return claimed, nil
+}
+
+func (m *mockWebhookRepository) RecordAttempt(ctx context.Context, delivery *domain.WebhookDelivery, disableAfter int) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.attempts = append(m.attempts, *delivery)
+	return m.disable && delivery.Status == domain.WebhookDeliveryFailed, nil
+}
+
+func TestWebhookNotifier_Queues(t *testing.T) {
+	repo := &mockWebhookRepository{}
+	n := NewWebhookNotifier(repo, testLogger(), WebhookOptions{})
+
+	ctx := domain.ContextWithTenant(context.Background(), "acme")
+	userID := uuid.New()
+	if err := n.Notify(ctx, domain.EventTypeUserSuspended, domain.EventData{UserID: userID}); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+
+	if len(repo.events) != 1 {
+		t.Fatalf("queued %d events, want 1", len(repo.events))
+	}
+	if e := repo.events[0]; e.TenantID != "acme" || e.EventType != domain.EventTypeUserSuspended || e.Data.UserID != userID {
+		t.Errorf("queued event = %+v", e)
+	}
+}
+
+func TestWebhookNotifier_Dispatch(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		attempts   int
+		disable    bool
+		wantStatus domain.WebhookDeliveryStatus
+		wantNext   bool
+	}{
+		{name: "delivered", status: http.StatusNoContent, wantStatus: domain.WebhookDeliverySucceeded},
+		{name: "retried later", status: http.StatusInternalServerError, wantStatus: domain.WebhookDeliveryPending, wantNext: true},
+		{name: "attempts exhausted", status: http.StatusBadGateway, attempts: 2, wantStatus: domain.WebhookDeliveryFailed},
+		{name: "subscription disabled", status: http.StatusGone, attempts: 2, disable: true, wantStatus: domain.WebhookDeliveryFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload := []byte(`{"eventId":"e1","eventType":"user.created"}`)
+			var gotHeader http.Header
+			var gotBody []byte
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotHeader = r.Header.Clone()
+				gotBody, _ = io.ReadAll(r.Body)
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			eventID := uuid.New()
+			repo := &mockWebhookRepository{
+				disable: tt.disable,
+				pending: []domain.PendingWebhookDelivery{{
+					WebhookDelivery: domain.WebhookDelivery{
+						ID:             uuid.New(),
+						SubscriptionID: uuid.New(),
+						EventID:        eventID,
+						EventType:      domain.EventTypeUserCreated,
+						Payload:        payload,
+						Status:         domain.WebhookDeliveryPending,
+						Attempts:       tt.attempts,
+					},
+					URL:    server.URL,
+					Secret: "s3cret",
+				}},
+			}
+			n := NewWebhookNotifier(repo, testLogger(), WebhookOptions{MaxAttempts: 3, Backoff: time.Minute, AllowPrivateNetworks: true})
+			n.dispatch(context.Background())
+
+			if err := events.VerifySignature("s3cret", gotHeader.Get(events.HeaderWebhookSignature), gotBody, time.Minute); err != nil {
+				t.Errorf("signature %q: %v", gotHeader.Get(events.HeaderWebhookSignature), err)
+			}
+			if gotHeader.Get(events.HeaderWebhookID) != eventID.String() || gotHeader.Get(events.HeaderWebhookEvent) != "user.created" {
+				t.Errorf("headers = %v", gotHeader)
+			}
+
+			if len(repo.attempts) != 1 {
+				t.Fatalf("recorded %d attempts, want 1", len(repo.attempts))
+			}
+			d := repo.attempts[0]
+			if d.Status != tt.wantStatus || d.Attempts != tt.attempts+1 || d.LastStatusCode != tt.status {
+				t.Errorf("delivery = status %s, attempts %d, last status %d", d.Status, d.Attempts, d.LastStatusCode)
+			}
+			if (d.NextAttemptAt != nil) != tt.wantNext {
+				t.Errorf("NextAttemptAt = %v, want set %v", d.NextAttemptAt, tt.wantNext)
+			}
+			if (d.DeliveredAt != nil) != (tt.wantStatus == domain.WebhookDeliverySucceeded) {
+				t.Errorf("DeliveredAt = %v", d.DeliveredAt)
+			}
+		})
+	}
+}
+
+func TestWebhookNotifier_DispatchesEveryBatch(t *testing.T) {
+	var mu sync.Mutex
+	received := make(map[string]bool)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event domain.UserEvent
+		json.NewDecoder(r.Body).Decode(&event)
+		mu.Lock()
+		received[event.EventID.String()] = true
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	repo := &mockWebhookRepository{}
+	for range webhookBatchSize + 5 {
+		event := domain.UserEvent{EventID: uuid.New(), EventType: domain.EventTypeUserUpdated}
+		payload, _ := json.Marshal(event)
+		repo.pending = append(repo.pending, domain.PendingWebhookDelivery{
+			WebhookDelivery: domain.WebhookDelivery{ID: uuid.New(), EventID: event.EventID, Payload: payload},
+			URL:             server.URL,
+		})
+	}
+	n := NewWebhookNotifier(repo, testLogger(), WebhookOptions{AllowPrivateNetworks: true})
+	n.dispatch(context.Background())
+
+	if len(received) != webhookBatchSize+5 || len(repo.attempts) != webhookBatchSize+5 {
+		t.Errorf("delivered %d and recorded %d, want %d", len(received), len(repo.attempts), webhookBatchSize+5)
+	}
+}
+
+func TestWebhookNotifier_RefusesInternalAddresses(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("delivery reached an internal address")
+	}))
+	defer target.Close()
+	redirect := httptest.NewServer(http.RedirectHandler(target.URL, http.StatusFound))
+	defer redirect.Close()
+
+	tests := []struct {
+		name       string
+		url        string
+		opts       WebhookOptions
+		wantStatus int
+	}{
+		{"loopback refused", target.URL, WebhookOptions{}, 0},
+		{"redirect not followed", redirect.URL, WebhookOptions{AllowPrivateNetworks: true}, http.StatusFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &mockWebhookRepository{pending: []domain.PendingWebhookDelivery{{
+				WebhookDelivery: domain.WebhookDelivery{ID: uuid.New(), EventID: uuid.New(), Payload: []byte(`{}`)},
+				URL:             tt.url,
+			}}}
+			NewWebhookNotifier(repo, testLogger(), tt.opts).dispatch(context.Background())
+
+			if len(repo.attempts) != 1 {
+				t.Fatalf("recorded %d attempts, want 1", len(repo.attempts))
+			}
+			if d := repo.attempts[0]; d.Status != domain.WebhookDeliveryPending || d.LastStatusCode != tt.wantStatus || d.LastError == "" {
+				t.Errorf("delivery = status %s, last status %d, error %q", d.Status, d.LastStatusCode, d.LastError)
+			}
+		})
+	}
+}
+
+func TestWebhookNotifier_Backoff(t *testing.T) {
+	n := NewWebhookNotifier(&mockWebhookRepository{}, testLogger(), WebhookOptions{Backoff: 30 * time.Second})
+
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{4, 4 * time.Minute},
+		{20, webhookMaxBackoff},
+	}
+	for _, tt := range tests {
+		if got := n.backoff(tt.attempt); got != tt.want {
+			t.Errorf("backoff(%d) = %v, want %v", tt.attempt, got, tt.want)
+		}
+	}
+}
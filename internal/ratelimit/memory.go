@@ -0,0 +1,57 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepEvery is the number of Take calls between sweeps of idle buckets.
+const sweepEvery = 1024
+
+type memoryEntry struct {
+	bucket Bucket
+	limit  Limit
+}
+
+// MemoryStore keeps buckets in process memory. Each replica enforces its own
+// quota, so use a shared store when running several.
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*memoryEntry
+	calls   int
+	now     func() time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]*memoryEntry), now: time.Now}
+}
+
+func (s *MemoryStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.calls++
+	if s.calls%sweepEvery == 0 {
+		s.sweep(now)
+	}
+
+	entry, ok := s.buckets[key]
+	if !ok {
+		entry = &memoryEntry{bucket: NewBucket(limit, now)}
+		s.buckets[key] = entry
+	}
+	entry.limit = limit
+	return entry.bucket.Take(limit, now), nil
+}
+
+// sweep drops buckets that have refilled completely, which behave exactly
+// like missing ones.
+func (s *MemoryStore) sweep(now time.Time) {
+	for key, entry := range s.buckets {
+		if now.Sub(entry.bucket.UpdatedAt) >= entry.limit.Per {
+			delete(s.buckets, key)
+		}
+	}
+}
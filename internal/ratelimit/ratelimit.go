@@ -0,0 +1,206 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limit allows Requests per Per, refilled continuously, with bursts of up to
+// Requests.
+type Limit struct {
+	Requests int
+	Per      time.Duration
+}
+
+func (l Limit) IsZero() bool {
+	return l.Requests <= 0 || l.Per <= 0
+}
+
+func (l Limit) String() string {
+	return fmt.Sprintf("%d/%s", l.Requests, l.Per)
+}
+
+func (l Limit) ratePerSecond() float64 {
+	return float64(l.Requests) / l.Per.Seconds()
+}
+
+// ParseLimit parses "<requests>/<period>" where period is s, m, h or a Go
+// duration, e.g. "100/m" or "20/10s".
+func ParseLimit(spec string) (Limit, error) {
+	reqStr, perStr, ok := strings.Cut(strings.TrimSpace(spec), "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("invalid limit %q: expected requests/period", spec)
+	}
+
+	requests, err := strconv.Atoi(strings.TrimSpace(reqStr))
+	if err != nil || requests <= 0 {
+		return Limit{}, fmt.Errorf("invalid limit %q: requests must be a positive integer", spec)
+	}
+
+	perStr = strings.TrimSpace(perStr)
+	var per time.Duration
+	switch perStr {
+	case "s":
+		per = time.Second
+	case "m":
+		per = time.Minute
+	case "h":
+		per = time.Hour
+	default:
+		per, err = time.ParseDuration(perStr)
+		if err != nil || per <= 0 {
+			return Limit{}, fmt.Errorf("invalid limit %q: period must be s, m, h or a positive duration", spec)
+		}
+	}
+
+	return Limit{Requests: requests, Per: per}, nil
+}
+
+// Rule applies Limit to requests matching Pattern, a net/http ServeMux
+// pattern such as "POST /api/v1/users".
+type Rule struct {
+	Pattern string
+	Limit   Limit
+}
+
+// ParseRules parses "pattern=limit" pairs separated by ";", e.g.
+// "POST /api/v1/users=10/m; GET /api/v1/users/{id}=300/m".
+func ParseRules(spec string) ([]Rule, error) {
+	var rules []Rule
+	for _, entry := range strings.Split(spec, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		pattern, limitStr, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid rule %q: expected pattern=limit", entry)
+		}
+		limit, err := ParseLimit(limitStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rule %q: %w", entry, err)
+		}
+		rules = append(rules, Rule{Pattern: strings.TrimSpace(pattern), Limit: limit})
+	}
+	return rules, nil
+}
+
+// Result describes the state of a bucket after a request was counted.
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration // until the bucket is full again
+	RetryAfter time.Duration // until the next request is allowed, zero if allowed
+}
+
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// Bucket is the token bucket state kept by stores.
+type Bucket struct {
+	Tokens    float64
+	UpdatedAt time.Time
+}
+
+// NewBucket returns a full bucket.
+func NewBucket(limit Limit, now time.Time) Bucket {
+	return Bucket{Tokens: float64(limit.Requests), UpdatedAt: now}
+}
+
+// Take refills the bucket for the time elapsed since its last update and
+// consumes one token if available.
+func (b *Bucket) Take(limit Limit, now time.Time) Result {
+	rate := limit.ratePerSecond()
+	capacity := float64(limit.Requests)
+
+	if elapsed := now.Sub(b.UpdatedAt).Seconds(); elapsed > 0 {
+		b.Tokens = math.Min(capacity, b.Tokens+elapsed*rate)
+	}
+	if b.Tokens > capacity {
+		b.Tokens = capacity
+	}
+	b.UpdatedAt = now
+
+	result := Result{Limit: limit.Requests}
+	if b.Tokens >= 1 {
+		b.Tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = secondsToDuration((1 - b.Tokens) / rate)
+	}
+	result.Remaining = int(math.Floor(b.Tokens))
+	result.Reset = secondsToDuration((capacity - b.Tokens) / rate)
+	return result
+}
+
+func secondsToDuration(s float64) time.Duration {
+	return time.Duration(math.Ceil(s * float64(time.Second)))
+}
+
+// Limiter picks the limit for a request from its rules, falling back to the
+// default, and counts the request in the client's bucket for that rule.
+type Limiter struct {
+	store    Store
+	fallback Limit
+	routes   *http.ServeMux
+	limits   map[string]Limit
+}
+
+func NewLimiter(store Store, fallback Limit, rules []Rule) (l *Limiter, err error) {
+	l = &Limiter{
+		store:    store,
+		fallback: fallback,
+		routes:   http.NewServeMux(),
+		limits:   make(map[string]Limit, len(rules)),
+	}
+
+	// ServeMux panics on invalid or conflicting patterns.
+	defer func() {
+		if r := recover(); r != nil {
+			l, err = nil, fmt.Errorf("invalid rate limit pattern: %v", r)
+		}
+	}()
+	for _, rule := range rules {
+		l.routes.Handle(rule.Pattern, http.NotFoundHandler())
+		l.limits[rule.Pattern] = rule.Limit
+	}
+	return l, nil
+}
+
+// Allow counts r against the quota of each client, stopping at the first one
+// that is exhausted, and returns the most restrictive result. The returned
+// bool is false when no limit applies to the request.
+func (l *Limiter) Allow(r *http.Request, clients ...string) (Result, bool, error) {
+	pattern := "*"
+	limit := l.fallback
+	if _, matched := l.routes.Handler(r); matched != "" {
+		pattern = matched
+		limit = l.limits[matched]
+	}
+	if limit.IsZero() || len(clients) == 0 {
+		return Result{}, false, nil
+	}
+
+	var result Result
+	for i, client := range clients {
+		clientResult, err := l.store.Take(r.Context(), pattern+"|"+client, limit)
+		if err != nil {
+			return Result{}, true, err
+		}
+		if i == 0 || clientResult.Remaining < result.Remaining || !clientResult.Allowed {
+			result = clientResult
+		}
+		if !clientResult.Allowed {
+			break
+		}
+	}
+	return result, true, nil
+}
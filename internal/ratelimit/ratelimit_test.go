@@ -0,0 +1,210 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    Limit
+		wantErr bool
+	}{
+		{"100/m", Limit{100, time.Minute}, false},
+		{"5/s", Limit{5, time.Second}, false},
+		{" 1000 / h ", Limit{1000, time.Hour}, false},
+		{"20/10s", Limit{20, 10 * time.Second}, false},
+		{"100", Limit{}, true},
+		{"0/m", Limit{}, true},
+		{"-1/m", Limit{}, true},
+		{"x/m", Limit{}, true},
+		{"10/day", Limit{}, true},
+		{"10/-1s", Limit{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := ParseLimit(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseLimit() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseLimit() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRules(t *testing.T) {
+	rules, err := ParseRules("POST /api/v1/users=10/m; GET /api/v1/users/{id}=300/m;")
+	if err != nil {
+		t.Fatalf("ParseRules() error = %v", err)
+	}
+	want := []Rule{
+		{"POST /api/v1/users", Limit{10, time.Minute}},
+		{"GET /api/v1/users/{id}", Limit{300, time.Minute}},
+	}
+	if len(rules) != len(want) {
+		t.Fatalf("ParseRules() = %v, want %v", rules, want)
+	}
+	for i := range want {
+		if rules[i] != want[i] {
+			t.Errorf("rule %d = %v, want %v", i, rules[i], want[i])
+		}
+	}
+
+	for _, spec := range []string{"POST /api/v1/users", "POST /api/v1/users=fast"} {
+		if _, err := ParseRules(spec); err == nil {
+			t.Errorf("ParseRules(%q) error = nil, want error", spec)
+		}
+	}
+}
+
+func TestBucket_Take(t *testing.T) {
+	limit := Limit{Requests: 2, Per: 2 * time.Second}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	bucket := NewBucket(limit, start)
+
+	tests := []struct {
+		name          string
+		at            time.Duration
+		wantAllowed   bool
+		wantRemaining int
+		wantRetry     time.Duration
+		wantReset     time.Duration
+	}{
+		{"first", 0, true, 1, 0, time.Second},
+		{"second", 0, true, 0, 0, 2 * time.Second},
+		{"exhausted", 0, false, 0, time.Second, 2 * time.Second},
+		{"half refilled", 500 * time.Millisecond, false, 0, 500 * time.Millisecond, 1500 * time.Millisecond},
+		{"one token refilled", time.Second, true, 0, 0, 2 * time.Second},
+		{"capped at capacity", time.Hour, true, 1, 0, time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := bucket.Take(limit, start.Add(tt.at))
+			if got.Allowed != tt.wantAllowed || got.Remaining != tt.wantRemaining {
+				t.Errorf("Take() = %+v, want allowed %v remaining %v", got, tt.wantAllowed, tt.wantRemaining)
+			}
+			if got.RetryAfter != tt.wantRetry || got.Reset != tt.wantReset {
+				t.Errorf("Take() retry %v reset %v, want %v %v", got.RetryAfter, got.Reset, tt.wantRetry, tt.wantReset)
+			}
+			if got.Limit != limit.Requests {
+				t.Errorf("Take() limit = %v, want %v", got.Limit, limit.Requests)
+			}
+		})
+	}
+}
+
+func TestNewLimiter_InvalidPattern(t *testing.T) {
+	rules := []Rule{
+		{"GET /api/v1/users/{id}", Limit{1, time.Minute}},
+		{"GET /api/v1/users/{id}", Limit{2, time.Minute}},
+	}
+	if _, err := NewLimiter(NewMemoryStore(), Limit{}, rules); err == nil {
+		t.Error("NewLimiter() error = nil, want error for conflicting patterns")
+	}
+	if _, err := NewLimiter(NewMemoryStore(), Limit{}, []Rule{{"GET /{", Limit{1, time.Minute}}}); err == nil {
+		t.Error("NewLimiter() error = nil, want error for invalid pattern")
+	}
+}
+
+func TestLimiter_Allow(t *testing.T) {
+	rules := []Rule{{"POST /api/v1/users", Limit{1, time.Minute}}}
+
+	tests := []struct {
+		name        string
+		fallback    Limit
+		method      string
+		path        string
+		client      string
+		wantApplies bool
+		wantAllowed bool
+	}{
+		{"rule first request", Limit{}, "POST", "/api/v1/users", "a", true, true},
+		{"rule exhausted", Limit{}, "POST", "/api/v1/users", "a", true, false},
+		{"rule other client", Limit{}, "POST", "/api/v1/users", "b", true, true},
+		{"no rule no default", Limit{}, "GET", "/api/v1/users", "a", false, true},
+		{"default applies", Limit{1, time.Minute}, "GET", "/api/v1/users", "a", true, true},
+		{"default exhausted", Limit{1, time.Minute}, "GET", "/api/v1/users/1", "a", true, false},
+		{"default bucket separate from rule", Limit{1, time.Minute}, "GET", "/api/v1/users", "b", true, true},
+	}
+
+	store := NewMemoryStore()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter, err := NewLimiter(store, tt.fallback, rules)
+			if err != nil {
+				t.Fatalf("NewLimiter() error = %v", err)
+			}
+			r := httptest.NewRequest(tt.method, tt.path, nil)
+			result, applies, err := limiter.Allow(r, tt.client)
+			if err != nil {
+				t.Fatalf("Allow() error = %v", err)
+			}
+			if applies != tt.wantApplies {
+				t.Errorf("Allow() applies = %v, want %v", applies, tt.wantApplies)
+			}
+			if applies && result.Allowed != tt.wantAllowed {
+				t.Errorf("Allow() allowed = %v, want %v", result.Allowed, tt.wantAllowed)
+			}
+		})
+	}
+}
+
+func TestLimiter_AllowClients(t *testing.T) {
+	limiter, err := NewLimiter(NewMemoryStore(), Limit{2, time.Minute}, nil)
+	if err != nil {
+		t.Fatalf("NewLimiter() error = %v", err)
+	}
+	r := httptest.NewRequest("POST", "/api/v1/users", nil)
+
+	tests := []struct {
+		name          string
+		clients       []string
+		wantAllowed   bool
+		wantRemaining int
+	}{
+		{"both buckets full", []string{"ip:1", "key:a"}, true, 1},
+		{"most restrictive bucket reported", []string{"ip:1", "key:b"}, true, 0},
+		{"first bucket exhausted", []string{"ip:1", "key:c"}, false, 0},
+		{"second bucket most restrictive", []string{"ip:2", "key:a"}, true, 0},
+		{"second bucket exhausted", []string{"ip:3", "key:a"}, false, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, applies, err := limiter.Allow(r, tt.clients...)
+			if err != nil || !applies {
+				t.Fatalf("Allow() = %v, %v", applies, err)
+			}
+			if result.Allowed != tt.wantAllowed || result.Remaining != tt.wantRemaining {
+				t.Errorf("Allow() = %+v, want allowed %v, remaining %v", result, tt.wantAllowed, tt.wantRemaining)
+			}
+		})
+	}
+}
+
+func TestMemoryStore_Sweep(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+	limit := Limit{Requests: 1, Per: time.Minute}
+
+	store.Take(context.Background(), "idle", limit)
+	now = now.Add(time.Minute)
+	for i := 1; i < sweepEvery; i++ {
+		store.Take(context.Background(), "busy", limit)
+	}
+
+	if _, ok := store.buckets["idle"]; ok {
+		t.Error("idle bucket was not swept")
+	}
+	if _, ok := store.buckets["busy"]; !ok {
+		t.Error("busy bucket was swept")
+	}
+}
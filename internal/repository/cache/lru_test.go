@@ -0,0 +1,53 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLRU(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewLRU(2)
+	c.now = func() time.Time { return now }
+
+	c.Set(ctx, "a", []byte("1"), time.Minute)
+	c.Set(ctx, "b", []byte("2"), time.Minute)
+	c.Get(ctx, "a")
+	c.Set(ctx, "c", []byte("3"), time.Minute)
+
+	tests := []struct {
+		key     string
+		wantOK  bool
+		wantVal string
+	}{
+		{"a", true, "1"},
+		{"b", false, ""},
+		{"c", true, "3"},
+	}
+	for _, tt := range tests {
+		got, ok, err := c.Get(ctx, tt.key)
+		if err != nil || ok != tt.wantOK || string(got) != tt.wantVal {
+			t.Errorf("Get(%q) = %q, %v, %v, want %q, %v", tt.key, got, ok, err, tt.wantVal, tt.wantOK)
+		}
+	}
+
+	c.Set(ctx, "a", []byte("updated"), time.Minute)
+	if got, _, _ := c.Get(ctx, "a"); string(got) != "updated" || c.Len() != 2 {
+		t.Errorf("Get() after overwrite = %q, len %d", got, c.Len())
+	}
+
+	now = now.Add(time.Minute)
+	if _, ok, _ := c.Get(ctx, "a"); ok {
+		t.Error("Get() returned an expired entry")
+	}
+	if c.Len() != 1 {
+		t.Errorf("Len() = %d, want expired entry removed", c.Len())
+	}
+
+	c.Delete(ctx, "c")
+	if _, ok, _ := c.Get(ctx, "c"); ok {
+		t.Error("Get() returned a deleted entry")
+	}
+}
@@ -0,0 +1,223 @@
+// Package cache provides a read-through cache in front of the user
+// repository.
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"hash/fnv"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/sync/singleflight"
+
+	"github.com/giannuccilli/user-api/internal/domain"
+	"github.com/giannuccilli/user-api/pkg/events"
+)
+
+const DefaultTTL = time.Minute
+
+// generationStripes is the number of invalidation counters keys are spread
+// over.
+const generationStripes = 256
+
+// Backend stores encoded users by key. A distributed implementation shares
+// the cache, and its invalidations, between replicas.
+type Backend interface {
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, key string) error
+}
+
+type Stats struct {
+	Hits   uint64 `json:"hits"`
+	Misses uint64 `json:"misses"`
+	// Errors counts backend failures. Lookups fall back to the repository.
+	Errors uint64 `json:"errors"`
+}
+
+// UserRepository caches GetByID and invalidates the cached user whenever it
+// is written through Update, Upsert, ReactivateSuspension or Delete. Other methods go straight to
+// the wrapped repository.
+type UserRepository struct {
+	domain.UserRepository
+
+	backend Backend
+	ttl     time.Duration
+	group   singleflight.Group
+	// generations are bumped by Invalidate, so that a load that read the row
+	// before a write does not leave it in the cache after the write.
+	generations [generationStripes]atomic.Uint64
+
+	hits   atomic.Uint64
+	misses atomic.Uint64
+	errors atomic.Uint64
+}
+
+type Option func(*UserRepository)
+
+func WithTTL(ttl time.Duration) Option {
+	return func(r *UserRepository) {
+		if ttl > 0 {
+			r.ttl = ttl
+		}
+	}
+}
+
+func NewUserRepository(next domain.UserRepository, backend Backend, opts ...Option) *UserRepository {
+	r := &UserRepository{UserRepository: next, backend: backend, ttl: DefaultTTL}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// cachedUser keeps the fields that domain.User leaves out of its JSON.
+type cachedUser struct {
+	domain.User
+	EmailCanonical string `json:"emailCanonical"`
+}
+
+func (r *UserRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.User, error) {
+	key := userKey(ctx, id)
+
+	data, ok, err := r.backend.Get(ctx, key)
+	if err != nil {
+		r.errors.Add(1)
+	}
+	if ok {
+		if user, err := decodeUser(data); err == nil {
+			r.hits.Add(1)
+			return user, nil
+		}
+		r.errors.Add(1)
+	}
+	r.misses.Add(1)
+
+	// Concurrent misses share one query. It runs detached from the caller's
+	// cancellation so one caller giving up does not fail the others.
+	v, err, _ := r.group.Do(key, func() (any, error) {
+		loadCtx := context.WithoutCancel(ctx)
+		generation := r.generation(key)
+		loadedAt := generation.Load()
+		user, err := r.UserRepository.GetByID(loadCtx, id)
+		if err != nil {
+			return nil, err
+		}
+		data, err := json.Marshal(cachedUser{User: *user, EmailCanonical: user.EmailCanonical})
+		if err != nil {
+			return nil, err
+		}
+		if generation.Load() != loadedAt {
+			return data, nil
+		}
+		if err := r.backend.Set(loadCtx, key, data, r.ttl); err != nil {
+			r.errors.Add(1)
+		}
+		// An invalidation between the check and Set deleted the key before
+		// the stale row was written, so drop it again.
+		if generation.Load() != loadedAt {
+			r.Invalidate(loadCtx, id)
+		}
+		return data, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Every caller decodes its own copy, so callers can modify the user.
+	return decodeUser(v.([]byte))
+}
+
+func (r *UserRepository) Update(ctx context.Context, user *domain.User) error {
+	err := r.UserRepository.Update(ctx, user)
+	r.Invalidate(ctx, user.ID)
+	return err
+}
+
+func (r *UserRepository) UpdateWithAudit(ctx context.Context, user *domain.User, entry *domain.AuditEntry) error {
+	err := r.UserRepository.UpdateWithAudit(ctx, user, entry)
+	r.Invalidate(ctx, user.ID)
+	return err
+}
+
+func (r *UserRepository) Upsert(ctx context.Context, user *domain.User) (domain.UpsertResult, error) {
+	result, err := r.UserRepository.Upsert(ctx, user)
+	if err == nil && result == domain.UpsertUpdated {
+		r.Invalidate(ctx, user.ID)
+	}
+	return result, err
+}
+
+func (r *UserRepository) ReactivateSuspension(ctx context.Context, user *domain.User, before time.Time, entry *domain.AuditEntry) (bool, error) {
+	reactivated, err := r.UserRepository.ReactivateSuspension(ctx, user, before, entry)
+	if err == nil && reactivated {
+		r.Invalidate(ctx, user.ID)
+	}
+	return reactivated, err
+}
+
+func (r *UserRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	err := r.UserRepository.Delete(ctx, id)
+	r.Invalidate(ctx, id)
+	return err
+}
+
+// Invalidate drops the cached user of the tenant in ctx. It is called on
+// every write and by HandleEvent to evict entries written by other replicas.
+// Lookups after it do not join a load that started before it.
+func (r *UserRepository) Invalidate(ctx context.Context, id uuid.UUID) {
+	key := userKey(ctx, id)
+	r.generation(key).Add(1)
+	r.group.Forget(key)
+	if err := r.backend.Delete(ctx, key); err != nil {
+		r.errors.Add(1)
+	}
+}
+
+// HandleEvent is an events.Handler that invalidates the user of every user
+// event, including those published by other replicas.
+func (r *UserRepository) HandleEvent(ctx context.Context, event events.Event) error {
+	id, err := uuid.Parse(event.Data.UserID)
+	if err != nil {
+		return nil
+	}
+	if event.TenantID != "" {
+		ctx = domain.ContextWithTenant(ctx, event.TenantID)
+	}
+	r.Invalidate(ctx, id)
+	return nil
+}
+
+func (r *UserRepository) Stats() Stats {
+	return Stats{
+		Hits:   r.hits.Load(),
+		Misses: r.misses.Load(),
+		Errors: r.errors.Load(),
+	}
+}
+
+func (r *UserRepository) generation(key string) *atomic.Uint64 {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return &r.generations[h.Sum32()%generationStripes]
+}
+
+func userKey(ctx context.Context, id uuid.UUID) string {
+	tenantID, ok := domain.TenantFromContext(ctx)
+	if !ok {
+		tenantID = domain.DefaultTenantID
+	}
+	return "user:" + tenantID + ":" + id.String()
+}
+
+func decodeUser(data []byte) (*domain.User, error) {
+	var cached cachedUser
+	if err := json.Unmarshal(data, &cached); err != nil {
+		return nil, err
+	}
+	user := cached.User
+	user.EmailCanonical = cached.EmailCanonical
+	return &user, nil
+}
@@ -0,0 +1,304 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/giannuccilli/user-api/internal/domain"
+	"github.com/giannuccilli/user-api/pkg/events"
+)
+
+// stubUserRepository implements the methods the cache overrides; the others
+// are not called.
+type stubUserRepository struct {
+	domain.UserRepository
+
+	mu      sync.Mutex
+	users   map[string]domain.User
+	gets    atomic.Int32
+	release chan struct{}
+}
+
+func newStubUserRepository(users ...domain.User) *stubUserRepository {
+	s := &stubUserRepository{users: make(map[string]domain.User)}
+	for _, user := range users {
+		s.users[user.TenantID+"/"+user.ID.String()] = user
+	}
+	return s
+}
+
+func stubKey(ctx context.Context, id uuid.UUID) string {
+	tenantID, ok := domain.TenantFromContext(ctx)
+	if !ok {
+		tenantID = domain.DefaultTenantID
+	}
+	return tenantID + "/" + id.String()
+}
+
+// GetByID reads the user before waiting for release, like a query whose
+// result is on its way back while other writes happen.
+func (s *stubUserRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.User, error) {
+	s.mu.Lock()
+	user, ok := s.users[stubKey(ctx, id)]
+	s.mu.Unlock()
+	s.gets.Add(1)
+	if s.release != nil {
+		<-s.release
+	}
+	if !ok {
+		return nil, domain.ErrUserNotFound
+	}
+	return &user, nil
+}
+
+func (s *stubUserRepository) Update(ctx context.Context, user *domain.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.users[stubKey(ctx, user.ID)] = *user
+	return nil
+}
+
+func (s *stubUserRepository) Upsert(ctx context.Context, user *domain.User) (domain.UpsertResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.users[stubKey(ctx, user.ID)] = *user
+	return domain.UpsertUpdated, nil
+}
+
+func (s *stubUserRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.users, stubKey(ctx, id))
+	return nil
+}
+
+type failingBackend struct{}
+
+func (failingBackend) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	return nil, false, errors.New("backend down")
+}
+
+func (failingBackend) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return errors.New("backend down")
+}
+
+func (failingBackend) Delete(ctx context.Context, key string) error {
+	return errors.New("backend down")
+}
+
+func testUser() domain.User {
+	return domain.User{
+		ID:             uuid.New(),
+		TenantID:       domain.DefaultTenantID,
+		Email:          "John.Doe@example.com",
+		EmailCanonical: "john.doe@example.com",
+		FirstName:      "John",
+		Attributes:     domain.Attributes{"plan": "pro"},
+	}
+}
+
+func TestUserRepository_GetByID(t *testing.T) {
+	user := testUser()
+	stub := newStubUserRepository(user)
+	repo := NewUserRepository(stub, NewLRU(10))
+	ctx := context.Background()
+
+	first, err := repo.GetByID(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+	first.FirstName = "Modified"
+
+	second, err := repo.GetByID(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+	if second.FirstName != "John" || second.EmailCanonical != user.EmailCanonical || second.Attributes["plan"] != "pro" {
+		t.Errorf("GetByID() cached = %+v, want unmodified copy of %+v", second, user)
+	}
+	if got := stub.gets.Load(); got != 1 {
+		t.Errorf("repository calls = %d, want 1", got)
+	}
+	if stats := repo.Stats(); stats.Hits != 1 || stats.Misses != 1 {
+		t.Errorf("Stats() = %+v, want 1 hit and 1 miss", stats)
+	}
+
+	if _, err := repo.GetByID(domain.ContextWithTenant(ctx, "acme"), user.ID); !errors.Is(err, domain.ErrUserNotFound) {
+		t.Errorf("GetByID() in another tenant error = %v, want %v", err, domain.ErrUserNotFound)
+	}
+	if _, err := repo.GetByID(ctx, uuid.New()); !errors.Is(err, domain.ErrUserNotFound) {
+		t.Errorf("GetByID() missing error = %v, want %v", err, domain.ErrUserNotFound)
+	}
+}
+
+func TestUserRepository_Invalidation(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name  string
+		write func(repo *UserRepository, user domain.User) error
+		want  string
+	}{
+		{"update", func(repo *UserRepository, user domain.User) error {
+			user.FirstName = "Jane"
+			return repo.Update(ctx, &user)
+		}, "Jane"},
+		{"upsert", func(repo *UserRepository, user domain.User) error {
+			user.FirstName = "Jim"
+			_, err := repo.Upsert(ctx, &user)
+			return err
+		}, "Jim"},
+		{"delete", func(repo *UserRepository, user domain.User) error {
+			return repo.Delete(ctx, user.ID)
+		}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := testUser()
+			repo := NewUserRepository(newStubUserRepository(user), NewLRU(10))
+			if _, err := repo.GetByID(ctx, user.ID); err != nil {
+				t.Fatalf("GetByID() error = %v", err)
+			}
+
+			if err := tt.write(repo, user); err != nil {
+				t.Fatalf("write error = %v", err)
+			}
+
+			got, err := repo.GetByID(ctx, user.ID)
+			if tt.want == "" {
+				if !errors.Is(err, domain.ErrUserNotFound) {
+					t.Errorf("GetByID() after delete error = %v, want %v", err, domain.ErrUserNotFound)
+				}
+				return
+			}
+			if err != nil || got.FirstName != tt.want {
+				t.Errorf("GetByID() after write = %+v, %v, want first name %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestUserRepository_CollapsesConcurrentMisses(t *testing.T) {
+	user := testUser()
+	stub := newStubUserRepository(user)
+	stub.release = make(chan struct{})
+	repo := NewUserRepository(stub, NewLRU(10))
+
+	const callers = 10
+	var wg sync.WaitGroup
+	errs := make(chan error, callers)
+	for range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := repo.GetByID(context.Background(), user.ID)
+			errs <- err
+		}()
+	}
+
+	// Let the callers pile up on the first query before it completes. Misses
+	// are counted just before joining the query, hence the extra wait.
+	for repo.Stats().Misses < callers {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(stub.release)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("GetByID() error = %v", err)
+		}
+	}
+	if got := stub.gets.Load(); got != 1 {
+		t.Errorf("repository calls = %d, want 1", got)
+	}
+}
+
+func TestUserRepository_BackendFailure(t *testing.T) {
+	user := testUser()
+	repo := NewUserRepository(newStubUserRepository(user), failingBackend{})
+
+	got, err := repo.GetByID(context.Background(), user.ID)
+	if err != nil || got.ID != user.ID {
+		t.Fatalf("GetByID() = %v, %v, want fallback to repository", got, err)
+	}
+	if err := repo.Delete(context.Background(), user.ID); err != nil {
+		t.Errorf("Delete() error = %v", err)
+	}
+	if stats := repo.Stats(); stats.Errors != 3 {
+		t.Errorf("Stats().Errors = %d, want 3", stats.Errors)
+	}
+}
+
+func TestUserRepository_WriteDuringLoad(t *testing.T) {
+	user := testUser()
+	stub := newStubUserRepository(user)
+	stub.release = make(chan struct{})
+	repo := NewUserRepository(stub, NewLRU(10))
+	ctx := context.Background()
+
+	loaded := make(chan struct{})
+	go func() {
+		defer close(loaded)
+		repo.GetByID(ctx, user.ID)
+	}()
+	for stub.gets.Load() < 1 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// The load has read the old row; the write lands before it is cached.
+	updated := user
+	updated.FirstName = "Jane"
+	if err := repo.Update(ctx, &updated); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	stub.release <- struct{}{}
+	<-loaded
+	close(stub.release)
+
+	got, err := repo.GetByID(ctx, user.ID)
+	if err != nil || got.FirstName != "Jane" {
+		t.Errorf("GetByID() after write = %+v, %v, want first name Jane", got, err)
+	}
+}
+
+func TestUserRepository_HandleEvent(t *testing.T) {
+	user := testUser()
+	user.TenantID = "acme"
+	stub := newStubUserRepository(user)
+	repo := NewUserRepository(stub, NewLRU(10))
+	ctx := domain.ContextWithTenant(context.Background(), "acme")
+
+	if _, err := repo.GetByID(ctx, user.ID); err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+
+	// Another replica writes the user and publishes the event.
+	updated := user
+	updated.FirstName = "Jane"
+	if err := stub.Update(ctx, &updated); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	event := events.Event{ID: "e1", Type: events.UserUpdated, TenantID: "acme", Data: events.Data{UserID: user.ID.String()}}
+	if err := repo.HandleEvent(context.Background(), event); err != nil {
+		t.Fatalf("HandleEvent() error = %v", err)
+	}
+
+	got, err := repo.GetByID(ctx, user.ID)
+	if err != nil || got.FirstName != "Jane" {
+		t.Errorf("GetByID() after event = %+v, %v, want first name Jane", got, err)
+	}
+
+	if err := repo.HandleEvent(context.Background(), events.Event{ID: "e2", Type: events.UserUpdated, Data: events.Data{UserID: "not-a-uuid"}}); err != nil {
+		t.Errorf("HandleEvent() invalid user ID error = %v, want nil", err)
+	}
+}
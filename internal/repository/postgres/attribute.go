@@ -0,0 +1,85 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/giannuccilli/user-api/internal/domain"
+)
+
+type AttributeRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewAttributeRepository(pool *pgxpool.Pool) *AttributeRepository {
+	return &AttributeRepository{pool: pool}
+}
+
+func (r *AttributeRepository) List(ctx context.Context) ([]domain.AttributeDefinition, error) {
+	query := `
+		SELECT key, description, schema, created_at, updated_at
+		FROM attribute_definitions
+		ORDER BY key`
+
+	rows, err := r.pool.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	defs := make([]domain.AttributeDefinition, 0)
+	for rows.Next() {
+		var def domain.AttributeDefinition
+		if err := rows.Scan(&def.Key, &def.Description, &def.Schema, &def.CreatedAt, &def.UpdatedAt); err != nil {
+			return nil, err
+		}
+		defs = append(defs, def)
+	}
+
+	return defs, rows.Err()
+}
+
+func (r *AttributeRepository) Get(ctx context.Context, key string) (*domain.AttributeDefinition, error) {
+	query := `
+		SELECT key, description, schema, created_at, updated_at
+		FROM attribute_definitions
+		WHERE key = $1`
+
+	var def domain.AttributeDefinition
+	err := r.pool.QueryRow(ctx, query, key).Scan(&def.Key, &def.Description, &def.Schema, &def.CreatedAt, &def.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrAttributeNotFound
+		}
+		return nil, err
+	}
+
+	return &def, nil
+}
+
+func (r *AttributeRepository) Save(ctx context.Context, def *domain.AttributeDefinition) error {
+	query := `
+		INSERT INTO attribute_definitions (key, description, schema)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (key) DO UPDATE
+		SET description = EXCLUDED.description, schema = EXCLUDED.schema, updated_at = CURRENT_TIMESTAMP
+		RETURNING created_at, updated_at`
+
+	return r.pool.QueryRow(ctx, query, def.Key, def.Description, string(def.Schema)).Scan(&def.CreatedAt, &def.UpdatedAt)
+}
+
+func (r *AttributeRepository) Delete(ctx context.Context, key string) error {
+	result, err := r.pool.Exec(ctx, `DELETE FROM attribute_definitions WHERE key = $1`, key)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return domain.ErrAttributeNotFound
+	}
+
+	return nil
+}
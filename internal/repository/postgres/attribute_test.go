@@ -0,0 +1,63 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/giannuccilli/user-api/internal/domain"
+)
+
+func TestAttributeRepository_SaveGetDelete(t *testing.T) {
+	if testPool == nil {
+		t.Skip("Database not available")
+	}
+	cleanupTestData(t)
+
+	repo := NewAttributeRepository(testPool)
+	ctx := context.Background()
+
+	def := &domain.AttributeDefinition{
+		Key:         "plan",
+		Description: "Billing plan",
+		Schema:      json.RawMessage(`{"type": "string"}`),
+	}
+	if err := repo.Save(ctx, def); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if def.CreatedAt.IsZero() || def.UpdatedAt.IsZero() {
+		t.Error("Save() did not set timestamps")
+	}
+
+	def.Description = "Subscription plan"
+	if err := repo.Save(ctx, def); err != nil {
+		t.Fatalf("Save() upsert error = %v", err)
+	}
+
+	got, err := repo.Get(ctx, "plan")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if got.Description != "Subscription plan" {
+		t.Errorf("Get() description = %v, want Subscription plan", got.Description)
+	}
+
+	defs, err := repo.List(ctx)
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(defs) != 1 {
+		t.Errorf("List() len = %v, want 1", len(defs))
+	}
+
+	if err := repo.Delete(ctx, "plan"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := repo.Get(ctx, "plan"); !errors.Is(err, domain.ErrAttributeNotFound) {
+		t.Errorf("Get() after delete error = %v, want %v", err, domain.ErrAttributeNotFound)
+	}
+	if err := repo.Delete(ctx, "plan"); !errors.Is(err, domain.ErrAttributeNotFound) {
+		t.Errorf("Delete() missing error = %v, want %v", err, domain.ErrAttributeNotFound)
+	}
+}
@@ -0,0 +1,92 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/giannuccilli/user-api/internal/domain"
+)
+
+type AuditRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewAuditRepository(pool *pgxpool.Pool) *AuditRepository {
+	return &AuditRepository{pool: pool}
+}
+
+func (r *AuditRepository) Save(ctx context.Context, entry *domain.AuditEntry) error {
+	return saveAudit(ctx, r.pool, entry)
+}
+
+// queryRower is implemented by both the pool and a transaction.
+type queryRower interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+func saveAudit(ctx context.Context, db queryRower, entry *domain.AuditEntry) error {
+	query := `
+		INSERT INTO user_audit_log (tenant_id, user_id, action, actor_id, from_status, to_status, reason, note)
+		VALUES ($8, $1, $2, $3, $4, $5, NULLIF($6, ''), $7)
+		RETURNING id, created_at`
+
+	return db.QueryRow(ctx, query,
+		entry.UserID,
+		entry.Action,
+		entry.ActorID,
+		entry.FromStatus,
+		entry.ToStatus,
+		entry.Reason,
+		entry.Note,
+		tenantID(ctx),
+	).Scan(&entry.ID, &entry.CreatedAt)
+}
+
+func (r *AuditRepository) ListByUser(ctx context.Context, userID uuid.UUID, limit, offset int) ([]domain.AuditEntry, int, error) {
+	countQuery := `SELECT COUNT(*) FROM user_audit_log WHERE tenant_id = $2 AND user_id = $1`
+	var total int
+	if err := r.pool.QueryRow(ctx, countQuery, userID, tenantID(ctx)).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := `
+		SELECT id, user_id, action, actor_id, from_status, to_status, COALESCE(reason, ''), note, created_at
+		FROM user_audit_log
+		WHERE tenant_id = $4 AND user_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2 OFFSET $3`
+
+	rows, err := r.pool.Query(ctx, query, userID, limit, offset, tenantID(ctx))
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	entries := make([]domain.AuditEntry, 0)
+	for rows.Next() {
+		var e domain.AuditEntry
+		if err := rows.Scan(
+			&e.ID,
+			&e.UserID,
+			&e.Action,
+			&e.ActorID,
+			&e.FromStatus,
+			&e.ToStatus,
+			&e.Reason,
+			&e.Note,
+			&e.CreatedAt,
+		); err != nil {
+			return nil, 0, err
+		}
+		entries = append(entries, e)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return entries, total, nil
+}
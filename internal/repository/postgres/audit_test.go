@@ -0,0 +1,120 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/giannuccilli/user-api/internal/domain"
+)
+
+func TestAuditRepository_SaveAndList(t *testing.T) {
+	if testPool == nil {
+		t.Skip("Database not available")
+	}
+	ctx := context.Background()
+	if _, err := testPool.Exec(ctx, "DELETE FROM user_audit_log"); err != nil {
+		t.Fatalf("Failed to cleanup test data: %v", err)
+	}
+
+	repo := NewAuditRepository(testPool)
+	userID := uuid.New()
+
+	entries := []*domain.AuditEntry{
+		{
+			UserID:     userID,
+			Action:     domain.EventTypeUserSuspended,
+			ActorID:    "admin-1",
+			FromStatus: domain.UserStatusActive,
+			ToStatus:   domain.UserStatusSuspended,
+			Reason:     domain.SuspensionReasonAbuse,
+			Note:       "spam reports",
+		},
+		{
+			UserID:     userID,
+			Action:     domain.EventTypeUserReactivated,
+			ActorID:    "admin-1",
+			FromStatus: domain.UserStatusSuspended,
+			ToStatus:   domain.UserStatusActive,
+		},
+	}
+	for _, e := range entries {
+		if err := repo.Save(ctx, e); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+		if e.ID == uuid.Nil {
+			t.Error("Save() did not set ID")
+		}
+	}
+
+	list, total, err := repo.ListByUser(ctx, userID, 10, 0)
+	if err != nil {
+		t.Fatalf("ListByUser() error = %v", err)
+	}
+	if total != 2 {
+		t.Errorf("ListByUser() total = %v, want 2", total)
+	}
+	if len(list) != 2 {
+		t.Fatalf("ListByUser() len = %v, want 2", len(list))
+	}
+
+	_, total, _ = repo.ListByUser(ctx, uuid.New(), 10, 0)
+	if total != 0 {
+		t.Errorf("ListByUser() for other user total = %v, want 0", total)
+	}
+
+	other := domain.ContextWithTenant(ctx, "acme")
+	list, total, err = repo.ListByUser(other, userID, 10, 0)
+	if err != nil || total != 0 || len(list) != 0 {
+		t.Errorf("ListByUser() from other tenant = %v, %v, %v, want none", list, total, err)
+	}
+}
+
+func TestUserRepository_UpdateWithAudit(t *testing.T) {
+	if testPool == nil {
+		t.Skip("Database not available")
+	}
+	cleanupTestData(t)
+	ctx := context.Background()
+	if _, err := testPool.Exec(ctx, "DELETE FROM user_audit_log"); err != nil {
+		t.Fatalf("Failed to cleanup test data: %v", err)
+	}
+
+	repo := NewUserRepository(testPool)
+	audit := NewAuditRepository(testPool)
+	user := &domain.User{Email: "audited@example.com", FirstName: "John", LastName: "Doe", Status: domain.UserStatusActive}
+	if err := repo.Create(ctx, user); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	user.Status = domain.UserStatusInactive
+	err := repo.UpdateWithAudit(ctx, user, &domain.AuditEntry{
+		UserID:     user.ID,
+		Action:     domain.EventTypeUserDeactivated,
+		FromStatus: "unknown",
+		ToStatus:   domain.UserStatusInactive,
+	})
+	if err == nil {
+		t.Fatal("UpdateWithAudit() with invalid entry error = nil")
+	}
+	if stored, _ := repo.GetByID(ctx, user.ID); stored.Status != domain.UserStatusActive {
+		t.Errorf("status after failed audit = %v, want the update rolled back", stored.Status)
+	}
+
+	entry := &domain.AuditEntry{
+		UserID:     user.ID,
+		Action:     domain.EventTypeUserDeactivated,
+		FromStatus: domain.UserStatusActive,
+		ToStatus:   domain.UserStatusInactive,
+	}
+	if err := repo.UpdateWithAudit(ctx, user, entry); err != nil {
+		t.Fatalf("UpdateWithAudit() error = %v", err)
+	}
+	if stored, _ := repo.GetByID(ctx, user.ID); stored.Status != domain.UserStatusInactive {
+		t.Errorf("status = %v, want inactive", stored.Status)
+	}
+	if _, total, _ := audit.ListByUser(ctx, user.ID, 10, 0); total != 1 {
+		t.Errorf("audit entries = %v, want 1", total)
+	}
+}
@@ -19,14 +19,15 @@ func NewFailedEventRepository(pool *pgxpool.Pool) *FailedEventRepository {
 
 func (r *FailedEventRepository) Save(ctx context.Context, event *domain.FailedEvent) error {
 	query := `
-		INSERT INTO failed_events (event_id, event_type, user_id, payload, error, attempts, created_at, last_error)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		INSERT INTO failed_events (event_id, event_type, user_id, sink, payload, error, attempts, created_at, last_error)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 		RETURNING id`
 
 	err := r.pool.QueryRow(ctx, query,
 		event.EventID,
 		event.EventType,
 		event.UserID,
+		event.Sink,
 		event.Payload,
 		event.Error,
 		event.Attempts,
@@ -45,7 +46,7 @@ func (r *FailedEventRepository) List(ctx context.Context, limit, offset int) ([]
 	}
 
 	query := `
-		SELECT id, event_id, event_type, user_id, payload, error, attempts, created_at, last_error
+		SELECT id, event_id, event_type, user_id, sink, payload, error, attempts, created_at, last_error
 		FROM failed_events
 		ORDER BY created_at DESC
 		LIMIT $1 OFFSET $2`
@@ -64,6 +65,7 @@ func (r *FailedEventRepository) List(ctx context.Context, limit, offset int) ([]
 			&e.EventID,
 			&e.EventType,
 			&e.UserID,
+			&e.Sink,
 			&e.Payload,
 			&e.Error,
 			&e.Attempts,
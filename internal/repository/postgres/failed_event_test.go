@@ -52,6 +52,7 @@ func TestFailedEventRepository_Save(t *testing.T) {
 		EventID:   uuid.New(),
 		EventType: domain.EventTypeUserCreated,
 		UserID:    uuid.New(),
+		Sink:      "kafka",
 		Payload:   `{"test": "payload"}`,
 		Error:     "connection refused",
 		Attempts:  3,
@@ -81,6 +82,7 @@ func TestFailedEventRepository_List(t *testing.T) {
 			EventID:   uuid.New(),
 			EventType: domain.EventTypeUserCreated,
 			UserID:    uuid.New(),
+			Sink:      "webhooks",
 			Payload:   `{"test": "payload"}`,
 			Error:     "connection refused",
 			Attempts:  3,
@@ -105,6 +107,10 @@ func TestFailedEventRepository_List(t *testing.T) {
 		t.Errorf("List() len = %v, want 5", len(events))
 	}
 
+	if len(events) > 0 && events[0].Sink != "webhooks" {
+		t.Errorf("List() sink = %q, want webhooks", events[0].Sink)
+	}
+
 	events, _, err = repo.List(context.Background(), 2, 0)
 	if err != nil {
 		t.Fatalf("List() with limit error = %v", err)
@@ -126,6 +132,7 @@ func TestFailedEventRepository_Delete(t *testing.T) {
 		EventID:   uuid.New(),
 		EventType: domain.EventTypeUserCreated,
 		UserID:    uuid.New(),
+		Sink:      "kafka",
 		Payload:   `{"test": "payload"}`,
 		Error:     "connection refused",
 		Attempts:  3,
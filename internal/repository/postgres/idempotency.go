@@ -0,0 +1,94 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/giannuccilli/user-api/internal/domain"
+)
+
+type IdempotencyRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewIdempotencyRepository(pool *pgxpool.Pool) *IdempotencyRepository {
+	return &IdempotencyRepository{pool: pool}
+}
+
+func (r *IdempotencyRepository) Reserve(ctx context.Context, record *domain.IdempotencyRecord) (*domain.IdempotencyRecord, error) {
+	// An expired record is taken over in place so the key can be reused.
+	query := `
+		INSERT INTO idempotency_keys (tenant_id, actor_id, key, fingerprint, expires_at)
+		VALUES ($5, $1, $2, $3, $4)
+		ON CONFLICT (tenant_id, actor_id, key) DO UPDATE
+		SET fingerprint = EXCLUDED.fingerprint, status_code = NULL, headers = NULL, body = NULL,
+		    created_at = CURRENT_TIMESTAMP, expires_at = EXCLUDED.expires_at
+		WHERE idempotency_keys.expires_at <= CURRENT_TIMESTAMP
+		RETURNING created_at
+	`
+
+	err := r.pool.QueryRow(ctx, query, record.ActorID, record.Key, record.Fingerprint, record.ExpiresAt, tenantID(ctx)).
+		Scan(&record.CreatedAt)
+	if err == nil {
+		return nil, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return nil, err
+	}
+
+	existing := &domain.IdempotencyRecord{ActorID: record.ActorID, Key: record.Key}
+	var statusCode *int
+	err = r.pool.QueryRow(ctx, `
+		SELECT fingerprint, status_code, headers, body, created_at, expires_at
+		FROM idempotency_keys
+		WHERE tenant_id = $3 AND actor_id = $1 AND key = $2
+	`, record.ActorID, record.Key, tenantID(ctx)).Scan(
+		&existing.Fingerprint,
+		&statusCode,
+		&existing.Header,
+		&existing.Body,
+		&existing.CreatedAt,
+		&existing.ExpiresAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if statusCode != nil {
+		existing.StatusCode = *statusCode
+	}
+	return existing, nil
+}
+
+func (r *IdempotencyRepository) Complete(ctx context.Context, record *domain.IdempotencyRecord) error {
+	query := `
+		UPDATE idempotency_keys
+		SET status_code = $3, headers = $4, body = $5, expires_at = $6
+		WHERE tenant_id = $8 AND actor_id = $1 AND key = $2 AND created_at = $7 AND status_code IS NULL
+	`
+
+	_, err := r.pool.Exec(ctx, query, record.ActorID, record.Key, record.StatusCode, record.Header, record.Body,
+		record.ExpiresAt, record.CreatedAt, tenantID(ctx))
+	return err
+}
+
+func (r *IdempotencyRepository) Release(ctx context.Context, record *domain.IdempotencyRecord) error {
+	query := `
+		DELETE FROM idempotency_keys
+		WHERE tenant_id = $4 AND actor_id = $1 AND key = $2 AND created_at = $3 AND status_code IS NULL
+	`
+
+	_, err := r.pool.Exec(ctx, query, record.ActorID, record.Key, record.CreatedAt, tenantID(ctx))
+	return err
+}
+
+func (r *IdempotencyRepository) DeleteExpired(ctx context.Context, before time.Time) (int, error) {
+	result, err := r.pool.Exec(ctx, `DELETE FROM idempotency_keys WHERE expires_at <= $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return int(result.RowsAffected()), nil
+}
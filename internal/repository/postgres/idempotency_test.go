@@ -0,0 +1,88 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/giannuccilli/user-api/internal/domain"
+)
+
+func TestIdempotencyRepository_ReserveAndComplete(t *testing.T) {
+	if testPool == nil {
+		t.Skip("Database not available")
+	}
+	ctx := context.Background()
+	if _, err := testPool.Exec(ctx, "DELETE FROM idempotency_keys"); err != nil {
+		t.Fatalf("Failed to cleanup test data: %v", err)
+	}
+
+	repo := NewIdempotencyRepository(testPool)
+	newRecord := func(expiresAt time.Time) *domain.IdempotencyRecord {
+		return &domain.IdempotencyRecord{ActorID: "a", Key: "k1", Fingerprint: "fp", ExpiresAt: expiresAt}
+	}
+
+	reserved := newRecord(time.Now().Add(time.Minute))
+	existing, err := repo.Reserve(ctx, reserved)
+	if err != nil || existing != nil {
+		t.Fatalf("Reserve() = %v, %v, want reserved", existing, err)
+	}
+
+	existing, err = repo.Reserve(ctx, newRecord(time.Now().Add(time.Hour)))
+	if err != nil || existing == nil || existing.Completed() {
+		t.Fatalf("Reserve() again = %+v, %v, want in-flight record", existing, err)
+	}
+
+	stale := *reserved
+	stale.CreatedAt = reserved.CreatedAt.Add(-time.Second)
+	stale.StatusCode = 500
+	if err := repo.Complete(ctx, &stale); err != nil {
+		t.Fatalf("Complete() with another reservation error = %v", err)
+	}
+
+	record := *reserved
+	record.ExpiresAt = time.Now().Add(time.Hour)
+	record.StatusCode = 201
+	record.Header = map[string][]string{"Location": {"/api/v1/users/1"}}
+	record.Body = []byte(`{"id":"1"}`)
+	if err := repo.Complete(ctx, &record); err != nil {
+		t.Fatalf("Complete() error = %v", err)
+	}
+
+	existing, err = repo.Reserve(ctx, newRecord(time.Now().Add(time.Hour)))
+	if err != nil || existing == nil {
+		t.Fatalf("Reserve() after complete = %v, %v", existing, err)
+	}
+	if existing.StatusCode != 201 || string(existing.Body) != `{"id":"1"}` || existing.Header["Location"][0] != "/api/v1/users/1" {
+		t.Errorf("Reserve() returned %+v, want stored response", existing)
+	}
+
+	if err := repo.Release(ctx, &record); err != nil {
+		t.Fatalf("Release() error = %v", err)
+	}
+	if existing, err := repo.Reserve(ctx, newRecord(time.Now().Add(time.Hour))); err != nil || existing == nil {
+		t.Fatalf("Reserve() after release of a completed record = %v, %v, want stored response", existing, err)
+	}
+
+	if _, err := testPool.Exec(ctx, "DELETE FROM idempotency_keys"); err != nil {
+		t.Fatalf("Failed to cleanup test data: %v", err)
+	}
+	expired := newRecord(time.Now().Add(-time.Minute))
+	if existing, err := repo.Reserve(ctx, expired); err != nil || existing != nil {
+		t.Fatalf("Reserve() with expired lease = %v, %v, want reserved", existing, err)
+	}
+	if existing, err := repo.Reserve(ctx, newRecord(time.Now().Add(time.Hour))); err != nil || existing != nil {
+		t.Errorf("Reserve() over expired lease = %v, %v, want reserved", existing, err)
+	}
+	if err := repo.Release(ctx, expired); err != nil {
+		t.Fatalf("Release() of a lease taken over error = %v", err)
+	}
+	if existing, err := repo.Reserve(ctx, newRecord(time.Now().Add(time.Hour))); err != nil || existing == nil {
+		t.Errorf("Reserve() after stale release = %v, %v, want the new reservation", existing, err)
+	}
+
+	count, err := repo.DeleteExpired(ctx, time.Now().Add(2*time.Hour))
+	if err != nil || count != 1 {
+		t.Errorf("DeleteExpired() = %v, %v, want 1", count, err)
+	}
+}
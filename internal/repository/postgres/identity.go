@@ -0,0 +1,138 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/giannuccilli/user-api/internal/domain"
+)
+
+type IdentityRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewIdentityRepository(pool *pgxpool.Pool) *IdentityRepository {
+	return &IdentityRepository{pool: pool}
+}
+
+func (r *IdentityRepository) Link(ctx context.Context, identity *domain.ExternalIdentity) error {
+	// Selecting the user keeps identities from pointing at users of another
+	// tenant. No row is returned if the user is missing or the identity exists.
+	query := `
+		INSERT INTO user_identities (tenant_id, provider, external_id, user_id)
+		SELECT tenant_id, $1, $2, id FROM users WHERE id = $3 AND tenant_id = $4
+		ON CONFLICT (tenant_id, provider, external_id) DO NOTHING
+		RETURNING created_at
+	`
+
+	err := r.pool.QueryRow(ctx, query, identity.Provider, identity.ExternalID, identity.UserID, tenantID(ctx)).Scan(&identity.CreatedAt)
+	if err == nil {
+		return nil
+	}
+	if isForeignKeyError(err) {
+		return domain.ErrUserNotFound
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return err
+	}
+
+	existing, err := r.Get(ctx, identity.Provider, identity.ExternalID)
+	if errors.Is(err, domain.ErrIdentityNotFound) {
+		return domain.ErrUserNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if existing.UserID != identity.UserID {
+		return domain.ErrIdentityConflict
+	}
+	identity.CreatedAt = existing.CreatedAt
+	return nil
+}
+
+func (r *IdentityRepository) CreateUser(ctx context.Context, user *domain.User, identity *domain.ExternalIdentity) error {
+	query := `
+		INSERT INTO user_identities (tenant_id, provider, external_id, user_id)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (tenant_id, provider, external_id) DO NOTHING
+		RETURNING created_at
+	`
+
+	return pgx.BeginFunc(ctx, r.pool, func(tx pgx.Tx) error {
+		if err := createUser(ctx, tx, user); err != nil {
+			return err
+		}
+
+		identity.UserID = user.ID
+		err := tx.QueryRow(ctx, query, user.TenantID, identity.Provider, identity.ExternalID, user.ID).Scan(&identity.CreatedAt)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return domain.ErrIdentityConflict
+		}
+		return err
+	})
+}
+
+func (r *IdentityRepository) Unlink(ctx context.Context, userID uuid.UUID, provider, externalID string) error {
+	query := `DELETE FROM user_identities WHERE tenant_id = $4 AND user_id = $1 AND provider = $2 AND external_id = $3`
+
+	result, err := r.pool.Exec(ctx, query, userID, provider, externalID, tenantID(ctx))
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return domain.ErrIdentityNotFound
+	}
+	return nil
+}
+
+func (r *IdentityRepository) Get(ctx context.Context, provider, externalID string) (*domain.ExternalIdentity, error) {
+	query := `
+		SELECT user_id, provider, external_id, created_at
+		FROM user_identities
+		WHERE tenant_id = $3 AND provider = $1 AND external_id = $2
+	`
+
+	var identity domain.ExternalIdentity
+	err := r.pool.QueryRow(ctx, query, provider, externalID, tenantID(ctx)).
+		Scan(&identity.UserID, &identity.Provider, &identity.ExternalID, &identity.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrIdentityNotFound
+		}
+		return nil, err
+	}
+	return &identity, nil
+}
+
+func (r *IdentityRepository) ListByUser(ctx context.Context, userID uuid.UUID) ([]domain.ExternalIdentity, error) {
+	query := `
+		SELECT user_id, provider, external_id, created_at
+		FROM user_identities
+		WHERE tenant_id = $2 AND user_id = $1
+		ORDER BY provider, external_id
+	`
+
+	rows, err := r.pool.Query(ctx, query, userID, tenantID(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	identities := make([]domain.ExternalIdentity, 0)
+	for rows.Next() {
+		var identity domain.ExternalIdentity
+		if err := rows.Scan(&identity.UserID, &identity.Provider, &identity.ExternalID, &identity.CreatedAt); err != nil {
+			return nil, err
+		}
+		identities = append(identities, identity)
+	}
+	return identities, rows.Err()
+}
+
+func isForeignKeyError(err error) bool {
+	return err != nil && contains(err.Error(), "foreign key")
+}
@@ -0,0 +1,100 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/giannuccilli/user-api/internal/domain"
+)
+
+func TestIdentityRepository_LinkAndUnlink(t *testing.T) {
+	if testPool == nil {
+		t.Skip("Database not available")
+	}
+	cleanupTestData(t)
+
+	users := NewUserRepository(testPool)
+	repo := NewIdentityRepository(testPool)
+	ctx := context.Background()
+
+	alice := &domain.User{Email: "alice@example.com", FirstName: "Alice", LastName: "Doe", Status: domain.UserStatusActive}
+	bob := &domain.User{Email: "bob@example.com", FirstName: "Bob", LastName: "Doe", Status: domain.UserStatusActive}
+	for _, u := range []*domain.User{alice, bob} {
+		if err := users.Create(ctx, u); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		userID  uuid.UUID
+		wantErr error
+	}{
+		{"link", alice.ID, nil},
+		{"relink same user", alice.ID, nil},
+		{"linked to another user", bob.ID, domain.ErrIdentityConflict},
+		{"unknown user", uuid.New(), domain.ErrUserNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			identity := &domain.ExternalIdentity{UserID: tt.userID, Provider: "okta", ExternalID: "00u1"}
+			err := repo.Link(ctx, identity)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Link() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	got, err := repo.Get(ctx, "okta", "00u1")
+	if err != nil || got.UserID != alice.ID {
+		t.Fatalf("Get() = %v, %v, want alice", got, err)
+	}
+
+	identities, err := repo.ListByUser(ctx, alice.ID)
+	if err != nil || len(identities) != 1 {
+		t.Errorf("ListByUser() = %v, %v, want 1 identity", identities, err)
+	}
+
+	if err := repo.Unlink(ctx, bob.ID, "okta", "00u1"); !errors.Is(err, domain.ErrIdentityNotFound) {
+		t.Errorf("Unlink() by other user error = %v, want %v", err, domain.ErrIdentityNotFound)
+	}
+	if err := repo.Unlink(ctx, alice.ID, "okta", "00u1"); err != nil {
+		t.Errorf("Unlink() error = %v", err)
+	}
+	if _, err := repo.Get(ctx, "okta", "00u1"); !errors.Is(err, domain.ErrIdentityNotFound) {
+		t.Errorf("Get() after unlink error = %v, want %v", err, domain.ErrIdentityNotFound)
+	}
+}
+
+func TestIdentityRepository_CreateUser(t *testing.T) {
+	if testPool == nil {
+		t.Skip("Database not available")
+	}
+	cleanupTestData(t)
+
+	users := NewUserRepository(testPool)
+	repo := NewIdentityRepository(testPool)
+	ctx := context.Background()
+
+	alice := &domain.User{Email: "alice@example.com", FirstName: "Alice", LastName: "Doe", Status: domain.UserStatusActive}
+	identity := &domain.ExternalIdentity{Provider: "okta", ExternalID: "00u1"}
+	if err := repo.CreateUser(ctx, alice, identity); err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	if linked, err := repo.Get(ctx, "okta", "00u1"); err != nil || linked.UserID != alice.ID {
+		t.Errorf("Get() = %+v, %v, want linked to the new user", linked, err)
+	}
+
+	bob := &domain.User{Email: "bob@example.com", FirstName: "Bob", LastName: "Doe", Status: domain.UserStatusActive}
+	err := repo.CreateUser(ctx, bob, &domain.ExternalIdentity{Provider: "okta", ExternalID: "00u1"})
+	if err != domain.ErrIdentityConflict {
+		t.Fatalf("CreateUser() for a linked identity error = %v, want %v", err, domain.ErrIdentityConflict)
+	}
+	if _, err := users.GetByEmail(ctx, "bob@example.com"); err != domain.ErrUserNotFound {
+		t.Errorf("GetByEmail() after conflict error = %v, want the user rolled back", err)
+	}
+}
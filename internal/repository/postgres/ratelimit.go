@@ -0,0 +1,65 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/giannuccilli/user-api/internal/ratelimit"
+)
+
+// RateLimitStore shares token buckets between replicas. Each Take locks the
+// bucket row, so concurrent requests of the same client are serialized, and
+// uses the database clock so replica clock skew does not matter.
+type RateLimitStore struct {
+	pool *pgxpool.Pool
+}
+
+func NewRateLimitStore(pool *pgxpool.Pool) *RateLimitStore {
+	return &RateLimitStore{pool: pool}
+}
+
+func (s *RateLimitStore) Take(ctx context.Context, key string, limit ratelimit.Limit) (ratelimit.Result, error) {
+	var result ratelimit.Result
+
+	err := pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO rate_limit_buckets (key, tokens, updated_at)
+			VALUES ($1, $2, clock_timestamp())
+			ON CONFLICT (key) DO NOTHING
+		`, key, float64(limit.Requests))
+		if err != nil {
+			return err
+		}
+
+		var bucket ratelimit.Bucket
+		var now time.Time
+		err = tx.QueryRow(ctx, `
+			SELECT tokens, updated_at, clock_timestamp() FROM rate_limit_buckets WHERE key = $1 FOR UPDATE
+		`, key).Scan(&bucket.Tokens, &bucket.UpdatedAt, &now)
+		if err != nil {
+			return err
+		}
+
+		result = bucket.Take(limit, now)
+
+		_, err = tx.Exec(ctx, `
+			UPDATE rate_limit_buckets SET tokens = $2, updated_at = $3 WHERE key = $1
+		`, key, bucket.Tokens, bucket.UpdatedAt)
+		return err
+	})
+
+	return result, err
+}
+
+// DeleteIdle removes buckets not used since before. Their clients start
+// again with a full bucket, which is what they would have refilled to.
+func (s *RateLimitStore) DeleteIdle(ctx context.Context, before time.Time) (int, error) {
+	result, err := s.pool.Exec(ctx, `DELETE FROM rate_limit_buckets WHERE updated_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return int(result.RowsAffected()), nil
+}
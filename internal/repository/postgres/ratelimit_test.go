@@ -0,0 +1,42 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/giannuccilli/user-api/internal/ratelimit"
+)
+
+func TestRateLimitStore_Take(t *testing.T) {
+	if testPool == nil {
+		t.Skip("Database not available")
+	}
+	ctx := context.Background()
+	if _, err := testPool.Exec(ctx, "DELETE FROM rate_limit_buckets"); err != nil {
+		t.Fatalf("Failed to cleanup test data: %v", err)
+	}
+
+	store := NewRateLimitStore(testPool)
+	limit := ratelimit.Limit{Requests: 2, Per: time.Hour}
+
+	for i, wantAllowed := range []bool{true, true, false} {
+		result, err := store.Take(ctx, "k1", limit)
+		if err != nil {
+			t.Fatalf("Take() #%d error = %v", i, err)
+		}
+		if result.Allowed != wantAllowed {
+			t.Errorf("Take() #%d allowed = %v, want %v", i, result.Allowed, wantAllowed)
+		}
+	}
+
+	result, err := store.Take(ctx, "k2", limit)
+	if err != nil || !result.Allowed || result.Remaining != 1 {
+		t.Errorf("Take() other key = %+v, %v, want allowed with 1 remaining", result, err)
+	}
+
+	deleted, err := store.DeleteIdle(ctx, time.Now().Add(time.Minute))
+	if err != nil || deleted != 2 {
+		t.Errorf("DeleteIdle() = %v, %v, want 2", deleted, err)
+	}
+}
@@ -0,0 +1,134 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/giannuccilli/user-api/internal/domain"
+)
+
+type TenantRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewTenantRepository(pool *pgxpool.Pool) *TenantRepository {
+	return &TenantRepository{pool: pool}
+}
+
+func (r *TenantRepository) Create(ctx context.Context, tenant *domain.Tenant) error {
+	query := `
+		INSERT INTO tenants (id, name)
+		VALUES ($1, $2)
+		RETURNING created_at, updated_at`
+
+	err := r.pool.QueryRow(ctx, query, tenant.ID, tenant.Name).Scan(&tenant.CreatedAt, &tenant.UpdatedAt)
+	if isDuplicateKeyError(err) {
+		return domain.ErrTenantExists
+	}
+	return err
+}
+
+func (r *TenantRepository) Get(ctx context.Context, id string) (*domain.Tenant, error) {
+	query := `
+		SELECT id, name, created_at, updated_at
+		FROM tenants
+		WHERE id = $1`
+
+	var tenant domain.Tenant
+	err := r.pool.QueryRow(ctx, query, id).Scan(&tenant.ID, &tenant.Name, &tenant.CreatedAt, &tenant.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrTenantNotFound
+		}
+		return nil, err
+	}
+
+	return &tenant, nil
+}
+
+func (r *TenantRepository) List(ctx context.Context) ([]domain.Tenant, error) {
+	query := `
+		SELECT id, name, created_at, updated_at
+		FROM tenants
+		ORDER BY id`
+
+	rows, err := r.pool.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tenants := make([]domain.Tenant, 0)
+	for rows.Next() {
+		var tenant domain.Tenant
+		if err := rows.Scan(&tenant.ID, &tenant.Name, &tenant.CreatedAt, &tenant.UpdatedAt); err != nil {
+			return nil, err
+		}
+		tenants = append(tenants, tenant)
+	}
+
+	return tenants, rows.Err()
+}
+
+func (r *TenantRepository) Update(ctx context.Context, tenant *domain.Tenant) error {
+	query := `
+		UPDATE tenants
+		SET name = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+		RETURNING created_at, updated_at`
+
+	err := r.pool.QueryRow(ctx, query, tenant.ID, tenant.Name).Scan(&tenant.CreatedAt, &tenant.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.ErrTenantNotFound
+	}
+	return err
+}
+
+func (r *TenantRepository) Delete(ctx context.Context, id string) error {
+	result, err := r.pool.Exec(ctx, `DELETE FROM tenants WHERE id = $1`, id)
+	if err != nil {
+		if isForeignKeyError(err) {
+			return domain.ErrTenantNotEmpty
+		}
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return domain.ErrTenantNotFound
+	}
+
+	return nil
+}
+
+// EnableRowLevelSecurity makes every connection acquired from the pool set
+// app.tenant_id to the tenant of the acquiring context, which the row-level
+// security policies on the user tables filter by. A context without a tenant
+// matches no rows unless it was marked with domain.ContextWithAllTenants, in
+// which case app.all_tenants is set instead.
+func EnableRowLevelSecurity(cfg *pgxpool.Config) {
+	cfg.PrepareConn = func(ctx context.Context, conn *pgx.Conn) (bool, error) {
+		tenant, _ := domain.TenantFromContext(ctx)
+		allTenants := "off"
+		if domain.AllTenantsFromContext(ctx) {
+			allTenants = "on"
+		}
+		if _, err := conn.Exec(ctx,
+			`SELECT set_config('app.tenant_id', $1, false), set_config('app.all_tenants', $2, false)`,
+			tenant, allTenants,
+		); err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+}
+
+// tenantID returns the tenant that queries on behalf of ctx are scoped to.
+func tenantID(ctx context.Context) string {
+	if id, ok := domain.TenantFromContext(ctx); ok {
+		return id
+	}
+	return domain.DefaultTenantID
+}
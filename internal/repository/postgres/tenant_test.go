@@ -0,0 +1,118 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/giannuccilli/user-api/internal/domain"
+)
+
+func TestTenantRepository_CRUD(t *testing.T) {
+	if testPool == nil {
+		t.Skip("Database not available")
+	}
+	cleanupTestData(t)
+
+	repo := NewTenantRepository(testPool)
+	ctx := context.Background()
+
+	tenant := &domain.Tenant{ID: "acme", Name: "Acme"}
+	if err := repo.Create(ctx, tenant); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if tenant.CreatedAt.IsZero() {
+		t.Error("Create() did not set CreatedAt")
+	}
+	if err := repo.Create(ctx, &domain.Tenant{ID: "acme", Name: "Other"}); !errors.Is(err, domain.ErrTenantExists) {
+		t.Errorf("Create() duplicate error = %v, want %v", err, domain.ErrTenantExists)
+	}
+
+	tenant.Name = "Acme Corp"
+	if err := repo.Update(ctx, tenant); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	got, err := repo.Get(ctx, "acme")
+	if err != nil || got.Name != "Acme Corp" {
+		t.Errorf("Get() = %+v, %v", got, err)
+	}
+
+	tenants, err := repo.List(ctx)
+	if err != nil || len(tenants) != 2 {
+		t.Errorf("List() = %v, %v, want default and acme", tenants, err)
+	}
+
+	users := NewUserRepository(testPool)
+	user := &domain.User{Email: "john@example.com", FirstName: "John", LastName: "Doe", Status: domain.UserStatusActive}
+	if err := users.Create(domain.ContextWithTenant(ctx, "acme"), user); err != nil {
+		t.Fatalf("Create() user error = %v", err)
+	}
+	if err := repo.Delete(ctx, "acme"); !errors.Is(err, domain.ErrTenantNotEmpty) {
+		t.Errorf("Delete() with users error = %v, want %v", err, domain.ErrTenantNotEmpty)
+	}
+
+	if err := users.Delete(domain.ContextWithTenant(ctx, "acme"), user.ID); err != nil {
+		t.Fatalf("Delete() user error = %v", err)
+	}
+	if err := repo.Delete(ctx, "acme"); err != nil {
+		t.Errorf("Delete() error = %v", err)
+	}
+	if _, err := repo.Get(ctx, "acme"); !errors.Is(err, domain.ErrTenantNotFound) {
+		t.Errorf("Get() after delete error = %v, want %v", err, domain.ErrTenantNotFound)
+	}
+}
+
+func TestUserRepository_TenantIsolation(t *testing.T) {
+	if testPool == nil {
+		t.Skip("Database not available")
+	}
+	cleanupTestData(t)
+
+	ctx := context.Background()
+	if err := NewTenantRepository(testPool).Create(ctx, &domain.Tenant{ID: "acme", Name: "Acme"}); err != nil {
+		t.Fatalf("Create() tenant error = %v", err)
+	}
+	acme := domain.ContextWithTenant(ctx, "acme")
+	repo := NewUserRepository(testPool)
+
+	newUser := func() *domain.User {
+		return &domain.User{Email: "john@example.com", FirstName: "John", LastName: "Doe", Status: domain.UserStatusActive}
+	}
+
+	defaultUser := newUser()
+	if err := repo.Create(ctx, defaultUser); err != nil {
+		t.Fatalf("Create() default tenant error = %v", err)
+	}
+	acmeUser := newUser()
+	if err := repo.Create(acme, acmeUser); err != nil {
+		t.Fatalf("Create() same email in another tenant error = %v", err)
+	}
+	if defaultUser.TenantID != domain.DefaultTenantID || acmeUser.TenantID != "acme" {
+		t.Errorf("TenantID = %q, %q", defaultUser.TenantID, acmeUser.TenantID)
+	}
+
+	if err := repo.Create(domain.ContextWithTenant(ctx, "missing"), newUser()); !errors.Is(err, domain.ErrTenantNotFound) {
+		t.Errorf("Create() unknown tenant error = %v, want %v", err, domain.ErrTenantNotFound)
+	}
+
+	if _, err := repo.GetByID(acme, defaultUser.ID); !errors.Is(err, domain.ErrUserNotFound) {
+		t.Errorf("GetByID() across tenants error = %v, want %v", err, domain.ErrUserNotFound)
+	}
+	got, err := repo.GetByEmail(acme, "john@example.com")
+	if err != nil || got.ID != acmeUser.ID {
+		t.Errorf("GetByEmail() = %v, %v, want the acme user", got, err)
+	}
+
+	users, total, err := repo.List(acme, domain.UserFilter{Limit: 10})
+	if err != nil || total != 1 || users[0].ID != acmeUser.ID {
+		t.Errorf("List() = %v, %d, %v, want only the acme user", users, total, err)
+	}
+
+	defaultUser.FirstName = "Jane"
+	if err := repo.Update(acme, defaultUser); !errors.Is(err, domain.ErrUserNotFound) {
+		t.Errorf("Update() across tenants error = %v, want %v", err, domain.ErrUserNotFound)
+	}
+	if err := repo.Delete(acme, defaultUser.ID); !errors.Is(err, domain.ErrUserNotFound) {
+		t.Errorf("Delete() across tenants error = %v, want %v", err, domain.ErrUserNotFound)
+	}
+}
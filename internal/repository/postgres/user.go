@@ -3,6 +3,9 @@ package postgres
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
@@ -11,6 +14,8 @@ import (
 	"github.com/giannuccilli/user-api/internal/domain"
 )
 
+const userColumns = `id, tenant_id, email, email_canonical, email_verified, COALESCE(pending_email, ''), first_name, last_name, status, COALESCE(status_reason, ''), suspended_until, attributes, created_at, updated_at`
+
 type UserRepository struct {
 	pool *pgxpool.Pool
 }
@@ -20,47 +25,52 @@ func NewUserRepository(pool *pgxpool.Pool) *UserRepository {
 }
 
 func (r *UserRepository) Create(ctx context.Context, user *domain.User) error {
+	return pgx.BeginFunc(ctx, r.pool, func(tx pgx.Tx) error {
+		return createUser(ctx, tx, user)
+	})
+}
+
+func createUser(ctx context.Context, tx pgx.Tx, user *domain.User) error {
 	query := `
-		INSERT INTO users (email, first_name, last_name, status)
-		VALUES ($1, $2, $3, $4)
-		RETURNING id, created_at, updated_at
+		INSERT INTO users (email, email_canonical, email_verified, first_name, last_name, status, status_reason, suspended_until, attributes, tenant_id)
+		VALUES ($1, COALESCE(NULLIF($2, ''), $1), $3, $4, $5, $6, NULLIF($7, ''), $8, $9, $10)
+		RETURNING id, tenant_id, email_canonical, created_at, updated_at
 	`
 
-	err := r.pool.QueryRow(ctx, query,
+	err := tx.QueryRow(ctx, query,
 		user.Email,
+		user.EmailCanonical,
+		user.EmailVerified,
 		user.FirstName,
 		user.LastName,
 		user.Status,
-	).Scan(&user.ID, &user.CreatedAt, &user.UpdatedAt)
+		user.StatusReason,
+		user.SuspendedUntil,
+		attributesOrEmpty(user.Attributes),
+		tenantID(ctx),
+	).Scan(&user.ID, &user.TenantID, &user.EmailCanonical, &user.CreatedAt, &user.UpdatedAt)
 
 	if err != nil {
 		if isDuplicateKeyError(err) {
 			return domain.ErrEmailExists
 		}
+		if isForeignKeyError(err) {
+			return domain.ErrTenantNotFound
+		}
 		return err
 	}
 
-	return nil
+	return insertHistory(ctx, tx, user)
 }
 
 func (r *UserRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.User, error) {
 	query := `
-		SELECT id, email, first_name, last_name, status, created_at, updated_at
+		SELECT ` + userColumns + `
 		FROM users
-		WHERE id = $1
+		WHERE id = $1 AND tenant_id = $2
 	`
 
-	user := &domain.User{}
-	err := r.pool.QueryRow(ctx, query, id).Scan(
-		&user.ID,
-		&user.Email,
-		&user.FirstName,
-		&user.LastName,
-		&user.Status,
-		&user.CreatedAt,
-		&user.UpdatedAt,
-	)
-
+	user, err := scanUser(r.pool.QueryRow(ctx, query, id, tenantID(ctx)))
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, domain.ErrUserNotFound
@@ -71,24 +81,54 @@ func (r *UserRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Use
 	return user, nil
 }
 
+func (r *UserRepository) GetByIDs(ctx context.Context, ids []uuid.UUID, fields domain.FieldSet) ([]domain.User, error) {
+	columns, err := selectUserFields(fields)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT ` + columns + `
+		FROM users
+		WHERE id = ANY($1) AND tenant_id = $2
+		ORDER BY array_position($1, id)
+	`
+
+	rows, err := r.pool.Query(ctx, query, ids, tenantID(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanUsers(rows, fields)
+}
+
 func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*domain.User, error) {
 	query := `
-		SELECT id, email, first_name, last_name, status, created_at, updated_at
+		SELECT ` + userColumns + `
 		FROM users
-		WHERE email = $1
+		WHERE email = $1 AND tenant_id = $2
 	`
 
-	user := &domain.User{}
-	err := r.pool.QueryRow(ctx, query, email).Scan(
-		&user.ID,
-		&user.Email,
-		&user.FirstName,
-		&user.LastName,
-		&user.Status,
-		&user.CreatedAt,
-		&user.UpdatedAt,
-	)
+	user, err := scanUser(r.pool.QueryRow(ctx, query, email, tenantID(ctx)))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	return user, nil
+}
 
+func (r *UserRepository) GetByCanonicalEmail(ctx context.Context, canonical string) (*domain.User, error) {
+	query := `
+		SELECT ` + userColumns + `
+		FROM users
+		WHERE email_canonical = $1 AND tenant_id = $2
+	`
+
+	user, err := scanUser(r.pool.QueryRow(ctx, query, canonical, tenantID(ctx)))
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, domain.ErrUserNotFound
@@ -99,44 +139,41 @@ func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*domain.
 	return user, nil
 }
 
-func (r *UserRepository) List(ctx context.Context, limit, offset int) ([]domain.User, int, error) {
-	countQuery := `SELECT COUNT(*) FROM users`
+func (r *UserRepository) List(ctx context.Context, filter domain.UserFilter) ([]domain.User, int, error) {
+	where := "WHERE tenant_id = $1"
+	args := []any{tenantID(ctx)}
+	if len(filter.Attributes) > 0 {
+		where += " AND attributes @> $2"
+		args = append(args, filter.Attributes)
+	}
+
+	countQuery := `SELECT COUNT(*) FROM users ` + where
 	var total int
-	if err := r.pool.QueryRow(ctx, countQuery).Scan(&total); err != nil {
+	if err := r.pool.QueryRow(ctx, countQuery, args...).Scan(&total); err != nil {
 		return nil, 0, err
 	}
 
-	query := `
-		SELECT id, email, first_name, last_name, status, created_at, updated_at
+	columns, err := selectUserFields(filter.Fields)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	query := fmt.Sprintf(`
+		SELECT `+columns+`
 		FROM users
+		%s
 		ORDER BY created_at DESC
-		LIMIT $1 OFFSET $2
-	`
+		LIMIT $%d OFFSET $%d
+	`, where, len(args)+1, len(args)+2)
 
-	rows, err := r.pool.Query(ctx, query, limit, offset)
+	rows, err := r.pool.Query(ctx, query, append(args, filter.Limit, filter.Offset)...)
 	if err != nil {
 		return nil, 0, err
 	}
 	defer rows.Close()
 
-	users := make([]domain.User, 0)
-	for rows.Next() {
-		var user domain.User
-		if err := rows.Scan(
-			&user.ID,
-			&user.Email,
-			&user.FirstName,
-			&user.LastName,
-			&user.Status,
-			&user.CreatedAt,
-			&user.UpdatedAt,
-		); err != nil {
-			return nil, 0, err
-		}
-		users = append(users, user)
-	}
-
-	if err := rows.Err(); err != nil {
+	users, err := scanUsers(rows, filter.Fields)
+	if err != nil {
 		return nil, 0, err
 	}
 
@@ -144,20 +181,46 @@ func (r *UserRepository) List(ctx context.Context, limit, offset int) ([]domain.
 }
 
 func (r *UserRepository) Update(ctx context.Context, user *domain.User) error {
+	return pgx.BeginFunc(ctx, r.pool, func(tx pgx.Tx) error {
+		return updateUser(ctx, tx, user)
+	})
+}
+
+// UpdateWithAudit saves entry in the same transaction as the update, so that
+// no status change is stored without its audit entry.
+func (r *UserRepository) UpdateWithAudit(ctx context.Context, user *domain.User, entry *domain.AuditEntry) error {
+	return pgx.BeginFunc(ctx, r.pool, func(tx pgx.Tx) error {
+		if err := updateUser(ctx, tx, user); err != nil {
+			return err
+		}
+		return saveAudit(ctx, tx, entry)
+	})
+}
+
+func updateUser(ctx context.Context, tx pgx.Tx, user *domain.User) error {
 	query := `
 		UPDATE users
-		SET email = $1, first_name = $2, last_name = $3, status = $4, updated_at = CURRENT_TIMESTAMP
-		WHERE id = $5
-		RETURNING updated_at
+		SET email = $1, email_canonical = COALESCE(NULLIF($2, ''), $1), email_verified = $3, pending_email = NULLIF($4, ''),
+		    first_name = $5, last_name = $6, status = $7, status_reason = NULLIF($8, ''), suspended_until = $9,
+		    attributes = $10, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $11 AND tenant_id = $12
+		RETURNING tenant_id, email_canonical, updated_at
 	`
 
-	err := r.pool.QueryRow(ctx, query,
+	err := tx.QueryRow(ctx, query,
 		user.Email,
+		user.EmailCanonical,
+		user.EmailVerified,
+		user.PendingEmail,
 		user.FirstName,
 		user.LastName,
 		user.Status,
+		user.StatusReason,
+		user.SuspendedUntil,
+		attributesOrEmpty(user.Attributes),
 		user.ID,
-	).Scan(&user.UpdatedAt)
+		tenantID(ctx),
+	).Scan(&user.TenantID, &user.EmailCanonical, &user.UpdatedAt)
 
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -169,22 +232,358 @@ func (r *UserRepository) Update(ctx context.Context, user *domain.User) error {
 		return err
 	}
 
-	return nil
+	if err := closeHistory(ctx, tx, user.ID, user.UpdatedAt); err != nil {
+		return err
+	}
+
+	return insertHistory(ctx, tx, user)
+}
+
+func (r *UserRepository) Upsert(ctx context.Context, user *domain.User) (domain.UpsertResult, error) {
+	patch, removed := splitAttributes(user.Attributes)
+
+	// The WHERE clause skips the update when nothing would change, in which
+	// case no row is returned. xmax is 0 only for freshly inserted rows.
+	query := `
+		INSERT INTO users (email, email_canonical, email_verified, first_name, last_name, status, attributes, tenant_id)
+		VALUES ($1, COALESCE(NULLIF($2, ''), $1), $3, $4, $5, $6, $7, $9)
+		ON CONFLICT (tenant_id, email_canonical) DO UPDATE
+		SET first_name = EXCLUDED.first_name, last_name = EXCLUDED.last_name,
+		    attributes = (users.attributes || EXCLUDED.attributes) - $8::text[],
+		    updated_at = CURRENT_TIMESTAMP
+		WHERE (users.first_name, users.last_name, users.attributes) IS DISTINCT FROM
+		      (EXCLUDED.first_name, EXCLUDED.last_name, (users.attributes || EXCLUDED.attributes) - $8::text[])
+		RETURNING id, xmax = 0
+	`
+
+	result := domain.UpsertUnchanged
+	err := pgx.BeginFunc(ctx, r.pool, func(tx pgx.Tx) error {
+		var id uuid.UUID
+		var inserted bool
+		err := tx.QueryRow(ctx, query,
+			user.Email,
+			user.EmailCanonical,
+			user.EmailVerified,
+			user.FirstName,
+			user.LastName,
+			user.Status,
+			patch,
+			removed,
+			tenantID(ctx),
+		).Scan(&id, &inserted)
+
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			stored, err := scanUser(tx.QueryRow(ctx, `SELECT `+userColumns+` FROM users WHERE tenant_id = $3 AND email_canonical = COALESCE(NULLIF($2, ''), $1)`,
+				user.Email, user.EmailCanonical, tenantID(ctx)))
+			if err != nil {
+				return err
+			}
+			*user = *stored
+			return nil
+		case err != nil:
+			if isDuplicateKeyError(err) {
+				return domain.ErrEmailExists
+			}
+			if isForeignKeyError(err) {
+				return domain.ErrTenantNotFound
+			}
+			return err
+		}
+
+		stored, err := scanUser(tx.QueryRow(ctx, `SELECT `+userColumns+` FROM users WHERE id = $1`, id))
+		if err != nil {
+			return err
+		}
+		*user = *stored
+
+		if inserted {
+			result = domain.UpsertCreated
+		} else {
+			result = domain.UpsertUpdated
+			if err := closeHistory(ctx, tx, user.ID, user.UpdatedAt); err != nil {
+				return err
+			}
+		}
+		return insertHistory(ctx, tx, user)
+	})
+	if err != nil {
+		return domain.UpsertUnchanged, err
+	}
+	return result, nil
 }
 
 func (r *UserRepository) Delete(ctx context.Context, id uuid.UUID) error {
-	query := `DELETE FROM users WHERE id = $1`
+	// The history is closed with the database clock, like in Update, so that
+	// the periods of a user neither overlap nor leave gaps.
+	query := `DELETE FROM users WHERE id = $1 AND tenant_id = $2 RETURNING CURRENT_TIMESTAMP`
+
+	return pgx.BeginFunc(ctx, r.pool, func(tx pgx.Tx) error {
+		var deletedAt time.Time
+		err := tx.QueryRow(ctx, query, id, tenantID(ctx)).Scan(&deletedAt)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return domain.ErrUserNotFound
+			}
+			return err
+		}
 
-	result, err := r.pool.Exec(ctx, query, id)
+		return closeHistory(ctx, tx, id, deletedAt)
+	})
+}
+
+func (r *UserRepository) GetAsOf(ctx context.Context, id uuid.UUID, asOf time.Time) (*domain.User, error) {
+	query := `
+		SELECT user_id, tenant_id, email, email, email_verified, '', first_name, last_name, status, COALESCE(status_reason, ''), suspended_until, attributes, created_at, updated_at
+		FROM user_history
+		WHERE user_id = $1
+		  AND tenant_id = $3
+		  AND valid_from <= $2
+		  AND (valid_to IS NULL OR valid_to > $2)
+	`
+
+	user, err := scanUser(r.pool.QueryRow(ctx, query, id, asOf, tenantID(ctx)))
 	if err != nil {
-		return err
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrUserNotFound
+		}
+		return nil, err
 	}
 
-	if result.RowsAffected() == 0 {
-		return domain.ErrUserNotFound
+	return user, nil
+}
+
+// ListExpiredSuspensions looks across all tenants; it is used by the
+// reactivation job, not by requests. With row-level security enabled, ctx must
+// be marked with domain.ContextWithAllTenants.
+func (r *UserRepository) ListExpiredSuspensions(ctx context.Context, before time.Time, limit int) ([]domain.User, error) {
+	query := `
+		SELECT ` + userColumns + `
+		FROM users
+		WHERE status = 'suspended'
+		  AND suspended_until IS NOT NULL
+		  AND suspended_until <= $1
+		ORDER BY suspended_until
+		LIMIT $2
+	`
+
+	rows, err := r.pool.Query(ctx, query, before, limit)
+	if err != nil {
+		return nil, err
 	}
+	defer rows.Close()
+
+	return scanUsers(rows, nil)
+}
+
+func (r *UserRepository) ReactivateSuspension(ctx context.Context, user *domain.User, before time.Time, entry *domain.AuditEntry) (bool, error) {
+	query := `
+		UPDATE users
+		SET status = 'active', status_reason = NULL, suspended_until = NULL, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND tenant_id = $2
+		  AND status = 'suspended'
+		  AND suspended_until IS NOT NULL
+		  AND suspended_until <= $3
+		RETURNING ` + userColumns
+
+	reactivated := false
+	err := pgx.BeginFunc(ctx, r.pool, func(tx pgx.Tx) error {
+		updated, err := scanUser(tx.QueryRow(ctx, query, user.ID, tenantID(ctx), before))
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return nil
+			}
+			return err
+		}
+
+		if err := closeHistory(ctx, tx, updated.ID, updated.UpdatedAt); err != nil {
+			return err
+		}
+		if err := insertHistory(ctx, tx, updated); err != nil {
+			return err
+		}
+		if entry != nil {
+			if err := saveAudit(ctx, tx, entry); err != nil {
+				return err
+			}
+		}
 
-	return nil
+		*user = *updated
+		reactivated = true
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+	return reactivated, nil
+}
+
+func scanUser(row pgx.Row) (*domain.User, error) {
+	user := &domain.User{}
+	err := row.Scan(
+		&user.ID,
+		&user.TenantID,
+		&user.Email,
+		&user.EmailCanonical,
+		&user.EmailVerified,
+		&user.PendingEmail,
+		&user.FirstName,
+		&user.LastName,
+		&user.Status,
+		&user.StatusReason,
+		&user.SuspendedUntil,
+		&user.Attributes,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// scanUsers reads rows selected with selectUserFields(fields).
+func scanUsers(rows pgx.Rows, fields domain.FieldSet) ([]domain.User, error) {
+	users := make([]domain.User, 0)
+	for rows.Next() {
+		user, err := scanUserFields(rows, fields)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, *user)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+// selectUserFields returns the select list for fields, or userColumns when
+// fields is nil.
+func selectUserFields(fields domain.FieldSet) (string, error) {
+	if fields == nil {
+		return userColumns, nil
+	}
+
+	columns := make([]string, len(fields))
+	for i, name := range fields {
+		column, _ := userField(&domain.User{}, name)
+		if column == "" {
+			return "", fmt.Errorf("unknown user field %q", name)
+		}
+		columns[i] = column
+	}
+	return strings.Join(columns, ", "), nil
+}
+
+func scanUserFields(row pgx.Row, fields domain.FieldSet) (*domain.User, error) {
+	if fields == nil {
+		return scanUser(row)
+	}
+
+	user := &domain.User{}
+	dest := make([]any, len(fields))
+	for i, name := range fields {
+		_, dest[i] = userField(user, name)
+	}
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// userField maps a domain.UserFields name to its column and the field of user
+// it is scanned into. The column is empty for unknown names.
+func userField(user *domain.User, name string) (string, any) {
+	switch name {
+	case "id":
+		return "id", &user.ID
+	case "tenantId":
+		return "tenant_id", &user.TenantID
+	case "email":
+		return "email", &user.Email
+	case "emailVerified":
+		return "email_verified", &user.EmailVerified
+	case "pendingEmail":
+		return "COALESCE(pending_email, '')", &user.PendingEmail
+	case "firstName":
+		return "first_name", &user.FirstName
+	case "lastName":
+		return "last_name", &user.LastName
+	case "status":
+		return "status", &user.Status
+	case "statusReason":
+		return "COALESCE(status_reason, '')", &user.StatusReason
+	case "suspendedUntil":
+		return "suspended_until", &user.SuspendedUntil
+	case "attributes":
+		return "attributes", &user.Attributes
+	case "createdAt":
+		return "created_at", &user.CreatedAt
+	case "updatedAt":
+		return "updated_at", &user.UpdatedAt
+	default:
+		return "", nil
+	}
+}
+
+func insertHistory(ctx context.Context, tx pgx.Tx, user *domain.User) error {
+	query := `
+		INSERT INTO user_history (user_id, tenant_id, email, email_verified, first_name, last_name, status, status_reason,
+		                          suspended_until, attributes, created_at, updated_at, valid_from)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''), $9, $10, $11, $12, $12)
+	`
+
+	_, err := tx.Exec(ctx, query,
+		user.ID,
+		user.TenantID,
+		user.Email,
+		user.EmailVerified,
+		user.FirstName,
+		user.LastName,
+		user.Status,
+		user.StatusReason,
+		user.SuspendedUntil,
+		attributesOrEmpty(user.Attributes),
+		user.CreatedAt,
+		user.UpdatedAt,
+	)
+	return err
+}
+
+func closeHistory(ctx context.Context, tx pgx.Tx, id uuid.UUID, validTo time.Time) error {
+	query := `
+		UPDATE user_history
+		SET valid_to = $2
+		WHERE user_id = $1 AND valid_to IS NULL
+	`
+
+	_, err := tx.Exec(ctx, query, id, validTo)
+	return err
+}
+
+func attributesOrEmpty(attrs domain.Attributes) domain.Attributes {
+	if attrs == nil {
+		return domain.Attributes{}
+	}
+	return attrs
+}
+
+// splitAttributes separates a merge patch into the values to set and the keys
+// to remove.
+func splitAttributes(attrs domain.Attributes) (domain.Attributes, []string) {
+	patch := domain.Attributes{}
+	removed := []string{}
+	for key, value := range attrs {
+		if value == nil {
+			removed = append(removed, key)
+		} else {
+			patch[key] = value
+		}
+	}
+	return patch, removed
 }
 
 func isDuplicateKeyError(err error) bool {
@@ -3,6 +3,7 @@ package postgres
 import (
 	"context"
 	"os"
+	"reflect"
 	"testing"
 	"time"
 
@@ -45,6 +46,22 @@ func cleanupTestData(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Failed to cleanup test data: %v", err)
 	}
+	_, err = testPool.Exec(context.Background(), "DELETE FROM user_history")
+	if err != nil {
+		t.Fatalf("Failed to cleanup test data: %v", err)
+	}
+	_, err = testPool.Exec(context.Background(), "DELETE FROM attribute_definitions")
+	if err != nil {
+		t.Fatalf("Failed to cleanup test data: %v", err)
+	}
+	_, err = testPool.Exec(context.Background(), "DELETE FROM webhook_subscriptions")
+	if err != nil {
+		t.Fatalf("Failed to cleanup test data: %v", err)
+	}
+	_, err = testPool.Exec(context.Background(), "DELETE FROM tenants WHERE id <> 'default'")
+	if err != nil {
+		t.Fatalf("Failed to cleanup test data: %v", err)
+	}
 }
 
 func TestUserRepository_Create(t *testing.T) {
@@ -147,6 +164,76 @@ func TestUserRepository_GetByID(t *testing.T) {
 	}
 }
 
+func TestUserRepository_GetByIDs(t *testing.T) {
+	if testPool == nil {
+		t.Skip("Database not available")
+	}
+	cleanupTestData(t)
+
+	repo := NewUserRepository(testPool)
+	ctx := context.Background()
+
+	first := &domain.User{Email: "first@example.com", FirstName: "A", LastName: "A", Status: domain.UserStatusActive}
+	second := &domain.User{Email: "second@example.com", FirstName: "B", LastName: "B", Status: domain.UserStatusActive}
+	repo.Create(ctx, first)
+	repo.Create(ctx, second)
+
+	users, err := repo.GetByIDs(ctx, []uuid.UUID{second.ID, uuid.New(), first.ID}, nil)
+	if err != nil {
+		t.Fatalf("GetByIDs() error = %v", err)
+	}
+	if len(users) != 2 || users[0].ID != second.ID || users[1].ID != first.ID {
+		t.Errorf("GetByIDs() = %v, want [second first]", users)
+	}
+
+	if err := NewTenantRepository(testPool).Create(ctx, &domain.Tenant{ID: "acme", Name: "Acme"}); err != nil {
+		t.Fatalf("Create tenant error = %v", err)
+	}
+	users, err = repo.GetByIDs(domain.ContextWithTenant(ctx, "acme"), []uuid.UUID{first.ID}, nil)
+	if err != nil {
+		t.Fatalf("GetByIDs() error = %v", err)
+	}
+	if len(users) != 0 {
+		t.Errorf("GetByIDs() in other tenant = %d users, want 0", len(users))
+	}
+}
+
+func TestUserRepository_Fields(t *testing.T) {
+	if testPool == nil {
+		t.Skip("Database not available")
+	}
+	cleanupTestData(t)
+
+	repo := NewUserRepository(testPool)
+	ctx := context.Background()
+
+	user := &domain.User{Email: "fields@example.com", FirstName: "John", LastName: "Doe", Status: domain.UserStatusActive}
+	repo.Create(ctx, user)
+
+	fields := domain.FieldSet{"id", "email"}
+	want := domain.User{ID: user.ID, Email: user.Email}
+
+	users, err := repo.GetByIDs(ctx, []uuid.UUID{user.ID}, fields)
+	if err != nil {
+		t.Fatalf("GetByIDs() error = %v", err)
+	}
+	if len(users) != 1 || !reflect.DeepEqual(users[0], want) {
+		t.Errorf("GetByIDs() = %+v, want %+v", users, want)
+	}
+
+	users, _, err = repo.List(ctx, domain.UserFilter{Limit: 10, Fields: fields})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(users) != 1 || !reflect.DeepEqual(users[0], want) {
+		t.Errorf("List() = %+v, want %+v", users, want)
+	}
+
+	if _, err := repo.GetByIDs(ctx, []uuid.UUID{user.ID}, domain.FieldSet{"password"}); err == nil {
+		t.Error("GetByIDs() with unknown field expected error")
+	}
+}
+
 func TestUserRepository_GetByEmail(t *testing.T) {
 	if testPool == nil {
 		t.Skip("Database not available")
@@ -178,6 +265,54 @@ func TestUserRepository_GetByEmail(t *testing.T) {
 	}
 }
 
+func TestUserRepository_GetByCanonicalEmail(t *testing.T) {
+	if testPool == nil {
+		t.Skip("Database not available")
+	}
+	cleanupTestData(t)
+
+	repo := NewUserRepository(testPool)
+	ctx := context.Background()
+
+	user := &domain.User{
+		Email:          "john.doe+news@gmail.com",
+		EmailCanonical: "johndoe@gmail.com",
+		FirstName:      "John",
+		LastName:       "Doe",
+		Status:         domain.UserStatusActive,
+	}
+	if err := repo.Create(ctx, user); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	found, err := repo.GetByCanonicalEmail(ctx, "johndoe@gmail.com")
+	if err != nil {
+		t.Fatalf("GetByCanonicalEmail() error = %v", err)
+	}
+	if found.ID != user.ID || found.Email != user.Email {
+		t.Errorf("GetByCanonicalEmail() = %+v, want user %v", found, user.ID)
+	}
+
+	duplicate := &domain.User{
+		Email:          "johndoe@gmail.com",
+		EmailCanonical: "johndoe@gmail.com",
+		FirstName:      "Jane",
+		LastName:       "Doe",
+		Status:         domain.UserStatusActive,
+	}
+	if err := repo.Create(ctx, duplicate); err != domain.ErrEmailExists {
+		t.Errorf("Create() with duplicate canonical email error = %v, want %v", err, domain.ErrEmailExists)
+	}
+
+	plain := &domain.User{Email: "plain@example.com", FirstName: "Jane", LastName: "Doe", Status: domain.UserStatusActive}
+	if err := repo.Create(ctx, plain); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if plain.EmailCanonical != plain.Email {
+		t.Errorf("Create() without canonical email stored %v, want %v", plain.EmailCanonical, plain.Email)
+	}
+}
+
 func TestUserRepository_List(t *testing.T) {
 	if testPool == nil {
 		t.Skip("Database not available")
@@ -197,7 +332,7 @@ func TestUserRepository_List(t *testing.T) {
 		repo.Create(ctx, user)
 	}
 
-	users, total, err := repo.List(ctx, 10, 0)
+	users, total, err := repo.List(ctx, domain.UserFilter{Limit: 10, Offset: 0})
 	if err != nil {
 		t.Fatalf("List() error = %v", err)
 	}
@@ -208,7 +343,7 @@ func TestUserRepository_List(t *testing.T) {
 		t.Errorf("List() total = %v, want 5", total)
 	}
 
-	users, total, err = repo.List(ctx, 2, 0)
+	users, total, err = repo.List(ctx, domain.UserFilter{Limit: 2, Offset: 0})
 	if err != nil {
 		t.Fatalf("List() with limit error = %v", err)
 	}
@@ -219,7 +354,7 @@ func TestUserRepository_List(t *testing.T) {
 		t.Errorf("List() with limit total = %v, want 5", total)
 	}
 
-	users, _, err = repo.List(ctx, 2, 4)
+	users, _, err = repo.List(ctx, domain.UserFilter{Limit: 2, Offset: 4})
 	if err != nil {
 		t.Fatalf("List() with offset error = %v", err)
 	}
@@ -228,6 +363,51 @@ func TestUserRepository_List(t *testing.T) {
 	}
 }
 
+func TestUserRepository_ListByAttributes(t *testing.T) {
+	if testPool == nil {
+		t.Skip("Database not available")
+	}
+	cleanupTestData(t)
+
+	repo := NewUserRepository(testPool)
+	ctx := context.Background()
+
+	plans := []string{"pro", "free", "pro"}
+	for i, plan := range plans {
+		user := &domain.User{
+			Email:      "attr" + string(rune('0'+i)) + "@example.com",
+			FirstName:  "John",
+			LastName:   "Doe",
+			Status:     domain.UserStatusActive,
+			Attributes: domain.Attributes{"plan": plan, "seats": float64(i + 1)},
+		}
+		if err := repo.Create(ctx, user); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+
+	users, total, err := repo.List(ctx, domain.UserFilter{Limit: 10, Attributes: domain.Attributes{"plan": "pro"}})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if total != 2 || len(users) != 2 {
+		t.Errorf("List() total = %v, len = %v, want 2", total, len(users))
+	}
+	for _, u := range users {
+		if u.Attributes["plan"] != "pro" {
+			t.Errorf("List() returned plan = %v, want pro", u.Attributes["plan"])
+		}
+	}
+
+	users, total, err = repo.List(ctx, domain.UserFilter{Limit: 10, Attributes: domain.Attributes{"plan": "pro", "seats": float64(3)}})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if total != 1 || len(users) != 1 {
+		t.Errorf("List() total = %v, len = %v, want 1", total, len(users))
+	}
+}
+
 func TestUserRepository_Update(t *testing.T) {
 	if testPool == nil {
 		t.Skip("Database not available")
@@ -274,6 +454,68 @@ func TestUserRepository_Update(t *testing.T) {
 	}
 }
 
+func TestUserRepository_Upsert(t *testing.T) {
+	if testPool == nil {
+		t.Skip("Database not available")
+	}
+	cleanupTestData(t)
+
+	repo := NewUserRepository(testPool)
+	ctx := context.Background()
+
+	newUser := func(firstName string, attrs domain.Attributes) *domain.User {
+		return &domain.User{
+			Email:          "upsert@example.com",
+			EmailCanonical: "upsert@example.com",
+			FirstName:      firstName,
+			LastName:       "Doe",
+			Status:         domain.UserStatusActive,
+			Attributes:     attrs,
+		}
+	}
+
+	tests := []struct {
+		name       string
+		user       *domain.User
+		wantResult domain.UpsertResult
+		wantAttrs  domain.Attributes
+	}{
+		{"insert", newUser("John", domain.Attributes{"plan": "free", "seats": float64(1)}), domain.UpsertCreated, domain.Attributes{"plan": "free", "seats": float64(1)}},
+		{"unchanged", newUser("John", nil), domain.UpsertUnchanged, domain.Attributes{"plan": "free", "seats": float64(1)}},
+		{"update merges attributes", newUser("Johnny", domain.Attributes{"plan": "pro", "seats": nil}), domain.UpsertUpdated, domain.Attributes{"plan": "pro"}},
+	}
+
+	var id uuid.UUID
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := repo.Upsert(ctx, tt.user)
+			if err != nil {
+				t.Fatalf("Upsert() error = %v", err)
+			}
+			if result != tt.wantResult {
+				t.Errorf("Upsert() result = %v, want %v", result, tt.wantResult)
+			}
+			if id == uuid.Nil {
+				id = tt.user.ID
+			}
+			if tt.user.ID != id {
+				t.Errorf("Upsert() ID = %v, want %v", tt.user.ID, id)
+			}
+			if len(tt.user.Attributes) != len(tt.wantAttrs) || tt.user.Attributes["plan"] != tt.wantAttrs["plan"] {
+				t.Errorf("Upsert() attributes = %v, want %v", tt.user.Attributes, tt.wantAttrs)
+			}
+		})
+	}
+
+	var versions int
+	if err := testPool.QueryRow(ctx, "SELECT COUNT(*) FROM user_history WHERE user_id = $1", id).Scan(&versions); err != nil {
+		t.Fatalf("count history: %v", err)
+	}
+	if versions != 2 {
+		t.Errorf("history versions = %v, want 2", versions)
+	}
+}
+
 func TestUserRepository_Delete(t *testing.T) {
 	if testPool == nil {
 		t.Skip("Database not available")
@@ -307,6 +549,179 @@ func TestUserRepository_Delete(t *testing.T) {
 	}
 }
 
+func TestUserRepository_GetAsOf(t *testing.T) {
+	if testPool == nil {
+		t.Skip("Database not available")
+	}
+	cleanupTestData(t)
+
+	repo := NewUserRepository(testPool)
+	ctx := context.Background()
+
+	user := &domain.User{
+		Email:     "history@example.com",
+		FirstName: "John",
+		LastName:  "Doe",
+		Status:    domain.UserStatusActive,
+	}
+	if err := repo.Create(ctx, user); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	createdAt := user.UpdatedAt
+
+	time.Sleep(10 * time.Millisecond)
+
+	user.FirstName = "Jane"
+	if err := repo.Update(ctx, user); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	updatedAt := user.UpdatedAt
+
+	found, err := repo.GetAsOf(ctx, user.ID, createdAt)
+	if err != nil {
+		t.Fatalf("GetAsOf() at creation error = %v", err)
+	}
+	if found.FirstName != "John" {
+		t.Errorf("GetAsOf() at creation FirstName = %v, want John", found.FirstName)
+	}
+
+	found, err = repo.GetAsOf(ctx, user.ID, updatedAt)
+	if err != nil {
+		t.Fatalf("GetAsOf() at update error = %v", err)
+	}
+	if found.FirstName != "Jane" {
+		t.Errorf("GetAsOf() at update FirstName = %v, want Jane", found.FirstName)
+	}
+
+	_, err = repo.GetAsOf(ctx, user.ID, createdAt.Add(-time.Second))
+	if err != domain.ErrUserNotFound {
+		t.Errorf("GetAsOf() before creation error = %v, want %v", err, domain.ErrUserNotFound)
+	}
+
+	if err := repo.Delete(ctx, user.ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+
+	_, err = repo.GetAsOf(ctx, user.ID, time.Now().Add(time.Second))
+	if err != domain.ErrUserNotFound {
+		t.Errorf("GetAsOf() after delete error = %v, want %v", err, domain.ErrUserNotFound)
+	}
+
+	var invalid int
+	err = testPool.QueryRow(ctx, `
+		SELECT count(*) FROM user_history a
+		JOIN user_history b ON b.user_id = a.user_id AND b.history_id <> a.history_id
+		WHERE a.user_id = $1 AND (a.valid_to < a.valid_from OR (a.valid_from < b.valid_to AND b.valid_from < a.valid_to))
+	`, user.ID).Scan(&invalid)
+	if err != nil || invalid != 0 {
+		t.Errorf("history has %d overlapping periods (%v)", invalid, err)
+	}
+
+	found, err = repo.GetAsOf(ctx, user.ID, updatedAt)
+	if err != nil {
+		t.Fatalf("GetAsOf() after delete at update error = %v", err)
+	}
+	if found.FirstName != "Jane" {
+		t.Errorf("GetAsOf() after delete FirstName = %v, want Jane", found.FirstName)
+	}
+}
+
+func TestUserRepository_ListExpiredSuspensions(t *testing.T) {
+	if testPool == nil {
+		t.Skip("Database not available")
+	}
+	cleanupTestData(t)
+
+	repo := NewUserRepository(testPool)
+	ctx := context.Background()
+
+	now := time.Now().UTC()
+	expired := now.Add(-time.Minute)
+	pending := now.Add(time.Hour)
+
+	expiredUser := &domain.User{
+		Email:          "expired@example.com",
+		FirstName:      "John",
+		LastName:       "Doe",
+		Status:         domain.UserStatusSuspended,
+		StatusReason:   domain.SuspensionReasonAbuse,
+		SuspendedUntil: &expired,
+	}
+	pendingUser := &domain.User{
+		Email:          "pending@example.com",
+		FirstName:      "John",
+		LastName:       "Doe",
+		Status:         domain.UserStatusSuspended,
+		StatusReason:   domain.SuspensionReasonAbuse,
+		SuspendedUntil: &pending,
+	}
+	repo.Create(ctx, expiredUser)
+	repo.Create(ctx, pendingUser)
+
+	users, err := repo.ListExpiredSuspensions(ctx, now, 10)
+	if err != nil {
+		t.Fatalf("ListExpiredSuspensions() error = %v", err)
+	}
+	if len(users) != 1 {
+		t.Fatalf("ListExpiredSuspensions() len = %v, want 1", len(users))
+	}
+	if users[0].ID != expiredUser.ID {
+		t.Errorf("ListExpiredSuspensions() ID = %v, want %v", users[0].ID, expiredUser.ID)
+	}
+	if users[0].StatusReason != domain.SuspensionReasonAbuse {
+		t.Errorf("ListExpiredSuspensions() StatusReason = %v, want %v", users[0].StatusReason, domain.SuspensionReasonAbuse)
+	}
+}
+
+func TestUserRepository_ReactivateSuspension(t *testing.T) {
+	if testPool == nil {
+		t.Skip("Database not available")
+	}
+	cleanupTestData(t)
+
+	repo := NewUserRepository(testPool)
+	ctx := context.Background()
+
+	now := time.Now().UTC()
+	expired := now.Add(-time.Minute)
+	user := &domain.User{
+		Email:          "expired@example.com",
+		FirstName:      "John",
+		LastName:       "Doe",
+		Status:         domain.UserStatusSuspended,
+		StatusReason:   domain.SuspensionReasonAbuse,
+		SuspendedUntil: &expired,
+	}
+	if err := repo.Create(ctx, user); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	listed := *user
+	entry := &domain.AuditEntry{UserID: user.ID, Action: domain.EventTypeUserReactivated, FromStatus: domain.UserStatusSuspended, ToStatus: domain.UserStatusActive}
+	ok, err := repo.ReactivateSuspension(ctx, &listed, now, entry)
+	if err != nil || !ok {
+		t.Fatalf("ReactivateSuspension() = %v, %v, want true", ok, err)
+	}
+	if listed.Status != domain.UserStatusActive || listed.StatusReason != "" || listed.SuspendedUntil != nil {
+		t.Errorf("ReactivateSuspension() user = %+v, want active with cleared suspension", listed)
+	}
+
+	// A second scheduler that listed the same user finds it already active.
+	again := *user
+	ok, err = repo.ReactivateSuspension(ctx, &again, now, entry)
+	if err != nil || ok {
+		t.Errorf("ReactivateSuspension() again = %v, %v, want false", ok, err)
+	}
+	if again.Status != domain.UserStatusSuspended {
+		t.Errorf("ReactivateSuspension() again modified the user: %+v", again)
+	}
+
+	entries, total, err := NewAuditRepository(testPool).ListByUser(ctx, user.ID, 10, 0)
+	if err != nil || total != 1 || len(entries) != 1 {
+		t.Errorf("ListByUser() = %v, %v, %v, want one entry", entries, total, err)
+	}
+}
+
 func TestUserRepository_FullCRUDFlow(t *testing.T) {
 	if testPool == nil {
 		t.Skip("Database not available")
@@ -345,7 +760,7 @@ func TestUserRepository_FullCRUDFlow(t *testing.T) {
 		t.Errorf("Update() FirstName not updated")
 	}
 
-	users, total, err := repo.List(ctx, 10, 0)
+	users, total, err := repo.List(ctx, domain.UserFilter{Limit: 10, Offset: 0})
 	if err != nil {
 		t.Fatalf("List() error = %v", err)
 	}
@@ -0,0 +1,332 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/giannuccilli/user-api/internal/domain"
+)
+
+// WebhookRepository methods are scoped to the tenant in the context, except
+// ClaimDue, RecordAttempt and DeleteDeliveriesBefore which are used by
+// background jobs.
+type WebhookRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewWebhookRepository(pool *pgxpool.Pool) *WebhookRepository {
+	return &WebhookRepository{pool: pool}
+}
+
+const webhookColumns = `id, tenant_id, url, event_types, secret, enabled, consecutive_failures, disabled_at, created_at, updated_at`
+
+func (r *WebhookRepository) Create(ctx context.Context, sub *domain.WebhookSubscription) error {
+	query := `
+		INSERT INTO webhook_subscriptions (tenant_id, url, event_types, secret, enabled)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, tenant_id, created_at, updated_at`
+
+	return r.pool.QueryRow(ctx, query, tenantID(ctx), sub.URL, eventTypeStrings(sub.EventTypes), sub.Secret, sub.Enabled).
+		Scan(&sub.ID, &sub.TenantID, &sub.CreatedAt, &sub.UpdatedAt)
+}
+
+func (r *WebhookRepository) Get(ctx context.Context, id uuid.UUID) (*domain.WebhookSubscription, error) {
+	query := `
+		SELECT ` + webhookColumns + `
+		FROM webhook_subscriptions
+		WHERE id = $1 AND tenant_id = $2`
+
+	sub, err := scanWebhook(r.pool.QueryRow(ctx, query, id, tenantID(ctx)))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrWebhookNotFound
+	}
+	return sub, err
+}
+
+func (r *WebhookRepository) List(ctx context.Context) ([]domain.WebhookSubscription, error) {
+	query := `
+		SELECT ` + webhookColumns + `
+		FROM webhook_subscriptions
+		WHERE tenant_id = $1
+		ORDER BY created_at`
+
+	rows, err := r.pool.Query(ctx, query, tenantID(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	subs := make([]domain.WebhookSubscription, 0)
+	for rows.Next() {
+		sub, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		subs = append(subs, *sub)
+	}
+
+	return subs, rows.Err()
+}
+
+func (r *WebhookRepository) Update(ctx context.Context, sub *domain.WebhookSubscription) error {
+	query := `
+		UPDATE webhook_subscriptions
+		SET url = $3, event_types = $4, secret = $5, enabled = $6, consecutive_failures = $7, disabled_at = $8,
+		    updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND tenant_id = $2
+		RETURNING tenant_id, created_at, updated_at`
+
+	err := r.pool.QueryRow(ctx, query,
+		sub.ID,
+		tenantID(ctx),
+		sub.URL,
+		eventTypeStrings(sub.EventTypes),
+		sub.Secret,
+		sub.Enabled,
+		sub.ConsecutiveFailures,
+		sub.DisabledAt,
+	).Scan(&sub.TenantID, &sub.CreatedAt, &sub.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.ErrWebhookNotFound
+	}
+	return err
+}
+
+func (r *WebhookRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	result, err := r.pool.Exec(ctx, `DELETE FROM webhook_subscriptions WHERE id = $1 AND tenant_id = $2`, id, tenantID(ctx))
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return domain.ErrWebhookNotFound
+	}
+
+	return nil
+}
+
+const deliveryColumns = `id, tenant_id, subscription_id, event_id, event_type, payload, status, attempts,
+	COALESCE(last_status_code, 0), COALESCE(last_error, ''), next_attempt_at, delivered_at, created_at, updated_at`
+
+func (r *WebhookRepository) ListDeliveries(ctx context.Context, subscriptionID uuid.UUID, limit, offset int) ([]domain.WebhookDelivery, int, error) {
+	var total int
+	err := r.pool.QueryRow(ctx,
+		`SELECT COUNT(*) FROM webhook_deliveries WHERE subscription_id = $1 AND tenant_id = $2`,
+		subscriptionID, tenantID(ctx),
+	).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	query := `
+		SELECT ` + deliveryColumns + `
+		FROM webhook_deliveries
+		WHERE subscription_id = $1 AND tenant_id = $2
+		ORDER BY created_at DESC
+		LIMIT $3 OFFSET $4`
+
+	rows, err := r.pool.Query(ctx, query, subscriptionID, tenantID(ctx), limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	deliveries := make([]domain.WebhookDelivery, 0)
+	for rows.Next() {
+		var d domain.WebhookDelivery
+		if err := scanDelivery(rows, &d); err != nil {
+			return nil, 0, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return deliveries, total, nil
+}
+
+func (r *WebhookRepository) Enqueue(ctx context.Context, event domain.UserEvent, payload []byte) (int, error) {
+	query := `
+		INSERT INTO webhook_deliveries (tenant_id, subscription_id, event_id, event_type, payload, next_attempt_at)
+		SELECT tenant_id, id, $2::uuid, $3::text, $4::jsonb, CURRENT_TIMESTAMP
+		FROM webhook_subscriptions
+		WHERE tenant_id = $1
+		  AND enabled
+		  AND (cardinality(event_types) = 0 OR $3::text = ANY(event_types))`
+
+	result, err := r.pool.Exec(ctx, query, event.TenantID, event.EventID, string(event.EventType), payload)
+	if err != nil {
+		return 0, err
+	}
+	return int(result.RowsAffected()), nil
+}
+
+func (r *WebhookRepository) ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]domain.PendingWebhookDelivery, error) {
+	query := `
+		WITH due AS (
+			SELECT d.id
+			FROM webhook_deliveries d
+			JOIN webhook_subscriptions s ON s.id = d.subscription_id
+			WHERE d.status = 'pending'
+			  AND d.next_attempt_at <= $1
+			  AND s.enabled
+			ORDER BY d.next_attempt_at
+			LIMIT $3
+			FOR UPDATE OF d SKIP LOCKED
+		)
+		UPDATE webhook_deliveries d
+		SET next_attempt_at = $2
+		FROM due, webhook_subscriptions s
+		WHERE d.id = due.id AND s.id = d.subscription_id
+		RETURNING d.id, d.tenant_id, d.subscription_id, d.event_id, d.event_type, d.payload, d.status, d.attempts,
+			COALESCE(d.last_status_code, 0), COALESCE(d.last_error, ''), d.next_attempt_at, d.delivered_at,
+			d.created_at, d.updated_at, s.url, s.secret`
+
+	rows, err := r.pool.Query(ctx, query, now, now.Add(lease), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var pending []domain.PendingWebhookDelivery
+	for rows.Next() {
+		var p domain.PendingWebhookDelivery
+		if err := scanDelivery(rows, &p.WebhookDelivery, &p.URL, &p.Secret); err != nil {
+			return nil, err
+		}
+		pending = append(pending, p)
+	}
+
+	return pending, rows.Err()
+}
+
+func (r *WebhookRepository) RecordAttempt(ctx context.Context, delivery *domain.WebhookDelivery, disableAfter int) (bool, error) {
+	var disabled bool
+	err := pgx.BeginFunc(ctx, r.pool, func(tx pgx.Tx) error {
+		var lastStatusCode *int
+		if delivery.LastStatusCode != 0 {
+			lastStatusCode = &delivery.LastStatusCode
+		}
+		var lastError *string
+		if delivery.LastError != "" {
+			lastError = &delivery.LastError
+		}
+
+		err := tx.QueryRow(ctx, `
+			UPDATE webhook_deliveries
+			SET status = $2, attempts = $3, last_status_code = $4, last_error = $5, next_attempt_at = $6,
+			    delivered_at = $7, updated_at = CURRENT_TIMESTAMP
+			WHERE id = $1
+			RETURNING updated_at`,
+			delivery.ID,
+			delivery.Status,
+			delivery.Attempts,
+			lastStatusCode,
+			lastError,
+			delivery.NextAttemptAt,
+			delivery.DeliveredAt,
+		).Scan(&delivery.UpdatedAt)
+		if err != nil {
+			return err
+		}
+
+		switch delivery.Status {
+		case domain.WebhookDeliverySucceeded:
+			_, err = tx.Exec(ctx, `
+				UPDATE webhook_subscriptions
+				SET consecutive_failures = 0
+				WHERE id = $1 AND consecutive_failures > 0`,
+				delivery.SubscriptionID)
+		case domain.WebhookDeliveryFailed:
+			var failures int
+			var enabled bool
+			err = tx.QueryRow(ctx, `
+				UPDATE webhook_subscriptions
+				SET consecutive_failures = consecutive_failures + 1
+				WHERE id = $1
+				RETURNING consecutive_failures, enabled`,
+				delivery.SubscriptionID,
+			).Scan(&failures, &enabled)
+			if err != nil || !enabled || failures < disableAfter {
+				return err
+			}
+			_, err = tx.Exec(ctx, `
+				UPDATE webhook_subscriptions
+				SET enabled = FALSE, disabled_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+				WHERE id = $1`,
+				delivery.SubscriptionID)
+			disabled = err == nil
+		}
+		return err
+	})
+	return disabled, err
+}
+
+// DeleteDeliveriesBefore removes the finished deliveries last updated before
+// before, of every tenant.
+func (r *WebhookRepository) DeleteDeliveriesBefore(ctx context.Context, before time.Time) (int, error) {
+	result, err := r.pool.Exec(ctx, `DELETE FROM webhook_deliveries WHERE status <> 'pending' AND updated_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return int(result.RowsAffected()), nil
+}
+
+func scanWebhook(row pgx.Row) (*domain.WebhookSubscription, error) {
+	var sub domain.WebhookSubscription
+	var eventTypes []string
+	err := row.Scan(
+		&sub.ID,
+		&sub.TenantID,
+		&sub.URL,
+		&eventTypes,
+		&sub.Secret,
+		&sub.Enabled,
+		&sub.ConsecutiveFailures,
+		&sub.DisabledAt,
+		&sub.CreatedAt,
+		&sub.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	sub.EventTypes = make([]domain.EventType, len(eventTypes))
+	for i, t := range eventTypes {
+		sub.EventTypes[i] = domain.EventType(t)
+	}
+	return &sub, nil
+}
+
+func scanDelivery(row pgx.Row, d *domain.WebhookDelivery, extra ...any) error {
+	dest := append([]any{
+		&d.ID,
+		&d.TenantID,
+		&d.SubscriptionID,
+		&d.EventID,
+		&d.EventType,
+		&d.Payload,
+		&d.Status,
+		&d.Attempts,
+		&d.LastStatusCode,
+		&d.LastError,
+		&d.NextAttemptAt,
+		&d.DeliveredAt,
+		&d.CreatedAt,
+		&d.UpdatedAt,
+	}, extra...)
+	return row.Scan(dest...)
+}
+
+func eventTypeStrings(types []domain.EventType) []string {
+	strs := make([]string, len(types))
+	for i, t := range types {
+		strs[i] = string(t)
+	}
+	return strs
+}
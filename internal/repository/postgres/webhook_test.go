@@ -0,0 +1,133 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/giannuccilli/user-api/internal/domain"
+)
+
+func TestWebhookRepository_CRUD(t *testing.T) {
+	if testPool == nil {
+		t.Skip("Database not available")
+	}
+	cleanupTestData(t)
+
+	repo := NewWebhookRepository(testPool)
+	ctx := context.Background()
+
+	sub := &domain.WebhookSubscription{
+		URL:        "https://example.com/hooks",
+		EventTypes: []domain.EventType{domain.EventTypeUserCreated},
+		Secret:     "s3cret",
+		Enabled:    true,
+	}
+	if err := repo.Create(ctx, sub); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if sub.ID == uuid.Nil || sub.TenantID != domain.DefaultTenantID {
+		t.Errorf("Create() = %+v", sub)
+	}
+
+	sub.EventTypes = []domain.EventType{domain.EventTypeUserCreated, domain.EventTypeUserDeleted}
+	if err := repo.Update(ctx, sub); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	got, err := repo.Get(ctx, sub.ID)
+	if err != nil || len(got.EventTypes) != 2 || got.Secret != "s3cret" {
+		t.Errorf("Get() = %+v, %v", got, err)
+	}
+
+	if _, err := repo.Get(domain.ContextWithTenant(ctx, "acme"), sub.ID); !errors.Is(err, domain.ErrWebhookNotFound) {
+		t.Errorf("Get() from another tenant error = %v, want %v", err, domain.ErrWebhookNotFound)
+	}
+	subs, err := repo.List(ctx)
+	if err != nil || len(subs) != 1 {
+		t.Errorf("List() = %v, %v", subs, err)
+	}
+
+	if err := repo.Delete(ctx, sub.ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if err := repo.Delete(ctx, sub.ID); !errors.Is(err, domain.ErrWebhookNotFound) {
+		t.Errorf("Delete() twice error = %v, want %v", err, domain.ErrWebhookNotFound)
+	}
+}
+
+func TestWebhookRepository_Deliveries(t *testing.T) {
+	if testPool == nil {
+		t.Skip("Database not available")
+	}
+	cleanupTestData(t)
+
+	repo := NewWebhookRepository(testPool)
+	ctx := context.Background()
+
+	all := &domain.WebhookSubscription{URL: "https://example.com/all", Secret: "a", Enabled: true}
+	deletes := &domain.WebhookSubscription{
+		URL:        "https://example.com/deletes",
+		EventTypes: []domain.EventType{domain.EventTypeUserDeleted},
+		Secret:     "b",
+		Enabled:    true,
+	}
+	for _, sub := range []*domain.WebhookSubscription{all, deletes} {
+		if err := repo.Create(ctx, sub); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+
+	event := domain.UserEvent{
+		EventID:   uuid.New(),
+		EventType: domain.EventTypeUserCreated,
+		TenantID:  domain.DefaultTenantID,
+		Timestamp: time.Now().UTC(),
+		Data:      domain.EventData{UserID: uuid.New()},
+	}
+	count, err := repo.Enqueue(ctx, event, []byte(`{"eventType":"user.created"}`))
+	if err != nil || count != 1 {
+		t.Fatalf("Enqueue() = %d, %v, want 1 delivery", count, err)
+	}
+
+	now := time.Now()
+	pending, err := repo.ClaimDue(ctx, now, time.Minute, 10)
+	if err != nil || len(pending) != 1 {
+		t.Fatalf("ClaimDue() = %v, %v, want 1 delivery", pending, err)
+	}
+	if p := pending[0]; p.SubscriptionID != all.ID || p.URL != all.URL || p.Secret != "a" || p.EventID != event.EventID {
+		t.Errorf("ClaimDue() = %+v", p)
+	}
+	if again, _ := repo.ClaimDue(ctx, now, time.Minute, 10); len(again) != 0 {
+		t.Errorf("ClaimDue() again = %d deliveries, want the claimed one to be leased", len(again))
+	}
+
+	delivery := pending[0].WebhookDelivery
+	delivery.Status = domain.WebhookDeliveryFailed
+	delivery.Attempts = 1
+	delivery.LastStatusCode = 500
+	delivery.LastError = "unexpected status 500"
+	delivery.NextAttemptAt = nil
+	disabled, err := repo.RecordAttempt(ctx, &delivery, 1)
+	if err != nil || !disabled {
+		t.Fatalf("RecordAttempt() = %v, %v, want the subscription disabled", disabled, err)
+	}
+
+	got, _ := repo.Get(ctx, all.ID)
+	if got.Enabled || got.ConsecutiveFailures != 1 || got.DisabledAt == nil {
+		t.Errorf("subscription after failure = %+v", got)
+	}
+	if count, _ := repo.Enqueue(ctx, event, []byte(`{}`)); count != 0 {
+		t.Errorf("Enqueue() to a disabled subscription = %d, want 0", count)
+	}
+
+	deliveries, total, err := repo.ListDeliveries(ctx, all.ID, 10, 0)
+	if err != nil || total != 1 || len(deliveries) != 1 {
+		t.Fatalf("ListDeliveries() = %v, %d, %v", deliveries, total, err)
+	}
+	if d := deliveries[0]; d.Status != domain.WebhookDeliveryFailed || d.LastStatusCode != 500 || d.Attempts != 1 {
+		t.Errorf("ListDeliveries() = %+v", d)
+	}
+}
@@ -0,0 +1,200 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+
+	"github.com/giannuccilli/user-api/internal/domain"
+)
+
+const (
+	FieldCodeUnknownAttribute = "UNKNOWN_ATTRIBUTE"
+	FieldCodeSchemaMismatch   = "SCHEMA_MISMATCH"
+
+	attributeSchemaURLPrefix = "urn:user-api:attribute:"
+)
+
+var attributeKeyPattern = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_]{0,62}$`)
+
+// AttributeRegistry manages the definitions of custom user attributes and
+// validates attribute values against their JSON Schema.
+type AttributeRegistry struct {
+	repo domain.AttributeRepository
+
+	mu       sync.Mutex
+	compiled map[string]compiledSchema
+}
+
+type compiledSchema struct {
+	updatedAt time.Time
+	schema    *jsonschema.Schema
+}
+
+func NewAttributeRegistry(repo domain.AttributeRepository) *AttributeRegistry {
+	return &AttributeRegistry{
+		repo:     repo,
+		compiled: make(map[string]compiledSchema),
+	}
+}
+
+func WithAttributeRegistry(registry *AttributeRegistry) Option {
+	return func(s *UserService) {
+		s.attributes = registry
+	}
+}
+
+func (r *AttributeRegistry) List(ctx context.Context) (*domain.AttributeDefinitionList, error) {
+	defs, err := r.repo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &domain.AttributeDefinitionList{Data: defs}, nil
+}
+
+func (r *AttributeRegistry) Get(ctx context.Context, key string) (*domain.AttributeDefinition, error) {
+	return r.repo.Get(ctx, key)
+}
+
+func (r *AttributeRegistry) Put(ctx context.Context, key string, req domain.PutAttributeDefinitionRequest) (*domain.AttributeDefinition, error) {
+	if !domain.ActorFromContext(ctx).IsPlatformAdmin() {
+		return nil, domain.ErrForbidden
+	}
+
+	v := &domain.ValidationError{}
+	if !attributeKeyPattern.MatchString(key) {
+		v.Add("key", domain.FieldCodeInvalidFormat, "must start with a letter and contain only letters, digits and underscores (max 63)")
+	}
+	if len(req.Description) > 500 {
+		v.Add("description", domain.FieldCodeTooLong, "must be at most 500 characters")
+	}
+	if len(bytes.TrimSpace(req.Schema)) == 0 {
+		v.Add("schema", domain.FieldCodeRequired, "is required")
+	} else if _, err := compileAttributeSchema(key, req.Schema); err != nil {
+		v.Add("schema", domain.FieldCodeInvalidValue, "is not a valid JSON Schema: "+schemaErrorMessage(err))
+	}
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+
+	def := &domain.AttributeDefinition{
+		Key:         key,
+		Description: req.Description,
+		Schema:      req.Schema,
+	}
+	if err := r.repo.Save(ctx, def); err != nil {
+		return nil, err
+	}
+	return def, nil
+}
+
+func (r *AttributeRegistry) Delete(ctx context.Context, key string) error {
+	if !domain.ActorFromContext(ctx).IsPlatformAdmin() {
+		return domain.ErrForbidden
+	}
+	return r.repo.Delete(ctx, key)
+}
+
+// Validate checks every non-null value in attrs against its definition and
+// records failures on v as attributes.<key>. Null values are removals and
+// are always accepted.
+func (r *AttributeRegistry) Validate(ctx context.Context, v *domain.ValidationError, attrs domain.Attributes) error {
+	defs, err := r.repo.List(ctx)
+	if err != nil {
+		return err
+	}
+	byKey := make(map[string]domain.AttributeDefinition, len(defs))
+	for _, def := range defs {
+		byKey[def.Key] = def
+	}
+
+	for key, value := range attrs {
+		if value == nil {
+			continue
+		}
+		field := "attributes." + key
+
+		def, ok := byKey[key]
+		if !ok {
+			v.Add(field, FieldCodeUnknownAttribute, "is not a registered attribute")
+			continue
+		}
+
+		schema, err := r.schema(def)
+		if err != nil {
+			return err
+		}
+		if err := schema.Validate(value); err != nil {
+			v.Add(field, FieldCodeSchemaMismatch, schemaErrorMessage(err))
+		}
+	}
+
+	return nil
+}
+
+func (r *AttributeRegistry) schema(def domain.AttributeDefinition) (*jsonschema.Schema, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if c, ok := r.compiled[def.Key]; ok && c.updatedAt.Equal(def.UpdatedAt) {
+		return c.schema, nil
+	}
+
+	schema, err := compileAttributeSchema(def.Key, def.Schema)
+	if err != nil {
+		return nil, err
+	}
+	r.compiled[def.Key] = compiledSchema{updatedAt: def.UpdatedAt, schema: schema}
+	return schema, nil
+}
+
+func compileAttributeSchema(key string, raw []byte) (*jsonschema.Schema, error) {
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(raw))
+	if err != nil {
+		return nil, err
+	}
+
+	url := attributeSchemaURLPrefix + key
+	c := jsonschema.NewCompiler()
+	// Definitions come from API requests, so a $ref must not make the
+	// server read local files or fetch URLs. Meta-schemas are built in.
+	c.UseLoader(jsonschema.SchemeURLLoader{})
+	if err := c.AddResource(url, doc); err != nil {
+		return nil, err
+	}
+	return c.Compile(url)
+}
+
+// schemaErrorMessage flattens a jsonschema error into its leaf messages.
+func schemaErrorMessage(err error) string {
+	var schemaErr *jsonschema.SchemaValidationError
+	if errors.As(err, &schemaErr) {
+		err = schemaErr.Err
+	}
+	var validationErr *jsonschema.ValidationError
+	if !errors.As(err, &validationErr) {
+		return err.Error()
+	}
+
+	var msgs []string
+	for _, e := range validationErr.BasicOutput().Errors {
+		if e.Error == nil {
+			continue
+		}
+		msg := e.Error.String()
+		if e.InstanceLocation != "" {
+			msg = "at '" + e.InstanceLocation + "': " + msg
+		}
+		msgs = append(msgs, msg)
+	}
+	if len(msgs) == 0 {
+		return err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
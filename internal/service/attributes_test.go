@@ -0,0 +1,260 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/giannuccilli/user-api/internal/domain"
+)
+
+type mockAttributeRepository struct {
+	defs map[string]domain.AttributeDefinition
+}
+
+func newMockAttributeRepository(defs ...domain.AttributeDefinition) *mockAttributeRepository {
+	m := &mockAttributeRepository{defs: make(map[string]domain.AttributeDefinition)}
+	for _, def := range defs {
+		m.defs[def.Key] = def
+	}
+	return m
+}
+
+func (m *mockAttributeRepository) List(ctx context.Context) ([]domain.AttributeDefinition, error) {
+	defs := make([]domain.AttributeDefinition, 0, len(m.defs))
+	for _, def := range m.defs {
+		defs = append(defs, def)
+	}
+	return defs, nil
+}
+
+func (m *mockAttributeRepository) Get(ctx context.Context, key string) (*domain.AttributeDefinition, error) {
+	def, ok := m.defs[key]
+	if !ok {
+		return nil, domain.ErrAttributeNotFound
+	}
+	return &def, nil
+}
+
+func (m *mockAttributeRepository) Save(ctx context.Context, def *domain.AttributeDefinition) error {
+	def.UpdatedAt = time.Now()
+	if existing, ok := m.defs[def.Key]; ok {
+		def.CreatedAt = existing.CreatedAt
+	} else {
+		def.CreatedAt = def.UpdatedAt
+	}
+	m.defs[def.Key] = *def
+	return nil
+}
+
+func (m *mockAttributeRepository) Delete(ctx context.Context, key string) error {
+	if _, ok := m.defs[key]; !ok {
+		return domain.ErrAttributeNotFound
+	}
+	delete(m.defs, key)
+	return nil
+}
+
+type recordingNotifier struct {
+	mockNotifier
+	created []domain.EventData
+	updated []domain.EventData
+}
+
+func (m *recordingNotifier) NotifyCreated(ctx context.Context, data domain.EventData) error {
+	m.created = append(m.created, data)
+	return nil
+}
+
+func (m *recordingNotifier) NotifyUpdated(ctx context.Context, data domain.EventData) error {
+	m.updated = append(m.updated, data)
+	return nil
+}
+
+func planDefinition() domain.AttributeDefinition {
+	return domain.AttributeDefinition{
+		Key:    "plan",
+		Schema: json.RawMessage(`{"type": "string", "enum": ["free", "pro"]}`),
+	}
+}
+
+func seatsDefinition() domain.AttributeDefinition {
+	return domain.AttributeDefinition{
+		Key:    "seats",
+		Schema: json.RawMessage(`{"type": "integer", "minimum": 1}`),
+	}
+}
+
+func TestAttributeRegistry_Put(t *testing.T) {
+	admin := domain.ContextWithActor(context.Background(), domain.Actor{ID: "admin-1", Roles: []string{domain.RolePlatformAdmin}})
+	tenantAdmin := domain.ContextWithActor(context.Background(), domain.Actor{ID: "admin-2", Roles: []string{domain.RoleAdmin}})
+
+	localSchema := filepath.Join(t.TempDir(), "schema.json")
+	if err := os.WriteFile(localSchema, []byte(`{"type": "string"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	fileRef := `{"$ref": "` + (&url.URL{Scheme: "file", Path: filepath.ToSlash(localSchema)}).String() + `"}`
+
+	tests := []struct {
+		name      string
+		ctx       context.Context
+		key       string
+		schema    string
+		wantErr   error
+		wantField string
+	}{
+		{"valid", admin, "plan", `{"type": "string"}`, nil, ""},
+		{"not admin", context.Background(), "plan", `{"type": "string"}`, domain.ErrForbidden, ""},
+		{"tenant admin", tenantAdmin, "plan", `{"type": "string"}`, domain.ErrForbidden, ""},
+		{"invalid key", admin, "1plan", `{"type": "string"}`, domain.ErrInvalidInput, "key"},
+		{"missing schema", admin, "plan", ``, domain.ErrInvalidInput, "schema"},
+		{"invalid schema", admin, "plan", `{"type": 5}`, domain.ErrInvalidInput, "schema"},
+		{"file ref", admin, "plan", fileRef, domain.ErrInvalidInput, "schema"},
+		{"remote ref", admin, "plan", `{"$ref": "https://example.com/schema.json"}`, domain.ErrInvalidInput, "schema"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := NewAttributeRegistry(newMockAttributeRepository())
+
+			def, err := registry.Put(tt.ctx, tt.key, domain.PutAttributeDefinitionRequest{Schema: json.RawMessage(tt.schema)})
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Put() error = %v, want %v", err, tt.wantErr)
+				}
+				var v *domain.ValidationError
+				if tt.wantField != "" && (!errors.As(err, &v) || v.Fields[0].Field != tt.wantField) {
+					t.Errorf("Put() error = %v, want field %q", err, tt.wantField)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Put() unexpected error = %v", err)
+			}
+			if def.Key != tt.key || def.CreatedAt.IsZero() {
+				t.Errorf("Put() = %+v", def)
+			}
+		})
+	}
+}
+
+func TestUserService_CreateWithAttributes(t *testing.T) {
+	tests := []struct {
+		name     string
+		attrs    domain.Attributes
+		wantCode string
+	}{
+		{"valid", domain.Attributes{"plan": "pro", "seats": float64(3)}, ""},
+		{"unknown attribute", domain.Attributes{"color": "red"}, FieldCodeUnknownAttribute},
+		{"schema mismatch", domain.Attributes{"plan": "enterprise"}, FieldCodeSchemaMismatch},
+		{"wrong type", domain.Attributes{"seats": "three"}, FieldCodeSchemaMismatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			notifier := &recordingNotifier{}
+			registry := NewAttributeRegistry(newMockAttributeRepository(planDefinition(), seatsDefinition()))
+			svc := NewUserService(newMockUserRepository(), notifier, WithAttributeRegistry(registry))
+
+			user, err := svc.Create(context.Background(), domain.CreateUserRequest{
+				Email:      "attrs@example.com",
+				FirstName:  "John",
+				LastName:   "Doe",
+				Attributes: tt.attrs,
+			})
+			if tt.wantCode != "" {
+				var v *domain.ValidationError
+				if !errors.As(err, &v) || v.Fields[0].Code != tt.wantCode {
+					t.Fatalf("Create() error = %v, want field code %s", err, tt.wantCode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Create() unexpected error = %v", err)
+			}
+			if user.Attributes["plan"] != "pro" {
+				t.Errorf("Create() attributes = %v", user.Attributes)
+			}
+			if len(notifier.created) != 1 || notifier.created[0].Attributes["plan"] != "pro" {
+				t.Errorf("NotifyCreated() data = %+v, want attributes", notifier.created)
+			}
+		})
+	}
+}
+
+func TestUserService_CreateWithAttributes_NoRegistry(t *testing.T) {
+	svc := NewUserService(newMockUserRepository(), &mockNotifier{})
+
+	_, err := svc.Create(context.Background(), domain.CreateUserRequest{
+		Email:      "attrs@example.com",
+		FirstName:  "John",
+		LastName:   "Doe",
+		Attributes: domain.Attributes{"plan": "pro"},
+	})
+
+	var v *domain.ValidationError
+	if !errors.As(err, &v) || v.Fields[0].Code != FieldCodeUnknownAttribute {
+		t.Fatalf("Create() error = %v, want %s", err, FieldCodeUnknownAttribute)
+	}
+}
+
+func TestUserService_UpdateMergesAttributes(t *testing.T) {
+	registry := NewAttributeRegistry(newMockAttributeRepository(planDefinition(), seatsDefinition()))
+	svc := NewUserService(newMockUserRepository(), &mockNotifier{}, WithAttributeRegistry(registry))
+	ctx := context.Background()
+
+	user, err := svc.Create(ctx, domain.CreateUserRequest{
+		Email:      "merge@example.com",
+		FirstName:  "John",
+		LastName:   "Doe",
+		Attributes: domain.Attributes{"plan": "free", "seats": float64(1)},
+	})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	updated, err := svc.Update(ctx, user.ID, domain.UpdateUserRequest{
+		Attributes: domain.Attributes{"plan": "pro", "seats": nil},
+	})
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	if updated.Attributes["plan"] != "pro" {
+		t.Errorf("Update() plan = %v, want pro", updated.Attributes["plan"])
+	}
+	if _, ok := updated.Attributes["seats"]; ok {
+		t.Errorf("Update() seats = %v, want removed", updated.Attributes["seats"])
+	}
+}
+
+func TestUserService_ListByAttributes(t *testing.T) {
+	registry := NewAttributeRegistry(newMockAttributeRepository(planDefinition()))
+	svc := NewUserService(newMockUserRepository(), &mockNotifier{}, WithAttributeRegistry(registry))
+	ctx := context.Background()
+
+	for i, plan := range []string{"pro", "free", "pro"} {
+		_, err := svc.Create(ctx, domain.CreateUserRequest{
+			Email:      "list" + string(rune('0'+i)) + "@example.com",
+			FirstName:  "John",
+			LastName:   "Doe",
+			Attributes: domain.Attributes{"plan": plan},
+		})
+		if err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+
+	list, err := svc.List(ctx, domain.UserFilter{Limit: 10, Attributes: domain.Attributes{"plan": "pro"}})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if list.Pagination.Total != 2 {
+		t.Errorf("List() total = %v, want 2", list.Pagination.Total)
+	}
+}
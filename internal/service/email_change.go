@@ -0,0 +1,102 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/giannuccilli/user-api/internal/domain"
+	"github.com/giannuccilli/user-api/internal/token"
+)
+
+const tokenPurposeEmailChange = "email_change"
+
+// emailChangeRequiresConfirmation reports whether email changes go through
+// the pending_email confirmation flow. It does whenever email verification is
+// configured, even without a mailer: Update then rejects the change instead of
+// applying it unconfirmed, unless SkipEmailChangeConfirmation is set.
+func (s *UserService) emailChangeRequiresConfirmation() bool {
+	return s.tokens != nil && !s.verification.SkipEmailChangeConfirmation
+}
+
+func (s *UserService) ConfirmEmailChange(ctx context.Context, req domain.ConfirmEmailChangeRequest) (*domain.User, error) {
+	if s.tokens == nil {
+		return nil, domain.ErrNotSupported
+	}
+
+	claims, err := s.tokens.Verify(req.Token, tokenPurposeEmailChange)
+	if err != nil {
+		return nil, tokenError(err)
+	}
+	ctx = tokenTenant(ctx, claims)
+
+	user, err := s.repo.GetByID(ctx, claims.UserID)
+	if err != nil {
+		if errors.Is(err, domain.ErrUserNotFound) {
+			return nil, domain.ErrInvalidToken
+		}
+		return nil, err
+	}
+
+	// A newer request replaces the pending address, which invalidates any
+	// token issued for the previous one.
+	if user.PendingEmail == "" || user.PendingEmail != claims.Email {
+		return nil, domain.ErrInvalidToken
+	}
+
+	v := &domain.ValidationError{}
+	addr := s.normalizeEmail(v, "email", user.PendingEmail)
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+	if err := s.ensureEmailAvailable(ctx, addr, user.ID); err != nil {
+		return nil, err
+	}
+
+	user.Email = addr.Email
+	user.EmailCanonical = addr.Canonical
+	user.PendingEmail = ""
+	user.EmailVerified = true
+
+	if err := s.repo.Update(ctx, user); err != nil {
+		return nil, err
+	}
+
+	s.notifier.Notify(ctx, domain.EventTypeUserEmailChanged, eventData(user))
+
+	return user, nil
+}
+
+func (s *UserService) sendEmailChangeEmails(ctx context.Context, user *domain.User) error {
+	tok, err := s.tokens.Sign(token.Claims{
+		Purpose:   tokenPurposeEmailChange,
+		TenantID:  user.TenantID,
+		UserID:    user.ID,
+		Email:     user.PendingEmail,
+		ExpiresAt: time.Now().UTC().Add(s.verification.TokenTTL),
+	})
+	if err != nil {
+		return err
+	}
+
+	confirmation := domain.MailMessage{
+		To:      user.PendingEmail,
+		Subject: "Confirm your new email address",
+		Body: fmt.Sprintf("Hi %s,\n\nPlease confirm your new email address using the link below:\n\n%s\n\nThe link expires in %s.\n",
+			user.FirstName, tokenLink(s.verification.ConfirmEmailChangeURL, tok), s.verification.TokenTTL),
+	}
+	if err := s.mailer.Send(ctx, confirmation); err != nil {
+		return err
+	}
+
+	notice := domain.MailMessage{
+		To:      user.Email,
+		Subject: "Your email address is being changed",
+		Body: fmt.Sprintf("Hi %s,\n\nWe received a request to change the email address of your account to %s.\n"+
+			"The change only takes effect once the new address is confirmed.\n\n"+
+			"If you did not request this change, please contact support immediately.\n",
+			user.FirstName, user.PendingEmail),
+	}
+	return s.mailer.Send(ctx, notice)
+}
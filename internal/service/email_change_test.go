@@ -0,0 +1,205 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/giannuccilli/user-api/internal/config"
+	"github.com/giannuccilli/user-api/internal/domain"
+	"github.com/giannuccilli/user-api/internal/mailer"
+	"github.com/giannuccilli/user-api/internal/token"
+)
+
+func TestUserService_Update_EmailChangeRequiresConfirmation(t *testing.T) {
+	svc, repo, sender, _ := newVerificationService(false)
+
+	user := &domain.User{ID: uuid.New(), Email: "old@example.com", EmailVerified: true, FirstName: "John", LastName: "Doe", Status: domain.UserStatusActive}
+	repo.users[user.ID] = user
+	repo.byEmail[user.Email] = user
+
+	newEmail := "New@Example.com"
+	updated, err := svc.Update(context.Background(), user.ID, domain.UpdateUserRequest{Email: &newEmail})
+	if err != nil {
+		t.Fatalf("Update() unexpected error = %v", err)
+	}
+
+	if updated.Email != "old@example.com" {
+		t.Errorf("Update() email = %v, want unchanged old@example.com", updated.Email)
+	}
+	if updated.PendingEmail != "new@example.com" {
+		t.Errorf("Update() pendingEmail = %v, want new@example.com", updated.PendingEmail)
+	}
+	if !updated.EmailVerified {
+		t.Error("Update() should keep the current address verified until the change is confirmed")
+	}
+
+	messages := sender.Messages()
+	if len(messages) != 2 {
+		t.Fatalf("Update() sent %v emails, want 2", len(messages))
+	}
+	if messages[0].To != "new@example.com" {
+		t.Errorf("confirmation sent to %v, want new@example.com", messages[0].To)
+	}
+	if messages[1].To != "old@example.com" {
+		t.Errorf("notice sent to %v, want old@example.com", messages[1].To)
+	}
+
+	notifier := &mockNotifier{}
+	svc.notifier = notifier
+
+	confirmed, err := svc.ConfirmEmailChange(context.Background(), domain.ConfirmEmailChangeRequest{Token: tokenFromMail(t, messages[0])})
+	if err != nil {
+		t.Fatalf("ConfirmEmailChange() unexpected error = %v", err)
+	}
+	if confirmed.Email != "new@example.com" || confirmed.PendingEmail != "" || !confirmed.EmailVerified {
+		t.Errorf("ConfirmEmailChange() user = %+v, want swapped and verified email", confirmed)
+	}
+	if len(notifier.events) != 1 || notifier.events[0] != domain.EventTypeUserEmailChanged {
+		t.Errorf("ConfirmEmailChange() events = %v, want [%v]", notifier.events, domain.EventTypeUserEmailChanged)
+	}
+
+	_, err = svc.ConfirmEmailChange(context.Background(), domain.ConfirmEmailChangeRequest{Token: tokenFromMail(t, messages[0])})
+	if err != domain.ErrInvalidToken {
+		t.Errorf("ConfirmEmailChange() reused token error = %v, want %v", err, domain.ErrInvalidToken)
+	}
+}
+
+func TestUserService_ConfirmEmailChange_SupersededRequest(t *testing.T) {
+	svc, repo, sender, _ := newVerificationService(false)
+
+	user := &domain.User{ID: uuid.New(), Email: "old@example.com", FirstName: "John", LastName: "Doe", Status: domain.UserStatusActive}
+	repo.users[user.ID] = user
+
+	first := "first@example.com"
+	second := "second@example.com"
+	svc.Update(context.Background(), user.ID, domain.UpdateUserRequest{Email: &first})
+	svc.Update(context.Background(), user.ID, domain.UpdateUserRequest{Email: &second})
+
+	firstToken := tokenFromMail(t, sender.Messages()[0])
+	if _, err := svc.ConfirmEmailChange(context.Background(), domain.ConfirmEmailChangeRequest{Token: firstToken}); err != domain.ErrInvalidToken {
+		t.Errorf("ConfirmEmailChange() superseded token error = %v, want %v", err, domain.ErrInvalidToken)
+	}
+
+	secondToken := tokenFromMail(t, sender.Messages()[2])
+	confirmed, err := svc.ConfirmEmailChange(context.Background(), domain.ConfirmEmailChangeRequest{Token: secondToken})
+	if err != nil {
+		t.Fatalf("ConfirmEmailChange() unexpected error = %v", err)
+	}
+	if confirmed.Email != second {
+		t.Errorf("ConfirmEmailChange() email = %v, want %v", confirmed.Email, second)
+	}
+}
+
+func TestUserService_ConfirmEmailChange_EmailTakenMeanwhile(t *testing.T) {
+	svc, repo, sender, _ := newVerificationService(false)
+
+	user := &domain.User{ID: uuid.New(), Email: "old@example.com", FirstName: "John", LastName: "Doe", Status: domain.UserStatusActive}
+	repo.users[user.ID] = user
+
+	newEmail := "new@example.com"
+	svc.Update(context.Background(), user.ID, domain.UpdateUserRequest{Email: &newEmail})
+
+	other := &domain.User{ID: uuid.New(), Email: newEmail, Status: domain.UserStatusActive}
+	repo.users[other.ID] = other
+	repo.byEmail[other.Email] = other
+
+	_, err := svc.ConfirmEmailChange(context.Background(), domain.ConfirmEmailChangeRequest{Token: tokenFromMail(t, sender.Messages()[0])})
+	if err != domain.ErrEmailExists {
+		t.Errorf("ConfirmEmailChange() error = %v, want %v", err, domain.ErrEmailExists)
+	}
+}
+
+func TestUserService_Update_EmailChangeCancelled(t *testing.T) {
+	svc, repo, _, _ := newVerificationService(false)
+
+	user := &domain.User{ID: uuid.New(), Email: "old@example.com", PendingEmail: "new@example.com", FirstName: "John", LastName: "Doe", Status: domain.UserStatusActive}
+	repo.users[user.ID] = user
+
+	current := "old@example.com"
+	updated, err := svc.Update(context.Background(), user.ID, domain.UpdateUserRequest{Email: &current})
+	if err != nil {
+		t.Fatalf("Update() unexpected error = %v", err)
+	}
+	if updated.PendingEmail != "" {
+		t.Errorf("Update() pendingEmail = %v, want cleared", updated.PendingEmail)
+	}
+}
+
+func TestUserService_Update_EmailChangeWithoutMailer(t *testing.T) {
+	signer := token.NewSigner([]byte("test-secret"))
+	// The wiring of cmd/api when SMTP_HOST is not set.
+	noMailer := mailer.NewSender(&config.Config{}, slog.New(slog.DiscardHandler))
+
+	tests := []struct {
+		name          string
+		opts          []Option
+		wantErr       error
+		wantImmediate bool
+	}{
+		{name: "no email verification", wantImmediate: true},
+		{
+			name:    "smtp not configured",
+			opts:    []Option{WithEmailVerification(noMailer, signer, VerificationConfig{TokenTTL: time.Hour})},
+			wantErr: domain.ErrNotSupported,
+		},
+		{
+			name:          "smtp not configured, confirmation skipped",
+			opts:          []Option{WithEmailVerification(noMailer, signer, VerificationConfig{TokenTTL: time.Hour, SkipEmailChangeConfirmation: true})},
+			wantImmediate: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newMockUserRepository()
+			svc := NewUserService(repo, &mockNotifier{}, tt.opts...)
+
+			user := &domain.User{ID: uuid.New(), Email: "old@example.com", EmailVerified: true, FirstName: "John", LastName: "Doe", Status: domain.UserStatusActive}
+			repo.users[user.ID] = user
+
+			newEmail := "new@example.com"
+			updated, err := svc.Update(context.Background(), user.ID, domain.UpdateUserRequest{Email: &newEmail})
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Update() error = %v, want %v", err, tt.wantErr)
+				}
+				if stored := repo.users[user.ID]; stored.Email != "old@example.com" || stored.PendingEmail != "" {
+					t.Errorf("stored user = %+v, want unchanged", stored)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Update() unexpected error = %v", err)
+			}
+			if updated.Email != newEmail || updated.PendingEmail != "" || updated.EmailVerified {
+				t.Errorf("Update() user = %+v, want email changed immediately and unverified", updated)
+			}
+		})
+	}
+}
+
+func TestUserService_ConfirmEmailChange_TenantFromToken(t *testing.T) {
+	svc, repo, sender, _ := newVerificationService(false)
+
+	user := &domain.User{ID: uuid.New(), TenantID: "acme", Email: "old@example.com", EmailVerified: true, FirstName: "John", LastName: "Doe", Status: domain.UserStatusActive}
+	repo.users[user.ID] = user
+	repo.byEmail[user.Email] = user
+
+	newEmail := "new@example.com"
+	if _, err := svc.Update(domain.ContextWithTenant(context.Background(), "acme"), user.ID, domain.UpdateUserRequest{Email: &newEmail}); err != nil {
+		t.Fatalf("Update() unexpected error = %v", err)
+	}
+
+	confirmed, err := svc.ConfirmEmailChange(context.Background(), domain.ConfirmEmailChangeRequest{Token: tokenFromMail(t, sender.Messages()[0])})
+	if err != nil {
+		t.Fatalf("ConfirmEmailChange() unexpected error = %v", err)
+	}
+	if confirmed.Email != "new@example.com" {
+		t.Errorf("ConfirmEmailChange() email = %v, want new@example.com", confirmed.Email)
+	}
+}
@@ -0,0 +1,196 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/giannuccilli/user-api/internal/domain"
+)
+
+var identityProviderPattern = regexp.MustCompile(`^[a-z][a-z0-9_.-]{0,62}$`)
+
+func WithIdentityRepository(identities domain.IdentityRepository) Option {
+	return func(s *UserService) {
+		s.identities = identities
+	}
+}
+
+func (s *UserService) LinkIdentity(ctx context.Context, userID uuid.UUID, req domain.LinkIdentityRequest) (*domain.ExternalIdentity, error) {
+	if s.identities == nil {
+		return nil, domain.ErrNotSupported
+	}
+
+	v := &domain.ValidationError{}
+	validateIdentity(v, req.Provider, req.ExternalID)
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.repo.GetByID(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	identity := &domain.ExternalIdentity{
+		UserID:     userID,
+		Provider:   req.Provider,
+		ExternalID: req.ExternalID,
+	}
+	if err := s.identities.Link(ctx, identity); err != nil {
+		return nil, err
+	}
+	return identity, nil
+}
+
+func (s *UserService) UnlinkIdentity(ctx context.Context, userID uuid.UUID, provider, externalID string) error {
+	if s.identities == nil {
+		return domain.ErrNotSupported
+	}
+	return s.identities.Unlink(ctx, userID, provider, externalID)
+}
+
+func (s *UserService) ListIdentities(ctx context.Context, userID uuid.UUID) (*domain.IdentityList, error) {
+	if s.identities == nil {
+		return nil, domain.ErrNotSupported
+	}
+
+	if _, err := s.repo.GetByID(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	identities, err := s.identities.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &domain.IdentityList{Data: identities}, nil
+}
+
+func (s *UserService) GetByExternalID(ctx context.Context, provider, externalID string) (*domain.User, error) {
+	if s.identities == nil {
+		return nil, domain.ErrNotSupported
+	}
+
+	identity, err := s.identities.Get(ctx, provider, externalID)
+	if err != nil {
+		if errors.Is(err, domain.ErrIdentityNotFound) {
+			return nil, domain.ErrUserNotFound
+		}
+		return nil, err
+	}
+	return s.repo.GetByID(ctx, identity.UserID)
+}
+
+// UpsertByExternalID creates the user and links the identity, or updates the
+// user already linked to it. Repeating an upsert with the same data leaves the
+// user untouched. The returned bool reports whether the user was created.
+func (s *UserService) UpsertByExternalID(ctx context.Context, provider, externalID string, req domain.CreateUserRequest) (*domain.User, bool, error) {
+	if s.identities == nil {
+		return nil, false, domain.ErrNotSupported
+	}
+
+	v := &domain.ValidationError{}
+	validateIdentity(v, provider, externalID)
+	if err := v.Err(); err != nil {
+		return nil, false, err
+	}
+
+	identity, err := s.identities.Get(ctx, provider, externalID)
+	switch {
+	case err == nil:
+		user, err := s.updateFromExternal(ctx, identity.UserID, req)
+		return user, false, err
+	case !errors.Is(err, domain.ErrIdentityNotFound):
+		return nil, false, err
+	}
+
+	user, err := s.newUser(ctx, req)
+	if err != nil {
+		return nil, false, err
+	}
+
+	identity = &domain.ExternalIdentity{Provider: provider, ExternalID: externalID}
+	err = s.identities.CreateUser(ctx, user, identity)
+	if errors.Is(err, domain.ErrIdentityConflict) {
+		// A concurrent upsert linked the identity first: converge on its
+		// user instead of failing.
+		winner, err := s.identities.Get(ctx, provider, externalID)
+		if err != nil {
+			return nil, false, err
+		}
+		user, err := s.updateFromExternal(ctx, winner.UserID, req)
+		return user, false, err
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	s.userCreated(ctx, user)
+
+	return user, true, nil
+}
+
+func (s *UserService) updateFromExternal(ctx context.Context, id uuid.UUID, req domain.CreateUserRequest) (*domain.User, error) {
+	user, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.matchesUser(user, req) {
+		return user, nil
+	}
+
+	update := domain.UpdateUserRequest{
+		FirstName:  &req.FirstName,
+		LastName:   &req.LastName,
+		Attributes: req.Attributes,
+	}
+	// Sending the email again would restart a pending change and mail the
+	// confirmation once more.
+	if !s.sameExternalEmail(user, req.Email) {
+		update.Email = &req.Email
+	}
+	return s.Update(ctx, id, update)
+}
+
+func (s *UserService) matchesUser(user *domain.User, req domain.CreateUserRequest) bool {
+	if !s.sameExternalEmail(user, req.Email) {
+		return false
+	}
+	if strings.TrimSpace(req.FirstName) != user.FirstName || strings.TrimSpace(req.LastName) != user.LastName {
+		return false
+	}
+	merged := mergeAttributes(user.Attributes, req.Attributes)
+	return len(merged) == len(user.Attributes) && (len(merged) == 0 || reflect.DeepEqual(merged, user.Attributes))
+}
+
+// sameExternalEmail reports whether email is the one the user has or, while
+// a change is pending, the one it is changing to.
+func (s *UserService) sameExternalEmail(user *domain.User, email string) bool {
+	addr, err := s.emails.Normalize(email)
+	if err != nil {
+		return false
+	}
+	if user.PendingEmail != "" {
+		return addr.Email == user.PendingEmail
+	}
+	return addr.Email == user.Email
+}
+
+func validateIdentity(v *domain.ValidationError, provider, externalID string) {
+	if provider == "" {
+		v.Add("provider", domain.FieldCodeRequired, "is required")
+	} else if !identityProviderPattern.MatchString(provider) {
+		v.Add("provider", domain.FieldCodeInvalidFormat, "must start with a lowercase letter and contain only lowercase letters, digits, '.', '-' and '_' (max 63)")
+	}
+
+	switch {
+	case externalID == "":
+		v.Add("externalId", domain.FieldCodeRequired, "is required")
+	case len(externalID) > 255:
+		v.Add("externalId", domain.FieldCodeTooLong, "must be at most 255 characters")
+	}
+}
@@ -0,0 +1,245 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/giannuccilli/user-api/internal/domain"
+	"github.com/giannuccilli/user-api/internal/mailer"
+	"github.com/giannuccilli/user-api/internal/token"
+)
+
+type identityKey struct {
+	provider   string
+	externalID string
+}
+
+type mockIdentityRepository struct {
+	identities map[identityKey]domain.ExternalIdentity
+	users      *mockUserRepository
+	// beforeCreate runs at the start of CreateUser, to simulate concurrent
+	// upserts.
+	beforeCreate func()
+}
+
+func newMockIdentityRepository(users *mockUserRepository) *mockIdentityRepository {
+	return &mockIdentityRepository{identities: make(map[identityKey]domain.ExternalIdentity), users: users}
+}
+
+func (m *mockIdentityRepository) CreateUser(ctx context.Context, user *domain.User, identity *domain.ExternalIdentity) error {
+	if m.beforeCreate != nil {
+		m.beforeCreate()
+	}
+	if _, ok := m.identities[identityKey{identity.Provider, identity.ExternalID}]; ok {
+		return domain.ErrIdentityConflict
+	}
+	if err := m.users.Create(ctx, user); err != nil {
+		return err
+	}
+	identity.UserID = user.ID
+	return m.Link(ctx, identity)
+}
+
+func (m *mockIdentityRepository) Link(ctx context.Context, identity *domain.ExternalIdentity) error {
+	key := identityKey{identity.Provider, identity.ExternalID}
+	if existing, ok := m.identities[key]; ok {
+		if existing.UserID != identity.UserID {
+			return domain.ErrIdentityConflict
+		}
+		identity.CreatedAt = existing.CreatedAt
+		return nil
+	}
+	identity.CreatedAt = time.Now()
+	m.identities[key] = *identity
+	return nil
+}
+
+func (m *mockIdentityRepository) Unlink(ctx context.Context, userID uuid.UUID, provider, externalID string) error {
+	key := identityKey{provider, externalID}
+	if existing, ok := m.identities[key]; !ok || existing.UserID != userID {
+		return domain.ErrIdentityNotFound
+	}
+	delete(m.identities, key)
+	return nil
+}
+
+func (m *mockIdentityRepository) Get(ctx context.Context, provider, externalID string) (*domain.ExternalIdentity, error) {
+	identity, ok := m.identities[identityKey{provider, externalID}]
+	if !ok {
+		return nil, domain.ErrIdentityNotFound
+	}
+	return &identity, nil
+}
+
+func (m *mockIdentityRepository) ListByUser(ctx context.Context, userID uuid.UUID) ([]domain.ExternalIdentity, error) {
+	identities := make([]domain.ExternalIdentity, 0)
+	for _, identity := range m.identities {
+		if identity.UserID == userID {
+			identities = append(identities, identity)
+		}
+	}
+	return identities, nil
+}
+
+func TestUserService_LinkIdentity(t *testing.T) {
+	repo := newMockUserRepository()
+	svc := NewUserService(repo, &mockNotifier{}, WithIdentityRepository(newMockIdentityRepository(repo)))
+	ctx := context.Background()
+
+	alice := &domain.User{ID: uuid.New(), Email: "alice@example.com", Status: domain.UserStatusActive}
+	bob := &domain.User{ID: uuid.New(), Email: "bob@example.com", Status: domain.UserStatusActive}
+	repo.users[alice.ID] = alice
+	repo.users[bob.ID] = bob
+
+	tests := []struct {
+		name    string
+		userID  uuid.UUID
+		req     domain.LinkIdentityRequest
+		wantErr error
+	}{
+		{"link", alice.ID, domain.LinkIdentityRequest{Provider: "okta", ExternalID: "00u1"}, nil},
+		{"relink same user", alice.ID, domain.LinkIdentityRequest{Provider: "okta", ExternalID: "00u1"}, nil},
+		{"linked to another user", bob.ID, domain.LinkIdentityRequest{Provider: "okta", ExternalID: "00u1"}, domain.ErrIdentityConflict},
+		{"same id other provider", bob.ID, domain.LinkIdentityRequest{Provider: "salesforce", ExternalID: "00u1"}, nil},
+		{"unknown user", uuid.New(), domain.LinkIdentityRequest{Provider: "okta", ExternalID: "00u2"}, domain.ErrUserNotFound},
+		{"invalid provider", alice.ID, domain.LinkIdentityRequest{Provider: "Okta!", ExternalID: "00u3"}, domain.ErrInvalidInput},
+		{"missing external id", alice.ID, domain.LinkIdentityRequest{Provider: "okta"}, domain.ErrInvalidInput},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := svc.LinkIdentity(ctx, tt.userID, tt.req)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("LinkIdentity() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	user, err := svc.GetByExternalID(ctx, "okta", "00u1")
+	if err != nil || user.ID != alice.ID {
+		t.Errorf("GetByExternalID() = %v, %v, want alice", user, err)
+	}
+
+	if err := svc.UnlinkIdentity(ctx, alice.ID, "okta", "00u1"); err != nil {
+		t.Fatalf("UnlinkIdentity() error = %v", err)
+	}
+	if _, err := svc.GetByExternalID(ctx, "okta", "00u1"); !errors.Is(err, domain.ErrUserNotFound) {
+		t.Errorf("GetByExternalID() after unlink error = %v, want %v", err, domain.ErrUserNotFound)
+	}
+}
+
+func TestUserService_UpsertByExternalID(t *testing.T) {
+	repo := newMockUserRepository()
+	notifier := &recordingNotifier{}
+	svc := NewUserService(repo, notifier, WithIdentityRepository(newMockIdentityRepository(repo)))
+	ctx := context.Background()
+
+	req := domain.CreateUserRequest{Email: "sync@example.com", FirstName: "John", LastName: "Doe"}
+
+	user, created, err := svc.UpsertByExternalID(ctx, "okta", "00u1", req)
+	if err != nil || !created {
+		t.Fatalf("UpsertByExternalID() created = %v, error = %v, want created", created, err)
+	}
+
+	again, created, err := svc.UpsertByExternalID(ctx, "okta", "00u1", req)
+	if err != nil || created {
+		t.Fatalf("UpsertByExternalID() repeat created = %v, error = %v", created, err)
+	}
+	if again.ID != user.ID || len(notifier.updated) != 0 {
+		t.Errorf("UpsertByExternalID() repeat = %+v, updates = %v, want unchanged", again, len(notifier.updated))
+	}
+
+	req.FirstName = "Johnny"
+	updated, created, err := svc.UpsertByExternalID(ctx, "okta", "00u1", req)
+	if err != nil || created {
+		t.Fatalf("UpsertByExternalID() update created = %v, error = %v", created, err)
+	}
+	if updated.ID != user.ID || updated.FirstName != "Johnny" {
+		t.Errorf("UpsertByExternalID() update = %+v, want FirstName Johnny", updated)
+	}
+	if len(repo.users) != 1 {
+		t.Errorf("users = %v, want 1", len(repo.users))
+	}
+
+	if _, _, err := svc.UpsertByExternalID(ctx, "", "00u1", req); !errors.Is(err, domain.ErrInvalidInput) {
+		t.Errorf("UpsertByExternalID() without provider error = %v, want %v", err, domain.ErrInvalidInput)
+	}
+
+	unconfigured := NewUserService(repo, &mockNotifier{})
+	if _, _, err := unconfigured.UpsertByExternalID(ctx, "okta", "00u1", req); !errors.Is(err, domain.ErrNotSupported) {
+		t.Errorf("UpsertByExternalID() without repository error = %v, want %v", err, domain.ErrNotSupported)
+	}
+}
+
+func TestUserService_UpsertByExternalID_ConcurrentLink(t *testing.T) {
+	repo := newMockUserRepository()
+	identities := newMockIdentityRepository(repo)
+	notifier := &recordingNotifier{}
+	svc := NewUserService(repo, notifier, WithIdentityRepository(identities))
+	ctx := context.Background()
+
+	winner := &domain.User{ID: uuid.New(), Email: "sync@example.com", FirstName: "John", LastName: "Doe", Status: domain.UserStatusActive}
+	identities.beforeCreate = func() {
+		repo.users[winner.ID] = winner
+		identities.Link(ctx, &domain.ExternalIdentity{UserID: winner.ID, Provider: "okta", ExternalID: "00u1"})
+	}
+
+	req := domain.CreateUserRequest{Email: "sync@example.com", FirstName: "Johnny", LastName: "Doe"}
+	user, created, err := svc.UpsertByExternalID(ctx, "okta", "00u1", req)
+	if err != nil || created {
+		t.Fatalf("UpsertByExternalID() created = %v, error = %v, want updated", created, err)
+	}
+	if user.ID != winner.ID || user.FirstName != "Johnny" {
+		t.Errorf("UpsertByExternalID() = %+v, want the linked user updated", user)
+	}
+	if len(repo.users) != 1 || len(notifier.created) != 0 {
+		t.Errorf("users = %v, created events = %v, want no user created", len(repo.users), len(notifier.created))
+	}
+}
+
+func TestUserService_UpsertByExternalID_PendingEmail(t *testing.T) {
+	repo := newMockUserRepository()
+	identities := newMockIdentityRepository(repo)
+	sender := mailer.NewMemorySender()
+	notifier := &recordingNotifier{}
+	svc := NewUserService(repo, notifier,
+		WithIdentityRepository(identities),
+		WithEmailVerification(sender, token.NewSigner([]byte("test-secret")), VerificationConfig{TokenTTL: time.Hour}),
+	)
+	ctx := context.Background()
+
+	user := &domain.User{ID: uuid.New(), Email: "old@example.com", PendingEmail: "new@example.com", FirstName: "John", LastName: "Doe", Status: domain.UserStatusActive}
+	repo.users[user.ID] = user
+	identities.Link(ctx, &domain.ExternalIdentity{UserID: user.ID, Provider: "okta", ExternalID: "00u1"})
+
+	tests := []struct {
+		name        string
+		req         domain.CreateUserRequest
+		wantUpdates int
+		wantMails   int
+		wantPending string
+	}{
+		{"pending email unchanged", domain.CreateUserRequest{Email: "new@example.com", FirstName: "John", LastName: "Doe"}, 0, 0, "new@example.com"},
+		{"names changed", domain.CreateUserRequest{Email: "New@Example.com", FirstName: "Johnny", LastName: "Doe"}, 1, 0, "new@example.com"},
+		{"another email", domain.CreateUserRequest{Email: "newer@example.com", FirstName: "Johnny", LastName: "Doe"}, 2, 2, "newer@example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _, err := svc.UpsertByExternalID(ctx, "okta", "00u1", tt.req)
+			if err != nil {
+				t.Fatalf("UpsertByExternalID() error = %v", err)
+			}
+			if got.PendingEmail != tt.wantPending || got.FirstName != tt.req.FirstName {
+				t.Errorf("UpsertByExternalID() = %+v, want pending %s", got, tt.wantPending)
+			}
+			if len(notifier.updated) != tt.wantUpdates || len(sender.Messages()) != tt.wantMails {
+				t.Errorf("updates = %v, mails = %v, want %v and %v", len(notifier.updated), len(sender.Messages()), tt.wantUpdates, tt.wantMails)
+			}
+		})
+	}
+}
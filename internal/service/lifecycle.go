@@ -0,0 +1,336 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/giannuccilli/user-api/internal/domain"
+)
+
+type Transition struct {
+	From      domain.UserStatus
+	To        domain.UserStatus
+	AdminOnly bool
+}
+
+type TransitionGraph struct {
+	transitions map[domain.UserStatus]map[domain.UserStatus]Transition
+}
+
+func NewTransitionGraph(transitions ...Transition) *TransitionGraph {
+	g := &TransitionGraph{transitions: make(map[domain.UserStatus]map[domain.UserStatus]Transition)}
+	for _, t := range transitions {
+		if g.transitions[t.From] == nil {
+			g.transitions[t.From] = make(map[domain.UserStatus]Transition)
+		}
+		g.transitions[t.From][t.To] = t
+	}
+	return g
+}
+
+func DefaultTransitionGraph() *TransitionGraph {
+	return NewTransitionGraph(
+		Transition{From: domain.UserStatusActive, To: domain.UserStatusInactive},
+		Transition{From: domain.UserStatusActive, To: domain.UserStatusSuspended},
+		Transition{From: domain.UserStatusInactive, To: domain.UserStatusActive},
+		Transition{From: domain.UserStatusInactive, To: domain.UserStatusSuspended},
+		Transition{From: domain.UserStatusSuspended, To: domain.UserStatusActive, AdminOnly: true},
+		Transition{From: domain.UserStatusSuspended, To: domain.UserStatusInactive, AdminOnly: true},
+		Transition{From: domain.UserStatusPendingVerification, To: domain.UserStatusActive, AdminOnly: true},
+		Transition{From: domain.UserStatusPendingVerification, To: domain.UserStatusInactive},
+		Transition{From: domain.UserStatusPendingVerification, To: domain.UserStatusSuspended},
+	)
+}
+
+// ParseTransitionGraph reads a comma-separated list of "from->to" edges,
+// where a ":admin" suffix restricts the edge to admins, e.g.
+// "active->suspended,suspended->active:admin".
+func ParseTransitionGraph(spec string) (*TransitionGraph, error) {
+	var transitions []Transition
+	for _, edge := range strings.Split(spec, ",") {
+		edge = strings.TrimSpace(edge)
+		if edge == "" {
+			continue
+		}
+
+		adminOnly := false
+		if rule, ok := strings.CutSuffix(edge, ":admin"); ok {
+			edge = rule
+			adminOnly = true
+		}
+
+		from, to, ok := strings.Cut(edge, "->")
+		if !ok {
+			return nil, fmt.Errorf("invalid transition %q: expected from->to", edge)
+		}
+
+		t := Transition{
+			From:      domain.UserStatus(strings.TrimSpace(from)),
+			To:        domain.UserStatus(strings.TrimSpace(to)),
+			AdminOnly: adminOnly,
+		}
+		if !t.From.IsValid() || !t.To.IsValid() {
+			return nil, fmt.Errorf("invalid transition %q: unknown status", edge)
+		}
+		transitions = append(transitions, t)
+	}
+
+	if len(transitions) == 0 {
+		return nil, fmt.Errorf("transition graph is empty")
+	}
+
+	return NewTransitionGraph(transitions...), nil
+}
+
+func (g *TransitionGraph) Lookup(from, to domain.UserStatus) (Transition, bool) {
+	t, ok := g.transitions[from][to]
+	return t, ok
+}
+
+type statusChange struct {
+	to             domain.UserStatus
+	reason         *domain.SuspensionReason
+	suspendedUntil *time.Time
+}
+
+// validateStatusChange reports the fields of an update-style status change,
+// named as in UpdateUserRequest.
+func validateStatusChange(v *domain.ValidationError, change statusChange) {
+	validateStatus(v, "status", change.to)
+	if change.to != domain.UserStatusSuspended {
+		return
+	}
+	validateSuspensionReason(v, "statusReason", change.reason)
+	if change.suspendedUntil != nil && !change.suspendedUntil.After(time.Now()) {
+		v.Add("suspendedUntil", domain.FieldCodeInvalidValue, "must be in the future")
+	}
+}
+
+// applyStatusChange validates the requested transition against the graph and
+// the actor in ctx, mutates user accordingly and returns the lifecycle event
+// to emit, if any.
+func (s *UserService) applyStatusChange(ctx context.Context, user *domain.User, change statusChange) (domain.EventType, error) {
+	v := &domain.ValidationError{}
+	validateStatusChange(v, change)
+	if err := v.Err(); err != nil {
+		return "", err
+	}
+
+	from := user.Status
+	switch {
+	case from == change.to && change.to != domain.UserStatusSuspended:
+		return "", nil
+	case from == change.to:
+		// Amending an existing suspension could be used to lift it early.
+		if !domain.ActorFromContext(ctx).IsAdmin() {
+			return "", domain.ErrForbidden
+		}
+	default:
+		transition, ok := s.transitions.Lookup(from, change.to)
+		if !ok {
+			return "", domain.ErrInvalidTransition
+		}
+		if transition.AdminOnly && !domain.ActorFromContext(ctx).IsAdmin() {
+			return "", domain.ErrForbidden
+		}
+	}
+
+	if change.to == domain.UserStatusSuspended {
+		user.Status = domain.UserStatusSuspended
+		user.StatusReason = *change.reason
+		user.SuspendedUntil = change.suspendedUntil
+		return domain.EventTypeUserSuspended, nil
+	}
+
+	user.Status = change.to
+	user.StatusReason = ""
+	user.SuspendedUntil = nil
+
+	switch {
+	case change.to == domain.UserStatusInactive:
+		return domain.EventTypeUserDeactivated, nil
+	case from == domain.UserStatusSuspended:
+		return domain.EventTypeUserReactivated, nil
+	default:
+		return domain.EventTypeUserActivated, nil
+	}
+}
+
+func (s *UserService) Suspend(ctx context.Context, id uuid.UUID, req domain.SuspendUserRequest) (*domain.User, error) {
+	reason := req.Reason
+
+	v := &domain.ValidationError{}
+	validateSuspensionReason(v, "reason", &reason)
+	validateNote(v, req.Note)
+
+	var suspendedUntil *time.Time
+	if req.Duration != "" {
+		duration, err := time.ParseDuration(req.Duration)
+		switch {
+		case err != nil:
+			v.Add("duration", domain.FieldCodeInvalidFormat, "must be a duration such as 72h")
+		case duration <= 0:
+			v.Add("duration", domain.FieldCodeInvalidValue, "must be positive")
+		default:
+			until := time.Now().UTC().Add(duration)
+			suspendedUntil = &until
+		}
+	}
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+
+	return s.changeStatus(ctx, id, statusChange{
+		to:             domain.UserStatusSuspended,
+		reason:         &reason,
+		suspendedUntil: suspendedUntil,
+	}, req.Note)
+}
+
+func (s *UserService) Activate(ctx context.Context, id uuid.UUID, req domain.ActivateUserRequest) (*domain.User, error) {
+	v := &domain.ValidationError{}
+	validateNote(v, req.Note)
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+	return s.changeStatus(ctx, id, statusChange{to: domain.UserStatusActive}, req.Note)
+}
+
+func (s *UserService) Deactivate(ctx context.Context, id uuid.UUID, req domain.DeactivateUserRequest) (*domain.User, error) {
+	v := &domain.ValidationError{}
+	validateNote(v, req.Note)
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+	return s.changeStatus(ctx, id, statusChange{to: domain.UserStatusInactive}, req.Note)
+}
+
+// ListAudit returns the audit trail of a user of the tenant in the context.
+func (s *UserService) ListAudit(ctx context.Context, id uuid.UUID, limit, offset int) (*domain.AuditList, error) {
+	limit, offset = normalizePage(limit, offset)
+
+	if _, err := s.repo.GetByID(ctx, id); err != nil {
+		return nil, err
+	}
+
+	entries, total := []domain.AuditEntry{}, 0
+	if s.audit != nil {
+		var err error
+		entries, total, err = s.audit.ListByUser(ctx, id, limit, offset)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &domain.AuditList{
+		Data: entries,
+		Pagination: domain.Pagination{
+			Total:  total,
+			Limit:  limit,
+			Offset: offset,
+		},
+	}, nil
+}
+
+func (s *UserService) changeStatus(ctx context.Context, id uuid.UUID, change statusChange, note string) (*domain.User, error) {
+	user, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	from := user.Status
+	event, err := s.applyStatusChange(ctx, user, change)
+	if err != nil {
+		return nil, err
+	}
+	if event == "" {
+		return user, nil
+	}
+
+	if err := s.update(ctx, user, event, from, note); err != nil {
+		return nil, err
+	}
+
+	s.notifier.Notify(ctx, event, lifecycleEventData(user))
+
+	return user, nil
+}
+
+// update stores user and, when auditing is enabled, the audit entry of its
+// status change in the same transaction. action is empty when the status did
+// not change.
+func (s *UserService) update(ctx context.Context, user *domain.User, action domain.EventType, from domain.UserStatus, note string) error {
+	if s.audit == nil || action == "" {
+		return s.repo.Update(ctx, user)
+	}
+
+	entry := &domain.AuditEntry{
+		UserID:     user.ID,
+		Action:     action,
+		ActorID:    domain.ActorFromContext(ctx).ID,
+		FromStatus: from,
+		ToStatus:   user.Status,
+		Reason:     user.StatusReason,
+		Note:       note,
+	}
+	return s.repo.UpdateWithAudit(ctx, user, entry)
+}
+
+func (s *UserService) ReactivateExpired(ctx context.Context, now time.Time, limit int) (int, error) {
+	users, err := s.repo.ListExpiredSuspensions(domain.ContextWithAllTenants(ctx), now, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	reactivated := 0
+	for i := range users {
+		user := &users[i]
+		// Suspensions are listed across tenants; each user is updated and
+		// notified within its own.
+		ctx := domain.ContextWithTenant(ctx, user.TenantID)
+
+		var entry *domain.AuditEntry
+		if s.audit != nil {
+			entry = &domain.AuditEntry{
+				UserID:     user.ID,
+				Action:     domain.EventTypeUserReactivated,
+				ActorID:    domain.ActorFromContext(ctx).ID,
+				FromStatus: domain.UserStatusSuspended,
+				ToStatus:   domain.UserStatusActive,
+				Note:       "suspension expired",
+			}
+		}
+
+		// The update only applies if the user is still suspended, so a user
+		// reactivated meanwhile by an admin or by another replica is skipped.
+		ok, err := s.repo.ReactivateSuspension(ctx, user, now, entry)
+		if err != nil {
+			s.logger.Error("failed to reactivate user",
+				slog.String("user_id", user.ID.String()),
+				slog.String("tenant_id", user.TenantID),
+				slog.String("error", err.Error()),
+			)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		reactivated++
+
+		s.notifier.Notify(ctx, domain.EventTypeUserReactivated, eventData(user))
+	}
+
+	return reactivated, nil
+}
+
+func lifecycleEventData(user *domain.User) domain.EventData {
+	data := eventData(user)
+	data.Reason = user.StatusReason
+	data.SuspendedUntil = user.SuspendedUntil
+	return data
+}
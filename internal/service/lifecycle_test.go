@@ -0,0 +1,477 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/giannuccilli/user-api/internal/domain"
+)
+
+func TestParseTransitionGraph(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    string
+		wantErr bool
+	}{
+		{"single edge", "active->inactive", false},
+		{"admin edge", "active->suspended, suspended->active:admin", false},
+		{"missing arrow", "active-inactive", true},
+		{"unknown status", "active->deleted", true},
+		{"empty", " , ", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseTransitionGraph(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseTransitionGraph(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			}
+		})
+	}
+
+	graph, _ := ParseTransitionGraph("active->suspended,suspended->active:admin")
+	if tr, ok := graph.Lookup(domain.UserStatusSuspended, domain.UserStatusActive); !ok || !tr.AdminOnly {
+		t.Errorf("Lookup(suspended, active) = %+v, %v, want admin-only edge", tr, ok)
+	}
+	if _, ok := graph.Lookup(domain.UserStatusActive, domain.UserStatusInactive); ok {
+		t.Error("Lookup(active, inactive) should not exist")
+	}
+}
+
+func TestUserService_Update_StatusTransitions(t *testing.T) {
+	abuse := domain.SuspensionReasonAbuse
+	invalidReason := domain.SuspensionReason("bored")
+	past := time.Now().Add(-time.Hour)
+	admin := domain.Actor{ID: "admin-1", Roles: []string{domain.RoleAdmin}}
+
+	tests := []struct {
+		name      string
+		from      domain.UserStatus
+		req       domain.UpdateUserRequest
+		actor     domain.Actor
+		wantErr   error
+		wantEvent domain.EventType
+	}{
+		{
+			name:      "deactivate",
+			from:      domain.UserStatusActive,
+			req:       domain.UpdateUserRequest{Status: statusPtr(domain.UserStatusInactive)},
+			wantEvent: domain.EventTypeUserDeactivated,
+		},
+		{
+			name:      "activate",
+			from:      domain.UserStatusInactive,
+			req:       domain.UpdateUserRequest{Status: statusPtr(domain.UserStatusActive)},
+			wantEvent: domain.EventTypeUserActivated,
+		},
+		{
+			name: "unchanged status",
+			from: domain.UserStatusActive,
+			req:  domain.UpdateUserRequest{Status: statusPtr(domain.UserStatusActive)},
+		},
+		{
+			name:      "suspend with reason",
+			from:      domain.UserStatusActive,
+			req:       domain.UpdateUserRequest{Status: statusPtr(domain.UserStatusSuspended), StatusReason: &abuse},
+			wantEvent: domain.EventTypeUserSuspended,
+		},
+		{
+			name:    "suspend without reason",
+			from:    domain.UserStatusActive,
+			req:     domain.UpdateUserRequest{Status: statusPtr(domain.UserStatusSuspended)},
+			wantErr: domain.ErrInvalidInput,
+		},
+		{
+			name:    "suspend with unknown reason",
+			from:    domain.UserStatusActive,
+			req:     domain.UpdateUserRequest{Status: statusPtr(domain.UserStatusSuspended), StatusReason: &invalidReason},
+			wantErr: domain.ErrInvalidInput,
+		},
+		{
+			name: "suspend until the past",
+			from: domain.UserStatusActive,
+			req: domain.UpdateUserRequest{
+				Status:         statusPtr(domain.UserStatusSuspended),
+				StatusReason:   &abuse,
+				SuspendedUntil: &past,
+			},
+			wantErr: domain.ErrInvalidInput,
+		},
+		{
+			name:    "reactivate without admin",
+			from:    domain.UserStatusSuspended,
+			req:     domain.UpdateUserRequest{Status: statusPtr(domain.UserStatusActive)},
+			wantErr: domain.ErrForbidden,
+		},
+		{
+			name:      "reactivate as admin",
+			from:      domain.UserStatusSuspended,
+			req:       domain.UpdateUserRequest{Status: statusPtr(domain.UserStatusActive)},
+			actor:     admin,
+			wantEvent: domain.EventTypeUserReactivated,
+		},
+		{
+			name:    "amend suspension without admin",
+			from:    domain.UserStatusSuspended,
+			req:     domain.UpdateUserRequest{Status: statusPtr(domain.UserStatusSuspended), StatusReason: &abuse},
+			wantErr: domain.ErrForbidden,
+		},
+		{
+			name:    "unknown status",
+			from:    domain.UserStatusActive,
+			req:     domain.UpdateUserRequest{Status: statusPtr("deleted")},
+			wantErr: domain.ErrInvalidInput,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newMockUserRepository()
+			notifier := &mockNotifier{}
+			svc := NewUserService(repo, notifier)
+
+			user := &domain.User{ID: uuid.New(), Email: "test@example.com", FirstName: "John", LastName: "Doe", Status: tt.from}
+			repo.users[user.ID] = user
+
+			ctx := domain.ContextWithActor(context.Background(), tt.actor)
+			updated, err := svc.Update(ctx, user.ID, tt.req)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Update() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+
+			if updated.Status != *tt.req.Status {
+				t.Errorf("Update() status = %v, want %v", updated.Status, *tt.req.Status)
+			}
+			if tt.wantEvent == "" && len(notifier.events) != 0 {
+				t.Errorf("Update() events = %v, want none", notifier.events)
+			}
+			if tt.wantEvent != "" && (len(notifier.events) != 1 || notifier.events[0] != tt.wantEvent) {
+				t.Errorf("Update() events = %v, want [%v]", notifier.events, tt.wantEvent)
+			}
+		})
+	}
+}
+
+func TestUserService_Update_InvalidTransition(t *testing.T) {
+	repo := newMockUserRepository()
+	graph := NewTransitionGraph(Transition{From: domain.UserStatusActive, To: domain.UserStatusInactive})
+	svc := NewUserService(repo, &mockNotifier{}, WithTransitionGraph(graph))
+
+	user := &domain.User{ID: uuid.New(), Email: "test@example.com", FirstName: "John", LastName: "Doe", Status: domain.UserStatusInactive}
+	repo.users[user.ID] = user
+
+	_, err := svc.Update(context.Background(), user.ID, domain.UpdateUserRequest{Status: statusPtr(domain.UserStatusActive)})
+	if err != domain.ErrInvalidTransition {
+		t.Errorf("Update() error = %v, want %v", err, domain.ErrInvalidTransition)
+	}
+}
+
+func TestUserService_ReactivateExpired(t *testing.T) {
+	repo := newMockUserRepository()
+	notifier := &mockNotifier{}
+	svc := NewUserService(repo, notifier)
+
+	now := time.Now().UTC()
+	expired := now.Add(-time.Minute)
+	pending := now.Add(time.Hour)
+
+	expiredUser := &domain.User{ID: uuid.New(), TenantID: "acme", Email: "expired@example.com", Status: domain.UserStatusSuspended,
+		StatusReason: domain.SuspensionReasonAbuse, SuspendedUntil: &expired}
+	pendingUser := &domain.User{ID: uuid.New(), Email: "pending@example.com", Status: domain.UserStatusSuspended,
+		StatusReason: domain.SuspensionReasonAbuse, SuspendedUntil: &pending}
+	indefiniteUser := &domain.User{ID: uuid.New(), Email: "indefinite@example.com", Status: domain.UserStatusSuspended,
+		StatusReason: domain.SuspensionReasonFraud}
+	repo.users[expiredUser.ID] = expiredUser
+	repo.users[pendingUser.ID] = pendingUser
+	repo.users[indefiniteUser.ID] = indefiniteUser
+
+	count, err := svc.ReactivateExpired(context.Background(), now, 10)
+	if err != nil {
+		t.Fatalf("ReactivateExpired() unexpected error = %v", err)
+	}
+	if count != 1 {
+		t.Errorf("ReactivateExpired() count = %v, want 1", count)
+	}
+
+	if got := repo.users[expiredUser.ID]; got.Status != domain.UserStatusActive || got.SuspendedUntil != nil || got.StatusReason != "" {
+		t.Errorf("expired user = %+v, want active with cleared suspension", got)
+	}
+	if repo.users[pendingUser.ID].Status != domain.UserStatusSuspended {
+		t.Error("pending user should still be suspended")
+	}
+	if repo.users[indefiniteUser.ID].Status != domain.UserStatusSuspended {
+		t.Error("indefinitely suspended user should still be suspended")
+	}
+	if len(notifier.events) != 1 || notifier.events[0] != domain.EventTypeUserReactivated {
+		t.Errorf("ReactivateExpired() events = %v, want [%v]", notifier.events, domain.EventTypeUserReactivated)
+	}
+}
+
+func TestUserService_ReactivateExpired_ContinuesAfterFailure(t *testing.T) {
+	var logs bytes.Buffer
+	repo := newMockUserRepository()
+	audit := &mockAuditRepository{}
+	repo.audit = audit
+	notifier := &mockNotifier{}
+	svc := NewUserService(repo, notifier, WithAuditRepository(audit), WithLogger(slog.New(slog.NewTextHandler(&logs, nil))))
+
+	now := time.Now().UTC()
+	expired := now.Add(-time.Minute)
+	failing := &domain.User{ID: uuid.New(), Email: "failing@example.com", Status: domain.UserStatusSuspended,
+		StatusReason: domain.SuspensionReasonAbuse, SuspendedUntil: &expired}
+	other := &domain.User{ID: uuid.New(), Email: "other@example.com", Status: domain.UserStatusSuspended,
+		StatusReason: domain.SuspensionReasonAbuse, SuspendedUntil: &expired}
+	repo.users[failing.ID] = failing
+	repo.users[other.ID] = other
+	repo.reactivateErrs = map[uuid.UUID]error{failing.ID: errors.New("connection reset")}
+
+	count, err := svc.ReactivateExpired(context.Background(), now, 10)
+	if err != nil {
+		t.Fatalf("ReactivateExpired() unexpected error = %v", err)
+	}
+	if count != 1 {
+		t.Errorf("ReactivateExpired() count = %v, want 1", count)
+	}
+	if repo.users[other.ID].Status != domain.UserStatusActive {
+		t.Error("other user should be reactivated despite the failure")
+	}
+	if repo.users[failing.ID].Status != domain.UserStatusSuspended {
+		t.Error("failing user should still be suspended")
+	}
+	if !strings.Contains(logs.String(), "failed to reactivate user") || !strings.Contains(logs.String(), failing.ID.String()) {
+		t.Errorf("logs = %q, want the failure of the failing user", logs.String())
+	}
+	if len(audit.entries) != 1 || audit.entries[0].UserID != other.ID || audit.entries[0].Action != domain.EventTypeUserReactivated {
+		t.Errorf("audit entries = %+v, want one reactivation of the other user", audit.entries)
+	}
+	if len(notifier.events) != 1 {
+		t.Errorf("ReactivateExpired() events = %v, want 1", notifier.events)
+	}
+}
+
+type mockAuditRepository struct {
+	entries []domain.AuditEntry
+}
+
+func (m *mockAuditRepository) Save(ctx context.Context, entry *domain.AuditEntry) error {
+	entry.ID = uuid.New()
+	entry.CreatedAt = time.Now().UTC()
+	m.entries = append(m.entries, *entry)
+	return nil
+}
+
+func (m *mockAuditRepository) ListByUser(ctx context.Context, userID uuid.UUID, limit, offset int) ([]domain.AuditEntry, int, error) {
+	entries := make([]domain.AuditEntry, 0)
+	for _, e := range m.entries {
+		if e.UserID == userID {
+			entries = append(entries, e)
+		}
+	}
+	return entries, len(entries), nil
+}
+
+func TestUserService_Suspend(t *testing.T) {
+	tests := []struct {
+		name      string
+		req       domain.SuspendUserRequest
+		wantErr   error
+		wantUntil bool
+	}{
+		{
+			name: "indefinite suspension",
+			req:  domain.SuspendUserRequest{Reason: domain.SuspensionReasonFraud, Note: "chargebacks"},
+		},
+		{
+			name:      "timed suspension",
+			req:       domain.SuspendUserRequest{Reason: domain.SuspensionReasonAbuse, Duration: "72h"},
+			wantUntil: true,
+		},
+		{
+			name:    "missing reason",
+			req:     domain.SuspendUserRequest{Duration: "72h"},
+			wantErr: domain.ErrInvalidInput,
+		},
+		{
+			name:    "invalid duration",
+			req:     domain.SuspendUserRequest{Reason: domain.SuspensionReasonAbuse, Duration: "three days"},
+			wantErr: domain.ErrInvalidInput,
+		},
+		{
+			name:    "negative duration",
+			req:     domain.SuspendUserRequest{Reason: domain.SuspensionReasonAbuse, Duration: "-1h"},
+			wantErr: domain.ErrInvalidInput,
+		},
+		{
+			name:    "note too long",
+			req:     domain.SuspendUserRequest{Reason: domain.SuspensionReasonAbuse, Note: string(make([]byte, 1001))},
+			wantErr: domain.ErrInvalidInput,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newMockUserRepository()
+			notifier := &mockNotifier{}
+			audit := &mockAuditRepository{}
+			repo.audit = audit
+			svc := NewUserService(repo, notifier, WithAuditRepository(audit))
+
+			user := &domain.User{ID: uuid.New(), Email: "test@example.com", FirstName: "John", LastName: "Doe", Status: domain.UserStatusActive}
+			repo.users[user.ID] = user
+
+			ctx := domain.ContextWithActor(context.Background(), domain.Actor{ID: "support-1"})
+			suspended, err := svc.Suspend(ctx, user.ID, tt.req)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Suspend() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				if len(audit.entries) != 0 || len(notifier.events) != 0 {
+					t.Error("Suspend() with error should not audit or notify")
+				}
+				return
+			}
+
+			if suspended.Status != domain.UserStatusSuspended || suspended.StatusReason != tt.req.Reason {
+				t.Errorf("Suspend() user = %+v, want suspended with reason %v", suspended, tt.req.Reason)
+			}
+			if (suspended.SuspendedUntil != nil) != tt.wantUntil {
+				t.Errorf("Suspend() suspendedUntil = %v, wantUntil %v", suspended.SuspendedUntil, tt.wantUntil)
+			}
+			if len(notifier.events) != 1 || notifier.events[0] != domain.EventTypeUserSuspended {
+				t.Errorf("Suspend() events = %v, want [%v]", notifier.events, domain.EventTypeUserSuspended)
+			}
+			if len(audit.entries) != 1 {
+				t.Fatalf("Suspend() audit entries = %v, want 1", len(audit.entries))
+			}
+			entry := audit.entries[0]
+			if entry.ActorID != "support-1" || entry.FromStatus != domain.UserStatusActive || entry.Note != tt.req.Note {
+				t.Errorf("Suspend() audit entry = %+v", entry)
+			}
+		})
+	}
+}
+
+func TestUserService_ActivateDeactivate(t *testing.T) {
+	admin := domain.Actor{ID: "admin-1", Roles: []string{domain.RoleAdmin}}
+
+	tests := []struct {
+		name       string
+		from       domain.UserStatus
+		activate   bool
+		actor      domain.Actor
+		wantErr    error
+		wantStatus domain.UserStatus
+		wantEvents []domain.EventType
+	}{
+		{"activate inactive", domain.UserStatusInactive, true, domain.Actor{}, nil, domain.UserStatusActive, []domain.EventType{domain.EventTypeUserActivated}},
+		{"activate suspended as admin", domain.UserStatusSuspended, true, admin, nil, domain.UserStatusActive, []domain.EventType{domain.EventTypeUserReactivated}},
+		{"activate suspended without admin", domain.UserStatusSuspended, true, domain.Actor{}, domain.ErrForbidden, "", nil},
+		{"activate already active", domain.UserStatusActive, true, domain.Actor{}, nil, domain.UserStatusActive, nil},
+		{"deactivate active", domain.UserStatusActive, false, domain.Actor{}, nil, domain.UserStatusInactive, []domain.EventType{domain.EventTypeUserDeactivated}},
+		{"deactivate suspended without admin", domain.UserStatusSuspended, false, domain.Actor{}, domain.ErrForbidden, "", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newMockUserRepository()
+			notifier := &mockNotifier{}
+			audit := &mockAuditRepository{}
+			repo.audit = audit
+			svc := NewUserService(repo, notifier, WithAuditRepository(audit))
+
+			user := &domain.User{ID: uuid.New(), Email: "test@example.com", FirstName: "John", LastName: "Doe", Status: tt.from}
+			repo.users[user.ID] = user
+
+			ctx := domain.ContextWithActor(context.Background(), tt.actor)
+			var got *domain.User
+			var err error
+			if tt.activate {
+				got, err = svc.Activate(ctx, user.ID, domain.ActivateUserRequest{Note: "ticket 42"})
+			} else {
+				got, err = svc.Deactivate(ctx, user.ID, domain.DeactivateUserRequest{Note: "ticket 42"})
+			}
+
+			if err != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if got.Status != tt.wantStatus {
+				t.Errorf("status = %v, want %v", got.Status, tt.wantStatus)
+			}
+			if len(notifier.events) != len(tt.wantEvents) || (len(tt.wantEvents) > 0 && notifier.events[0] != tt.wantEvents[0]) {
+				t.Errorf("events = %v, want %v", notifier.events, tt.wantEvents)
+			}
+			if len(audit.entries) != len(tt.wantEvents) {
+				t.Errorf("audit entries = %v, want %v", len(audit.entries), len(tt.wantEvents))
+			}
+		})
+	}
+
+	repo := newMockUserRepository()
+	svc := NewUserService(repo, &mockNotifier{})
+	if _, err := svc.Activate(context.Background(), uuid.New(), domain.ActivateUserRequest{}); err != domain.ErrUserNotFound {
+		t.Errorf("Activate() for non-existing error = %v, want %v", err, domain.ErrUserNotFound)
+	}
+}
+
+func TestUserService_Suspend_AuditFails(t *testing.T) {
+	repo := newMockUserRepository()
+	repo.auditErr = errors.New("audit log unavailable")
+	notifier := &mockNotifier{}
+	svc := NewUserService(repo, notifier, WithAuditRepository(&mockAuditRepository{}))
+
+	user := &domain.User{ID: uuid.New(), Email: "test@example.com", FirstName: "John", LastName: "Doe", Status: domain.UserStatusActive}
+	repo.users[user.ID] = user
+
+	_, err := svc.Suspend(context.Background(), user.ID, domain.SuspendUserRequest{Reason: domain.SuspensionReasonAbuse})
+	if !errors.Is(err, repo.auditErr) {
+		t.Fatalf("Suspend() error = %v, want %v", err, repo.auditErr)
+	}
+	if len(notifier.events) != 0 {
+		t.Errorf("Suspend() events = %v, want none", notifier.events)
+	}
+}
+
+func TestUserService_ListAudit(t *testing.T) {
+	repo := newMockUserRepository()
+	audit := &mockAuditRepository{}
+	repo.audit = audit
+	svc := NewUserService(repo, &mockNotifier{}, WithAuditRepository(audit))
+
+	user := &domain.User{ID: uuid.New(), Email: "test@example.com", FirstName: "John", LastName: "Doe", Status: domain.UserStatusActive}
+	repo.users[user.ID] = user
+
+	svc.Deactivate(context.Background(), user.ID, domain.DeactivateUserRequest{})
+	svc.Activate(context.Background(), user.ID, domain.ActivateUserRequest{})
+
+	list, err := svc.ListAudit(context.Background(), user.ID, 0, -1)
+	if err != nil {
+		t.Fatalf("ListAudit() unexpected error = %v", err)
+	}
+	if list.Pagination.Total != 2 || len(list.Data) != 2 {
+		t.Errorf("ListAudit() total = %v, len = %v, want 2", list.Pagination.Total, len(list.Data))
+	}
+	if list.Pagination.Limit != 20 || list.Pagination.Offset != 0 {
+		t.Errorf("ListAudit() pagination = %+v, want limit 20 offset 0", list.Pagination)
+	}
+
+	if _, err := svc.ListAudit(context.Background(), uuid.New(), 0, 0); !errors.Is(err, domain.ErrUserNotFound) {
+		t.Errorf("ListAudit() for unknown user error = %v, want %v", err, domain.ErrUserNotFound)
+	}
+}
+
+func statusPtr(status domain.UserStatus) *domain.UserStatus {
+	return &status
+}
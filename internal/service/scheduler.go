@@ -0,0 +1,58 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+const (
+	reactivationBatchSize = 100
+	defaultCheckInterval  = time.Minute
+)
+
+type ReactivationScheduler struct {
+	service  *UserService
+	interval time.Duration
+	logger   *slog.Logger
+}
+
+func NewReactivationScheduler(service *UserService, interval time.Duration, logger *slog.Logger) *ReactivationScheduler {
+	if interval <= 0 {
+		interval = defaultCheckInterval
+	}
+	return &ReactivationScheduler{service: service, interval: interval, logger: logger}
+}
+
+func (s *ReactivationScheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	s.logger.Info("reactivation scheduler started", slog.Duration("interval", s.interval))
+
+	for {
+		select {
+		case <-ctx.Done():
+			s.logger.Info("reactivation scheduler stopped")
+			return
+		case <-ticker.C:
+			s.tick(ctx)
+		}
+	}
+}
+
+func (s *ReactivationScheduler) tick(ctx context.Context) {
+	for {
+		count, err := s.service.ReactivateExpired(ctx, time.Now().UTC(), reactivationBatchSize)
+		if err != nil {
+			s.logger.Error("failed to reactivate expired suspensions", slog.String("error", err.Error()))
+			return
+		}
+		if count > 0 {
+			s.logger.Info("reactivated expired suspensions", slog.Int("count", count))
+		}
+		if count < reactivationBatchSize {
+			return
+		}
+	}
+}
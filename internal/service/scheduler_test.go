@@ -0,0 +1,23 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewReactivationScheduler_Interval(t *testing.T) {
+	tests := []struct {
+		interval time.Duration
+		want     time.Duration
+	}{
+		{30 * time.Second, 30 * time.Second},
+		{0, defaultCheckInterval},
+		{-time.Second, defaultCheckInterval},
+	}
+
+	for _, tt := range tests {
+		if got := NewReactivationScheduler(nil, tt.interval, nil).interval; got != tt.want {
+			t.Errorf("NewReactivationScheduler(%v).interval = %v, want %v", tt.interval, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package service
+
+import (
+	"context"
+	"strings"
+
+	"github.com/giannuccilli/user-api/internal/domain"
+)
+
+// TenantService manages tenants. Tenants span the whole deployment, so every
+// operation is restricted to platform admins; tenant admins cannot use it.
+type TenantService struct {
+	repo domain.TenantRepository
+}
+
+func NewTenantService(repo domain.TenantRepository) *TenantService {
+	return &TenantService{repo: repo}
+}
+
+func (s *TenantService) List(ctx context.Context) (*domain.TenantList, error) {
+	if !domain.ActorFromContext(ctx).IsPlatformAdmin() {
+		return nil, domain.ErrForbidden
+	}
+	tenants, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &domain.TenantList{Data: tenants}, nil
+}
+
+func (s *TenantService) Get(ctx context.Context, id string) (*domain.Tenant, error) {
+	if !domain.ActorFromContext(ctx).IsPlatformAdmin() {
+		return nil, domain.ErrForbidden
+	}
+	return s.repo.Get(ctx, id)
+}
+
+func (s *TenantService) Create(ctx context.Context, req domain.CreateTenantRequest) (*domain.Tenant, error) {
+	if !domain.ActorFromContext(ctx).IsPlatformAdmin() {
+		return nil, domain.ErrForbidden
+	}
+
+	v := &domain.ValidationError{}
+	if !domain.IsValidTenantID(req.ID) {
+		v.Add("id", domain.FieldCodeInvalidFormat, "must contain only lowercase letters, digits and hyphens (max 63)")
+	}
+	validateTenantName(v, req.Name)
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+
+	tenant := &domain.Tenant{ID: req.ID, Name: strings.TrimSpace(req.Name)}
+	if err := s.repo.Create(ctx, tenant); err != nil {
+		return nil, err
+	}
+	return tenant, nil
+}
+
+func (s *TenantService) Update(ctx context.Context, id string, req domain.UpdateTenantRequest) (*domain.Tenant, error) {
+	if !domain.ActorFromContext(ctx).IsPlatformAdmin() {
+		return nil, domain.ErrForbidden
+	}
+
+	v := &domain.ValidationError{}
+	validateTenantName(v, req.Name)
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+
+	tenant := &domain.Tenant{ID: id, Name: strings.TrimSpace(req.Name)}
+	if err := s.repo.Update(ctx, tenant); err != nil {
+		return nil, err
+	}
+	return tenant, nil
+}
+
+// Delete removes a tenant without users. The default tenant cannot be
+// deleted because requests without a tenant resolve to it.
+func (s *TenantService) Delete(ctx context.Context, id string) error {
+	if !domain.ActorFromContext(ctx).IsPlatformAdmin() || id == domain.DefaultTenantID {
+		return domain.ErrForbidden
+	}
+	return s.repo.Delete(ctx, id)
+}
+
+func validateTenantName(v *domain.ValidationError, name string) {
+	name = strings.TrimSpace(name)
+	switch {
+	case name == "":
+		v.Add("name", domain.FieldCodeRequired, "is required")
+	case len(name) > 200:
+		v.Add("name", domain.FieldCodeTooLong, "must be at most 200 characters")
+	}
+}
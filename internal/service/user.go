@@ -3,77 +3,285 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
+	"log/slog"
+	"reflect"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 
 	"github.com/giannuccilli/user-api/internal/domain"
+	"github.com/giannuccilli/user-api/internal/emailaddr"
+	"github.com/giannuccilli/user-api/internal/token"
 )
 
 type UserService struct {
-	repo     domain.UserRepository
-	notifier domain.UserNotifier
+	repo        domain.UserRepository
+	notifier    domain.UserNotifier
+	transitions *TransitionGraph
+	audit       domain.AuditRepository
+	emails      *emailaddr.Policy
+	attributes  *AttributeRegistry
+	identities  domain.IdentityRepository
+
+	mailer       domain.MailSender
+	tokens       *token.Signer
+	verification VerificationConfig
+
+	logger *slog.Logger
+}
+
+type Option func(*UserService)
+
+func WithTransitionGraph(graph *TransitionGraph) Option {
+	return func(s *UserService) {
+		s.transitions = graph
+	}
+}
+
+func WithAuditRepository(audit domain.AuditRepository) Option {
+	return func(s *UserService) {
+		s.audit = audit
+	}
+}
+
+func WithEmailPolicy(policy *emailaddr.Policy) Option {
+	return func(s *UserService) {
+		s.emails = policy
+	}
+}
+
+// WithLogger sets the logger for failures that do not fail the request, such
+// as emails that could not be sent after the user was saved.
+func WithLogger(logger *slog.Logger) Option {
+	return func(s *UserService) {
+		s.logger = logger
+	}
 }
 
-func NewUserService(repo domain.UserRepository, notifier domain.UserNotifier) *UserService {
-	return &UserService{repo: repo, notifier: notifier}
+func NewUserService(repo domain.UserRepository, notifier domain.UserNotifier, opts ...Option) *UserService {
+	s := &UserService{
+		repo:        repo,
+		notifier:    notifier,
+		transitions: DefaultTransitionGraph(),
+		emails:      emailaddr.NewPolicy(emailaddr.Config{}),
+		logger:      slog.New(slog.DiscardHandler),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
 func (s *UserService) Create(ctx context.Context, req domain.CreateUserRequest) (*domain.User, error) {
-	email := strings.TrimSpace(strings.ToLower(req.Email))
+	user, err := s.newUser(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Create(ctx, user); err != nil {
+		return nil, err
+	}
+
+	s.userCreated(ctx, user)
+
+	return user, nil
+}
+
+// newUser validates req and returns the user to store.
+func (s *UserService) newUser(ctx context.Context, req domain.CreateUserRequest) (*domain.User, error) {
 	firstName := strings.TrimSpace(req.FirstName)
 	lastName := strings.TrimSpace(req.LastName)
 
-	if err := validateEmail(email); err != nil {
+	v := &domain.ValidationError{}
+	addr := s.normalizeEmail(v, "email", req.Email)
+	validateName(v, "firstName", firstName)
+	validateName(v, "lastName", lastName)
+	if err := s.validateAttributes(ctx, v, req.Attributes); err != nil {
 		return nil, err
 	}
-	if err := validateName(firstName, "firstName"); err != nil {
+	if err := v.Err(); err != nil {
 		return nil, err
 	}
-	if err := validateName(lastName, "lastName"); err != nil {
+
+	if err := s.ensureEmailAvailable(ctx, addr, uuid.Nil); err != nil {
 		return nil, err
 	}
 
-	existingUser, err := s.repo.GetByEmail(ctx, email)
-	if err != nil && !errors.Is(err, domain.ErrUserNotFound) {
-		return nil, err
+	user := &domain.User{
+		Email:          addr.Email,
+		EmailCanonical: addr.Canonical,
+		FirstName:      firstName,
+		LastName:       lastName,
+		Status:         s.initialStatus(),
+		Attributes:     mergeAttributes(nil, req.Attributes),
 	}
-	if existingUser != nil {
-		return nil, domain.ErrEmailExists
+
+	return user, nil
+}
+
+// UpsertByEmail creates the user with the given email or updates the names
+// and attributes of the existing one in a single statement, so concurrent
+// calls cannot race. The returned bool reports whether the user was created.
+func (s *UserService) UpsertByEmail(ctx context.Context, email string, req domain.UpsertUserRequest) (*domain.User, bool, error) {
+	firstName := strings.TrimSpace(req.FirstName)
+	lastName := strings.TrimSpace(req.LastName)
+
+	v := &domain.ValidationError{}
+	addr := s.normalizeEmail(v, "email", email)
+	validateName(v, "firstName", firstName)
+	validateName(v, "lastName", lastName)
+	if err := s.validateAttributes(ctx, v, req.Attributes); err != nil {
+		return nil, false, err
+	}
+	if err := v.Err(); err != nil {
+		return nil, false, err
 	}
 
 	user := &domain.User{
-		Email:     email,
-		FirstName: firstName,
-		LastName:  lastName,
-		Status:    domain.UserStatusActive,
+		Email:          addr.Email,
+		EmailCanonical: addr.Canonical,
+		FirstName:      firstName,
+		LastName:       lastName,
+		Status:         s.initialStatus(),
+		Attributes:     req.Attributes,
 	}
 
-	if err := s.repo.Create(ctx, user); err != nil {
+	result, err := s.repo.Upsert(ctx, user)
+	if err != nil {
+		return nil, false, err
+	}
+
+	switch result {
+	case domain.UpsertCreated:
+		s.userCreated(ctx, user)
+	case domain.UpsertUpdated:
+		s.notifier.NotifyUpdated(ctx, eventData(user))
+	}
+
+	return user, result == domain.UpsertCreated, nil
+}
+
+func (s *UserService) initialStatus() domain.UserStatus {
+	if s.verification.Required {
+		return domain.UserStatusPendingVerification
+	}
+	return domain.UserStatusActive
+}
+
+func (s *UserService) userCreated(ctx context.Context, user *domain.User) {
+	s.notifier.NotifyCreated(ctx, eventData(user))
+
+	if s.verification.Required && s.tokens != nil && s.mailer != nil {
+		if err := s.sendVerificationEmail(ctx, user); err != nil {
+			s.logger.Error("failed to send verification email",
+				slog.String("user_id", user.ID.String()),
+				slog.String("error", err.Error()),
+			)
+		}
+	}
+}
+
+// GetByID reads only the given fields when fields is not nil.
+func (s *UserService) GetByID(ctx context.Context, id uuid.UUID, fields domain.FieldSet) (*domain.User, error) {
+	if fields == nil {
+		return s.repo.GetByID(ctx, id)
+	}
+
+	users, err := s.repo.GetByIDs(ctx, []uuid.UUID{id}, fields)
+	if err != nil {
 		return nil, err
 	}
+	if len(users) == 0 {
+		return nil, domain.ErrUserNotFound
+	}
+	return &users[0], nil
+}
 
-	s.notifier.NotifyCreated(ctx, user.ID)
+// MaxBatchGetIDs is the most IDs BatchGet accepts in one call.
+const MaxBatchGetIDs = 100
 
-	return user, nil
+// BatchGet looks up several users with one query. Repeated IDs are returned
+// once, at their first position.
+func (s *UserService) BatchGet(ctx context.Context, rawIDs []string, fields domain.FieldSet) (*domain.BatchGetUsersResult, error) {
+	v := &domain.ValidationError{}
+	switch {
+	case len(rawIDs) == 0:
+		v.Add("ids", domain.FieldCodeRequired, "is required")
+	case len(rawIDs) > MaxBatchGetIDs:
+		v.Add("ids", domain.FieldCodeTooLong, fmt.Sprintf("must contain at most %d IDs", MaxBatchGetIDs))
+	}
+
+	ids := make([]uuid.UUID, 0, len(rawIDs))
+	seen := make(map[uuid.UUID]bool, len(rawIDs))
+	for i, raw := range rawIDs {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			v.Add(fmt.Sprintf("ids[%d]", i), domain.FieldCodeInvalidFormat, "must be a UUID")
+			continue
+		}
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+
+	users, err := s.repo.GetByIDs(ctx, ids, fields)
+	if err != nil {
+		return nil, err
+	}
+
+	found := make(map[uuid.UUID]bool, len(users))
+	for _, user := range users {
+		found[user.ID] = true
+	}
+	notFound := make([]uuid.UUID, 0)
+	for _, id := range ids {
+		if !found[id] {
+			notFound = append(notFound, id)
+		}
+	}
+
+	return &domain.BatchGetUsersResult{Data: users, NotFound: notFound}, nil
 }
 
-func (s *UserService) GetByID(ctx context.Context, id uuid.UUID) (*domain.User, error) {
-	return s.repo.GetByID(ctx, id)
+func (s *UserService) GetAsOf(ctx context.Context, id uuid.UUID, asOf time.Time) (*domain.User, error) {
+	return s.repo.GetAsOf(ctx, id, asOf)
 }
 
-func (s *UserService) List(ctx context.Context, limit, offset int) (*domain.UserList, error) {
-	if limit <= 0 {
-		limit = 20
+func (s *UserService) Diff(ctx context.Context, id uuid.UUID, from, to time.Time) (*domain.UserDiff, error) {
+	if to.Before(from) {
+		v := &domain.ValidationError{}
+		v.Add("to", domain.FieldCodeInvalidValue, "must not be before from")
+		return nil, v
 	}
-	if limit > 100 {
-		limit = 100
+
+	before, err := s.repo.GetAsOf(ctx, id, from)
+	if err != nil {
+		return nil, err
 	}
-	if offset < 0 {
-		offset = 0
+
+	after, err := s.repo.GetAsOf(ctx, id, to)
+	if err != nil {
+		return nil, err
 	}
 
-	users, total, err := s.repo.List(ctx, limit, offset)
+	return &domain.UserDiff{
+		UserID:  id,
+		From:    from,
+		To:      to,
+		Changes: diffUsers(before, after),
+	}, nil
+}
+
+func (s *UserService) List(ctx context.Context, filter domain.UserFilter) (*domain.UserList, error) {
+	filter.Limit, filter.Offset = normalizePage(filter.Limit, filter.Offset)
+
+	users, total, err := s.repo.List(ctx, filter)
 	if err != nil {
 		return nil, err
 	}
@@ -82,64 +290,109 @@ func (s *UserService) List(ctx context.Context, limit, offset int) (*domain.User
 		Data: users,
 		Pagination: domain.Pagination{
 			Total:  total,
-			Limit:  limit,
-			Offset: offset,
+			Limit:  filter.Limit,
+			Offset: filter.Offset,
 		},
 	}, nil
 }
 
 func (s *UserService) Update(ctx context.Context, id uuid.UUID, req domain.UpdateUserRequest) (*domain.User, error) {
+	v := &domain.ValidationError{}
+	var addr emailaddr.Address
+	if req.Email != nil {
+		addr = s.normalizeEmail(v, "email", *req.Email)
+	}
+	var firstName, lastName string
+	if req.FirstName != nil {
+		firstName = strings.TrimSpace(*req.FirstName)
+		validateName(v, "firstName", firstName)
+	}
+	if req.LastName != nil {
+		lastName = strings.TrimSpace(*req.LastName)
+		validateName(v, "lastName", lastName)
+	}
+	if req.Status != nil {
+		validateStatusChange(v, statusChange{
+			to:             *req.Status,
+			reason:         req.StatusReason,
+			suspendedUntil: req.SuspendedUntil,
+		})
+	}
+	if err := s.validateAttributes(ctx, v, req.Attributes); err != nil {
+		return nil, err
+	}
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+
 	user, err := s.repo.GetByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
 
+	emailChangeRequested := false
 	if req.Email != nil {
-		email := strings.TrimSpace(strings.ToLower(*req.Email))
-		if err := validateEmail(email); err != nil {
-			return nil, err
-		}
-
-		if email != user.Email {
-			existingUser, err := s.repo.GetByEmail(ctx, email)
-			if err != nil && !errors.Is(err, domain.ErrUserNotFound) {
+		switch {
+		case addr.Email == user.Email:
+			user.PendingEmail = ""
+		default:
+			if err := s.ensureEmailAvailable(ctx, addr, user.ID); err != nil {
 				return nil, err
 			}
-			if existingUser != nil {
-				return nil, domain.ErrEmailExists
+			if s.emailChangeRequiresConfirmation() {
+				if s.mailer == nil {
+					return nil, domain.ErrNotSupported
+				}
+				user.PendingEmail = addr.Email
+				emailChangeRequested = true
+			} else {
+				user.Email = addr.Email
+				user.EmailCanonical = addr.Canonical
+				user.EmailVerified = false
 			}
-			user.Email = email
 		}
 	}
 
 	if req.FirstName != nil {
-		firstName := strings.TrimSpace(*req.FirstName)
-		if err := validateName(firstName, "firstName"); err != nil {
-			return nil, err
-		}
 		user.FirstName = firstName
 	}
-
 	if req.LastName != nil {
-		lastName := strings.TrimSpace(*req.LastName)
-		if err := validateName(lastName, "lastName"); err != nil {
-			return nil, err
-		}
 		user.LastName = lastName
 	}
+	if req.Attributes != nil {
+		user.Attributes = mergeAttributes(user.Attributes, req.Attributes)
+	}
 
+	from := user.Status
+	var lifecycleEvent domain.EventType
 	if req.Status != nil {
-		if err := validateStatus(*req.Status); err != nil {
+		lifecycleEvent, err = s.applyStatusChange(ctx, user, statusChange{
+			to:             *req.Status,
+			reason:         req.StatusReason,
+			suspendedUntil: req.SuspendedUntil,
+		})
+		if err != nil {
 			return nil, err
 		}
-		user.Status = *req.Status
 	}
 
-	if err := s.repo.Update(ctx, user); err != nil {
+	if err := s.update(ctx, user, lifecycleEvent, from, ""); err != nil {
 		return nil, err
 	}
 
-	s.notifier.NotifyUpdated(ctx, user.ID)
+	s.notifier.NotifyUpdated(ctx, eventData(user))
+	if lifecycleEvent != "" {
+		s.notifier.Notify(ctx, lifecycleEvent, lifecycleEventData(user))
+	}
+
+	if emailChangeRequested {
+		if err := s.sendEmailChangeEmails(ctx, user); err != nil {
+			s.logger.Error("failed to send email change emails",
+				slog.String("user_id", user.ID.String()),
+				slog.String("error", err.Error()),
+			)
+		}
+	}
 
 	return user, nil
 }
@@ -154,34 +407,149 @@ func (s *UserService) Delete(ctx context.Context, id uuid.UUID) error {
 	return nil
 }
 
-func validateEmail(email string) error {
-	if email == "" {
-		return domain.ErrInvalidInput
+func diffUsers(before, after *domain.User) []domain.FieldChange {
+	changes := make([]domain.FieldChange, 0)
+
+	if before.Email != after.Email {
+		changes = append(changes, domain.FieldChange{Field: "email", From: before.Email, To: after.Email})
 	}
-	if len(email) > 255 {
-		return domain.ErrInvalidInput
+	if before.EmailVerified != after.EmailVerified {
+		changes = append(changes, domain.FieldChange{Field: "emailVerified", From: before.EmailVerified, To: after.EmailVerified})
 	}
-	if !strings.Contains(email, "@") || !strings.Contains(email, ".") {
-		return domain.ErrInvalidInput
+	if before.FirstName != after.FirstName {
+		changes = append(changes, domain.FieldChange{Field: "firstName", From: before.FirstName, To: after.FirstName})
 	}
-	return nil
+	if before.LastName != after.LastName {
+		changes = append(changes, domain.FieldChange{Field: "lastName", From: before.LastName, To: after.LastName})
+	}
+	if before.Status != after.Status {
+		changes = append(changes, domain.FieldChange{Field: "status", From: before.Status, To: after.Status})
+	}
+	if before.StatusReason != after.StatusReason {
+		changes = append(changes, domain.FieldChange{Field: "statusReason", From: before.StatusReason, To: after.StatusReason})
+	}
+	if !sameTime(before.SuspendedUntil, after.SuspendedUntil) {
+		changes = append(changes, domain.FieldChange{Field: "suspendedUntil", From: before.SuspendedUntil, To: after.SuspendedUntil})
+	}
+	if !reflect.DeepEqual(before.Attributes, after.Attributes) {
+		changes = append(changes, domain.FieldChange{Field: "attributes", From: before.Attributes, To: after.Attributes})
+	}
+
+	return changes
 }
 
-func validateName(name, field string) error {
-	if name == "" {
-		return domain.ErrInvalidInput
+// sameTime reports whether a and b are both unset or the same instant.
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
 	}
-	if len(name) > 100 {
-		return domain.ErrInvalidInput
+	return a.Equal(*b)
+}
+
+func (s *UserService) validateAttributes(ctx context.Context, v *domain.ValidationError, attrs domain.Attributes) error {
+	if len(attrs) == 0 {
+		return nil
+	}
+	if s.attributes == nil {
+		for key, value := range attrs {
+			if value != nil {
+				v.Add("attributes."+key, FieldCodeUnknownAttribute, "is not a registered attribute")
+			}
+		}
+		return nil
+	}
+	return s.attributes.Validate(ctx, v, attrs)
+}
+
+// mergeAttributes applies patch to current as a JSON merge patch limited to
+// the top level: null removes a key, any other value replaces it.
+func mergeAttributes(current, patch domain.Attributes) domain.Attributes {
+	merged := make(domain.Attributes, len(current)+len(patch))
+	for key, value := range current {
+		merged[key] = value
+	}
+	for key, value := range patch {
+		if value == nil {
+			delete(merged, key)
+		} else {
+			merged[key] = value
+		}
+	}
+	return merged
+}
+
+func eventData(user *domain.User) domain.EventData {
+	return domain.EventData{UserID: user.ID, Attributes: user.Attributes}
+}
+
+// ensureEmailAvailable checks the canonical form so that variants such as
+// plus-addresses collide with the original. self is the user being updated,
+// who may switch between variants of their own address.
+func (s *UserService) ensureEmailAvailable(ctx context.Context, addr emailaddr.Address, self uuid.UUID) error {
+	existingUser, err := s.repo.GetByCanonicalEmail(ctx, addr.Canonical)
+	if err != nil && !errors.Is(err, domain.ErrUserNotFound) {
+		return err
+	}
+	if existingUser != nil && existingUser.ID != self {
+		return domain.ErrEmailExists
 	}
 	return nil
 }
 
-func validateStatus(status domain.UserStatus) error {
-	switch status {
-	case domain.UserStatusActive, domain.UserStatusInactive, domain.UserStatusSuspended:
-		return nil
-	default:
-		return domain.ErrInvalidInput
+func normalizePage(limit, offset int) (int, int) {
+	if limit <= 0 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	return limit, offset
+}
+
+// normalizeEmail records a field error on v when raw is rejected by the
+// email policy, keeping the EmailError reachable through errors.Is.
+func (s *UserService) normalizeEmail(v *domain.ValidationError, field, raw string) emailaddr.Address {
+	addr, err := s.emails.Normalize(raw)
+	if err != nil {
+		var emailErr *domain.EmailError
+		if errors.As(err, &emailErr) {
+			v.AddCause(field, emailErr.Code(), emailErr.Reason, emailErr)
+		} else {
+			v.AddCause(field, domain.FieldCodeInvalidFormat, err.Error(), err)
+		}
+	}
+	return addr
+}
+
+func validateName(v *domain.ValidationError, field, name string) {
+	switch {
+	case name == "":
+		v.Add(field, domain.FieldCodeRequired, "is required")
+	case len(name) > 100:
+		v.Add(field, domain.FieldCodeTooLong, "must be at most 100 characters")
+	}
+}
+
+func validateNote(v *domain.ValidationError, note string) {
+	if len(note) > 1000 {
+		v.Add("note", domain.FieldCodeTooLong, "must be at most 1000 characters")
+	}
+}
+
+func validateStatus(v *domain.ValidationError, field string, status domain.UserStatus) {
+	if !status.IsValid() {
+		v.Add(field, domain.FieldCodeInvalidValue, "must be one of active, inactive, suspended, pending_verification")
+	}
+}
+
+func validateSuspensionReason(v *domain.ValidationError, field string, reason *domain.SuspensionReason) {
+	switch {
+	case reason == nil || *reason == "":
+		v.Add(field, domain.FieldCodeRequired, "is required when suspending")
+	case !reason.IsValid():
+		v.Add(field, domain.FieldCodeInvalidValue, "must be one of fraud, abuse, policy_violation, payment_issue, security, other")
 	}
 }
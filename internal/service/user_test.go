@@ -2,23 +2,35 @@ package service
 
 import (
 	"context"
+	"errors"
+	"reflect"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 
 	"github.com/giannuccilli/user-api/internal/domain"
+	"github.com/giannuccilli/user-api/internal/emailaddr"
 )
 
 type mockUserRepository struct {
 	users    map[uuid.UUID]*domain.User
 	byEmail  map[string]*domain.User
+	history  map[uuid.UUID][]domain.User
 	createFn func(ctx context.Context, user *domain.User) error
+	// audit receives the entries of UpdateWithAudit; auditErr fails it
+	// before anything is stored.
+	audit    *mockAuditRepository
+	auditErr error
+	// reactivateErrs fails ReactivateSuspension for the given users.
+	reactivateErrs map[uuid.UUID]error
 }
 
 func newMockUserRepository() *mockUserRepository {
 	return &mockUserRepository{
 		users:   make(map[uuid.UUID]*domain.User),
 		byEmail: make(map[string]*domain.User),
+		history: make(map[uuid.UUID][]domain.User),
 	}
 }
 
@@ -39,6 +51,16 @@ func (m *mockUserRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain
 	return nil, domain.ErrUserNotFound
 }
 
+func (m *mockUserRepository) GetByIDs(ctx context.Context, ids []uuid.UUID, fields domain.FieldSet) ([]domain.User, error) {
+	users := make([]domain.User, 0, len(ids))
+	for _, id := range ids {
+		if user, ok := m.users[id]; ok {
+			users = append(users, *user)
+		}
+	}
+	return users, nil
+}
+
 func (m *mockUserRepository) GetByEmail(ctx context.Context, email string) (*domain.User, error) {
 	if user, ok := m.byEmail[email]; ok {
 		return user, nil
@@ -46,13 +68,25 @@ func (m *mockUserRepository) GetByEmail(ctx context.Context, email string) (*dom
 	return nil, domain.ErrUserNotFound
 }
 
-func (m *mockUserRepository) List(ctx context.Context, limit, offset int) ([]domain.User, int, error) {
+func (m *mockUserRepository) GetByCanonicalEmail(ctx context.Context, canonical string) (*domain.User, error) {
+	for _, user := range m.users {
+		if user.EmailCanonical == canonical || (user.EmailCanonical == "" && user.Email == canonical) {
+			return user, nil
+		}
+	}
+	return nil, domain.ErrUserNotFound
+}
+
+func (m *mockUserRepository) List(ctx context.Context, filter domain.UserFilter) ([]domain.User, int, error) {
 	users := make([]domain.User, 0)
 	for _, u := range m.users {
-		users = append(users, *u)
+		if matchesAttributes(u.Attributes, filter.Attributes) {
+			users = append(users, *u)
+		}
 	}
 	total := len(users)
 
+	offset, limit := filter.Offset, filter.Limit
 	if offset >= len(users) {
 		return []domain.User{}, total, nil
 	}
@@ -65,8 +99,22 @@ func (m *mockUserRepository) List(ctx context.Context, limit, offset int) ([]dom
 	return users[offset:end], total, nil
 }
 
+func matchesAttributes(attrs, filter domain.Attributes) bool {
+	for k, want := range filter {
+		got, ok := attrs[k]
+		if !ok || !reflect.DeepEqual(got, want) {
+			return false
+		}
+	}
+	return true
+}
+
 func (m *mockUserRepository) Update(ctx context.Context, user *domain.User) error {
-	if _, ok := m.users[user.ID]; !ok {
+	existing, ok := m.users[user.ID]
+	if !ok {
+		return domain.ErrUserNotFound
+	}
+	if tenantID, _ := domain.TenantFromContext(ctx); existing.TenantID != "" && existing.TenantID != tenantID {
 		return domain.ErrUserNotFound
 	}
 	m.users[user.ID] = user
@@ -74,6 +122,55 @@ func (m *mockUserRepository) Update(ctx context.Context, user *domain.User) erro
 	return nil
 }
 
+func (m *mockUserRepository) UpdateWithAudit(ctx context.Context, user *domain.User, entry *domain.AuditEntry) error {
+	if m.auditErr != nil {
+		return m.auditErr
+	}
+	if err := m.Update(ctx, user); err != nil {
+		return err
+	}
+	if m.audit != nil {
+		return m.audit.Save(ctx, entry)
+	}
+	return nil
+}
+
+func (m *mockUserRepository) Upsert(ctx context.Context, user *domain.User) (domain.UpsertResult, error) {
+	merged := domain.Attributes{}
+	existing, err := m.GetByCanonicalEmail(ctx, user.EmailCanonical)
+	if err == nil {
+		for k, v := range existing.Attributes {
+			merged[k] = v
+		}
+	}
+	for k, v := range user.Attributes {
+		if v == nil {
+			delete(merged, k)
+		} else {
+			merged[k] = v
+		}
+	}
+
+	if existing == nil {
+		user.ID = uuid.New()
+		user.Attributes = merged
+		m.users[user.ID] = user
+		m.byEmail[user.Email] = user
+		return domain.UpsertCreated, nil
+	}
+
+	if existing.FirstName == user.FirstName && existing.LastName == user.LastName &&
+		len(merged) == len(existing.Attributes) && (len(merged) == 0 || reflect.DeepEqual(merged, existing.Attributes)) {
+		*user = *existing
+		return domain.UpsertUnchanged, nil
+	}
+	existing.FirstName = user.FirstName
+	existing.LastName = user.LastName
+	existing.Attributes = merged
+	*user = *existing
+	return domain.UpsertUpdated, nil
+}
+
 func (m *mockUserRepository) Delete(ctx context.Context, id uuid.UUID) error {
 	user, ok := m.users[id]
 	if !ok {
@@ -84,12 +181,67 @@ func (m *mockUserRepository) Delete(ctx context.Context, id uuid.UUID) error {
 	return nil
 }
 
-type mockNotifier struct{}
+func (m *mockUserRepository) GetAsOf(ctx context.Context, id uuid.UUID, asOf time.Time) (*domain.User, error) {
+	var found *domain.User
+	for _, version := range m.history[id] {
+		if !version.UpdatedAt.After(asOf) {
+			v := version
+			found = &v
+		}
+	}
+	if found == nil {
+		return nil, domain.ErrUserNotFound
+	}
+	return found, nil
+}
 
-func (m *mockNotifier) NotifyCreated(ctx context.Context, userID uuid.UUID) error { return nil }
-func (m *mockNotifier) NotifyUpdated(ctx context.Context, userID uuid.UUID) error { return nil }
-func (m *mockNotifier) NotifyDeleted(ctx context.Context, userID uuid.UUID) error { return nil }
-func (m *mockNotifier) Close() error                                              { return nil }
+func (m *mockUserRepository) ListExpiredSuspensions(ctx context.Context, before time.Time, limit int) ([]domain.User, error) {
+	users := make([]domain.User, 0)
+	for _, u := range m.users {
+		if u.Status == domain.UserStatusSuspended && u.SuspendedUntil != nil && !u.SuspendedUntil.After(before) {
+			users = append(users, *u)
+		}
+	}
+	if len(users) > limit {
+		users = users[:limit]
+	}
+	return users, nil
+}
+
+func (m *mockUserRepository) ReactivateSuspension(ctx context.Context, user *domain.User, before time.Time, entry *domain.AuditEntry) (bool, error) {
+	if err := m.reactivateErrs[user.ID]; err != nil {
+		return false, err
+	}
+	existing, ok := m.users[user.ID]
+	if !ok || existing.Status != domain.UserStatusSuspended || existing.SuspendedUntil == nil || existing.SuspendedUntil.After(before) {
+		return false, nil
+	}
+	if tenantID, _ := domain.TenantFromContext(ctx); existing.TenantID != "" && existing.TenantID != tenantID {
+		return false, nil
+	}
+	existing.Status = domain.UserStatusActive
+	existing.StatusReason = ""
+	existing.SuspendedUntil = nil
+	*user = *existing
+	if entry != nil && m.audit != nil {
+		return true, m.audit.Save(ctx, entry)
+	}
+	return true, nil
+}
+
+type mockNotifier struct {
+	events []domain.EventType
+}
+
+func (m *mockNotifier) NotifyCreated(ctx context.Context, data domain.EventData) error { return nil }
+func (m *mockNotifier) NotifyUpdated(ctx context.Context, data domain.EventData) error { return nil }
+func (m *mockNotifier) NotifyDeleted(ctx context.Context, userID uuid.UUID) error      { return nil }
+func (m *mockNotifier) Close() error                                                   { return nil }
+
+func (m *mockNotifier) Notify(ctx context.Context, eventType domain.EventType, data domain.EventData) error {
+	m.events = append(m.events, eventType)
+	return nil
+}
 
 func TestUserService_Create(t *testing.T) {
 	tests := []struct {
@@ -113,7 +265,7 @@ func TestUserService_Create(t *testing.T) {
 				FirstName: "John",
 				LastName:  "Doe",
 			},
-			wantErr: domain.ErrInvalidInput,
+			wantErr: domain.ErrInvalidEmail,
 		},
 		{
 			name: "invalid email format",
@@ -122,7 +274,7 @@ func TestUserService_Create(t *testing.T) {
 				FirstName: "John",
 				LastName:  "Doe",
 			},
-			wantErr: domain.ErrInvalidInput,
+			wantErr: domain.ErrInvalidEmail,
 		},
 		{
 			name: "empty firstName",
@@ -152,7 +304,7 @@ func TestUserService_Create(t *testing.T) {
 			user, err := svc.Create(context.Background(), tt.req)
 
 			if tt.wantErr != nil {
-				if err != tt.wantErr {
+				if !errors.Is(err, tt.wantErr) {
 					t.Errorf("Create() error = %v, wantErr %v", err, tt.wantErr)
 				}
 				return
@@ -194,6 +346,34 @@ func TestUserService_Create_DuplicateEmail(t *testing.T) {
 	}
 }
 
+func TestUserService_Create_CanonicalDuplicate(t *testing.T) {
+	repo := newMockUserRepository()
+	policy := emailaddr.NewPolicy(emailaddr.Config{StripPlusTag: true, CollapseGmailDots: true})
+	svc := NewUserService(repo, &mockNotifier{}, WithEmailPolicy(policy))
+
+	user, err := svc.Create(context.Background(), domain.CreateUserRequest{Email: "john.doe@gmail.com", FirstName: "John", LastName: "Doe"})
+	if err != nil {
+		t.Fatalf("Create() unexpected error = %v", err)
+	}
+	if user.EmailCanonical != "johndoe@gmail.com" {
+		t.Errorf("Create() canonical = %v, want johndoe@gmail.com", user.EmailCanonical)
+	}
+
+	_, err = svc.Create(context.Background(), domain.CreateUserRequest{Email: "JohnDoe+spam@gmail.com", FirstName: "Jane", LastName: "Doe"})
+	if err != domain.ErrEmailExists {
+		t.Errorf("Create() with canonical duplicate error = %v, want %v", err, domain.ErrEmailExists)
+	}
+
+	variant := "john.doe+news@gmail.com"
+	updated, err := svc.Update(context.Background(), user.ID, domain.UpdateUserRequest{Email: &variant})
+	if err != nil {
+		t.Fatalf("Update() to own variant unexpected error = %v", err)
+	}
+	if updated.Email != variant {
+		t.Errorf("Update() email = %v, want %v", updated.Email, variant)
+	}
+}
+
 func TestUserService_GetByID(t *testing.T) {
 	repo := newMockUserRepository()
 	svc := NewUserService(repo, &mockNotifier{})
@@ -206,7 +386,7 @@ func TestUserService_GetByID(t *testing.T) {
 
 	created, _ := svc.Create(context.Background(), req)
 
-	user, err := svc.GetByID(context.Background(), created.ID)
+	user, err := svc.GetByID(context.Background(), created.ID, nil)
 	if err != nil {
 		t.Errorf("GetByID() unexpected error = %v", err)
 	}
@@ -214,10 +394,66 @@ func TestUserService_GetByID(t *testing.T) {
 		t.Errorf("GetByID() ID = %v, want %v", user.ID, created.ID)
 	}
 
-	_, err = svc.GetByID(context.Background(), uuid.New())
+	_, err = svc.GetByID(context.Background(), uuid.New(), nil)
 	if err != domain.ErrUserNotFound {
 		t.Errorf("GetByID() error = %v, want %v", err, domain.ErrUserNotFound)
 	}
+
+	projected, err := svc.GetByID(context.Background(), created.ID, domain.FieldSet{"id", "email"})
+	if err != nil || projected.ID != created.ID {
+		t.Errorf("GetByID() with fields = %v, %v", projected, err)
+	}
+
+	_, err = svc.GetByID(context.Background(), uuid.New(), domain.FieldSet{"id"})
+	if !errors.Is(err, domain.ErrUserNotFound) {
+		t.Errorf("GetByID() with fields for non-existing error = %v, want %v", err, domain.ErrUserNotFound)
+	}
+}
+
+func TestUserService_BatchGet(t *testing.T) {
+	repo := newMockUserRepository()
+	svc := NewUserService(repo, &mockNotifier{})
+
+	first := &domain.User{ID: uuid.New(), Email: "first@example.com"}
+	second := &domain.User{ID: uuid.New(), Email: "second@example.com"}
+	repo.users[first.ID] = first
+	repo.users[second.ID] = second
+	missing := uuid.New()
+
+	result, err := svc.BatchGet(context.Background(), []string{second.ID.String(), missing.String(), first.ID.String(), second.ID.String()}, nil)
+	if err != nil {
+		t.Fatalf("BatchGet() unexpected error = %v", err)
+	}
+	if len(result.Data) != 2 || result.Data[0].ID != second.ID || result.Data[1].ID != first.ID {
+		t.Errorf("BatchGet() data = %v, want [second first]", result.Data)
+	}
+	if len(result.NotFound) != 1 || result.NotFound[0] != missing {
+		t.Errorf("BatchGet() notFound = %v, want [%v]", result.NotFound, missing)
+	}
+
+	tooMany := make([]string, MaxBatchGetIDs+1)
+	for i := range tooMany {
+		tooMany[i] = uuid.NewString()
+	}
+
+	tests := []struct {
+		name      string
+		ids       []string
+		wantField string
+	}{
+		{"empty", nil, "ids"},
+		{"too many", tooMany, "ids"},
+		{"invalid id", []string{first.ID.String(), "nope"}, "ids[1]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := svc.BatchGet(context.Background(), tt.ids, nil)
+			var v *domain.ValidationError
+			if !errors.As(err, &v) || v.Fields[0].Field != tt.wantField {
+				t.Errorf("BatchGet() error = %v, want validation error on %s", err, tt.wantField)
+			}
+		})
+	}
 }
 
 func TestUserService_List(t *testing.T) {
@@ -233,7 +469,7 @@ func TestUserService_List(t *testing.T) {
 		svc.Create(context.Background(), req)
 	}
 
-	list, err := svc.List(context.Background(), 10, 0)
+	list, err := svc.List(context.Background(), domain.UserFilter{Limit: 10})
 	if err != nil {
 		t.Errorf("List() unexpected error = %v", err)
 	}
@@ -244,7 +480,7 @@ func TestUserService_List(t *testing.T) {
 		t.Errorf("List() total = %v, want 5", list.Pagination.Total)
 	}
 
-	list, err = svc.List(context.Background(), 2, 0)
+	list, err = svc.List(context.Background(), domain.UserFilter{Limit: 2})
 	if err != nil {
 		t.Errorf("List() unexpected error = %v", err)
 	}
@@ -284,6 +520,51 @@ func TestUserService_Update(t *testing.T) {
 	}
 }
 
+func TestUserService_UpsertByEmail(t *testing.T) {
+	repo := newMockUserRepository()
+	notifier := &recordingNotifier{}
+	svc := NewUserService(repo, notifier)
+	ctx := context.Background()
+
+	tests := []struct {
+		name        string
+		email       string
+		firstName   string
+		wantErr     error
+		wantCreated bool
+		wantCreates int
+		wantUpdates int
+	}{
+		{"creates", "Upsert@Example.com", "John", nil, true, 1, 0},
+		{"unchanged", "upsert@example.com", "John", nil, false, 1, 0},
+		{"updates", "upsert@example.com", "Johnny", nil, false, 1, 1},
+		{"invalid email", "not-an-email", "John", domain.ErrInvalidInput, false, 1, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user, created, err := svc.UpsertByEmail(ctx, tt.email, domain.UpsertUserRequest{FirstName: tt.firstName, LastName: "Doe"})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("UpsertByEmail() error = %v, want %v", err, tt.wantErr)
+			}
+			if created != tt.wantCreated {
+				t.Errorf("UpsertByEmail() created = %v, want %v", created, tt.wantCreated)
+			}
+			if err == nil && user.FirstName != tt.firstName {
+				t.Errorf("UpsertByEmail() FirstName = %v, want %v", user.FirstName, tt.firstName)
+			}
+			if len(notifier.created) != tt.wantCreates || len(notifier.updated) != tt.wantUpdates {
+				t.Errorf("events created = %v, updated = %v, want %v, %v",
+					len(notifier.created), len(notifier.updated), tt.wantCreates, tt.wantUpdates)
+			}
+		})
+	}
+
+	if len(repo.users) != 1 {
+		t.Errorf("users = %v, want 1", len(repo.users))
+	}
+}
+
 func TestUserService_Delete(t *testing.T) {
 	repo := newMockUserRepository()
 	svc := NewUserService(repo, &mockNotifier{})
@@ -301,7 +582,7 @@ func TestUserService_Delete(t *testing.T) {
 		t.Errorf("Delete() unexpected error = %v", err)
 	}
 
-	_, err = svc.GetByID(context.Background(), created.ID)
+	_, err = svc.GetByID(context.Background(), created.ID, nil)
 	if err != domain.ErrUserNotFound {
 		t.Errorf("GetByID() after delete error = %v, want %v", err, domain.ErrUserNotFound)
 	}
@@ -311,3 +592,110 @@ func TestUserService_Delete(t *testing.T) {
 		t.Errorf("Delete() error = %v, want %v", err, domain.ErrUserNotFound)
 	}
 }
+
+func TestUserService_GetAsOf(t *testing.T) {
+	repo := newMockUserRepository()
+	svc := NewUserService(repo, &mockNotifier{})
+
+	id := uuid.New()
+	created := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+	renamed := time.Date(2026, 2, 1, 10, 0, 0, 0, time.UTC)
+	repo.history[id] = []domain.User{
+		{ID: id, Email: "test@example.com", FirstName: "John", LastName: "Doe", Status: domain.UserStatusActive, UpdatedAt: created},
+		{ID: id, Email: "test@example.com", FirstName: "Jane", LastName: "Doe", Status: domain.UserStatusActive, UpdatedAt: renamed},
+	}
+
+	tests := []struct {
+		name          string
+		asOf          time.Time
+		wantFirstName string
+		wantErr       error
+	}{
+		{"before creation", created.Add(-time.Hour), "", domain.ErrUserNotFound},
+		{"first version", created.Add(time.Hour), "John", nil},
+		{"second version", renamed.Add(time.Hour), "Jane", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user, err := svc.GetAsOf(context.Background(), id, tt.asOf)
+			if err != tt.wantErr {
+				t.Fatalf("GetAsOf() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && user.FirstName != tt.wantFirstName {
+				t.Errorf("GetAsOf() firstName = %v, want %v", user.FirstName, tt.wantFirstName)
+			}
+		})
+	}
+}
+
+func TestUserService_Diff(t *testing.T) {
+	repo := newMockUserRepository()
+	svc := NewUserService(repo, &mockNotifier{})
+
+	id := uuid.New()
+	created := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+	updated := time.Date(2026, 2, 1, 10, 0, 0, 0, time.UTC)
+	repo.history[id] = []domain.User{
+		{ID: id, Email: "test@example.com", FirstName: "John", LastName: "Doe", Status: domain.UserStatusActive, UpdatedAt: created},
+		{ID: id, Email: "test@example.com", FirstName: "Jane", LastName: "Doe", Status: domain.UserStatusInactive, UpdatedAt: updated},
+	}
+
+	diff, err := svc.Diff(context.Background(), id, created, updated)
+	if err != nil {
+		t.Fatalf("Diff() unexpected error = %v", err)
+	}
+	if len(diff.Changes) != 2 {
+		t.Fatalf("Diff() changes = %v, want 2", len(diff.Changes))
+	}
+	if diff.Changes[0].Field != "firstName" || diff.Changes[0].From != "John" || diff.Changes[0].To != "Jane" {
+		t.Errorf("Diff() changes[0] = %+v, want firstName John -> Jane", diff.Changes[0])
+	}
+	if diff.Changes[1].Field != "status" {
+		t.Errorf("Diff() changes[1].Field = %v, want status", diff.Changes[1].Field)
+	}
+
+	_, err = svc.Diff(context.Background(), id, updated, created)
+	if !errors.Is(err, domain.ErrInvalidInput) {
+		t.Errorf("Diff() with reversed range error = %v, want %v", err, domain.ErrInvalidInput)
+	}
+
+	_, err = svc.Diff(context.Background(), id, created.Add(-time.Hour), updated)
+	if err != domain.ErrUserNotFound {
+		t.Errorf("Diff() before creation error = %v, want %v", err, domain.ErrUserNotFound)
+	}
+}
+
+func TestDiffUsers_Suspension(t *testing.T) {
+	until := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	later := until.Add(24 * time.Hour)
+	sameInstant := until.In(time.FixedZone("UTC-3", -3*60*60))
+
+	active := domain.User{Status: domain.UserStatusActive}
+	suspended := domain.User{Status: domain.UserStatusSuspended, StatusReason: domain.SuspensionReasonAbuse, SuspendedUntil: &until}
+
+	tests := []struct {
+		name   string
+		before domain.User
+		after  domain.User
+		want   []string
+	}{
+		{"suspended", active, suspended, []string{"status", "statusReason", "suspendedUntil"}},
+		{"reactivated", suspended, active, []string{"status", "statusReason", "suspendedUntil"}},
+		{"reason changed", suspended, domain.User{Status: domain.UserStatusSuspended, StatusReason: domain.SuspensionReasonFraud, SuspendedUntil: &until}, []string{"statusReason"}},
+		{"extended", suspended, domain.User{Status: domain.UserStatusSuspended, StatusReason: domain.SuspensionReasonAbuse, SuspendedUntil: &later}, []string{"suspendedUntil"}},
+		{"same instant", suspended, domain.User{Status: domain.UserStatusSuspended, StatusReason: domain.SuspensionReasonAbuse, SuspendedUntil: &sameInstant}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, c := range diffUsers(&tt.before, &tt.after) {
+				got = append(got, c.Field)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("diffUsers() fields = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/giannuccilli/user-api/internal/domain"
+	"github.com/giannuccilli/user-api/internal/token"
+)
+
+const tokenPurposeEmailVerification = "email_verification"
+
+type VerificationConfig struct {
+	// Required makes new users start as pending_verification until they
+	// confirm their address.
+	Required              bool
+	TokenTTL              time.Duration
+	VerifyURL             string
+	ConfirmEmailChangeURL string
+	// SkipEmailChangeConfirmation applies email changes immediately instead
+	// of waiting for the new address to be confirmed.
+	SkipEmailChangeConfirmation bool
+}
+
+func WithEmailVerification(mailer domain.MailSender, signer *token.Signer, cfg VerificationConfig) Option {
+	return func(s *UserService) {
+		s.mailer = mailer
+		s.tokens = signer
+		s.verification = cfg
+	}
+}
+
+func (s *UserService) SendVerification(ctx context.Context, id uuid.UUID) error {
+	if s.tokens == nil || s.mailer == nil {
+		return domain.ErrNotSupported
+	}
+
+	user, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if user.EmailVerified {
+		return domain.ErrEmailAlreadyVerified
+	}
+
+	return s.sendVerificationEmail(ctx, user)
+}
+
+func (s *UserService) VerifyEmail(ctx context.Context, req domain.VerifyEmailRequest) (*domain.User, error) {
+	if s.tokens == nil {
+		return nil, domain.ErrNotSupported
+	}
+
+	claims, err := s.tokens.Verify(req.Token, tokenPurposeEmailVerification)
+	if err != nil {
+		return nil, tokenError(err)
+	}
+	ctx = tokenTenant(ctx, claims)
+
+	user, err := s.repo.GetByID(ctx, claims.UserID)
+	if err != nil {
+		if errors.Is(err, domain.ErrUserNotFound) {
+			return nil, domain.ErrInvalidToken
+		}
+		return nil, err
+	}
+
+	// The token is bound to the address it was sent to, so it stops working
+	// as soon as the email changes.
+	if user.Email != claims.Email {
+		return nil, domain.ErrInvalidToken
+	}
+	if user.EmailVerified {
+		return user, nil
+	}
+
+	user.EmailVerified = true
+	if user.Status == domain.UserStatusPendingVerification {
+		user.Status = domain.UserStatusActive
+	}
+
+	if err := s.repo.Update(ctx, user); err != nil {
+		return nil, err
+	}
+
+	s.notifier.Notify(ctx, domain.EventTypeUserEmailVerified, eventData(user))
+
+	return user, nil
+}
+
+func (s *UserService) sendVerificationEmail(ctx context.Context, user *domain.User) error {
+	tok, err := s.tokens.Sign(token.Claims{
+		Purpose:   tokenPurposeEmailVerification,
+		TenantID:  user.TenantID,
+		UserID:    user.ID,
+		Email:     user.Email,
+		ExpiresAt: time.Now().UTC().Add(s.verification.TokenTTL),
+	})
+	if err != nil {
+		return err
+	}
+
+	body := fmt.Sprintf("Hi %s,\n\nPlease confirm your email address using the link below:\n\n%s\n\nThe link expires in %s.\n",
+		user.FirstName, tokenLink(s.verification.VerifyURL, tok), s.verification.TokenTTL)
+
+	return s.mailer.Send(ctx, domain.MailMessage{
+		To:      user.Email,
+		Subject: "Confirm your email address",
+		Body:    body,
+	})
+}
+
+func tokenLink(baseURL, tok string) string {
+	if baseURL == "" {
+		return tok
+	}
+
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return tok
+	}
+
+	q := u.Query()
+	q.Set("token", tok)
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// tokenTenant scopes ctx to the tenant the token was issued in, since the
+// links in the emails are opened without one.
+func tokenTenant(ctx context.Context, claims token.Claims) context.Context {
+	if claims.TenantID == "" {
+		return ctx
+	}
+	return domain.ContextWithTenant(ctx, claims.TenantID)
+}
+
+func tokenError(err error) error {
+	if errors.Is(err, token.ErrExpired) {
+		return domain.ErrTokenExpired
+	}
+	return domain.ErrInvalidToken
+}
@@ -0,0 +1,242 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/giannuccilli/user-api/internal/domain"
+	"github.com/giannuccilli/user-api/internal/mailer"
+	"github.com/giannuccilli/user-api/internal/token"
+)
+
+func newVerificationService(required bool) (*UserService, *mockUserRepository, *mailer.MemorySender, *token.Signer) {
+	repo := newMockUserRepository()
+	sender := mailer.NewMemorySender()
+	signer := token.NewSigner([]byte("test-secret"))
+	svc := NewUserService(repo, &mockNotifier{}, WithEmailVerification(sender, signer, VerificationConfig{
+		Required:              required,
+		TokenTTL:              time.Hour,
+		VerifyURL:             "https://app.example.com/verify-email",
+		ConfirmEmailChangeURL: "https://app.example.com/confirm-email-change",
+	}))
+	return svc, repo, sender, signer
+}
+
+func tokenFromMail(t *testing.T, msg domain.MailMessage) string {
+	t.Helper()
+	_, tok, ok := strings.Cut(msg.Body, "?token=")
+	if !ok {
+		t.Fatalf("mail body has no token link: %q", msg.Body)
+	}
+	tok, _, _ = strings.Cut(tok, "\n")
+	return tok
+}
+
+func TestUserService_Create_VerificationRequired(t *testing.T) {
+	svc, _, sender, _ := newVerificationService(true)
+
+	user, err := svc.Create(context.Background(), domain.CreateUserRequest{
+		Email:     "test@example.com",
+		FirstName: "John",
+		LastName:  "Doe",
+	})
+	if err != nil {
+		t.Fatalf("Create() unexpected error = %v", err)
+	}
+
+	if user.Status != domain.UserStatusPendingVerification {
+		t.Errorf("Create() status = %v, want %v", user.Status, domain.UserStatusPendingVerification)
+	}
+	if user.EmailVerified {
+		t.Error("Create() emailVerified = true, want false")
+	}
+
+	messages := sender.Messages()
+	if len(messages) != 1 || messages[0].To != "test@example.com" {
+		t.Fatalf("Create() sent %v, want one verification email", messages)
+	}
+	if !strings.Contains(messages[0].Body, "https://app.example.com/verify-email?token=") {
+		t.Errorf("verification email body = %q, want verify link", messages[0].Body)
+	}
+}
+
+func TestUserService_Create_VerificationOptional(t *testing.T) {
+	svc, _, sender, _ := newVerificationService(false)
+
+	user, err := svc.Create(context.Background(), domain.CreateUserRequest{
+		Email:     "test@example.com",
+		FirstName: "John",
+		LastName:  "Doe",
+	})
+	if err != nil {
+		t.Fatalf("Create() unexpected error = %v", err)
+	}
+
+	if user.Status != domain.UserStatusActive {
+		t.Errorf("Create() status = %v, want %v", user.Status, domain.UserStatusActive)
+	}
+	if len(sender.Messages()) != 0 {
+		t.Errorf("Create() sent %v emails, want 0", len(sender.Messages()))
+	}
+}
+
+func TestUserService_VerifyEmail(t *testing.T) {
+	svc, repo, sender, _ := newVerificationService(true)
+
+	user, _ := svc.Create(context.Background(), domain.CreateUserRequest{
+		Email:     "test@example.com",
+		FirstName: "John",
+		LastName:  "Doe",
+	})
+	tok := tokenFromMail(t, sender.Messages()[0])
+
+	verified, err := svc.VerifyEmail(context.Background(), domain.VerifyEmailRequest{Token: tok})
+	if err != nil {
+		t.Fatalf("VerifyEmail() unexpected error = %v", err)
+	}
+	if !verified.EmailVerified || verified.Status != domain.UserStatusActive {
+		t.Errorf("VerifyEmail() user = %+v, want verified and active", verified)
+	}
+
+	if _, err := svc.VerifyEmail(context.Background(), domain.VerifyEmailRequest{Token: tok}); err != nil {
+		t.Errorf("VerifyEmail() twice error = %v, want nil", err)
+	}
+
+	repo.users[user.ID].Email = "changed@example.com"
+	repo.users[user.ID].EmailVerified = false
+	if _, err := svc.VerifyEmail(context.Background(), domain.VerifyEmailRequest{Token: tok}); err != domain.ErrInvalidToken {
+		t.Errorf("VerifyEmail() after email change error = %v, want %v", err, domain.ErrInvalidToken)
+	}
+}
+
+func TestUserService_VerifyEmail_InvalidTokens(t *testing.T) {
+	svc, _, _, signer := newVerificationService(true)
+
+	expired, _ := signer.Sign(token.Claims{
+		Purpose:   tokenPurposeEmailVerification,
+		UserID:    uuid.New(),
+		Email:     "test@example.com",
+		ExpiresAt: time.Now().Add(-time.Minute),
+	})
+	unknownUser, _ := signer.Sign(token.Claims{
+		Purpose:   tokenPurposeEmailVerification,
+		UserID:    uuid.New(),
+		Email:     "test@example.com",
+		ExpiresAt: time.Now().Add(time.Hour),
+	})
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr error
+	}{
+		{"garbage", "not-a-token", domain.ErrInvalidToken},
+		{"expired", expired, domain.ErrTokenExpired},
+		{"unknown user", unknownUser, domain.ErrInvalidToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := svc.VerifyEmail(context.Background(), domain.VerifyEmailRequest{Token: tt.token})
+			if err != tt.wantErr {
+				t.Errorf("VerifyEmail() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestUserService_SendVerification(t *testing.T) {
+	svc, repo, sender, _ := newVerificationService(false)
+
+	user := &domain.User{ID: uuid.New(), Email: "test@example.com", FirstName: "John", LastName: "Doe", Status: domain.UserStatusActive}
+	repo.users[user.ID] = user
+
+	if err := svc.SendVerification(context.Background(), user.ID); err != nil {
+		t.Fatalf("SendVerification() unexpected error = %v", err)
+	}
+	if len(sender.Messages()) != 1 {
+		t.Errorf("SendVerification() sent %v emails, want 1", len(sender.Messages()))
+	}
+
+	user.EmailVerified = true
+	if err := svc.SendVerification(context.Background(), user.ID); err != domain.ErrEmailAlreadyVerified {
+		t.Errorf("SendVerification() for verified user error = %v, want %v", err, domain.ErrEmailAlreadyVerified)
+	}
+
+	if err := svc.SendVerification(context.Background(), uuid.New()); err != domain.ErrUserNotFound {
+		t.Errorf("SendVerification() for non-existing error = %v, want %v", err, domain.ErrUserNotFound)
+	}
+
+	unconfigured := NewUserService(repo, &mockNotifier{})
+	if err := unconfigured.SendVerification(context.Background(), user.ID); err != domain.ErrNotSupported {
+		t.Errorf("SendVerification() without mailer error = %v, want %v", err, domain.ErrNotSupported)
+	}
+}
+
+func TestUserService_VerifyEmail_TenantFromToken(t *testing.T) {
+	svc, repo, sender, _ := newVerificationService(true)
+
+	user := &domain.User{ID: uuid.New(), TenantID: "acme", Email: "test@example.com", FirstName: "John", LastName: "Doe", Status: domain.UserStatusPendingVerification}
+	repo.users[user.ID] = user
+	repo.byEmail[user.Email] = user
+
+	if err := svc.SendVerification(domain.ContextWithTenant(context.Background(), "acme"), user.ID); err != nil {
+		t.Fatalf("SendVerification() unexpected error = %v", err)
+	}
+
+	// The link is opened without a tenant; the one in the token applies.
+	verified, err := svc.VerifyEmail(context.Background(), domain.VerifyEmailRequest{Token: tokenFromMail(t, sender.Messages()[0])})
+	if err != nil {
+		t.Fatalf("VerifyEmail() unexpected error = %v", err)
+	}
+	if !verified.EmailVerified {
+		t.Errorf("VerifyEmail() user = %+v, want verified", verified)
+	}
+}
+
+type failingMailer struct{}
+
+func (failingMailer) Send(ctx context.Context, msg domain.MailMessage) error {
+	return errors.New("smtp unavailable")
+}
+
+func TestUserService_MailerFailureIsLogged(t *testing.T) {
+	var logs bytes.Buffer
+	repo := newMockUserRepository()
+	svc := NewUserService(repo, &mockNotifier{},
+		WithEmailVerification(failingMailer{}, token.NewSigner([]byte("test-secret")), VerificationConfig{Required: true, TokenTTL: time.Hour}),
+		WithLogger(slog.New(slog.NewTextHandler(&logs, nil))),
+	)
+
+	user, err := svc.Create(context.Background(), domain.CreateUserRequest{
+		Email:     "test@example.com",
+		FirstName: "John",
+		LastName:  "Doe",
+	})
+	if err != nil {
+		t.Fatalf("Create() unexpected error = %v", err)
+	}
+	if !strings.Contains(logs.String(), "failed to send verification email") || !strings.Contains(logs.String(), user.ID.String()) {
+		t.Errorf("logs = %q, want verification email failure for the user", logs.String())
+	}
+
+	logs.Reset()
+	newEmail := "new@example.com"
+	updated, err := svc.Update(context.Background(), user.ID, domain.UpdateUserRequest{Email: &newEmail})
+	if err != nil {
+		t.Fatalf("Update() unexpected error = %v", err)
+	}
+	if updated.PendingEmail != newEmail {
+		t.Errorf("Update() pendingEmail = %v, want %v", updated.PendingEmail, newEmail)
+	}
+	if !strings.Contains(logs.String(), "failed to send email change emails") {
+		t.Errorf("logs = %q, want email change failure", logs.String())
+	}
+}
@@ -0,0 +1,233 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/giannuccilli/user-api/internal/domain"
+	"github.com/giannuccilli/user-api/internal/netguard"
+)
+
+const (
+	maxWebhookURLLength    = 2048
+	minWebhookSecretLength = 16
+	maxWebhookSecretLength = 255
+)
+
+// WebhookService manages the webhook subscriptions of the tenant in the
+// context. Subscriptions receive the tenant's user data, so every operation
+// is restricted to admins.
+type WebhookService struct {
+	repo domain.WebhookRepository
+	opts WebhookServiceOptions
+}
+
+type WebhookServiceOptions struct {
+	// AllowPrivateNetworks accepts URLs pointing to localhost or to
+	// loopback, private and link-local addresses. Names resolving to such
+	// addresses are refused when delivering, see notifier.WebhookOptions.
+	AllowPrivateNetworks bool
+}
+
+func NewWebhookService(repo domain.WebhookRepository, opts WebhookServiceOptions) *WebhookService {
+	return &WebhookService{repo: repo, opts: opts}
+}
+
+func (s *WebhookService) List(ctx context.Context) (*domain.WebhookList, error) {
+	if !domain.ActorFromContext(ctx).IsAdmin() {
+		return nil, domain.ErrForbidden
+	}
+	subs, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range subs {
+		subs[i].Secret = ""
+	}
+	return &domain.WebhookList{Data: subs}, nil
+}
+
+func (s *WebhookService) Get(ctx context.Context, id uuid.UUID) (*domain.WebhookSubscription, error) {
+	if !domain.ActorFromContext(ctx).IsAdmin() {
+		return nil, domain.ErrForbidden
+	}
+	sub, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	sub.Secret = ""
+	return sub, nil
+}
+
+// Create returns the subscription with its secret, which is not returned
+// again.
+func (s *WebhookService) Create(ctx context.Context, req domain.CreateWebhookRequest) (*domain.WebhookSubscription, error) {
+	if !domain.ActorFromContext(ctx).IsAdmin() {
+		return nil, domain.ErrForbidden
+	}
+
+	v := &domain.ValidationError{}
+	s.validateURL(v, req.URL)
+	validateEventTypes(v, req.EventTypes)
+	if req.Secret != "" {
+		validateWebhookSecret(v, req.Secret)
+	}
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+
+	secret := req.Secret
+	if secret == "" {
+		var err error
+		if secret, err = generateWebhookSecret(); err != nil {
+			return nil, err
+		}
+	}
+
+	sub := &domain.WebhookSubscription{
+		URL:        req.URL,
+		EventTypes: compactEventTypes(req.EventTypes),
+		Secret:     secret,
+		Enabled:    true,
+	}
+	if err := s.repo.Create(ctx, sub); err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+func (s *WebhookService) Update(ctx context.Context, id uuid.UUID, req domain.UpdateWebhookRequest) (*domain.WebhookSubscription, error) {
+	if !domain.ActorFromContext(ctx).IsAdmin() {
+		return nil, domain.ErrForbidden
+	}
+
+	v := &domain.ValidationError{}
+	if req.URL != nil {
+		s.validateURL(v, *req.URL)
+	}
+	if req.EventTypes != nil {
+		validateEventTypes(v, *req.EventTypes)
+	}
+	if req.Secret != nil {
+		validateWebhookSecret(v, *req.Secret)
+	}
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+
+	sub, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if req.URL != nil {
+		sub.URL = *req.URL
+	}
+	if req.EventTypes != nil {
+		sub.EventTypes = compactEventTypes(*req.EventTypes)
+	}
+	if req.Secret != nil {
+		sub.Secret = *req.Secret
+	}
+	if req.Enabled != nil && *req.Enabled != sub.Enabled {
+		sub.Enabled = *req.Enabled
+		if sub.Enabled {
+			sub.ConsecutiveFailures = 0
+			sub.DisabledAt = nil
+		} else {
+			now := time.Now().UTC()
+			sub.DisabledAt = &now
+		}
+	}
+
+	if err := s.repo.Update(ctx, sub); err != nil {
+		return nil, err
+	}
+	sub.Secret = ""
+	return sub, nil
+}
+
+func (s *WebhookService) Delete(ctx context.Context, id uuid.UUID) error {
+	if !domain.ActorFromContext(ctx).IsAdmin() {
+		return domain.ErrForbidden
+	}
+	return s.repo.Delete(ctx, id)
+}
+
+func (s *WebhookService) ListDeliveries(ctx context.Context, id uuid.UUID, limit, offset int) (*domain.WebhookDeliveryList, error) {
+	if !domain.ActorFromContext(ctx).IsAdmin() {
+		return nil, domain.ErrForbidden
+	}
+	if _, err := s.repo.Get(ctx, id); err != nil {
+		return nil, err
+	}
+
+	limit, offset = normalizePage(limit, offset)
+	deliveries, total, err := s.repo.ListDeliveries(ctx, id, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	return &domain.WebhookDeliveryList{
+		Data: deliveries,
+		Pagination: domain.Pagination{
+			Total:  total,
+			Limit:  limit,
+			Offset: offset,
+		},
+	}, nil
+}
+
+func (s *WebhookService) validateURL(v *domain.ValidationError, rawURL string) {
+	if rawURL == "" {
+		v.Add("url", domain.FieldCodeRequired, "is required")
+		return
+	}
+	if len(rawURL) > maxWebhookURLLength {
+		v.Add("url", domain.FieldCodeTooLong, "must be at most 2048 characters")
+		return
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		v.Add("url", domain.FieldCodeInvalidFormat, "must be an absolute http or https URL")
+		return
+	}
+	if !s.opts.AllowPrivateNetworks && !netguard.IsPublicHost(u.Hostname()) {
+		v.Add("url", domain.FieldCodeInvalidValue, "must not point to a loopback, private or link-local address")
+	}
+}
+
+func validateEventTypes(v *domain.ValidationError, types []domain.EventType) {
+	for _, t := range types {
+		if !t.IsValid() {
+			v.Add("eventTypes", domain.FieldCodeInvalidValue, "unknown event type "+string(t))
+		}
+	}
+}
+
+func validateWebhookSecret(v *domain.ValidationError, secret string) {
+	switch {
+	case len(secret) < minWebhookSecretLength:
+		v.Add("secret", domain.FieldCodeInvalidValue, "must be at least 16 characters")
+	case len(secret) > maxWebhookSecretLength:
+		v.Add("secret", domain.FieldCodeTooLong, "must be at most 255 characters")
+	}
+}
+
+func compactEventTypes(types []domain.EventType) []domain.EventType {
+	types = append([]domain.EventType{}, types...)
+	slices.Sort(types)
+	return slices.Compact(types)
+}
+
+func generateWebhookSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
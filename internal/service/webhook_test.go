@@ -0,0 +1,144 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/giannuccilli/user-api/internal/domain"
+)
+
+type mockWebhookRepository struct {
+	subs map[uuid.UUID]domain.WebhookSubscription
+}
+
+func (m *mockWebhookRepository) Create(ctx context.Context, sub *domain.WebhookSubscription) error {
+	sub.ID = uuid.New()
+	m.subs[sub.ID] = *sub
+	return nil
+}
+
+func (m *mockWebhookRepository) Get(ctx context.Context, id uuid.UUID) (*domain.WebhookSubscription, error) {
+	sub, ok := m.subs[id]
+	if !ok {
+		return nil, domain.ErrWebhookNotFound
+	}
+	return &sub, nil
+}
+
+func (m *mockWebhookRepository) List(ctx context.Context) ([]domain.WebhookSubscription, error) {
+	subs := make([]domain.WebhookSubscription, 0, len(m.subs))
+	for _, sub := range m.subs {
+		subs = append(subs, sub)
+	}
+	return subs, nil
+}
+
+func (m *mockWebhookRepository) Update(ctx context.Context, sub *domain.WebhookSubscription) error {
+	m.subs[sub.ID] = *sub
+	return nil
+}
+
+func (m *mockWebhookRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	delete(m.subs, id)
+	return nil
+}
+
+func (m *mockWebhookRepository) ListDeliveries(ctx context.Context, subscriptionID uuid.UUID, limit, offset int) ([]domain.WebhookDelivery, int, error) {
+	return nil, 0, nil
+}
+
+func (m *mockWebhookRepository) Enqueue(ctx context.Context, event domain.UserEvent, payload []byte) (int, error) {
+	return 0, nil
+}
+
+func (m *mockWebhookRepository) ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]domain.PendingWebhookDelivery, error) {
+	return nil, nil
+}
+
+func (m *mockWebhookRepository) RecordAttempt(ctx context.Context, delivery *domain.WebhookDelivery, disableAfter int) (bool, error) {
+	return false, nil
+}
+
+func TestWebhookService_Create(t *testing.T) {
+	admin := domain.ContextWithActor(context.Background(), domain.Actor{ID: "ops", Roles: []string{domain.RoleAdmin}})
+
+	tests := []struct {
+		name       string
+		ctx        context.Context
+		req        domain.CreateWebhookRequest
+		opts       WebhookServiceOptions
+		wantErr    error
+		wantFields []string
+	}{
+		{name: "generated secret", ctx: admin, req: domain.CreateWebhookRequest{URL: "https://example.com/hooks"}},
+		{name: "own secret", ctx: admin, req: domain.CreateWebhookRequest{URL: "http://hooks.internal:8080/users", Secret: strings.Repeat("s", 16)}},
+		{name: "not admin", ctx: context.Background(), req: domain.CreateWebhookRequest{URL: "https://example.com/hooks"}, wantErr: domain.ErrForbidden},
+		{
+			name:       "invalid",
+			ctx:        admin,
+			req:        domain.CreateWebhookRequest{URL: "/hooks", EventTypes: []domain.EventType{"user.created", "user.renamed"}, Secret: "short"},
+			wantErr:    domain.ErrInvalidInput,
+			wantFields: []string{"url:INVALID_FORMAT", "eventTypes:INVALID_VALUE", "secret:INVALID_VALUE"},
+		},
+		{name: "missing url", ctx: admin, req: domain.CreateWebhookRequest{}, wantErr: domain.ErrInvalidInput, wantFields: []string{"url:REQUIRED"}},
+		{name: "metadata endpoint", ctx: admin, req: domain.CreateWebhookRequest{URL: "http://169.254.169.254/latest/meta-data"}, wantErr: domain.ErrInvalidInput, wantFields: []string{"url:INVALID_VALUE"}},
+		{name: "localhost", ctx: admin, req: domain.CreateWebhookRequest{URL: "http://localhost:8080/hooks"}, wantErr: domain.ErrInvalidInput, wantFields: []string{"url:INVALID_VALUE"}},
+		{name: "private network allowed", ctx: admin, req: domain.CreateWebhookRequest{URL: "http://10.0.0.5/hooks"}, opts: WebhookServiceOptions{AllowPrivateNetworks: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := NewWebhookService(&mockWebhookRepository{subs: make(map[uuid.UUID]domain.WebhookSubscription)}, tt.opts)
+			sub, err := svc.Create(tt.ctx, tt.req)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Create() error = %v, want %v", err, tt.wantErr)
+			}
+
+			var v *domain.ValidationError
+			if errors.As(err, &v) {
+				var got []string
+				for _, f := range v.Fields {
+					got = append(got, f.Field+":"+f.Code)
+				}
+				if strings.Join(got, ",") != strings.Join(tt.wantFields, ",") {
+					t.Errorf("fields = %v, want %v", got, tt.wantFields)
+				}
+			}
+			if err == nil && (!sub.Enabled || len(sub.Secret) < minWebhookSecretLength) {
+				t.Errorf("Create() = %+v, want an enabled subscription with its secret", sub)
+			}
+		})
+	}
+}
+
+func TestWebhookService_UpdateEnabled(t *testing.T) {
+	ctx := domain.ContextWithActor(context.Background(), domain.Actor{ID: "ops", Roles: []string{domain.RoleAdmin}})
+	disabledAt := time.Now().Add(-time.Hour)
+	id := uuid.New()
+	repo := &mockWebhookRepository{subs: map[uuid.UUID]domain.WebhookSubscription{
+		id: {ID: id, URL: "https://example.com/hooks", Secret: "s3cret", ConsecutiveFailures: 10, DisabledAt: &disabledAt},
+	}}
+	svc := NewWebhookService(repo, WebhookServiceOptions{})
+
+	enabled := true
+	sub, err := svc.Update(ctx, id, domain.UpdateWebhookRequest{Enabled: &enabled})
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if !sub.Enabled || sub.ConsecutiveFailures != 0 || sub.DisabledAt != nil || sub.Secret != "" {
+		t.Errorf("Update() = %+v, want enabled with failures reset and no secret", sub)
+	}
+	if repo.subs[id].Secret != "s3cret" {
+		t.Errorf("stored secret = %q, want it unchanged", repo.subs[id].Secret)
+	}
+
+	enabled = false
+	if sub, _ := svc.Update(ctx, id, domain.UpdateWebhookRequest{Enabled: &enabled}); sub.Enabled || sub.DisabledAt == nil {
+		t.Errorf("Update() = %+v, want disabled", sub)
+	}
+}
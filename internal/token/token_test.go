@@ -0,0 +1,65 @@
+package token
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestSigner_SignAndVerify(t *testing.T) {
+	signer := NewSigner([]byte("test-secret"))
+	claims := Claims{
+		Purpose:   "email_verification",
+		TenantID:  "acme",
+		UserID:    uuid.New(),
+		Email:     "test@example.com",
+		ExpiresAt: time.Now().Add(time.Hour).UTC(),
+	}
+
+	tok, err := signer.Sign(claims)
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+
+	got, err := signer.Verify(tok, "email_verification")
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if got.TenantID != claims.TenantID || got.UserID != claims.UserID || got.Email != claims.Email {
+		t.Errorf("Verify() claims = %+v, want %+v", got, claims)
+	}
+}
+
+func TestSigner_Verify_Errors(t *testing.T) {
+	signer := NewSigner([]byte("test-secret"))
+	valid, _ := signer.Sign(Claims{Purpose: "email_verification", UserID: uuid.New(), ExpiresAt: time.Now().Add(time.Hour)})
+	expired, _ := signer.Sign(Claims{Purpose: "email_verification", UserID: uuid.New(), ExpiresAt: time.Now().Add(-time.Minute)})
+	otherKey, _ := NewSigner([]byte("other-secret")).Sign(Claims{Purpose: "email_verification", ExpiresAt: time.Now().Add(time.Hour)})
+
+	payload, sig, _ := strings.Cut(valid, ".")
+	tampered := payload[:len(payload)-2] + "xx." + sig
+
+	tests := []struct {
+		name    string
+		token   string
+		purpose string
+		wantErr error
+	}{
+		{"empty", "", "email_verification", ErrInvalid},
+		{"no signature", payload, "email_verification", ErrInvalid},
+		{"tampered payload", tampered, "email_verification", ErrInvalid},
+		{"signed with other key", otherKey, "email_verification", ErrInvalid},
+		{"wrong purpose", valid, "email_change", ErrInvalid},
+		{"expired", expired, "email_verification", ErrExpired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := signer.Verify(tt.token, tt.purpose); err != tt.wantErr {
+				t.Errorf("Verify() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS user_history (
+    history_id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL,
+    email VARCHAR(255) NOT NULL,
+    first_name VARCHAR(100) NOT NULL,
+    last_name VARCHAR(100) NOT NULL,
+    status user_status NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    valid_from TIMESTAMP WITH TIME ZONE NOT NULL,
+    valid_to TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX idx_user_history_user_id_valid ON user_history(user_id, valid_from, valid_to);
+CREATE UNIQUE INDEX idx_user_history_current ON user_history(user_id) WHERE valid_to IS NULL;
+
+INSERT INTO user_history (user_id, email, first_name, last_name, status, created_at, updated_at, valid_from)
+SELECT id, email, first_name, last_name, status, created_at, updated_at, updated_at
+FROM users;
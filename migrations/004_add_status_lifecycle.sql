@@ -0,0 +1,9 @@
+ALTER TABLE users
+    ADD COLUMN status_reason VARCHAR(50),
+    ADD COLUMN suspended_until TIMESTAMP WITH TIME ZONE;
+
+ALTER TABLE user_history
+    ADD COLUMN status_reason VARCHAR(50),
+    ADD COLUMN suspended_until TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX idx_users_suspended_until ON users(suspended_until) WHERE status = 'suspended';
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS user_audit_log (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL,
+    action VARCHAR(50) NOT NULL,
+    actor_id VARCHAR(255) NOT NULL DEFAULT '',
+    from_status user_status NOT NULL,
+    to_status user_status NOT NULL,
+    reason VARCHAR(50),
+    note TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_user_audit_log_user_id ON user_audit_log(user_id, created_at);
@@ -0,0 +1,5 @@
+ALTER TYPE user_status ADD VALUE IF NOT EXISTS 'pending_verification';
+
+ALTER TABLE users ADD COLUMN email_verified BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE user_history ADD COLUMN email_verified BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,7 @@
+ALTER TABLE users ADD COLUMN email_canonical VARCHAR(255);
+
+UPDATE users SET email_canonical = LOWER(email);
+
+ALTER TABLE users ALTER COLUMN email_canonical SET NOT NULL;
+
+CREATE UNIQUE INDEX idx_users_email_canonical ON users(email_canonical);
@@ -0,0 +1,12 @@
+ALTER TABLE users ADD COLUMN attributes JSONB NOT NULL DEFAULT '{}';
+ALTER TABLE user_history ADD COLUMN attributes JSONB NOT NULL DEFAULT '{}';
+
+CREATE INDEX idx_users_attributes ON users USING GIN (attributes jsonb_path_ops);
+
+CREATE TABLE IF NOT EXISTS attribute_definitions (
+    key VARCHAR(63) PRIMARY KEY,
+    description VARCHAR(500) NOT NULL DEFAULT '',
+    schema JSONB NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS user_identities (
+    provider VARCHAR(63) NOT NULL,
+    external_id VARCHAR(255) NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (provider, external_id)
+);
+
+CREATE INDEX idx_user_identities_user_id ON user_identities(user_id);
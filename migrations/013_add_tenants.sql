@@ -0,0 +1,55 @@
+CREATE TABLE IF NOT EXISTS tenants (
+    id VARCHAR(63) PRIMARY KEY,
+    name VARCHAR(200) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO tenants (id, name) VALUES ('default', 'Default') ON CONFLICT DO NOTHING;
+
+-- Users
+
+ALTER TABLE users ADD COLUMN tenant_id VARCHAR(63) NOT NULL DEFAULT 'default' REFERENCES tenants(id);
+ALTER TABLE users ALTER COLUMN tenant_id DROP DEFAULT;
+
+ALTER TABLE users DROP CONSTRAINT users_email_key;
+DROP INDEX idx_users_email;
+DROP INDEX idx_users_email_canonical;
+
+CREATE INDEX idx_users_tenant_email ON users(tenant_id, email);
+CREATE UNIQUE INDEX idx_users_tenant_email_canonical ON users(tenant_id, email_canonical);
+CREATE INDEX idx_users_tenant_created_at ON users(tenant_id, created_at);
+
+ALTER TABLE user_history ADD COLUMN tenant_id VARCHAR(63) NOT NULL DEFAULT 'default';
+ALTER TABLE user_history ALTER COLUMN tenant_id DROP DEFAULT;
+
+-- External identities are unique per tenant
+
+ALTER TABLE user_identities ADD COLUMN tenant_id VARCHAR(63) NOT NULL DEFAULT 'default';
+ALTER TABLE user_identities ALTER COLUMN tenant_id DROP DEFAULT;
+ALTER TABLE user_identities DROP CONSTRAINT user_identities_pkey;
+ALTER TABLE user_identities ADD PRIMARY KEY (tenant_id, provider, external_id);
+
+-- Idempotency keys are scoped to the tenant and the actor
+
+ALTER TABLE idempotency_keys ADD COLUMN tenant_id VARCHAR(63) NOT NULL DEFAULT 'default';
+ALTER TABLE idempotency_keys ALTER COLUMN tenant_id DROP DEFAULT;
+ALTER TABLE idempotency_keys DROP CONSTRAINT idempotency_keys_pkey;
+ALTER TABLE idempotency_keys ADD PRIMARY KEY (tenant_id, actor_id, key);
+
+-- Row-level security. It only applies to roles that do not own the tables,
+-- so the service must connect with such a role and DATABASE_ROW_LEVEL_SECURITY
+-- enabled, which sets app.tenant_id on every connection it uses. Connections
+-- without app.tenant_id (background jobs, migrations) see every tenant.
+
+ALTER TABLE users ENABLE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON users
+    USING (COALESCE(current_setting('app.tenant_id', true), '') IN ('', tenant_id));
+
+ALTER TABLE user_history ENABLE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON user_history
+    USING (COALESCE(current_setting('app.tenant_id', true), '') IN ('', tenant_id));
+
+ALTER TABLE user_identities ENABLE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON user_identities
+    USING (COALESCE(current_setting('app.tenant_id', true), '') IN ('', tenant_id));
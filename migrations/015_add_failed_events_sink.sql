@@ -0,0 +1,7 @@
+-- Events are fanned out to several sinks and may fail in only some of them,
+-- so each failed event records the sink that did not receive it.
+
+ALTER TABLE failed_events ADD COLUMN sink VARCHAR(50) NOT NULL DEFAULT 'kafka';
+ALTER TABLE failed_events ALTER COLUMN sink DROP DEFAULT;
+
+CREATE INDEX idx_failed_events_sink ON failed_events(sink);
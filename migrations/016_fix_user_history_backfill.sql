@@ -0,0 +1,11 @@
+-- 003 backfilled the history of existing users from updated_at, so asOf
+-- queries between their creation and their last update found nothing. Their
+-- first history row is valid since the user was created.
+
+UPDATE user_history h
+SET valid_from = h.created_at
+WHERE h.valid_from > h.created_at
+  AND NOT EXISTS (
+      SELECT 1 FROM user_history e
+      WHERE e.user_id = h.user_id AND e.valid_from < h.valid_from
+  );
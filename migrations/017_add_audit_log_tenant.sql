@@ -0,0 +1,29 @@
+-- The audit log was not scoped to a tenant, so any tenant could read the
+-- entries of another tenant's users. Entries take the tenant of their user,
+-- from the history if the user was deleted since.
+
+ALTER TABLE user_audit_log ADD COLUMN tenant_id VARCHAR(63);
+
+UPDATE user_audit_log a
+SET tenant_id = u.tenant_id
+FROM users u
+WHERE u.id = a.user_id;
+
+UPDATE user_audit_log a
+SET tenant_id = h.tenant_id
+FROM (
+    SELECT DISTINCT ON (user_id) user_id, tenant_id
+    FROM user_history
+    ORDER BY user_id, valid_from DESC
+) h
+WHERE a.tenant_id IS NULL AND h.user_id = a.user_id;
+
+UPDATE user_audit_log SET tenant_id = 'default' WHERE tenant_id IS NULL;
+ALTER TABLE user_audit_log ALTER COLUMN tenant_id SET NOT NULL;
+
+DROP INDEX idx_user_audit_log_user_id;
+CREATE INDEX idx_user_audit_log_tenant_user_id ON user_audit_log(tenant_id, user_id, created_at);
+
+ALTER TABLE user_audit_log ENABLE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON user_audit_log
+    USING (COALESCE(current_setting('app.tenant_id', true), '') IN ('', tenant_id));
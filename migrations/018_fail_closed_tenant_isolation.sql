@@ -0,0 +1,24 @@
+-- The tenant_isolation policies let connections without app.tenant_id see
+-- every tenant, so a request that lost its tenant read across tenants. A
+-- missing tenant now matches no rows; background jobs that work across
+-- tenants set app.all_tenants instead.
+
+DROP POLICY tenant_isolation ON users;
+CREATE POLICY tenant_isolation ON users
+    USING (tenant_id = current_setting('app.tenant_id', true)
+        OR current_setting('app.all_tenants', true) = 'on');
+
+DROP POLICY tenant_isolation ON user_history;
+CREATE POLICY tenant_isolation ON user_history
+    USING (tenant_id = current_setting('app.tenant_id', true)
+        OR current_setting('app.all_tenants', true) = 'on');
+
+DROP POLICY tenant_isolation ON user_identities;
+CREATE POLICY tenant_isolation ON user_identities
+    USING (tenant_id = current_setting('app.tenant_id', true)
+        OR current_setting('app.all_tenants', true) = 'on');
+
+DROP POLICY tenant_isolation ON user_audit_log;
+CREATE POLICY tenant_isolation ON user_audit_log
+    USING (tenant_id = current_setting('app.tenant_id', true)
+        OR current_setting('app.all_tenants', true) = 'on');
@@ -0,0 +1,261 @@
+// Package client is a Go client for the user API.
+//
+//	c := client.New("http://localhost:8080", client.WithTenant("acme"))
+//	user, err := c.Get(ctx, id)
+//	if errors.Is(err, client.ErrUserNotFound) {
+//		...
+//	}
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	RequestIDHeader      = "X-Request-ID"
+	IdempotencyKeyHeader = "Idempotency-Key"
+	TenantHeader         = "X-Tenant-ID"
+	ActorIDHeader        = "X-Actor-ID"
+	ActorRolesHeader     = "X-Actor-Roles"
+)
+
+// RetryPolicy controls how failed requests are retried. Only requests that
+// are safe to repeat are retried: GET, PUT and DELETE, and POST requests
+// carrying an Idempotency-Key.
+type RetryPolicy struct {
+	// MaxAttempts includes the first attempt; 1 disables retries.
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    3,
+	InitialBackoff: 100 * time.Millisecond,
+	MaxBackoff:     2 * time.Second,
+}
+
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	retry      RetryPolicy
+	tenantID   string
+	actorID    string
+	actorRoles []string
+}
+
+type Option func(*Client)
+
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(c *Client) {
+		c.retry = policy
+	}
+}
+
+// WithTenant sends every request for the given tenant. The service accepts it
+// when it matches the tenant of the bearer token, or from behind a gateway
+// when TENANT_TRUST_HEADER is set.
+func WithTenant(tenantID string) Option {
+	return func(c *Client) {
+		c.tenantID = tenantID
+	}
+}
+
+// WithActor identifies who performs the operations, as the API gateway
+// would.
+func WithActor(id string, roles ...string) Option {
+	return func(c *Client) {
+		c.actorID = id
+		c.actorRoles = roles
+	}
+}
+
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: http.DefaultClient,
+		retry:      DefaultRetryPolicy,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.retry.MaxAttempts < 1 {
+		c.retry.MaxAttempts = 1
+	}
+	return c
+}
+
+type requestIDKey struct{}
+
+// ContextWithRequestID makes the requests made with ctx carry id in the
+// X-Request-ID header, so they can be traced with the caller's own request.
+// Without it, each call gets a new ID shared by its retries.
+func ContextWithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+func requestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+type request struct {
+	method         string
+	path           string
+	query          url.Values
+	body           any
+	idempotencyKey string
+}
+
+// do sends req, retrying it as allowed by the retry policy, and decodes a
+// successful response body into out if it is not nil.
+func (c *Client) do(ctx context.Context, req request, out any) error {
+	var body []byte
+	if req.body != nil {
+		var err error
+		if body, err = json.Marshal(req.body); err != nil {
+			return fmt.Errorf("encode request: %w", err)
+		}
+	}
+
+	requestID := requestIDFromContext(ctx)
+	if requestID == "" {
+		requestID = uuid.NewString()
+	}
+
+	retryable := req.method != http.MethodPost || req.idempotencyKey != ""
+	for attempt := 1; ; attempt++ {
+		resp, err := c.send(ctx, req, body, requestID)
+		if err == nil && resp.StatusCode < 300 {
+			defer resp.Body.Close()
+			if out == nil {
+				return nil
+			}
+			if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+				return fmt.Errorf("decode response: %w", err)
+			}
+			return nil
+		}
+
+		var wait time.Duration
+		if err == nil {
+			wait = retryAfter(resp)
+			err = decodeError(resp)
+			resp.Body.Close()
+		}
+		if !retryable || attempt >= c.retry.MaxAttempts || !isRetryable(err) {
+			return err
+		}
+		// A server asking to wait longer than the policy allows gets the
+		// error back rather than an early retry.
+		if c.retry.MaxBackoff > 0 && wait > c.retry.MaxBackoff {
+			return err
+		}
+
+		if wait == 0 {
+			wait = c.backoff(attempt)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+func (c *Client) send(ctx context.Context, req request, body []byte, requestID string) (*http.Response, error) {
+	u := c.baseURL + req.path
+	if len(req.query) > 0 {
+		u += "?" + req.query.Encode()
+	}
+
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, req.method, u, reader)
+	if err != nil {
+		return nil, err
+	}
+
+	httpReq.Header.Set("Accept", "application/json")
+	httpReq.Header.Set(RequestIDHeader, requestID)
+	if body != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+	if req.idempotencyKey != "" {
+		httpReq.Header.Set(IdempotencyKeyHeader, req.idempotencyKey)
+	}
+	if c.tenantID != "" {
+		httpReq.Header.Set(TenantHeader, c.tenantID)
+	}
+	if c.actorID != "" {
+		httpReq.Header.Set(ActorIDHeader, c.actorID)
+	}
+	if len(c.actorRoles) > 0 {
+		httpReq.Header.Set(ActorRolesHeader, strings.Join(c.actorRoles, ","))
+	}
+
+	return c.httpClient.Do(httpReq)
+}
+
+// isRetryable reports whether err is a transport error or a response that
+// may succeed if the request is sent again.
+func isRetryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var apiErr *Error
+	if !errors.As(err, &apiErr) {
+		return true
+	}
+	switch apiErr.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	case http.StatusConflict:
+		// Another attempt with the same Idempotency-Key is still running.
+		return apiErr.Code == CodeIdempotencyKeyInUse
+	default:
+		return false
+	}
+}
+
+// backoff returns the exponential backoff before the retry following
+// attempt, with full jitter.
+func (c *Client) backoff(attempt int) time.Duration {
+	d := c.retry.InitialBackoff << (attempt - 1)
+	if d <= 0 || (c.retry.MaxBackoff > 0 && d > c.retry.MaxBackoff) {
+		d = c.retry.MaxBackoff
+	}
+	if d <= 0 {
+		return 0
+	}
+	return rand.N(d) + 1
+}
+
+func retryAfter(resp *http.Response) time.Duration {
+	seconds, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	if err != nil || seconds < 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
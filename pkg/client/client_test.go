@@ -0,0 +1,434 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/giannuccilli/user-api/internal/domain"
+	"github.com/giannuccilli/user-api/internal/handler"
+	"github.com/giannuccilli/user-api/internal/service"
+)
+
+type mockUserRepository struct {
+	mu    sync.Mutex
+	users []*domain.User
+}
+
+func (m *mockUserRepository) find(id uuid.UUID) (int, *domain.User) {
+	for i, u := range m.users {
+		if u.ID == id {
+			return i, u
+		}
+	}
+	return -1, nil
+}
+
+func (m *mockUserRepository) Create(ctx context.Context, user *domain.User) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, u := range m.users {
+		if u.EmailCanonical == user.EmailCanonical {
+			return domain.ErrEmailExists
+		}
+	}
+	user.ID = uuid.New()
+	user.TenantID, _ = domain.TenantFromContext(ctx)
+	user.CreatedAt = time.Now()
+	user.UpdatedAt = user.CreatedAt
+	stored := *user
+	m.users = append(m.users, &stored)
+	return nil
+}
+
+func (m *mockUserRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, u := m.find(id); u != nil {
+		copied := *u
+		return &copied, nil
+	}
+	return nil, domain.ErrUserNotFound
+}
+
+func (m *mockUserRepository) GetByIDs(ctx context.Context, ids []uuid.UUID, fields domain.FieldSet) ([]domain.User, error) {
+	return nil, domain.ErrNotSupported
+}
+
+func (m *mockUserRepository) GetByEmail(ctx context.Context, email string) (*domain.User, error) {
+	return nil, domain.ErrUserNotFound
+}
+
+func (m *mockUserRepository) GetByCanonicalEmail(ctx context.Context, canonical string) (*domain.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, u := range m.users {
+		if u.EmailCanonical == canonical {
+			copied := *u
+			return &copied, nil
+		}
+	}
+	return nil, domain.ErrUserNotFound
+}
+
+func (m *mockUserRepository) List(ctx context.Context, filter domain.UserFilter) ([]domain.User, int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	users := make([]domain.User, 0)
+	for i := filter.Offset; i < len(m.users) && len(users) < filter.Limit; i++ {
+		users = append(users, *m.users[i])
+	}
+	return users, len(m.users), nil
+}
+
+func (m *mockUserRepository) Update(ctx context.Context, user *domain.User) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, u := m.find(user.ID); u != nil {
+		*u = *user
+		return nil
+	}
+	return domain.ErrUserNotFound
+}
+
+func (m *mockUserRepository) UpdateWithAudit(ctx context.Context, user *domain.User, entry *domain.AuditEntry) error {
+	return m.Update(ctx, user)
+}
+
+func (m *mockUserRepository) Upsert(ctx context.Context, user *domain.User) (domain.UpsertResult, error) {
+	return domain.UpsertUnchanged, domain.ErrNotSupported
+}
+
+func (m *mockUserRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if i, _ := m.find(id); i >= 0 {
+		m.users = append(m.users[:i], m.users[i+1:]...)
+		return nil
+	}
+	return domain.ErrUserNotFound
+}
+
+func (m *mockUserRepository) GetAsOf(ctx context.Context, id uuid.UUID, asOf time.Time) (*domain.User, error) {
+	return m.GetByID(ctx, id)
+}
+
+func (m *mockUserRepository) ListExpiredSuspensions(ctx context.Context, before time.Time, limit int) ([]domain.User, error) {
+	return nil, nil
+}
+
+func (m *mockUserRepository) ReactivateSuspension(ctx context.Context, user *domain.User, before time.Time, entry *domain.AuditEntry) (bool, error) {
+	return false, nil
+}
+
+func (m *mockUserRepository) count() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.users)
+}
+
+type mockNotifier struct{}
+
+func (mockNotifier) NotifyCreated(ctx context.Context, data domain.EventData) error { return nil }
+func (mockNotifier) NotifyUpdated(ctx context.Context, data domain.EventData) error { return nil }
+func (mockNotifier) NotifyDeleted(ctx context.Context, userID uuid.UUID) error      { return nil }
+func (mockNotifier) Notify(ctx context.Context, eventType domain.EventType, data domain.EventData) error {
+	return nil
+}
+func (mockNotifier) Close() error { return nil }
+
+type mockIdempotencyRepository struct {
+	mu      sync.Mutex
+	records map[string]domain.IdempotencyRecord
+}
+
+func (m *mockIdempotencyRepository) Reserve(ctx context.Context, record *domain.IdempotencyRecord) (*domain.IdempotencyRecord, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	k := record.ActorID + "/" + record.Key
+	if existing, ok := m.records[k]; ok {
+		return &existing, nil
+	}
+	m.records[k] = *record
+	return nil, nil
+}
+
+func (m *mockIdempotencyRepository) Complete(ctx context.Context, record *domain.IdempotencyRecord) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.records[record.ActorID+"/"+record.Key] = *record
+	return nil
+}
+
+func (m *mockIdempotencyRepository) Release(ctx context.Context, record *domain.IdempotencyRecord) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.records, record.ActorID+"/"+record.Key)
+	return nil
+}
+
+func (m *mockIdempotencyRepository) DeleteExpired(ctx context.Context, before time.Time) (int, error) {
+	return 0, nil
+}
+
+// testServer runs the real handlers. Its first failResponses responses are
+// replaced with a 503 after the request was handled, as if a proxy had lost
+// them.
+type testServer struct {
+	*httptest.Server
+	repo          *mockUserRepository
+	failResponses atomic.Int32
+
+	mu       sync.Mutex
+	requests []*http.Request
+}
+
+func newTestServer(t *testing.T, errorFormat string) *testServer {
+	t.Helper()
+
+	ts := &testServer{repo: &mockUserRepository{}}
+	mux := http.NewServeMux()
+	handler.NewUserHandler(service.NewUserService(ts.repo, mockNotifier{})).RegisterRoutes(mux)
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	app := handler.Chain(mux,
+		handler.Logging(logger),
+		handler.ErrorFormat(handler.ErrorOptions{Format: errorFormat}),
+		handler.Actor(),
+		handler.Tenant(handler.TenantOptions{Default: domain.DefaultTenantID}),
+		handler.Idempotency(&mockIdempotencyRepository{records: map[string]domain.IdempotencyRecord{}}, logger, handler.IdempotencyOptions{}),
+	)
+
+	ts.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ts.mu.Lock()
+		ts.requests = append(ts.requests, r.Clone(context.Background()))
+		ts.mu.Unlock()
+
+		if ts.failResponses.Add(-1) >= 0 {
+			app.ServeHTTP(httptest.NewRecorder(), r)
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		app.ServeHTTP(w, r)
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func (ts *testServer) recorded() []*http.Request {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	return ts.requests
+}
+
+var testRetryPolicy = RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: 10 * time.Millisecond}
+
+func TestClient_CRUD(t *testing.T) {
+	ts := newTestServer(t, handler.ErrorFormatProblem)
+	c := New(ts.URL, WithRetryPolicy(testRetryPolicy))
+	ctx := context.Background()
+
+	created, err := c.Create(ctx, CreateUserRequest{Email: "john@example.com", FirstName: "John", LastName: "Doe"})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if created.ID == "" || created.Status != UserStatusActive || created.TenantID != domain.DefaultTenantID {
+		t.Errorf("Create() = %+v", created)
+	}
+
+	got, err := c.Get(ctx, created.ID)
+	if err != nil || got.Email != "john@example.com" {
+		t.Fatalf("Get() = %+v, %v", got, err)
+	}
+
+	name := "Johnny"
+	updated, err := c.Update(ctx, created.ID, UpdateUserRequest{FirstName: &name})
+	if err != nil || updated.FirstName != name || updated.LastName != "Doe" {
+		t.Fatalf("Update() = %+v, %v", updated, err)
+	}
+
+	if err := c.Delete(ctx, created.ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+
+	_, err = c.Get(ctx, created.ID)
+	if !errors.Is(err, ErrUserNotFound) {
+		t.Fatalf("Get() after delete error = %v, want ErrUserNotFound", err)
+	}
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound || apiErr.RequestID == "" {
+		t.Errorf("Get() error = %+v, want 404 with request ID", apiErr)
+	}
+}
+
+func TestClient_Errors(t *testing.T) {
+	for _, format := range []string{handler.ErrorFormatProblem, handler.ErrorFormatLegacy} {
+		t.Run(format, func(t *testing.T) {
+			ts := newTestServer(t, format)
+			c := New(ts.URL, WithRetryPolicy(testRetryPolicy))
+			ctx := context.Background()
+
+			_, err := c.Create(ctx, CreateUserRequest{Email: "not-an-email", LastName: "Doe"})
+			var apiErr *Error
+			if !errors.As(err, &apiErr) || !errors.Is(err, ErrValidationFailed) {
+				t.Fatalf("Create() error = %v, want ErrValidationFailed", err)
+			}
+			if apiErr.StatusCode != http.StatusUnprocessableEntity || apiErr.Message == "" || len(apiErr.Fields) != 2 {
+				t.Errorf("Create() error = %+v", apiErr)
+			}
+
+			_, err = c.Get(ctx, "nope")
+			if !errors.Is(err, ErrInvalidID) || errors.Is(err, ErrUserNotFound) {
+				t.Errorf("Get() error = %v, want ErrInvalidID", err)
+			}
+			if got := len(ts.recorded()); got != 2 {
+				t.Errorf("server got %d requests, want 2 (client errors are not retried)", got)
+			}
+		})
+	}
+}
+
+func TestClient_List(t *testing.T) {
+	ts := newTestServer(t, handler.ErrorFormatProblem)
+	c := New(ts.URL, WithRetryPolicy(testRetryPolicy))
+	ctx := context.Background()
+
+	const total = 45
+	for range total {
+		ts.repo.users = append(ts.repo.users, &domain.User{ID: uuid.New(), Status: domain.UserStatusActive})
+	}
+
+	seen := make(map[string]bool)
+	for user, err := range c.List(ctx, ListOptions{PageSize: 20}) {
+		if err != nil {
+			t.Fatalf("List() error = %v", err)
+		}
+		seen[user.ID] = true
+	}
+	if len(seen) != total {
+		t.Errorf("List() yielded %d users, want %d", len(seen), total)
+	}
+	if got := len(ts.recorded()); got != 3 {
+		t.Errorf("List() made %d requests, want 3", got)
+	}
+
+	n := 0
+	for range c.List(ctx, ListOptions{PageSize: 20}) {
+		n++
+		if n == 5 {
+			break
+		}
+	}
+	if got := len(ts.recorded()); got != 4 {
+		t.Errorf("stopping early made %d requests in total, want 4", got)
+	}
+}
+
+func TestClient_RetriesCreateOnce(t *testing.T) {
+	ts := newTestServer(t, handler.ErrorFormatProblem)
+	ts.failResponses.Store(2)
+	c := New(ts.URL, WithRetryPolicy(testRetryPolicy))
+
+	user, err := c.Create(context.Background(), CreateUserRequest{Email: "john@example.com", FirstName: "John", LastName: "Doe"})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if user.Email != "john@example.com" || ts.repo.count() != 1 {
+		t.Errorf("Create() = %+v with %d stored users, want one", user, ts.repo.count())
+	}
+
+	requests := ts.recorded()
+	if len(requests) != 3 {
+		t.Fatalf("server got %d requests, want 3", len(requests))
+	}
+	key, requestID := requests[0].Header.Get(IdempotencyKeyHeader), requests[0].Header.Get(RequestIDHeader)
+	for _, r := range requests {
+		if key == "" || r.Header.Get(IdempotencyKeyHeader) != key || r.Header.Get(RequestIDHeader) != requestID {
+			t.Errorf("attempt headers = %v, want the same Idempotency-Key and X-Request-ID", r.Header)
+		}
+	}
+}
+
+func TestClient_GivesUpAfterMaxAttempts(t *testing.T) {
+	ts := newTestServer(t, handler.ErrorFormatProblem)
+	ts.failResponses.Store(10)
+	c := New(ts.URL, WithRetryPolicy(testRetryPolicy))
+
+	_, err := c.Get(context.Background(), uuid.NewString())
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("Get() error = %v, want 503", err)
+	}
+	if got := len(ts.recorded()); got != testRetryPolicy.MaxAttempts {
+		t.Errorf("server got %d requests, want %d", got, testRetryPolicy.MaxAttempts)
+	}
+}
+
+func TestClient_Headers(t *testing.T) {
+	ts := newTestServer(t, handler.ErrorFormatProblem)
+	c := New(ts.URL, WithTenant("acme"), WithActor("admin-1", "admin", "support"))
+	ctx := ContextWithRequestID(context.Background(), "req-42")
+
+	_, err := c.Get(ctx, uuid.NewString())
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.RequestID != "req-42" {
+		t.Errorf("Get() error = %+v, want request ID req-42", apiErr)
+	}
+
+	r := ts.recorded()[0]
+	for header, want := range map[string]string{
+		RequestIDHeader:  "req-42",
+		TenantHeader:     "acme",
+		ActorIDHeader:    "admin-1",
+		ActorRolesHeader: "admin,support",
+	} {
+		if got := r.Header.Get(header); got != want {
+			t.Errorf("%s = %q, want %q", header, got, want)
+		}
+	}
+}
+
+func TestErrorCodesMatchHandler(t *testing.T) {
+	codes := map[string]string{
+		CodeInvalidRequest:       handler.ErrCodeInvalidRequest,
+		CodeInvalidID:            handler.ErrCodeInvalidID,
+		CodeUserNotFound:         handler.ErrCodeUserNotFound,
+		CodeEmailExists:          handler.ErrCodeEmailExists,
+		CodeInternalError:        handler.ErrCodeInternalError,
+		CodeInvalidTransition:    handler.ErrCodeInvalidTransition,
+		CodeForbidden:            handler.ErrCodeForbidden,
+		CodeUnknownAction:        handler.ErrCodeUnknownAction,
+		CodeInvalidToken:         handler.ErrCodeInvalidToken,
+		CodeTokenExpired:         handler.ErrCodeTokenExpired,
+		CodeEmailAlreadyVerified: handler.ErrCodeEmailAlreadyVerified,
+		CodeNotImplemented:       handler.ErrCodeNotImplemented,
+		CodeValidationFailed:     handler.ErrCodeValidationFailed,
+		CodeUnsupportedMediaType: handler.ErrCodeUnsupportedMediaType,
+		CodeRequestTooLarge:      handler.ErrCodeRequestTooLarge,
+		CodeIdempotencyKeyReused: handler.ErrCodeIdempotencyKeyReused,
+		CodeIdempotencyKeyInUse:  handler.ErrCodeIdempotencyKeyInUse,
+		CodeRateLimited:          handler.ErrCodeRateLimited,
+		CodeAttributeNotFound:    handler.ErrCodeAttributeNotFound,
+		CodeIdentityNotFound:     handler.ErrCodeIdentityNotFound,
+		CodeIdentityConflict:     handler.ErrCodeIdentityConflict,
+		CodeTenantRequired:       handler.ErrCodeTenantRequired,
+		CodeTenantNotFound:       handler.ErrCodeTenantNotFound,
+		CodeTenantExists:         handler.ErrCodeTenantExists,
+		CodeTenantNotEmpty:       handler.ErrCodeTenantNotEmpty,
+		CodeWebhookNotFound:      handler.ErrCodeWebhookNotFound,
+	}
+	for client, server := range codes {
+		if client != server {
+			t.Errorf("client code %q != handler code %q", client, server)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Error codes returned by the API, the same as the handler's ErrCode
+// constants.
+const (
+	CodeInvalidRequest = "INVALID_REQUEST"
+	CodeInvalidID      = "INVALID_ID"
+	CodeUserNotFound   = "USER_NOT_FOUND"
+	CodeEmailExists    = "EMAIL_EXISTS"
+	CodeInternalError  = "INTERNAL_ERROR"
+
+	CodeInvalidTransition = "INVALID_STATUS_TRANSITION"
+	CodeForbidden         = "FORBIDDEN"
+	CodeUnknownAction     = "UNKNOWN_ACTION"
+
+	CodeInvalidToken         = "INVALID_TOKEN"
+	CodeTokenExpired         = "TOKEN_EXPIRED"
+	CodeEmailAlreadyVerified = "EMAIL_ALREADY_VERIFIED"
+	CodeNotImplemented       = "NOT_IMPLEMENTED"
+
+	CodeValidationFailed = "VALIDATION_FAILED"
+
+	CodeUnsupportedMediaType = "UNSUPPORTED_MEDIA_TYPE"
+	CodeRequestTooLarge      = "REQUEST_TOO_LARGE"
+
+	CodeIdempotencyKeyReused = "IDEMPOTENCY_KEY_REUSED"
+	CodeIdempotencyKeyInUse  = "IDEMPOTENCY_KEY_IN_USE"
+	CodeRateLimited          = "RATE_LIMITED"
+
+	CodeAttributeNotFound = "ATTRIBUTE_NOT_FOUND"
+	CodeIdentityNotFound  = "IDENTITY_NOT_FOUND"
+	CodeIdentityConflict  = "IDENTITY_CONFLICT"
+
+	CodeTenantRequired = "TENANT_REQUIRED"
+	CodeTenantNotFound = "TENANT_NOT_FOUND"
+	CodeTenantExists   = "TENANT_EXISTS"
+	CodeTenantNotEmpty = "TENANT_NOT_EMPTY"
+
+	CodeWebhookNotFound = "WEBHOOK_NOT_FOUND"
+)
+
+// Sentinel errors to compare with errors.Is. They match any *Error with the
+// same code.
+var (
+	ErrInvalidID         = &Error{Code: CodeInvalidID}
+	ErrUserNotFound      = &Error{Code: CodeUserNotFound}
+	ErrEmailExists       = &Error{Code: CodeEmailExists}
+	ErrInvalidTransition = &Error{Code: CodeInvalidTransition}
+	ErrForbidden         = &Error{Code: CodeForbidden}
+	ErrValidationFailed  = &Error{Code: CodeValidationFailed}
+	ErrRateLimited       = &Error{Code: CodeRateLimited}
+	ErrTenantRequired    = &Error{Code: CodeTenantRequired}
+)
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Error is an error response of the API, decoded from either error format.
+type Error struct {
+	StatusCode int
+	Code       string
+	Message    string
+	RequestID  string
+	Details    []string
+	Fields     []FieldError
+}
+
+func (e *Error) Error() string {
+	if e.StatusCode == 0 {
+		return e.Code
+	}
+	return fmt.Sprintf("user api: %d %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// errorBody holds the fields of both the RFC 7807 and the legacy format.
+type errorBody struct {
+	Code      string       `json:"code"`
+	Message   string       `json:"message"`
+	Detail    string       `json:"detail"`
+	RequestID string       `json:"requestId"`
+	Details   []string     `json:"details"`
+	Fields    []FieldError `json:"fields"`
+}
+
+func decodeError(resp *http.Response) error {
+	apiErr := &Error{
+		StatusCode: resp.StatusCode,
+		RequestID:  resp.Header.Get(RequestIDHeader),
+	}
+
+	var body errorBody
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err := json.Unmarshal(data, &body); err != nil || body.Code == "" {
+		// Not an API error, e.g. from a proxy in front of it.
+		apiErr.Message = http.StatusText(resp.StatusCode)
+		return apiErr
+	}
+
+	apiErr.Code = body.Code
+	apiErr.Message = body.Message
+	if apiErr.Message == "" {
+		apiErr.Message = body.Detail
+	}
+	if body.RequestID != "" {
+		apiErr.RequestID = body.RequestID
+	}
+	apiErr.Details = body.Details
+	apiErr.Fields = body.Fields
+	return apiErr
+}
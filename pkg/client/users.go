@@ -0,0 +1,158 @@
+package client
+
+import (
+	"context"
+	"iter"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type UserStatus string
+
+const (
+	UserStatusActive              UserStatus = "active"
+	UserStatusInactive            UserStatus = "inactive"
+	UserStatusSuspended           UserStatus = "suspended"
+	UserStatusPendingVerification UserStatus = "pending_verification"
+)
+
+type User struct {
+	ID             string         `json:"id"`
+	TenantID       string         `json:"tenantId"`
+	Email          string         `json:"email"`
+	EmailVerified  bool           `json:"emailVerified"`
+	PendingEmail   string         `json:"pendingEmail,omitempty"`
+	FirstName      string         `json:"firstName"`
+	LastName       string         `json:"lastName"`
+	Status         UserStatus     `json:"status"`
+	StatusReason   string         `json:"statusReason,omitempty"`
+	SuspendedUntil *time.Time     `json:"suspendedUntil,omitempty"`
+	Attributes     map[string]any `json:"attributes,omitempty"`
+	CreatedAt      time.Time      `json:"createdAt"`
+	UpdatedAt      time.Time      `json:"updatedAt"`
+}
+
+type CreateUserRequest struct {
+	Email      string         `json:"email"`
+	FirstName  string         `json:"firstName"`
+	LastName   string         `json:"lastName"`
+	Attributes map[string]any `json:"attributes,omitempty"`
+}
+
+// UpdateUserRequest changes only the fields that are set. Attributes are
+// merged into the existing ones; a nil value removes the key.
+type UpdateUserRequest struct {
+	Email      *string        `json:"email,omitempty"`
+	FirstName  *string        `json:"firstName,omitempty"`
+	LastName   *string        `json:"lastName,omitempty"`
+	Status     *UserStatus    `json:"status,omitempty"`
+	Attributes map[string]any `json:"attributes,omitempty"`
+}
+
+type Pagination struct {
+	Total  int `json:"total"`
+	Limit  int `json:"limit"`
+	Offset int `json:"offset"`
+}
+
+type UserList struct {
+	Data       []User     `json:"data"`
+	Pagination Pagination `json:"pagination"`
+}
+
+type ListOptions struct {
+	// PageSize is the number of users fetched per request, at most 100.
+	PageSize int
+	Offset   int
+	// Attributes keeps the users whose attributes have these values.
+	Attributes map[string]string
+}
+
+const defaultPageSize = 20
+
+// Create creates a user. The request is sent with a new Idempotency-Key so
+// that it can be retried without creating the user twice.
+func (c *Client) Create(ctx context.Context, req CreateUserRequest) (*User, error) {
+	var user User
+	err := c.do(ctx, request{
+		method:         http.MethodPost,
+		path:           "/api/v1/users",
+		body:           req,
+		idempotencyKey: uuid.NewString(),
+	}, &user)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (c *Client) Get(ctx context.Context, id string) (*User, error) {
+	var user User
+	if err := c.do(ctx, request{method: http.MethodGet, path: userPath(id)}, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// ListPage fetches a single page of users.
+func (c *Client) ListPage(ctx context.Context, opts ListOptions) (*UserList, error) {
+	if opts.PageSize <= 0 {
+		opts.PageSize = defaultPageSize
+	}
+	query := url.Values{}
+	query.Set("limit", strconv.Itoa(opts.PageSize))
+	query.Set("offset", strconv.Itoa(opts.Offset))
+	for key, value := range opts.Attributes {
+		query.Set("attr."+key, value)
+	}
+
+	var list UserList
+	if err := c.do(ctx, request{method: http.MethodGet, path: "/api/v1/users", query: query}, &list); err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// List iterates over every user matching opts, fetching pages as needed. It
+// stops at the first error, which it yields with a zero User.
+func (c *Client) List(ctx context.Context, opts ListOptions) iter.Seq2[User, error] {
+	return func(yield func(User, error) bool) {
+		for {
+			page, err := c.ListPage(ctx, opts)
+			if err != nil {
+				yield(User{}, err)
+				return
+			}
+			for _, user := range page.Data {
+				if !yield(user, nil) {
+					return
+				}
+			}
+
+			opts.Offset += len(page.Data)
+			if len(page.Data) == 0 || opts.Offset >= page.Pagination.Total {
+				return
+			}
+		}
+	}
+}
+
+func (c *Client) Update(ctx context.Context, id string, req UpdateUserRequest) (*User, error) {
+	var user User
+	if err := c.do(ctx, request{method: http.MethodPut, path: userPath(id), body: req}, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (c *Client) Delete(ctx context.Context, id string) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: userPath(id)}, nil)
+}
+
+func userPath(id string) string {
+	return "/api/v1/users/" + url.PathEscape(id)
+}
@@ -0,0 +1,281 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// Headers added to the messages routed to the retry and dead letter topics.
+const (
+	HeaderRetryCount    = "retry-count"
+	HeaderError         = "error"
+	HeaderOriginalTopic = "original-topic"
+)
+
+// Handler processes an event. Returning an error makes the consumer retry
+// it and, if it keeps failing, route it to the retry or dead letter topic.
+type Handler func(ctx context.Context, event Event) error
+
+// Reader is the part of *kafka.Reader used by the consumer. The reader must
+// belong to a consumer group so that offsets can be committed.
+type Reader interface {
+	FetchMessage(ctx context.Context) (kafka.Message, error)
+	CommitMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+// Writer is the part of *kafka.Writer used to route failed events. The
+// writer must not set a Topic, as each message names its own.
+type Writer interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+}
+
+type Consumer struct {
+	reader   Reader
+	handlers map[Type]Handler
+	fallback Handler
+	dedupe   DedupeStore
+	logger   *slog.Logger
+
+	maxAttempts int
+	backoff     time.Duration
+
+	writer     Writer
+	retryTopic string
+	maxRetries int
+	dlqTopic   string
+}
+
+type Option func(*Consumer)
+
+func WithLogger(logger *slog.Logger) Option {
+	return func(c *Consumer) {
+		c.logger = logger
+	}
+}
+
+// WithDedupe skips events whose ID is already in store and records the
+// events handled successfully.
+func WithDedupe(store DedupeStore) Option {
+	return func(c *Consumer) {
+		c.dedupe = store
+	}
+}
+
+// WithAttempts sets how many times a failing event is handled before it is
+// routed, waiting backoff, doubled each time, between attempts.
+func WithAttempts(maxAttempts int, backoff time.Duration) Option {
+	return func(c *Consumer) {
+		c.maxAttempts = maxAttempts
+		c.backoff = backoff
+	}
+}
+
+// WithRetryTopic republishes events that still fail to topic, so that a
+// consumer of that topic retries them later without blocking this one. An
+// event is republished at most maxRetries times, then sent to the dead
+// letter topic.
+func WithRetryTopic(writer Writer, topic string, maxRetries int) Option {
+	return func(c *Consumer) {
+		c.writer = writer
+		c.retryTopic = topic
+		c.maxRetries = maxRetries
+	}
+}
+
+// WithDeadLetterTopic publishes events that cannot be processed, including
+// messages that are not valid events, to topic.
+func WithDeadLetterTopic(writer Writer, topic string) Option {
+	return func(c *Consumer) {
+		c.writer = writer
+		c.dlqTopic = topic
+	}
+}
+
+func NewConsumer(reader Reader, opts ...Option) *Consumer {
+	c := &Consumer{
+		reader:      reader,
+		handlers:    make(map[Type]Handler),
+		logger:      slog.New(slog.DiscardHandler),
+		maxAttempts: 3,
+		backoff:     time.Second,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.maxAttempts < 1 {
+		c.maxAttempts = 1
+	}
+	return c
+}
+
+// Handle registers the handler of an event type. Events of types without a
+// handler go to the fallback handler, if any, or are skipped.
+func (c *Consumer) Handle(eventType Type, handler Handler) {
+	c.handlers[eventType] = handler
+}
+
+// HandleOther registers the fallback handler.
+func (c *Consumer) HandleOther(handler Handler) {
+	c.fallback = handler
+}
+
+// Run processes messages until ctx is canceled, committing each one once it
+// has been handled, skipped or routed. It returns nil when ctx is canceled.
+// A failing event that cannot be routed stops the consumer without
+// committing it, so it is delivered again on restart.
+func (c *Consumer) Run(ctx context.Context) error {
+	for {
+		msg, err := c.reader.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("fetch message: %w", err)
+		}
+
+		if err := c.process(ctx, msg); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		if err := c.reader.CommitMessages(ctx, msg); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("commit message: %w", err)
+		}
+	}
+}
+
+func (c *Consumer) Close() error {
+	return c.reader.Close()
+}
+
+func (c *Consumer) process(ctx context.Context, msg kafka.Message) error {
+	event, err := Decode(msg.Value)
+	if err != nil {
+		c.logger.Error("invalid event",
+			slog.String("topic", msg.Topic),
+			slog.Int64("offset", msg.Offset),
+			slog.String("error", err.Error()),
+		)
+		if c.dlqTopic == "" {
+			return nil
+		}
+		return c.route(ctx, msg, c.dlqTopic, err)
+	}
+
+	handler, ok := c.handlers[event.Type]
+	if !ok {
+		handler = c.fallback
+	}
+	if handler == nil {
+		return nil
+	}
+
+	if c.dedupe != nil {
+		seen, err := c.dedupe.Seen(ctx, event.ID)
+		if err != nil {
+			return fmt.Errorf("dedupe event %s: %w", event.ID, err)
+		}
+		if seen {
+			c.logger.Debug("duplicate event skipped", slog.String("event_id", event.ID))
+			return nil
+		}
+	}
+
+	if err := c.handle(ctx, handler, event); err != nil {
+		c.logger.Error("failed to handle event",
+			slog.String("event_id", event.ID),
+			slog.String("event_type", string(event.Type)),
+			slog.String("error", err.Error()),
+		)
+		retries := retryCount(msg)
+		switch {
+		case c.retryTopic != "" && retries < c.maxRetries:
+			return c.route(ctx, msg, c.retryTopic, err)
+		case c.dlqTopic != "":
+			return c.route(ctx, msg, c.dlqTopic, err)
+		default:
+			return fmt.Errorf("handle event %s: %w", event.ID, err)
+		}
+	}
+
+	if c.dedupe != nil {
+		if err := c.dedupe.Mark(ctx, event.ID); err != nil {
+			return fmt.Errorf("dedupe event %s: %w", event.ID, err)
+		}
+	}
+	return nil
+}
+
+func (c *Consumer) handle(ctx context.Context, handler Handler, event Event) error {
+	delay := c.backoff
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = handler(ctx, event); err == nil || attempt >= c.maxAttempts {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return errors.Join(err, ctx.Err())
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// route publishes msg to topic with the retry count incremented and the
+// cause of the failure.
+func (c *Consumer) route(ctx context.Context, msg kafka.Message, topic string, cause error) error {
+	originalTopic := msg.Topic
+	headers := make([]kafka.Header, 0, len(msg.Headers)+3)
+	for _, h := range msg.Headers {
+		switch h.Key {
+		case HeaderRetryCount, HeaderError:
+		case HeaderOriginalTopic:
+			originalTopic = string(h.Value)
+		default:
+			headers = append(headers, h)
+		}
+	}
+	headers = append(headers,
+		kafka.Header{Key: HeaderRetryCount, Value: []byte(strconv.Itoa(retryCount(msg) + 1))},
+		kafka.Header{Key: HeaderError, Value: []byte(cause.Error())},
+		kafka.Header{Key: HeaderOriginalTopic, Value: []byte(originalTopic)},
+	)
+
+	err := c.writer.WriteMessages(ctx, kafka.Message{
+		Topic:   topic,
+		Key:     msg.Key,
+		Value:   msg.Value,
+		Headers: headers,
+	})
+	if err != nil {
+		return fmt.Errorf("route message to %s: %w", topic, err)
+	}
+	c.logger.Warn("event routed",
+		slog.String("topic", topic),
+		slog.String("original_topic", originalTopic),
+		slog.Int64("offset", msg.Offset),
+	)
+	return nil
+}
+
+func retryCount(msg kafka.Message) int {
+	for _, h := range msg.Headers {
+		if h.Key == HeaderRetryCount {
+			n, _ := strconv.Atoi(string(h.Value))
+			return n
+		}
+	}
+	return 0
+}
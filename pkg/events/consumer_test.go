@@ -0,0 +1,267 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/segmentio/kafka-go"
+
+	"github.com/giannuccilli/user-api/internal/domain"
+)
+
+// fakeReader returns its messages in order, then blocks until the context is
+// canceled.
+type fakeReader struct {
+	msgs      []kafka.Message
+	committed []kafka.Message
+	cancel    context.CancelFunc
+}
+
+func (r *fakeReader) FetchMessage(ctx context.Context) (kafka.Message, error) {
+	if len(r.msgs) == 0 {
+		r.cancel()
+		<-ctx.Done()
+		return kafka.Message{}, ctx.Err()
+	}
+	msg := r.msgs[0]
+	r.msgs = r.msgs[1:]
+	return msg, nil
+}
+
+func (r *fakeReader) CommitMessages(ctx context.Context, msgs ...kafka.Message) error {
+	r.committed = append(r.committed, msgs...)
+	return nil
+}
+
+func (r *fakeReader) Close() error { return nil }
+
+type fakeWriter struct {
+	msgs []kafka.Message
+}
+
+func (w *fakeWriter) WriteMessages(ctx context.Context, msgs ...kafka.Message) error {
+	w.msgs = append(w.msgs, msgs...)
+	return nil
+}
+
+func eventMessage(t *testing.T, eventID uuid.UUID, eventType domain.EventType, headers ...kafka.Header) kafka.Message {
+	t.Helper()
+	payload, err := json.Marshal(domain.UserEvent{
+		EventID:   eventID,
+		EventType: eventType,
+		TenantID:  "acme",
+		Timestamp: time.Now().UTC(),
+		Data:      domain.EventData{UserID: uuid.New(), Reason: domain.SuspensionReasonFraud},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return kafka.Message{Topic: "user-events", Value: payload, Headers: headers}
+}
+
+func run(t *testing.T, c *Consumer, reader *fakeReader) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	reader.cancel = cancel
+	if err := c.Run(ctx); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+}
+
+func header(msg kafka.Message, key string) string {
+	for _, h := range msg.Headers {
+		if h.Key == key {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
+
+func TestConsumer_DispatchesAndDedupes(t *testing.T) {
+	duplicate := uuid.New()
+	reader := &fakeReader{msgs: []kafka.Message{
+		eventMessage(t, duplicate, domain.EventTypeUserCreated),
+		eventMessage(t, uuid.New(), domain.EventTypeUserSuspended),
+		eventMessage(t, duplicate, domain.EventTypeUserCreated),
+		eventMessage(t, uuid.New(), domain.EventTypeUserDeleted),
+	}}
+
+	var got []Type
+	c := NewConsumer(reader, WithDedupe(NewMemoryDedupeStore(10)))
+	c.Handle(UserCreated, func(ctx context.Context, e Event) error {
+		got = append(got, e.Type)
+		return nil
+	})
+	c.Handle(UserSuspended, func(ctx context.Context, e Event) error {
+		if e.TenantID != "acme" || e.Data.Reason != "fraud" || e.Data.UserID == "" {
+			t.Errorf("event = %+v", e)
+		}
+		got = append(got, e.Type)
+		return nil
+	})
+	run(t, c, reader)
+
+	if len(got) != 2 || got[0] != UserCreated || got[1] != UserSuspended {
+		t.Errorf("handled %v, want [user.created user.suspended]", got)
+	}
+	if len(reader.committed) != 4 {
+		t.Errorf("committed %d messages, want 4", len(reader.committed))
+	}
+}
+
+func TestConsumer_RetriesThenRoutes(t *testing.T) {
+	maxRetries := 2
+	tests := []struct {
+		name       string
+		failures   int
+		retryCount int
+		dlq        bool
+		wantTopic  string
+		wantCalls  int
+	}{
+		{name: "succeeds on a later attempt", failures: 2, wantCalls: 3},
+		{name: "routed to the retry topic", failures: 10, wantTopic: "user-events.retry", wantCalls: 3},
+		{name: "retries exhausted", failures: 10, retryCount: maxRetries, dlq: true, wantTopic: "user-events.dlq", wantCalls: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := eventMessage(t, uuid.New(), domain.EventTypeUserUpdated,
+				kafka.Header{Key: "event-type", Value: []byte(domain.EventTypeUserUpdated)})
+			if tt.retryCount > 0 {
+				msg.Headers = append(msg.Headers,
+					kafka.Header{Key: HeaderRetryCount, Value: []byte(strconv.Itoa(tt.retryCount))},
+					kafka.Header{Key: HeaderOriginalTopic, Value: []byte("user-events")})
+				msg.Topic = "user-events.retry"
+			}
+			reader := &fakeReader{msgs: []kafka.Message{msg}}
+			writer := &fakeWriter{}
+
+			calls := 0
+			c := NewConsumer(reader,
+				WithAttempts(3, time.Millisecond),
+				WithRetryTopic(writer, "user-events.retry", maxRetries),
+				WithDeadLetterTopic(writer, "user-events.dlq"),
+			)
+			c.Handle(UserUpdated, func(ctx context.Context, e Event) error {
+				calls++
+				if calls <= tt.failures {
+					return errors.New("downstream unavailable")
+				}
+				return nil
+			})
+			run(t, c, reader)
+
+			if calls != tt.wantCalls {
+				t.Errorf("handler called %d times, want %d", calls, tt.wantCalls)
+			}
+			if len(reader.committed) != 1 {
+				t.Errorf("committed %d messages, want 1", len(reader.committed))
+			}
+			if tt.wantTopic == "" {
+				if len(writer.msgs) != 0 {
+					t.Errorf("routed %d messages, want none", len(writer.msgs))
+				}
+				return
+			}
+
+			if len(writer.msgs) != 1 {
+				t.Fatalf("routed %d messages, want 1", len(writer.msgs))
+			}
+			routed := writer.msgs[0]
+			if routed.Topic != tt.wantTopic || string(routed.Value) != string(msg.Value) {
+				t.Errorf("routed to %q, want %q", routed.Topic, tt.wantTopic)
+			}
+			if got := header(routed, HeaderRetryCount); got != strconv.Itoa(tt.retryCount+1) {
+				t.Errorf("retry count = %q, want %d", got, tt.retryCount+1)
+			}
+			if header(routed, HeaderError) != "downstream unavailable" || header(routed, HeaderOriginalTopic) != "user-events" ||
+				header(routed, "event-type") != string(domain.EventTypeUserUpdated) {
+				t.Errorf("routed headers = %v", routed.Headers)
+			}
+		})
+	}
+}
+
+func TestConsumer_FailureWithoutRouting(t *testing.T) {
+	reader := &fakeReader{msgs: []kafka.Message{eventMessage(t, uuid.New(), domain.EventTypeUserCreated)}}
+	reader.cancel = func() {}
+	c := NewConsumer(reader, WithAttempts(1, 0))
+	c.Handle(UserCreated, func(ctx context.Context, e Event) error {
+		return errors.New("boom")
+	})
+
+	if err := c.Run(context.Background()); err == nil {
+		t.Fatal("Run() error = nil, want the handler error")
+	}
+	if len(reader.committed) != 0 {
+		t.Errorf("committed %d messages, want none", len(reader.committed))
+	}
+}
+
+func TestConsumer_InvalidAndUnhandledMessages(t *testing.T) {
+	reader := &fakeReader{msgs: []kafka.Message{
+		{Topic: "user-events", Value: []byte("not json")},
+		eventMessage(t, uuid.New(), domain.EventTypeUserEmailChanged),
+		eventMessage(t, uuid.New(), domain.EventTypeUserActivated),
+	}}
+	writer := &fakeWriter{}
+
+	var other []Type
+	c := NewConsumer(reader, WithDeadLetterTopic(writer, "user-events.dlq"))
+	c.Handle(UserActivated, func(ctx context.Context, e Event) error { return nil })
+	c.HandleOther(func(ctx context.Context, e Event) error {
+		other = append(other, e.Type)
+		return nil
+	})
+	run(t, c, reader)
+
+	if len(writer.msgs) != 1 || writer.msgs[0].Topic != "user-events.dlq" {
+		t.Errorf("routed %v, want the invalid message in the DLQ", writer.msgs)
+	}
+	if len(other) != 1 || other[0] != UserEmailChanged {
+		t.Errorf("fallback handled %v, want [user.email_changed]", other)
+	}
+	if len(reader.committed) != 3 {
+		t.Errorf("committed %d messages, want 3", len(reader.committed))
+	}
+}
+
+func TestMemoryDedupeStore_Evicts(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryDedupeStore(2)
+	for _, id := range []string{"a", "b", "c"} {
+		store.Mark(ctx, id)
+	}
+
+	for id, want := range map[string]bool{"a": false, "b": true, "c": true} {
+		if seen, _ := store.Seen(ctx, id); seen != want {
+			t.Errorf("Seen(%q) = %v, want %v", id, seen, want)
+		}
+	}
+}
+
+func TestTypesMatchDomain(t *testing.T) {
+	types := map[Type]domain.EventType{
+		UserCreated:       domain.EventTypeUserCreated,
+		UserUpdated:       domain.EventTypeUserUpdated,
+		UserDeleted:       domain.EventTypeUserDeleted,
+		UserSuspended:     domain.EventTypeUserSuspended,
+		UserReactivated:   domain.EventTypeUserReactivated,
+		UserActivated:     domain.EventTypeUserActivated,
+		UserDeactivated:   domain.EventTypeUserDeactivated,
+		UserEmailVerified: domain.EventTypeUserEmailVerified,
+		UserEmailChanged:  domain.EventTypeUserEmailChanged,
+	}
+	for got, want := range types {
+		if string(got) != string(want) {
+			t.Errorf("type %q != domain type %q", got, want)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package events
+
+import (
+	"container/list"
+	"context"
+	"sync"
+)
+
+// DedupeStore remembers the events already processed, so that redelivered
+// events are skipped. Consumers running on several instances need a shared
+// store, e.g. backed by a database table keyed by event ID.
+type DedupeStore interface {
+	Seen(ctx context.Context, eventID string) (bool, error)
+	Mark(ctx context.Context, eventID string) error
+}
+
+// MemoryDedupeStore keeps the IDs of the last processed events in memory.
+type MemoryDedupeStore struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List
+	ids      map[string]*list.Element
+}
+
+func NewMemoryDedupeStore(capacity int) *MemoryDedupeStore {
+	return &MemoryDedupeStore{
+		capacity: capacity,
+		order:    list.New(),
+		ids:      make(map[string]*list.Element),
+	}
+}
+
+func (s *MemoryDedupeStore) Seen(ctx context.Context, eventID string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.ids[eventID]
+	return ok, nil
+}
+
+func (s *MemoryDedupeStore) Mark(ctx context.Context, eventID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.ids[eventID]; ok {
+		return nil
+	}
+	s.ids[eventID] = s.order.PushBack(eventID)
+	if s.capacity > 0 && s.order.Len() > s.capacity {
+		oldest := s.order.Front()
+		s.order.Remove(oldest)
+		delete(s.ids, oldest.Value.(string))
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+// Package events consumes the user events the API publishes to Kafka.
+//
+//	c := events.NewConsumer(kafka.NewReader(kafka.ReaderConfig{
+//		Brokers: []string{"localhost:9092"},
+//		Topic:   "user-events",
+//		GroupID: "billing",
+//	}), events.WithDedupe(events.NewMemoryDedupeStore(10000)))
+//	c.Handle(events.UserCreated, func(ctx context.Context, e events.Event) error {
+//		...
+//	})
+//	err := c.Run(ctx)
+//
+// Webhook receivers decode the request body with Decode after checking it
+// with VerifySignature.
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+type Type string
+
+const (
+	UserCreated Type = "user.created"
+	UserUpdated Type = "user.updated"
+	UserDeleted Type = "user.deleted"
+
+	UserSuspended   Type = "user.suspended"
+	UserReactivated Type = "user.reactivated"
+	UserActivated   Type = "user.activated"
+	UserDeactivated Type = "user.deactivated"
+
+	UserEmailVerified Type = "user.email_verified"
+	UserEmailChanged  Type = "user.email_changed"
+)
+
+// Event is a user event as published by the API.
+type Event struct {
+	ID        string    `json:"eventId"`
+	Type      Type      `json:"eventType"`
+	TenantID  string    `json:"tenantId"`
+	Timestamp time.Time `json:"timestamp"`
+	Data      Data      `json:"data"`
+}
+
+type Data struct {
+	UserID         string         `json:"userId"`
+	Reason         string         `json:"reason,omitempty"`
+	SuspendedUntil *time.Time     `json:"suspendedUntil,omitempty"`
+	Attributes     map[string]any `json:"attributes,omitempty"`
+}
+
+// Decode parses a message value into an Event.
+func Decode(value []byte) (Event, error) {
+	var event Event
+	if err := json.Unmarshal(value, &event); err != nil {
+		return Event{}, fmt.Errorf("decode event: %w", err)
+	}
+	if event.ID == "" || event.Type == "" {
+		return Event{}, fmt.Errorf("decode event: missing eventId or eventType")
+	}
+	return event, nil
+}
@@ -0,0 +1,44 @@
+package events
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerifySignature(t *testing.T) {
+	body := []byte(`{"eventId":"1","eventType":"user.created"}`)
+	now := time.Now()
+	valid := Sign("s3cret", now, body)
+	_, validSig, _ := strings.Cut(valid, ",")
+
+	tests := []struct {
+		name      string
+		secret    string
+		header    string
+		body      []byte
+		tolerance time.Duration
+		wantErr   bool
+	}{
+		{name: "valid", secret: "s3cret", header: valid, body: body, tolerance: time.Minute},
+		{name: "one of several signatures", secret: "s3cret", header: Sign("old", now, body) + "," + validSig, body: body},
+		{name: "wrong secret", secret: "other", header: valid, body: body, wantErr: true},
+		{name: "tampered body", secret: "s3cret", header: valid, body: []byte(`{}`), wantErr: true},
+		{name: "too old", secret: "s3cret", header: Sign("s3cret", now.Add(-time.Hour), body), body: body, tolerance: time.Minute, wantErr: true},
+		{name: "any age", secret: "s3cret", header: Sign("s3cret", now.Add(-time.Hour), body), body: body},
+		{name: "malformed", secret: "s3cret", header: "sha256=abc", body: body, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := VerifySignature(tt.secret, tt.header, tt.body, tt.tolerance)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("VerifySignature() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidSignature) {
+				t.Errorf("VerifySignature() error = %v, want %v", err, ErrInvalidSignature)
+			}
+		})
+	}
+}
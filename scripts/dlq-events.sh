@@ -14,6 +14,7 @@ echo ""
 docker-compose exec -T postgres psql -U userapi -d userapi -c "
 SELECT 
     id,
+    sink,
     event_type,
     user_id,
     error,
@@ -0,0 +1,17 @@
+#!/bin/bash
+
+set -e
+
+echo "=== Protobuf Code Generation ==="
+
+# Requires protoc, protoc-gen-go and protoc-gen-go-grpc:
+#   go install google.golang.org/protobuf/cmd/protoc-gen-go@latest
+#   go install google.golang.org/grpc/cmd/protoc-gen-go-grpc@latest
+cd "$(dirname "$0")/../api/proto"
+
+protoc \
+    --go_out=. --go_opt=paths=source_relative \
+    --go-grpc_out=. --go-grpc_opt=paths=source_relative \
+    user/v1/user.proto
+
+echo "=== Generated api/proto/user/v1 ==="